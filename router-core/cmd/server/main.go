@@ -1,6 +1,11 @@
 package main
 
-import "aigendrug.com/router-core/internal/interface/rest"
+import (
+	// timezones of tool schedules, the image may have no zoneinfo
+	_ "time/tzdata"
+
+	"aigendrug.com/router-core/internal/interface/rest"
+)
 
 // @title           ATP Central Router Core API
 // @version         1.0.0
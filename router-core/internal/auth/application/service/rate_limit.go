@@ -0,0 +1,171 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"aigendrug.com/router-core/internal/auth/domain"
+	"aigendrug.com/router-core/internal/config"
+)
+
+type bucketPolicy struct {
+	capacity  float64
+	perSecond float64
+}
+
+// RateLimitDecision is the outcome of a request against the token bucket of its client.
+//
+// - Limited: The class has a rate limit, the other fields are only set then.
+// - Limit / Remaining: Capacity of the bucket and tokens left.
+// - Reset: Time until the bucket is full again.
+// - RetryAfter: Time until the next token, when the request was rejected.
+type RateLimitDecision struct {
+	Limited    bool
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	Reset      time.Duration
+	RetryAfter time.Duration
+}
+
+// QuotaDecision is the outcome of an execution against the quotas of its client.
+// DailyLimit and MonthlyLimit are 0 when unlimited, RetryAfter is the time until the exceeded quota resets.
+type QuotaDecision struct {
+	Limited      bool
+	Allowed      bool
+	Usage        domain.ExecutionUsage
+	DailyLimit   int
+	MonthlyLimit int
+	RetryAfter   time.Duration
+}
+
+// RateLimitService applies the rate limits (RATE_LIMIT_*) of the API keys. The buckets and the usage are kept
+// in the database, so that the limits hold across replicas.
+type RateLimitService struct {
+	Repo              domain.RateLimitRepository
+	buckets           map[domain.RateLimitClass]bucketPolicy
+	dailyExecutions   int
+	monthlyExecutions int
+}
+
+func NewRateLimitService(config *config.Config, repo domain.RateLimitRepository) *RateLimitService {
+	s := &RateLimitService{
+		Repo:    repo,
+		buckets: map[domain.RateLimitClass]bucketPolicy{},
+	}
+
+	if config != nil {
+		limits := config.RateLimit
+		s.setBucket(domain.RateLimitClassSelect, limits.SelectPerMinute, limits.SelectBurst)
+		s.setBucket(domain.RateLimitClassExecute, limits.ExecutePerMinute, limits.ExecuteBurst)
+		s.setBucket(domain.RateLimitClassRead, limits.ReadPerMinute, limits.ReadBurst)
+		s.dailyExecutions = max(limits.DailyExecutions, 0)
+		s.monthlyExecutions = max(limits.MonthlyExecutions, 0)
+	}
+
+	return s
+}
+
+// setBucket limits the class to perMinute requests, with bursts up to burst requests (perMinute when not set).
+func (s *RateLimitService) setBucket(class domain.RateLimitClass, perMinute float64, burst int) {
+	if perMinute <= 0 {
+		return
+	}
+	capacity := float64(burst)
+	if burst <= 0 {
+		capacity = math.Max(math.Ceil(perMinute), 1)
+	}
+	s.buckets[class] = bucketPolicy{capacity: capacity, perSecond: perMinute / 60}
+}
+
+func (s *RateLimitService) Allow(
+	ctx context.Context, clientID int, class domain.RateLimitClass,
+) (RateLimitDecision, error) {
+	policy, ok := s.buckets[class]
+	if !ok {
+		return RateLimitDecision{Allowed: true}, nil
+	}
+
+	tokens, taken, err := s.Repo.TakeToken(ctx, clientID, class, policy.capacity, policy.perSecond)
+	if err != nil {
+		return RateLimitDecision{}, err
+	}
+
+	decision := RateLimitDecision{
+		Limited:   true,
+		Allowed:   taken,
+		Limit:     int(policy.capacity),
+		Remaining: max(int(math.Floor(tokens)), 0),
+		Reset:     secondsToDuration((policy.capacity - tokens) / policy.perSecond),
+	}
+	if !taken {
+		decision.RetryAfter = secondsToDuration((1 - tokens) / policy.perSecond)
+	}
+	return decision, nil
+}
+
+// ReserveExecution counts an execution of the client, unless its daily or monthly quota is reached.
+// An execution which ends up not being accepted is given back with ReleaseExecution.
+func (s *RateLimitService) ReserveExecution(ctx context.Context, clientID int) (QuotaDecision, error) {
+	if s.dailyExecutions == 0 && s.monthlyExecutions == 0 {
+		return QuotaDecision{Allowed: true}, nil
+	}
+
+	decision := QuotaDecision{
+		Limited:      true,
+		DailyLimit:   s.dailyExecutions,
+		MonthlyLimit: s.monthlyExecutions,
+	}
+	usage, reserved, err := s.Repo.ReserveExecution(ctx, clientID, func(usage domain.ExecutionUsage) bool {
+		switch {
+		case s.monthlyExecutions > 0 && usage.Monthly >= s.monthlyExecutions:
+			decision.RetryAfter = time.Until(usage.Day.AddDate(0, 1, 1-usage.Day.Day()))
+		case s.dailyExecutions > 0 && usage.Daily >= s.dailyExecutions:
+			decision.RetryAfter = time.Until(usage.Day.AddDate(0, 0, 1))
+		default:
+			return true
+		}
+		return false
+	})
+	if err != nil {
+		return QuotaDecision{}, err
+	}
+
+	decision.Allowed = reserved
+	decision.Usage = usage
+	return decision, nil
+}
+
+func (s *RateLimitService) ReleaseExecution(ctx context.Context, clientID int, day time.Time) error {
+	return s.Repo.ReleaseExecution(ctx, clientID, day)
+}
+
+// ReserveToolExecution is the execution quota of the tool service (tool service ExecutionQuota):
+// it reserves an execution of the client and returns the func giving it back.
+func (s *RateLimitService) ReserveToolExecution(
+	ctx context.Context, clientID int,
+) (func(context.Context), time.Duration, bool, error) {
+	decision, err := s.ReserveExecution(ctx, clientID)
+	if err != nil {
+		return nil, 0, false, err
+	}
+	if !decision.Allowed {
+		return nil, decision.RetryAfter, false, nil
+	}
+	if !decision.Limited {
+		return nil, 0, true, nil
+	}
+
+	release := func(ctx context.Context) {
+		if err := s.ReleaseExecution(ctx, clientID, decision.Usage.Day); err != nil {
+			fmt.Printf("failed to release execution of client %d: %v\n", clientID, err)
+		}
+	}
+	return release, 0, true, nil
+}
+
+func secondsToDuration(seconds float64) time.Duration {
+	return time.Duration(math.Max(seconds, 0) * float64(time.Second))
+}
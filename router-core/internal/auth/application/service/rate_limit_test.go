@@ -0,0 +1,261 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"aigendrug.com/router-core/internal/auth/domain"
+	"aigendrug.com/router-core/internal/config"
+)
+
+// fakeRateLimitRepo answers TakeToken with the tokens left after the take, and reserves executions on top of usage.
+type fakeRateLimitRepo struct {
+	tokens float64
+	taken  bool
+	usage  domain.ExecutionUsage
+
+	capacity  float64
+	perSecond float64
+	takes     int
+	reserves  int
+	released  []time.Time
+}
+
+func (r *fakeRateLimitRepo) TakeToken(
+	_ context.Context, _ int, _ domain.RateLimitClass, capacity float64, perSecond float64,
+) (float64, bool, error) {
+	r.takes++
+	r.capacity, r.perSecond = capacity, perSecond
+	return r.tokens, r.taken, nil
+}
+
+func (r *fakeRateLimitRepo) ReserveExecution(
+	_ context.Context, _ int, accept func(usage domain.ExecutionUsage) bool,
+) (domain.ExecutionUsage, bool, error) {
+	r.reserves++
+	if !accept(r.usage) {
+		return r.usage, false, nil
+	}
+	r.usage.Daily++
+	r.usage.Monthly++
+	return r.usage, true, nil
+}
+
+func (r *fakeRateLimitRepo) ReleaseExecution(_ context.Context, _ int, day time.Time) error {
+	r.released = append(r.released, day)
+	return nil
+}
+
+func rateLimitConfig(perMinute float64, burst int, daily int, monthly int) *config.Config {
+	cfg := &config.Config{}
+	cfg.RateLimit.ExecutePerMinute = perMinute
+	cfg.RateLimit.ExecuteBurst = burst
+	cfg.RateLimit.DailyExecutions = daily
+	cfg.RateLimit.MonthlyExecutions = monthly
+	return cfg
+}
+
+func TestRateLimitServiceAllow(t *testing.T) {
+	tests := []struct {
+		name          string
+		perMinute     float64
+		burst         int
+		tokens        float64
+		taken         bool
+		wantCapacity  float64
+		wantPerSecond float64
+		want          RateLimitDecision
+	}{
+		{
+			name:      "token taken",
+			perMinute: 60, burst: 10, tokens: 9, taken: true,
+			wantCapacity: 10, wantPerSecond: 1,
+			want: RateLimitDecision{Limited: true, Allowed: true, Limit: 10, Remaining: 9, Reset: time.Second},
+		},
+		{
+			name:      "last token taken",
+			perMinute: 60, burst: 10, tokens: 0.5, taken: true,
+			wantCapacity: 10, wantPerSecond: 1,
+			want: RateLimitDecision{Limited: true, Allowed: true, Limit: 10, Remaining: 0, Reset: 9500 * time.Millisecond},
+		},
+		{
+			name:      "empty bucket",
+			perMinute: 60, burst: 10, tokens: 0.25, taken: false,
+			wantCapacity: 10, wantPerSecond: 1,
+			want: RateLimitDecision{
+				Limited: true, Allowed: false, Limit: 10, Remaining: 0,
+				Reset: 9750 * time.Millisecond, RetryAfter: 750 * time.Millisecond,
+			},
+		},
+		{
+			name:      "burst defaults to the rate",
+			perMinute: 30, tokens: 29, taken: true,
+			wantCapacity: 30, wantPerSecond: 0.5,
+			want: RateLimitDecision{Limited: true, Allowed: true, Limit: 30, Remaining: 29, Reset: 2 * time.Second},
+		},
+		{
+			name:      "slow rate holds a single token",
+			perMinute: 0.5, tokens: 0.5, taken: false,
+			wantCapacity: 1, wantPerSecond: 0.5 / 60,
+			want: RateLimitDecision{
+				Limited: true, Allowed: false, Limit: 1, Remaining: 0,
+				Reset: time.Minute, RetryAfter: time.Minute,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &fakeRateLimitRepo{tokens: tt.tokens, taken: tt.taken}
+			svc := NewRateLimitService(rateLimitConfig(tt.perMinute, tt.burst, 0, 0), repo)
+
+			got, err := svc.Allow(context.Background(), 1, domain.RateLimitClassExecute)
+			if err != nil {
+				t.Fatalf("Allow() error = %v", err)
+			}
+			if got != tt.want {
+				t.Fatalf("Allow() = %+v, want %+v", got, tt.want)
+			}
+			if repo.capacity != tt.wantCapacity || repo.perSecond != tt.wantPerSecond {
+				t.Fatalf("bucket = %v tokens at %v/s, want %v at %v/s",
+					repo.capacity, repo.perSecond, tt.wantCapacity, tt.wantPerSecond)
+			}
+		})
+	}
+}
+
+func TestRateLimitServiceAllowUnlimitedClass(t *testing.T) {
+	repo := &fakeRateLimitRepo{}
+	svc := NewRateLimitService(rateLimitConfig(60, 10, 0, 0), repo)
+
+	got, err := svc.Allow(context.Background(), 1, domain.RateLimitClassRead)
+	if err != nil {
+		t.Fatalf("Allow() error = %v", err)
+	}
+	if got != (RateLimitDecision{Allowed: true}) {
+		t.Fatalf("Allow() = %+v, want allowed without limit", got)
+	}
+	if repo.takes != 0 {
+		t.Fatalf("TakeToken called %d times for an unlimited class", repo.takes)
+	}
+}
+
+func TestRateLimitServiceReserveExecution(t *testing.T) {
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+
+	tests := []struct {
+		name         string
+		daily        int
+		monthly      int
+		usage        domain.ExecutionUsage
+		wantAllowed  bool
+		wantRetryAt  time.Time
+		wantReserves int
+	}{
+		{
+			name:        "no quota",
+			usage:       domain.ExecutionUsage{Day: today, Daily: 1000, Monthly: 1000},
+			wantAllowed: true,
+		},
+		{
+			name:  "under the quotas",
+			daily: 10, monthly: 100,
+			usage:       domain.ExecutionUsage{Day: today, Daily: 9, Monthly: 99},
+			wantAllowed: true, wantReserves: 1,
+		},
+		{
+			name:  "daily quota reached",
+			daily: 10, monthly: 100,
+			usage:       domain.ExecutionUsage{Day: today, Daily: 10, Monthly: 50},
+			wantRetryAt: today.AddDate(0, 0, 1), wantReserves: 1,
+		},
+		{
+			name:  "monthly quota reached",
+			daily: 10, monthly: 100,
+			usage:       domain.ExecutionUsage{Day: time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC), Daily: 10, Monthly: 100},
+			wantRetryAt: time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC), wantReserves: 1,
+		},
+		{
+			name:    "monthly quota only",
+			monthly: 100,
+			usage:   domain.ExecutionUsage{Day: time.Date(2024, 12, 15, 0, 0, 0, 0, time.UTC), Daily: 500, Monthly: 100},
+			// the month ends with the year
+			wantRetryAt: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), wantReserves: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &fakeRateLimitRepo{usage: tt.usage}
+			svc := NewRateLimitService(rateLimitConfig(0, 0, tt.daily, tt.monthly), repo)
+
+			got, err := svc.ReserveExecution(context.Background(), 1)
+			if err != nil {
+				t.Fatalf("ReserveExecution() error = %v", err)
+			}
+			if got.Allowed != tt.wantAllowed {
+				t.Fatalf("ReserveExecution() allowed = %v, want %v", got.Allowed, tt.wantAllowed)
+			}
+			if repo.reserves != tt.wantReserves {
+				t.Fatalf("ReserveExecution called the repository %d times, want %d", repo.reserves, tt.wantReserves)
+			}
+			if tt.wantAllowed {
+				if got.RetryAfter != 0 {
+					t.Fatalf("ReserveExecution() RetryAfter = %v for an allowed execution", got.RetryAfter)
+				}
+				return
+			}
+
+			retryAt := time.Now().Add(got.RetryAfter)
+			if diff := retryAt.Sub(tt.wantRetryAt); diff < -time.Second || diff > time.Second {
+				t.Fatalf("ReserveExecution() retries at %v, want %v", retryAt, tt.wantRetryAt)
+			}
+		})
+	}
+}
+
+func TestRateLimitServiceReserveToolExecution(t *testing.T) {
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+
+	tests := []struct {
+		name         string
+		daily        int
+		usage        domain.ExecutionUsage
+		wantReserved bool
+		wantRelease  bool
+	}{
+		{name: "no quota", usage: domain.ExecutionUsage{Day: today}, wantReserved: true},
+		{name: "reserved", daily: 2, usage: domain.ExecutionUsage{Day: today, Daily: 1}, wantReserved: true, wantRelease: true},
+		{name: "quota reached", daily: 2, usage: domain.ExecutionUsage{Day: today, Daily: 2}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &fakeRateLimitRepo{usage: tt.usage}
+			svc := NewRateLimitService(rateLimitConfig(0, 0, tt.daily, 0), repo)
+
+			release, retryAfter, reserved, err := svc.ReserveToolExecution(context.Background(), 1)
+			if err != nil {
+				t.Fatalf("ReserveToolExecution() error = %v", err)
+			}
+			if reserved != tt.wantReserved {
+				t.Fatalf("ReserveToolExecution() reserved = %v, want %v", reserved, tt.wantReserved)
+			}
+			if !reserved && retryAfter <= 0 {
+				t.Fatalf("ReserveToolExecution() retryAfter = %v when the quota is reached", retryAfter)
+			}
+			if (release != nil) != tt.wantRelease {
+				t.Fatalf("ReserveToolExecution() release = %v, want %v", release != nil, tt.wantRelease)
+			}
+			if release == nil {
+				return
+			}
+
+			release(context.Background())
+			if len(repo.released) != 1 || !repo.released[0].Equal(today) {
+				t.Fatalf("released %v, want one execution on %v", repo.released, today)
+			}
+		})
+	}
+}
@@ -0,0 +1,98 @@
+package delivery
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"aigendrug.com/router-core/internal/auth/application/service"
+	"aigendrug.com/router-core/internal/auth/domain"
+	shared_types "aigendrug.com/router-core/internal/shared/types"
+	"github.com/gin-gonic/gin"
+)
+
+// RateLimits builds the rate limit middlewares of the routes, placed after the authentication middleware.
+// Requests of admin console sessions are not limited. When the limits can not be read from the database,
+// requests are let through rather than failed.
+type RateLimits struct {
+	svc *service.RateLimitService
+}
+
+func NewRateLimits(svc *service.RateLimitService) *RateLimits {
+	return &RateLimits{svc: svc}
+}
+
+// Select limits the tool selections of the client.
+func (r *RateLimits) Select() gin.HandlerFunc {
+	return RateLimitMiddleware(r.svc, domain.RateLimitClassSelect)
+}
+
+// Execute limits the tool executions of the client. Their daily and monthly quotas are counted by the tool
+// service, see RateLimitService.ReserveToolExecution.
+func (r *RateLimits) Execute() gin.HandlerFunc {
+	return RateLimitMiddleware(r.svc, domain.RateLimitClassExecute)
+}
+
+// Read limits the GET requests of the client, other methods are not limited.
+func (r *RateLimits) Read() gin.HandlerFunc {
+	rateLimit := RateLimitMiddleware(r.svc, domain.RateLimitClassRead)
+	return func(c *gin.Context) {
+		if c.Request.Method != http.MethodGet {
+			c.Next()
+			return
+		}
+		rateLimit(c)
+	}
+}
+
+// RateLimitMiddleware takes a token from the bucket of the client for the class, and rejects the request
+// with 429 when the bucket is empty. X-RateLimit-Limit, X-RateLimit-Remaining and X-RateLimit-Reset (seconds
+// until the bucket is full) are set on every response, Retry-After on rejections.
+func RateLimitMiddleware(svc *service.RateLimitService, class domain.RateLimitClass) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		clientID, ok := rateLimitedClientID(c)
+		if !ok {
+			c.Next()
+			return
+		}
+
+		decision, err := svc.Allow(c.Request.Context(), clientID, class)
+		if err != nil {
+			fmt.Printf("failed to apply %s rate limit of client %d: %v\n", class, clientID, err)
+			c.Next()
+			return
+		}
+		if !decision.Limited {
+			c.Next()
+			return
+		}
+
+		c.Header("X-RateLimit-Limit", strconv.Itoa(decision.Limit))
+		c.Header("X-RateLimit-Remaining", strconv.Itoa(decision.Remaining))
+		c.Header("X-RateLimit-Reset", strconv.Itoa(ceilSeconds(decision.Reset)))
+		if !decision.Allowed {
+			c.Header("Retry-After", strconv.Itoa(max(ceilSeconds(decision.RetryAfter), 1)))
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, shared_types.HttpErrorResponse{
+				Msg: fmt.Sprintf("Rate limit exceeded for %s requests (%d requests burst). Please retry later.",
+					class, decision.Limit),
+			})
+			return
+		}
+		c.Next()
+	}
+}
+
+// rateLimitedClientID returns the client authenticated by its API key, admin console sessions are not limited.
+func rateLimitedClientID(c *gin.Context) (int, bool) {
+	if c.GetBool("isAdmin") {
+		return 0, false
+	}
+	clientID := c.GetInt("clientID")
+	return clientID, clientID > 0
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
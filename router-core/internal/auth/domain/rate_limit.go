@@ -0,0 +1,41 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// RateLimitClass groups the endpoints sharing a token bucket per client.
+type RateLimitClass string
+
+const (
+	RateLimitClassSelect  RateLimitClass = "select"
+	RateLimitClassExecute RateLimitClass = "execute"
+	RateLimitClassRead    RateLimitClass = "read"
+)
+
+func (c RateLimitClass) String() string {
+	return string(c)
+}
+
+// ExecutionUsage is the number of executions accepted for a client on a UTC day and in its month.
+type ExecutionUsage struct {
+	Day     time.Time
+	Daily   int
+	Monthly int
+}
+
+type RateLimitRepository interface {
+	// TakeToken refills the bucket of the client for the elapsed time and takes a token when one is available.
+	// It returns the tokens left and whether a token was taken. A new bucket starts full.
+	TakeToken(
+		ctx context.Context, clientID int, class RateLimitClass, capacity float64, perSecond float64,
+	) (tokens float64, taken bool, err error)
+	// ReserveExecution counts an execution of the client on the current UTC day, unless accept rejects
+	// the usage before it. It returns the usage, including the execution when it was counted.
+	ReserveExecution(
+		ctx context.Context, clientID int, accept func(usage ExecutionUsage) bool,
+	) (usage ExecutionUsage, reserved bool, err error)
+	// ReleaseExecution gives back an execution counted on day.
+	ReleaseExecution(ctx context.Context, clientID int, day time.Time) error
+}
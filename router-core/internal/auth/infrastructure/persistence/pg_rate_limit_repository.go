@@ -0,0 +1,110 @@
+package persistence
+
+import (
+	"context"
+	"time"
+
+	"aigendrug.com/router-core/internal/auth/domain"
+	"aigendrug.com/router-core/internal/shared/database/postgres"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// executionUsageLock is the advisory lock (with the client id) serializing the executions counted for a client.
+const executionUsageLock = 7242003
+
+type PgRateLimitRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewPgRateLimitRepository(db *pgxpool.Pool) *PgRateLimitRepository {
+	return &PgRateLimitRepository{db: db}
+}
+
+func (r *PgRateLimitRepository) TakeToken(
+	ctx context.Context, clientID int, class domain.RateLimitClass, capacity float64, perSecond float64,
+) (float64, bool, error) {
+	// tokens of the bucket refilled for the time elapsed since its last update
+	const refilled = `LEAST($3::float8, client_rate_limits.tokens +
+		EXTRACT(EPOCH FROM (CURRENT_TIMESTAMP - client_rate_limits.updated_at))::float8 * $4::float8)`
+
+	query := `
+		INSERT INTO client_rate_limits (client_id, class, tokens, allowed, updated_at)
+		VALUES ($1, $2, $3::float8 - 1, TRUE, CURRENT_TIMESTAMP)
+		ON CONFLICT (client_id, class) DO UPDATE
+		SET
+			tokens = CASE WHEN ` + refilled + ` >= 1 THEN ` + refilled + ` - 1 ELSE ` + refilled + ` END,
+			allowed = ` + refilled + ` >= 1,
+			updated_at = CURRENT_TIMESTAMP
+		RETURNING tokens, allowed
+	`
+
+	var tokens float64
+	var taken bool
+	if err := r.db.QueryRow(ctx, query, clientID, class.String(), capacity, perSecond).Scan(&tokens, &taken); err != nil {
+		return 0, false, err
+	}
+	return tokens, taken, nil
+}
+
+func (r *PgRateLimitRepository) ReserveExecution(
+	ctx context.Context, clientID int, accept func(usage domain.ExecutionUsage) bool,
+) (domain.ExecutionUsage, bool, error) {
+	var usage domain.ExecutionUsage
+	var reserved bool
+
+	err := postgres.WithTx(ctx, r.db, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock($1, $2)`, executionUsageLock, clientID); err != nil {
+			return err
+		}
+
+		query := `
+			WITH today AS (
+				SELECT (CURRENT_TIMESTAMP AT TIME ZONE 'UTC')::date AS day
+			)
+			SELECT
+				today.day,
+				COALESCE(SUM(u.executions) FILTER (WHERE u.day = today.day), 0),
+				COALESCE(SUM(u.executions), 0)
+			FROM today
+			LEFT JOIN client_execution_usage u
+				ON u.client_id = $1 AND u.day >= date_trunc('month', today.day)::date
+			GROUP BY today.day
+		`
+		if err := tx.QueryRow(ctx, query, clientID).Scan(&usage.Day, &usage.Daily, &usage.Monthly); err != nil {
+			return err
+		}
+
+		if !accept(usage) {
+			return nil
+		}
+
+		_, err := tx.Exec(ctx, `
+			INSERT INTO client_execution_usage (client_id, day, executions)
+			VALUES ($1, $2, 1)
+			ON CONFLICT (client_id, day) DO UPDATE
+			SET executions = client_execution_usage.executions + 1
+		`, clientID, usage.Day)
+		if err != nil {
+			return err
+		}
+		usage.Daily++
+		usage.Monthly++
+		reserved = true
+		return nil
+	})
+	if err != nil {
+		return domain.ExecutionUsage{}, false, err
+	}
+
+	return usage, reserved, nil
+}
+
+func (r *PgRateLimitRepository) ReleaseExecution(ctx context.Context, clientID int, day time.Time) error {
+	_, err := r.db.Exec(ctx, `
+		UPDATE client_execution_usage
+		SET executions = GREATEST(executions - 1, 0)
+		WHERE client_id = $1 AND day = $2
+	`, clientID, day)
+	return err
+}
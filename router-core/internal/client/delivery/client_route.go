@@ -9,11 +9,12 @@ import (
 func SetupClientRoutes(
 	router *gin.Engine,
 	db *pgxpool.Pool,
+	rateLimits *authd.RateLimits,
 	clientHandler *ClientHandler,
 ) {
 	clientRoutes := router.Group("/v1/clients")
 	{
-		clientDefaultRoutes := clientRoutes.Group("", authd.DefaultAuthMiddleWare(db))
+		clientDefaultRoutes := clientRoutes.Group("", authd.DefaultAuthMiddleWare(db), rateLimits.Read())
 		{
 			clientDefaultRoutes.GET("/identifier/:identifier", clientHandler.GetClientByClientIdentifier)
 			clientDefaultRoutes.GET("/current", clientHandler.GetCurrentClient)
@@ -33,8 +33,71 @@ func bindEnvVars(v *viper.Viper) {
 		"aws.region":            "AWS_REGION",
 		"aws.access_key_id":     "AWS_ACCESS_KEY_ID",
 		"aws.secret_access_key": "AWS_SECRET_ACCESS_KEY",
+		"aws.session_token":     "AWS_SESSION_TOKEN",
+		"aws.profile":           "AWS_PROFILE",
+		"aws.role_session_name": "AWS_ROLE_SESSION_NAME",
+		"aws.endpoint_url":      "AWS_ENDPOINT_URL",
+		"aws.lambda_endpoint":   "AWS_LAMBDA_ENDPOINT",
+		"aws.s3_endpoint":       "AWS_S3_ENDPOINT",
+		"aws.s3_use_path_style": "AWS_S3_USE_PATH_STYLE",
 
 		"selector.url": "SELECTOR_SERVICE_URL",
+
+		"executor.poll_interval_seconds":     "EXECUTOR_POLL_INTERVAL_SECONDS",
+		"executor.poll_max_interval_seconds": "EXECUTOR_POLL_MAX_INTERVAL_SECONDS",
+		"executor.poll_backoff_multiplier":   "EXECUTOR_POLL_BACKOFF_MULTIPLIER",
+		"executor.poll_timeout_seconds":      "EXECUTOR_POLL_TIMEOUT_SECONDS",
+		"executor.breaker_enabled":           "EXECUTOR_BREAKER_ENABLED",
+		"executor.breaker_failure_rate":      "EXECUTOR_BREAKER_FAILURE_RATE",
+		"executor.breaker_min_requests":      "EXECUTOR_BREAKER_MIN_REQUESTS",
+		"executor.breaker_window_seconds":    "EXECUTOR_BREAKER_WINDOW_SECONDS",
+		"executor.breaker_open_seconds":      "EXECUTOR_BREAKER_OPEN_SECONDS",
+		"executor.breaker_half_open_trials":  "EXECUTOR_BREAKER_HALF_OPEN_TRIALS",
+		"executor.http_max_response_bytes":   "EXECUTOR_HTTP_MAX_RESPONSE_BYTES",
+		"scheduler.workers":                  "SCHEDULER_WORKERS",
+		"scheduler.lease_seconds":            "SCHEDULER_LEASE_SECONDS",
+		"scheduler.heartbeat_seconds":        "SCHEDULER_HEARTBEAT_SECONDS",
+		"scheduler.poll_interval_seconds":    "SCHEDULER_POLL_INTERVAL_SECONDS",
+		"scheduler.max_claims":               "SCHEDULER_MAX_CLAIMS",
+		"scheduler.max_per_client":           "SCHEDULER_MAX_PER_CLIENT",
+		"scheduler.max_per_tool":             "SCHEDULER_MAX_PER_TOOL",
+		"scheduler.max_per_client_tool":      "SCHEDULER_MAX_PER_CLIENT_TOOL",
+		"webhook.workers":                    "WEBHOOK_WORKERS",
+		"webhook.max_attempts":               "WEBHOOK_MAX_ATTEMPTS",
+		"webhook.timeout_seconds":            "WEBHOOK_TIMEOUT_SECONDS",
+		"webhook.base_delay_seconds":         "WEBHOOK_BASE_DELAY_SECONDS",
+		"webhook.max_delay_seconds":          "WEBHOOK_MAX_DELAY_SECONDS",
+		"webhook.poll_interval_seconds":      "WEBHOOK_POLL_INTERVAL_SECONDS",
+		"webhook.allow_private":              "WEBHOOK_ALLOW_PRIVATE",
+		"schedule.poll_interval_seconds":     "SCHEDULE_POLL_INTERVAL_SECONDS",
+		"schedule.misfire_grace_seconds":     "SCHEDULE_MISFIRE_GRACE_SECONDS",
+		"blob_store.backend":                 "BLOB_STORE_BACKEND",
+		"blob_store.local_dir":               "BLOB_STORE_LOCAL_DIR",
+		"blob_store.s3_bucket":               "BLOB_STORE_S3_BUCKET",
+		"blob_store.s3_prefix":               "BLOB_STORE_S3_PREFIX",
+		"blob_store.reference_ttl_seconds":   "BLOB_STORE_REFERENCE_TTL_SECONDS",
+		"blob_store.max_upload_bytes":        "BLOB_STORE_MAX_UPLOAD_BYTES",
+		"blob_store.offload_threshold_bytes": "BLOB_STORE_OFFLOAD_THRESHOLD_BYTES",
+		"local_exec.enabled":                 "LOCAL_EXEC_ENABLED",
+		"local_exec.work_dir":                "LOCAL_EXEC_WORK_DIR",
+		"local_exec.command_dirs":            "LOCAL_EXEC_COMMAND_DIRS",
+		"local_exec.max_output_bytes":        "LOCAL_EXEC_MAX_OUTPUT_BYTES",
+		"local_exec.max_stderr_bytes":        "LOCAL_EXEC_MAX_STDERR_BYTES",
+		"health.enabled":                     "HEALTH_ENABLED",
+		"health.interval_seconds":            "HEALTH_INTERVAL_SECONDS",
+		"health.timeout_seconds":             "HEALTH_TIMEOUT_SECONDS",
+		"health.unhealthy_threshold":         "HEALTH_UNHEALTHY_THRESHOLD",
+		"health.history_limit":               "HEALTH_HISTORY_LIMIT",
+		"health.concurrency":                 "HEALTH_CONCURRENCY",
+		"health.selection_policy":            "HEALTH_SELECTION_POLICY",
+		"rate_limit.select_per_minute":       "RATE_LIMIT_SELECT_PER_MINUTE",
+		"rate_limit.select_burst":            "RATE_LIMIT_SELECT_BURST",
+		"rate_limit.execute_per_minute":      "RATE_LIMIT_EXECUTE_PER_MINUTE",
+		"rate_limit.execute_burst":           "RATE_LIMIT_EXECUTE_BURST",
+		"rate_limit.read_per_minute":         "RATE_LIMIT_READ_PER_MINUTE",
+		"rate_limit.read_burst":              "RATE_LIMIT_READ_BURST",
+		"rate_limit.daily_executions":        "RATE_LIMIT_DAILY_EXECUTIONS",
+		"rate_limit.monthly_executions":      "RATE_LIMIT_MONTHLY_EXECUTIONS",
 	}
 
 	for key, env := range envMap {
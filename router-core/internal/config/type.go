@@ -19,9 +19,95 @@ type Config struct {
 		URL string `mapstructure:"url"`
 	} `mapstructure:"selector"`
 
+	Executor struct {
+		PollIntervalSeconds    float64 `mapstructure:"poll_interval_seconds"`
+		PollMaxIntervalSeconds float64 `mapstructure:"poll_max_interval_seconds"`
+		PollBackoffMultiplier  float64 `mapstructure:"poll_backoff_multiplier"`
+		PollTimeoutSeconds     float64 `mapstructure:"poll_timeout_seconds"`
+		BreakerEnabled         bool    `mapstructure:"breaker_enabled"`
+		BreakerFailureRate     float64 `mapstructure:"breaker_failure_rate"`
+		BreakerMinRequests     int     `mapstructure:"breaker_min_requests"`
+		BreakerWindowSeconds   float64 `mapstructure:"breaker_window_seconds"`
+		BreakerOpenSeconds     float64 `mapstructure:"breaker_open_seconds"`
+		BreakerHalfOpenTrials  int     `mapstructure:"breaker_half_open_trials"`
+		HTTPMaxResponseBytes   int64   `mapstructure:"http_max_response_bytes"`
+	} `mapstructure:"executor"`
+
+	Scheduler struct {
+		Workers             int     `mapstructure:"workers"`
+		LeaseSeconds        float64 `mapstructure:"lease_seconds"`
+		HeartbeatSeconds    float64 `mapstructure:"heartbeat_seconds"`
+		PollIntervalSeconds float64 `mapstructure:"poll_interval_seconds"`
+		MaxClaims           int     `mapstructure:"max_claims"`
+		MaxPerClient        int     `mapstructure:"max_per_client"`
+		MaxPerTool          int     `mapstructure:"max_per_tool"`
+		MaxPerClientTool    int     `mapstructure:"max_per_client_tool"`
+	} `mapstructure:"scheduler"`
+
+	Webhook struct {
+		Workers             int     `mapstructure:"workers"`
+		MaxAttempts         int     `mapstructure:"max_attempts"`
+		TimeoutSeconds      float64 `mapstructure:"timeout_seconds"`
+		BaseDelaySeconds    float64 `mapstructure:"base_delay_seconds"`
+		MaxDelaySeconds     float64 `mapstructure:"max_delay_seconds"`
+		PollIntervalSeconds float64 `mapstructure:"poll_interval_seconds"`
+		AllowPrivate        bool    `mapstructure:"allow_private"`
+	} `mapstructure:"webhook"`
+
+	Schedule struct {
+		PollIntervalSeconds float64 `mapstructure:"poll_interval_seconds"`
+		MisfireGraceSeconds float64 `mapstructure:"misfire_grace_seconds"`
+	} `mapstructure:"schedule"`
+
+	BlobStore struct {
+		Backend               string  `mapstructure:"backend"`
+		LocalDir              string  `mapstructure:"local_dir"`
+		S3Bucket              string  `mapstructure:"s3_bucket"`
+		S3Prefix              string  `mapstructure:"s3_prefix"`
+		ReferenceTTLSeconds   float64 `mapstructure:"reference_ttl_seconds"`
+		MaxUploadBytes        int64   `mapstructure:"max_upload_bytes"`
+		OffloadThresholdBytes int     `mapstructure:"offload_threshold_bytes"`
+	} `mapstructure:"blob_store"`
+
+	LocalExec struct {
+		Enabled        bool   `mapstructure:"enabled"`
+		WorkDir        string `mapstructure:"work_dir"`
+		CommandDirs    string `mapstructure:"command_dirs"`
+		MaxOutputBytes int64  `mapstructure:"max_output_bytes"`
+		MaxStderrBytes int64  `mapstructure:"max_stderr_bytes"`
+	} `mapstructure:"local_exec"`
+
+	Health struct {
+		Enabled            bool    `mapstructure:"enabled"`
+		IntervalSeconds    float64 `mapstructure:"interval_seconds"`
+		TimeoutSeconds     float64 `mapstructure:"timeout_seconds"`
+		UnhealthyThreshold int     `mapstructure:"unhealthy_threshold"`
+		HistoryLimit       int     `mapstructure:"history_limit"`
+		Concurrency        int     `mapstructure:"concurrency"`
+		SelectionPolicy    string  `mapstructure:"selection_policy"`
+	} `mapstructure:"health"`
+
+	RateLimit struct {
+		SelectPerMinute   float64 `mapstructure:"select_per_minute"`
+		SelectBurst       int     `mapstructure:"select_burst"`
+		ExecutePerMinute  float64 `mapstructure:"execute_per_minute"`
+		ExecuteBurst      int     `mapstructure:"execute_burst"`
+		ReadPerMinute     float64 `mapstructure:"read_per_minute"`
+		ReadBurst         int     `mapstructure:"read_burst"`
+		DailyExecutions   int     `mapstructure:"daily_executions"`
+		MonthlyExecutions int     `mapstructure:"monthly_executions"`
+	} `mapstructure:"rate_limit"`
+
 	AWS struct {
 		Region          string `mapstructure:"region"`
 		AccessKeyID     string `mapstructure:"access_key_id"`
 		SecretAccessKey string `mapstructure:"secret_access_key"`
+		SessionToken    string `mapstructure:"session_token"`
+		Profile         string `mapstructure:"profile"`
+		RoleSessionName string `mapstructure:"role_session_name"`
+		EndpointURL     string `mapstructure:"endpoint_url"`
+		LambdaEndpoint  string `mapstructure:"lambda_endpoint"`
+		S3Endpoint      string `mapstructure:"s3_endpoint"`
+		S3UsePathStyle  bool   `mapstructure:"s3_use_path_style"`
 	} `mapstructure:"aws"`
 }
@@ -6,16 +6,28 @@ import (
 
 	api_client_delivery "aigendrug.com/router-core/internal/api_client/delivery"
 	api_docs_delivery "aigendrug.com/router-core/internal/api_docs/delivery"
+	auth_service "aigendrug.com/router-core/internal/auth/application/service"
+	auth_delivery "aigendrug.com/router-core/internal/auth/delivery"
+	auth_persistence "aigendrug.com/router-core/internal/auth/infrastructure/persistence"
 	client_service "aigendrug.com/router-core/internal/client/application/service"
 	client_delivery "aigendrug.com/router-core/internal/client/delivery"
 	client_persistence "aigendrug.com/router-core/internal/client/infrastructure/persistence"
 	"aigendrug.com/router-core/internal/config"
+	aws_wrapper "aigendrug.com/router-core/internal/shared/aws-wrapper"
+	"aigendrug.com/router-core/internal/shared/blobstore"
 	"aigendrug.com/router-core/internal/shared/database/postgres"
+	exec_wrapper "aigendrug.com/router-core/internal/shared/exec-wrapper"
+	grpc_wrapper "aigendrug.com/router-core/internal/shared/grpc-wrapper"
+	http_wrapper "aigendrug.com/router-core/internal/shared/http-wrapper"
 	lambda_wrapper "aigendrug.com/router-core/internal/shared/lambda-wrapper"
+	s3_wrapper "aigendrug.com/router-core/internal/shared/s3-wrapper"
 	"aigendrug.com/router-core/internal/shared/selector"
 	tool_service "aigendrug.com/router-core/internal/tool/application/service"
 	tool_delivery "aigendrug.com/router-core/internal/tool/delivery"
 	tool_persistence "aigendrug.com/router-core/internal/tool/infrastructure/persistence"
+	webhook_service "aigendrug.com/router-core/internal/webhook/application/service"
+	webhook_delivery "aigendrug.com/router-core/internal/webhook/delivery"
+	webhook_persistence "aigendrug.com/router-core/internal/webhook/infrastructure/persistence"
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 )
@@ -51,6 +63,12 @@ func Run() {
 			"Content-Type",
 			"X-API-Key",
 		},
+		ExposeHeaders: []string{
+			"Retry-After",
+			"X-RateLimit-Limit",
+			"X-RateLimit-Remaining",
+			"X-RateLimit-Reset",
+		},
 	}))
 
 	router.Handle("GET", "/health", func(c *gin.Context) {
@@ -74,25 +92,69 @@ func Run() {
 		log.Fatalf("Failed to create postgres pool: %v", err)
 	}
 
-	lambdaClient := lambda_wrapper.NewLambdaWrapperClient(config)
+	awsConfig, err := aws_wrapper.LoadConfig(ctx, config)
+	if err != nil {
+		log.Fatalf("Failed to load AWS config: %v", err)
+	}
+
+	lambdaClients := lambda_wrapper.NewLambdaClientPool(config, awsConfig)
+	httpClient := http_wrapper.NewHTTPWrapperClient(http_wrapper.ClientOptions{
+		MaxResponseBytes: config.Executor.HTTPMaxResponseBytes,
+	})
+	execClient := exec_wrapper.NewExecWrapperClient()
+	grpcClient := grpc_wrapper.NewGRPCWrapperClient()
+	s3Client := s3_wrapper.NewS3WrapperClient(config, awsConfig)
+	blobStore, err := blobstore.NewBlobStore(config, s3Client)
+	if err != nil {
+		// file inputs are rejected until the blob store is configured
+		log.Printf("Blob store disabled: %v", err)
+	}
 
 	selectorService := selector.NewSelectorService(config)
 
 	clientRepo := client_persistence.NewPgClientRepository(pgPool)
 	toolRepo := tool_persistence.NewPgToolRepository(pgPool)
+	webhookRepo := webhook_persistence.NewPgWebhookRepository(pgPool)
+	rateLimitRepo := auth_persistence.NewPgRateLimitRepository(pgPool)
+
+	payloadStore := tool_service.NewPayloadStore(config, blobStore)
+
+	rateLimitService := auth_service.NewRateLimitService(config, rateLimitRepo)
+
+	toolRequestNotifier := tool_service.NewToolRequestNotifier(pgPool)
+	functionExecutor := tool_service.NewFunctionExecutor(config, toolRepo, lambdaClients, httpClient, execClient, grpcClient, s3Client, toolRequestNotifier, blobStore, payloadStore)
+	toolRequestScheduler := tool_service.NewToolRequestScheduler(config, pgPool, toolRepo, functionExecutor, toolRequestNotifier)
+	toolScheduleTrigger := tool_service.NewToolScheduleTrigger(config, pgPool, toolRepo, toolRequestScheduler, payloadStore, rateLimitService.ReserveToolExecution)
+	toolHealthProber := tool_service.NewToolHealthProber(config, pgPool, toolRepo, functionExecutor)
+	webhookDispatcher := webhook_service.NewWebhookDispatcher(config, webhookRepo)
 
 	clientService := client_service.NewClientService(pgPool, clientRepo)
-	toolService := tool_service.NewToolService(pgPool, toolRepo, selectorService, lambdaClient)
+	toolService := tool_service.NewToolService(pgPool, toolRepo, selectorService, functionExecutor, lambdaClients.Default(), toolRequestScheduler, toolRequestNotifier, blobStore, payloadStore, toolHealthProber, rateLimitService.ReserveToolExecution)
+	webhookService := webhook_service.NewWebhookService(pgPool, webhookRepo, toolRepo, webhookDispatcher)
+	toolRequestNotifier.OnFinished(webhookService.EnqueueToolRequestDeliveries)
+	webhookDeliverySweeper := webhook_service.NewWebhookDeliverySweeper(webhookRepo, webhookService)
+
+	// workers start once every hook is registered, so no finished request misses its webhook deliveries
+	toolRequestNotifier.Start(ctx)
+	webhookDispatcher.Start(ctx)
+	webhookDeliverySweeper.Start(ctx)
+	functionExecutor.Start(ctx)
+	toolRequestScheduler.Start(ctx)
+	toolScheduleTrigger.Start(ctx)
+	toolHealthProber.Start(ctx)
 
 	apiDocsHandler := api_docs_delivery.NewAPIDocsHandler(config)
 	apiClientHandler := api_client_delivery.NewAPIClientHandler(config)
 	clientHandler := client_delivery.NewClientHandler(clientService)
-	toolHandler := tool_delivery.NewToolHandler(toolService)
+	toolHandler := tool_delivery.NewToolHandler(config, toolService)
+	webhookHandler := webhook_delivery.NewWebhookHandler(webhookService)
+	rateLimits := auth_delivery.NewRateLimits(rateLimitService)
 
 	api_docs_delivery.SetupAPIDocsRoutes(router, apiDocsHandler)
 	api_client_delivery.SetupAPIClientRoutes(router, apiClientHandler)
-	client_delivery.SetupClientRoutes(router, pgPool, clientHandler)
-	tool_delivery.SetupToolRoutes(router, pgPool, toolHandler)
+	client_delivery.SetupClientRoutes(router, pgPool, rateLimits, clientHandler)
+	tool_delivery.SetupToolRoutes(router, pgPool, rateLimits, toolHandler)
+	webhook_delivery.SetupWebhookRoutes(router, pgPool, rateLimits, webhookHandler)
 
 	router.Run(":" + port)
 }
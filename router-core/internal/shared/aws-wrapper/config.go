@@ -0,0 +1,100 @@
+package aws_wrapper
+
+import (
+	"cmp"
+	"context"
+	"time"
+
+	"aigendrug.com/router-core/internal/config"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+)
+
+// DefaultRoleSessionName names the sessions of the roles assumed by the router (AWS_ROLE_SESSION_NAME).
+const DefaultRoleSessionName = "router-core"
+
+const (
+	// STS region of the roles assumed without a region
+	stsGlobalRegion = "us-east-1"
+
+	// credentials are refreshed this long before they expire
+	credentialsExpiryWindow = 5 * time.Minute
+)
+
+// LoadConfig returns the AWS config of the router. AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY are used when set,
+// otherwise the credentials are resolved by the default chain of the SDK: environment, web identity
+// (AWS_WEB_IDENTITY_TOKEN_FILE and AWS_ROLE_ARN), shared config and credentials files of profile AWS_PROFILE
+// (role_arn with source_profile, SSO, credential_process), ECS task role, then EC2 instance role.
+// AWS_ENDPOINT_URL points every client to a local emulator, AWS_ENDPOINT_URL_<SERVICE> (e.g. AWS_ENDPOINT_URL_STS)
+// a single service.
+func LoadConfig(ctx context.Context, config *config.Config) (aws.Config, error) {
+	sessionName := func(name *string) {
+		*name = cmp.Or(*name, config.AWS.RoleSessionName, DefaultRoleSessionName)
+	}
+
+	options := []func(*awsconfig.LoadOptions) error{
+		awsconfig.WithCredentialsCacheOptions(func(options *aws.CredentialsCacheOptions) {
+			options.ExpiryWindow = credentialsExpiryWindow
+		}),
+		awsconfig.WithWebIdentityRoleCredentialOptions(func(options *stscreds.WebIdentityRoleOptions) {
+			sessionName(&options.RoleSessionName)
+		}),
+		awsconfig.WithAssumeRoleCredentialOptions(func(options *stscreds.AssumeRoleOptions) {
+			sessionName(&options.RoleSessionName)
+		}),
+	}
+	if config.AWS.Region != "" {
+		options = append(options, awsconfig.WithRegion(config.AWS.Region))
+	}
+	if config.AWS.Profile != "" {
+		options = append(options, awsconfig.WithSharedConfigProfile(config.AWS.Profile))
+	}
+	if config.AWS.EndpointURL != "" {
+		options = append(options, awsconfig.WithBaseEndpoint(config.AWS.EndpointURL))
+	}
+	if config.AWS.AccessKeyID != "" && config.AWS.SecretAccessKey != "" {
+		options = append(options, awsconfig.WithCredentialsProvider(credentials.NewStaticCredentialsProvider(
+			config.AWS.AccessKeyID, config.AWS.SecretAccessKey, config.AWS.SessionToken,
+		)))
+	}
+
+	return awsconfig.LoadDefaultConfig(ctx, options...)
+}
+
+// AssumeRoleOptions
+//
+// - RoleARN: Role assumed, possibly in another account.
+// - ExternalID: External id required by the trust policy of the role, if any.
+// - Duration: Lifetime of the session, 1 hour (the STS default) when zero.
+type AssumeRoleOptions struct {
+	RoleARN    string
+	ExternalID string
+	Duration   time.Duration
+}
+
+// NewAssumeRoleProvider returns the credentials of a role assumed with the credentials of awsConfig, through the
+// STS endpoint of region, cached until shortly before the session expires.
+func NewAssumeRoleProvider(
+	awsConfig aws.Config, config *config.Config, region string, options AssumeRoleOptions,
+) aws.CredentialsProvider {
+	stsConfig := awsConfig.Copy()
+	stsConfig.Region = cmp.Or(region, awsConfig.Region, stsGlobalRegion)
+
+	provider := stscreds.NewAssumeRoleProvider(sts.NewFromConfig(stsConfig), options.RoleARN,
+		func(assumeRoleOptions *stscreds.AssumeRoleOptions) {
+			assumeRoleOptions.RoleSessionName = cmp.Or(config.AWS.RoleSessionName, DefaultRoleSessionName)
+			if options.ExternalID != "" {
+				assumeRoleOptions.ExternalID = aws.String(options.ExternalID)
+			}
+			if options.Duration > 0 {
+				assumeRoleOptions.Duration = options.Duration
+			}
+		})
+
+	return aws.NewCredentialsCache(provider, func(options *aws.CredentialsCacheOptions) {
+		options.ExpiryWindow = credentialsExpiryWindow
+	})
+}
@@ -0,0 +1,113 @@
+// Package blobstore keeps files too large for a JSON column (uploaded artifacts, etc.)
+// in the local filesystem or in an S3-compatible bucket.
+package blobstore
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"time"
+
+	"aigendrug.com/router-core/internal/config"
+	s3_wrapper "aigendrug.com/router-core/internal/shared/s3-wrapper"
+)
+
+// Backends of the blob store (BLOB_STORE_BACKEND).
+const (
+	BackendLocal = "local"
+	BackendS3    = "s3"
+)
+
+// Defaults of the blob store settings.
+// Overridden by config (BLOB_STORE_*).
+const (
+	DefaultLocalDir       = "/var/lib/router-core/blobs"
+	DefaultReferenceTTL   = 1 * time.Hour
+	DefaultMaxUploadBytes = 256 << 20
+)
+
+var (
+	ErrNotFound   = errors.New("blob not found")
+	ErrInvalidKey = errors.New("invalid blob key")
+)
+
+// Object describes a stored blob.
+type Object struct {
+	Key         string
+	Size        int64
+	SHA256      string
+	ContentType string
+}
+
+type BlobStore interface {
+	// Backend returns the name of the backend (local or s3).
+	Backend() string
+
+	// Put stores body under key, replacing the blob stored under it, and returns its size and checksum.
+	Put(ctx context.Context, key string, body io.ReadSeeker, contentType string) (*Object, error)
+
+	// Open streams the blob, the caller closes it. A missing blob is ErrNotFound.
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+
+	// Delete removes the blob. A missing blob is not an error.
+	Delete(ctx context.Context, key string) error
+
+	// Reference returns what a tool is given to read the blob:
+	// its path for the local backend, a presigned URL for the s3 backend.
+	Reference(ctx context.Context, key string) (string, error)
+}
+
+// NewBlobStore returns the blob store selected by config.
+func NewBlobStore(config *config.Config, s3Client s3_wrapper.S3WrapperClient) (BlobStore, error) {
+	ttl := DefaultReferenceTTL
+	if v := config.BlobStore.ReferenceTTLSeconds; v > 0 {
+		ttl = time.Duration(v * float64(time.Second))
+	}
+
+	switch config.BlobStore.Backend {
+	case "", BackendLocal:
+		dir := config.BlobStore.LocalDir
+		if dir == "" {
+			dir = DefaultLocalDir
+		}
+		return NewLocalBlobStore(dir)
+	case BackendS3:
+		if config.BlobStore.S3Bucket == "" {
+			return nil, fmt.Errorf("blob store bucket is not configured (BLOB_STORE_S3_BUCKET)")
+		}
+		return NewS3BlobStore(s3Client, config.BlobStore.S3Bucket, config.BlobStore.S3Prefix, ttl), nil
+	default:
+		return nil, fmt.Errorf("unknown blob store backend %q", config.BlobStore.Backend)
+	}
+}
+
+// checkKey rejects keys escaping the store (absolute, "..", empty segments).
+func checkKey(key string) error {
+	if key == "" || strings.HasPrefix(key, "/") || path.Clean(key) != key {
+		return fmt.Errorf("%w: %q", ErrInvalidKey, key)
+	}
+	for _, segment := range strings.Split(key, "/") {
+		if segment == ".." || segment == "." {
+			return fmt.Errorf("%w: %q", ErrInvalidKey, key)
+		}
+	}
+	return nil
+}
+
+// digest reads body to compute its size and checksum, then rewinds it.
+func digest(body io.ReadSeeker) (int64, string, error) {
+	hash := sha256.New()
+	size, err := io.Copy(hash, body)
+	if err != nil {
+		return 0, "", err
+	}
+	if _, err := body.Seek(0, io.SeekStart); err != nil {
+		return 0, "", err
+	}
+	return size, hex.EncodeToString(hash.Sum(nil)), nil
+}
@@ -0,0 +1,117 @@
+package blobstore
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+type localBlobStore struct {
+	dir string
+}
+
+// NewLocalBlobStore stores blobs as files under dir, which is created when missing.
+// References are absolute paths, so tools must share the filesystem (volume) of the router.
+func NewLocalBlobStore(dir string) (BlobStore, error) {
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(absDir, 0o750); err != nil {
+		return nil, fmt.Errorf("failed to create blob store directory: %w", err)
+	}
+	return &localBlobStore{dir: absDir}, nil
+}
+
+func (s *localBlobStore) Backend() string {
+	return BackendLocal
+}
+
+func (s *localBlobStore) path(key string) (string, error) {
+	if err := checkKey(key); err != nil {
+		return "", err
+	}
+	return filepath.Join(s.dir, filepath.FromSlash(key)), nil
+}
+
+// Put writes to a temporary file renamed once complete, so readers never see a partial blob.
+func (s *localBlobStore) Put(ctx context.Context, key string, body io.ReadSeeker, contentType string) (*Object, error) {
+	target, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(target), 0o750); err != nil {
+		return nil, err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(target), ".upload-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(tmp.Name())
+
+	hash := sha256.New()
+	size, err := io.Copy(io.MultiWriter(tmp, hash), body)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if err := os.Rename(tmp.Name(), target); err != nil {
+		return nil, err
+	}
+
+	return &Object{
+		Key:         key,
+		Size:        size,
+		SHA256:      hex.EncodeToString(hash.Sum(nil)),
+		ContentType: contentType,
+	}, nil
+}
+
+func (s *localBlobStore) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	target, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.Open(target)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	return file, err
+}
+
+func (s *localBlobStore) Delete(ctx context.Context, key string) error {
+	target, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(target); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+func (s *localBlobStore) Reference(ctx context.Context, key string) (string, error) {
+	target, err := s.path(key)
+	if err != nil {
+		return "", err
+	}
+	if _, err := os.Stat(target); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return "", ErrNotFound
+		}
+		return "", err
+	}
+	return target, nil
+}
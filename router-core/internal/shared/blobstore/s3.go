@@ -0,0 +1,96 @@
+package blobstore
+
+import (
+	"context"
+	"io"
+	"strings"
+	"time"
+
+	s3_wrapper "aigendrug.com/router-core/internal/shared/s3-wrapper"
+)
+
+type s3BlobStore struct {
+	s3Client s3_wrapper.S3WrapperClient
+	bucket   string
+	prefix   string
+	ttl      time.Duration
+}
+
+// NewS3BlobStore stores blobs in bucket under prefix.
+// References are presigned GET URLs valid for ttl, so tools need no credentials of the bucket.
+func NewS3BlobStore(s3Client s3_wrapper.S3WrapperClient, bucket string, prefix string, ttl time.Duration) BlobStore {
+	prefix = strings.Trim(prefix, "/")
+	if prefix != "" {
+		prefix += "/"
+	}
+	return &s3BlobStore{
+		s3Client: s3Client,
+		bucket:   bucket,
+		prefix:   prefix,
+		ttl:      ttl,
+	}
+}
+
+func (s *s3BlobStore) Backend() string {
+	return BackendS3
+}
+
+func (s *s3BlobStore) objectKey(key string) (string, error) {
+	if err := checkKey(key); err != nil {
+		return "", err
+	}
+	return s.prefix + key, nil
+}
+
+func (s *s3BlobStore) Put(ctx context.Context, key string, body io.ReadSeeker, contentType string) (*Object, error) {
+	objectKey, err := s.objectKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	size, checksum, err := digest(body)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.s3Client.PutObject(ctx, s.bucket, objectKey, body, size, contentType); err != nil {
+		return nil, err
+	}
+
+	return &Object{
+		Key:         key,
+		Size:        size,
+		SHA256:      checksum,
+		ContentType: contentType,
+	}, nil
+}
+
+func (s *s3BlobStore) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	objectKey, err := s.objectKey(key)
+	if err != nil {
+		return nil, err
+	}
+	output, err := s.s3Client.OpenObject(ctx, s.bucket, objectKey)
+	if err != nil {
+		if s3_wrapper.IsNotFound(err) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return output.Body, nil
+}
+
+func (s *s3BlobStore) Delete(ctx context.Context, key string) error {
+	objectKey, err := s.objectKey(key)
+	if err != nil {
+		return err
+	}
+	return s.s3Client.DeleteObject(ctx, s.bucket, objectKey)
+}
+
+func (s *s3BlobStore) Reference(ctx context.Context, key string) (string, error) {
+	objectKey, err := s.objectKey(key)
+	if err != nil {
+		return "", err
+	}
+	return s.s3Client.PresignGetObject(ctx, s.bucket, objectKey, s.ttl)
+}
@@ -0,0 +1,265 @@
+// Package cron parses standard five-field cron expressions and computes their activation times.
+//
+// An expression is "minute hour day-of-month month day-of-week", each field being "*", a value,
+// a range "a-b", a step "*/n" or "a-b/n", or a comma separated list of those.
+// Months and days of the week also accept their English abbreviations (jan-dec, sun-sat), Sunday is 0 or 7.
+// As in Vixie cron, when both day fields are restricted a day matches either of them.
+//
+// The macros @yearly (@annually), @monthly, @weekly, @daily (@midnight) and @hourly are supported.
+package cron
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrInvalidExpression = errors.New("invalid cron expression")
+
+// searchYears bounds the search of the next activation, expressions which never match (e.g. "0 0 30 2 *")
+// have no activation.
+const searchYears = 5
+
+var macros = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+var monthNames = map[string]int{
+	"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+	"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+}
+
+var dayNames = map[string]int{
+	"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+}
+
+type field struct {
+	name  string
+	min   int
+	max   int
+	names map[string]int
+}
+
+var (
+	minuteField = field{name: "minute", min: 0, max: 59}
+	hourField   = field{name: "hour", min: 0, max: 23}
+	domField    = field{name: "day of month", min: 1, max: 31}
+	monthField  = field{name: "month", min: 1, max: 12, names: monthNames}
+	// 7 is accepted as Sunday and folded to 0
+	dowField = field{name: "day of week", min: 0, max: 7, names: dayNames}
+)
+
+// Schedule is a parsed cron expression.
+type Schedule struct {
+	expression string
+
+	minute uint64
+	hour   uint64
+	dom    uint64
+	month  uint64
+	dow    uint64
+
+	// a day field left as "*" does not restrict the other one
+	domAny bool
+	dowAny bool
+}
+
+// Parse parses a cron expression.
+func Parse(expression string) (*Schedule, error) {
+	expression = strings.TrimSpace(expression)
+
+	spec := expression
+	if strings.HasPrefix(spec, "@") {
+		macro, ok := macros[strings.ToLower(spec)]
+		if !ok {
+			return nil, fmt.Errorf("%w: unknown macro %q", ErrInvalidExpression, spec)
+		}
+		spec = macro
+	}
+
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("%w: expected 5 fields (minute hour day-of-month month day-of-week), got %d",
+			ErrInvalidExpression, len(fields))
+	}
+
+	s := &Schedule{expression: expression}
+	var err error
+	if s.minute, err = minuteField.parse(fields[0]); err != nil {
+		return nil, err
+	}
+	if s.hour, err = hourField.parse(fields[1]); err != nil {
+		return nil, err
+	}
+	if s.dom, err = domField.parse(fields[2]); err != nil {
+		return nil, err
+	}
+	if s.month, err = monthField.parse(fields[3]); err != nil {
+		return nil, err
+	}
+	if s.dow, err = dowField.parse(fields[4]); err != nil {
+		return nil, err
+	}
+	if s.dow&(1<<7) != 0 {
+		s.dow = s.dow&^(1<<7) | 1
+	}
+	s.domAny = fields[2] == "*" || fields[2] == "?"
+	s.dowAny = fields[4] == "*" || fields[4] == "?"
+
+	return s, nil
+}
+
+// String returns the expression the schedule was parsed from.
+func (s *Schedule) String() string {
+	return s.expression
+}
+
+// parse returns the bit set of the values matched by a field.
+func (f field) parse(spec string) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(spec, ",") {
+		partBits, err := f.parsePart(part)
+		if err != nil {
+			return 0, fmt.Errorf("%w: %s field %q: %v", ErrInvalidExpression, f.name, spec, err)
+		}
+		bits |= partBits
+	}
+	return bits, nil
+}
+
+func (f field) parsePart(part string) (uint64, error) {
+	rangeSpec, stepSpec, hasStep := strings.Cut(part, "/")
+
+	step := 1
+	if hasStep {
+		var err error
+		if step, err = strconv.Atoi(stepSpec); err != nil || step <= 0 {
+			return 0, fmt.Errorf("invalid step %q", stepSpec)
+		}
+	}
+
+	var low, high int
+	switch {
+	case rangeSpec == "*" || rangeSpec == "?":
+		low, high = f.min, f.max
+	case strings.Contains(rangeSpec, "-"):
+		lowSpec, highSpec, _ := strings.Cut(rangeSpec, "-")
+		var err error
+		if low, err = f.value(lowSpec); err != nil {
+			return 0, err
+		}
+		if high, err = f.value(highSpec); err != nil {
+			return 0, err
+		}
+		if low > high {
+			return 0, fmt.Errorf("range %q is reversed", rangeSpec)
+		}
+	default:
+		value, err := f.value(rangeSpec)
+		if err != nil {
+			return 0, err
+		}
+		// "a/n" starts at a and runs to the end of the field
+		low, high = value, value
+		if hasStep {
+			high = f.max
+		}
+	}
+
+	var bits uint64
+	for v := low; v <= high; v += step {
+		bits |= 1 << uint(v)
+	}
+	return bits, nil
+}
+
+func (f field) value(spec string) (int, error) {
+	if v, ok := f.names[strings.ToLower(spec)]; ok {
+		return v, nil
+	}
+
+	v, err := strconv.Atoi(spec)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value %q", spec)
+	}
+	if v < f.min || v > f.max {
+		return 0, fmt.Errorf("value %d out of range [%d, %d]", v, f.min, f.max)
+	}
+	return v, nil
+}
+
+// Next returns the first activation strictly after t, in the location of t,
+// or the zero time when the schedule has no activation within the next years.
+// Wall clock times skipped by a DST change do not activate, times repeated by it activate twice.
+func (s *Schedule) Next(t time.Time) time.Time {
+	loc := t.Location()
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(searchYears, 0, 0)
+
+	for t.Before(limit) {
+		if s.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc)
+			continue
+		}
+		if !s.matchDay(t) {
+			t = nextDay(t)
+			continue
+		}
+		if s.hour&(1<<uint(t.Hour())) == 0 {
+			t = t.Add(time.Duration(60-t.Minute()) * time.Minute)
+			continue
+		}
+		if s.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+// Preview returns the next count activations after t.
+func (s *Schedule) Preview(t time.Time, count int) []time.Time {
+	runs := make([]time.Time, 0, count)
+	for len(runs) < count {
+		t = s.Next(t)
+		if t.IsZero() {
+			break
+		}
+		runs = append(runs, t)
+	}
+	return runs
+}
+
+func (s *Schedule) matchDay(t time.Time) bool {
+	domMatch := s.dom&(1<<uint(t.Day())) != 0
+	dowMatch := s.dow&(1<<uint(t.Weekday())) != 0
+
+	switch {
+	case s.domAny && s.dowAny:
+		return true
+	case s.domAny:
+		return dowMatch
+	case s.dowAny:
+		return domMatch
+	default:
+		return domMatch || dowMatch
+	}
+}
+
+// nextDay returns the start of the day after t. Days starting with a DST gap start at the first valid time.
+func nextDay(t time.Time) time.Time {
+	next := time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+	if !next.After(t) {
+		next = t.Add(time.Hour)
+	}
+	return next
+}
@@ -0,0 +1,218 @@
+package cron
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestParseRejectsInvalidExpressions(t *testing.T) {
+	tests := []struct {
+		name       string
+		expression string
+	}{
+		{name: "empty", expression: ""},
+		{name: "four fields", expression: "* * * *"},
+		{name: "six fields", expression: "0 * * * * *"},
+		{name: "unknown macro", expression: "@reboot"},
+		{name: "minute out of range", expression: "60 * * * *"},
+		{name: "hour out of range", expression: "0 24 * * *"},
+		{name: "day of month zero", expression: "0 0 0 * *"},
+		{name: "month out of range", expression: "0 0 1 13 *"},
+		{name: "day of week out of range", expression: "0 0 * * 8"},
+		{name: "unknown name", expression: "0 0 * foo *"},
+		{name: "reversed range", expression: "5-1 * * * *"},
+		{name: "zero step", expression: "*/0 * * * *"},
+		{name: "invalid step", expression: "*/x * * * *"},
+		{name: "empty list item", expression: "1,,2 * * * *"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(tt.expression)
+			if !errors.Is(err, ErrInvalidExpression) {
+				t.Fatalf("Parse(%q) error = %v, want ErrInvalidExpression", tt.expression, err)
+			}
+		})
+	}
+}
+
+func TestScheduleNext(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("time zone database unavailable: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		expression string
+		from       time.Time
+		want       time.Time
+	}{
+		{
+			name:       "step within the hour",
+			expression: "*/15 * * * *",
+			from:       time.Date(2024, 1, 1, 10, 7, 0, 0, time.UTC),
+			want:       time.Date(2024, 1, 1, 10, 15, 0, 0, time.UTC),
+		},
+		{
+			name:       "strictly after an activation",
+			expression: "*/15 * * * *",
+			from:       time.Date(2024, 1, 1, 10, 15, 0, 0, time.UTC),
+			want:       time.Date(2024, 1, 1, 10, 30, 0, 0, time.UTC),
+		},
+		{
+			name:       "seconds are ignored",
+			expression: "@hourly",
+			from:       time.Date(2024, 1, 1, 10, 59, 59, 0, time.UTC),
+			want:       time.Date(2024, 1, 1, 11, 0, 0, 0, time.UTC),
+		},
+		{
+			name:       "step from a value runs to the end of the field",
+			expression: "5/20 * * * *",
+			from:       time.Date(2024, 1, 1, 10, 45, 0, 0, time.UTC),
+			want:       time.Date(2024, 1, 1, 11, 5, 0, 0, time.UTC),
+		},
+		{
+			name:       "weekday names skip the weekend",
+			expression: "0 9 * * mon-fri",
+			from:       time.Date(2024, 1, 5, 9, 0, 0, 0, time.UTC),
+			want:       time.Date(2024, 1, 8, 9, 0, 0, 0, time.UTC),
+		},
+		{
+			name:       "sunday as 7",
+			expression: "0 0 * * 7",
+			from:       time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+			want:       time.Date(2024, 1, 7, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:       "restricted day fields match either",
+			expression: "0 0 1 * mon",
+			from:       time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC),
+			want:       time.Date(2024, 1, 8, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:       "leap day",
+			expression: "0 0 29 2 *",
+			from:       time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC),
+			want:       time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:       "month names",
+			expression: "0 0 1 jan,jul *",
+			from:       time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC),
+			want:       time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:       "never matching",
+			expression: "0 0 30 2 *",
+			from:       time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+			want:       time.Time{},
+		},
+		{
+			name:       "in the location of from",
+			expression: "0 9 * * *",
+			from:       time.Date(2024, 1, 1, 10, 0, 0, 0, newYork),
+			want:       time.Date(2024, 1, 2, 9, 0, 0, 0, newYork),
+		},
+		{
+			name:       "wall clock time skipped by DST",
+			expression: "30 2 * * *",
+			from:       time.Date(2024, 3, 9, 3, 0, 0, 0, newYork),
+			want:       time.Date(2024, 3, 11, 2, 30, 0, 0, newYork),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schedule, err := Parse(tt.expression)
+			if err != nil {
+				t.Fatalf("Parse(%q) error = %v", tt.expression, err)
+			}
+			if got := schedule.Next(tt.from); !got.Equal(tt.want) {
+				t.Fatalf("Next(%v) = %v, want %v", tt.from, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScheduleNextRepeatsWallClockTimeOnDSTFallBack(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("time zone database unavailable: %v", err)
+	}
+
+	schedule, err := Parse("30 1 * * *")
+	if err != nil {
+		t.Fatalf("Parse error = %v", err)
+	}
+
+	runs := schedule.Preview(time.Date(2024, 11, 3, 0, 0, 0, 0, newYork), 3)
+	want := []time.Time{
+		time.Date(2024, 11, 3, 5, 30, 0, 0, time.UTC), // 01:30 EDT
+		time.Date(2024, 11, 3, 6, 30, 0, 0, time.UTC), // 01:30 EST
+		time.Date(2024, 11, 4, 6, 30, 0, 0, time.UTC),
+	}
+	if len(runs) != len(want) {
+		t.Fatalf("Preview returned %d runs, want %d", len(runs), len(want))
+	}
+	for i := range want {
+		if !runs[i].Equal(want[i]) {
+			t.Errorf("run %d = %v, want %v", i, runs[i], want[i])
+		}
+	}
+}
+
+func TestSchedulePreview(t *testing.T) {
+	tests := []struct {
+		name       string
+		expression string
+		count      int
+		want       []time.Time
+	}{
+		{
+			name:       "every six hours",
+			expression: "0 */6 * * *",
+			count:      3,
+			want: []time.Time{
+				time.Date(2024, 1, 1, 6, 0, 0, 0, time.UTC),
+				time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC),
+				time.Date(2024, 1, 1, 18, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			name:       "monthly macro",
+			expression: "@monthly",
+			count:      2,
+			want: []time.Time{
+				time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC),
+				time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			name:       "no activation",
+			expression: "0 0 31 4 *",
+			count:      5,
+			want:       []time.Time{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schedule, err := Parse(tt.expression)
+			if err != nil {
+				t.Fatalf("Parse(%q) error = %v", tt.expression, err)
+			}
+
+			runs := schedule.Preview(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), tt.count)
+			if len(runs) != len(tt.want) {
+				t.Fatalf("Preview returned %v, want %v", runs, tt.want)
+			}
+			for i := range tt.want {
+				if !runs[i].Equal(tt.want[i]) {
+					t.Errorf("run %d = %v, want %v", i, runs[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
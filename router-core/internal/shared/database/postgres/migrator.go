@@ -3,9 +3,15 @@ package postgres
 import (
 	"context"
 	_ "embed"
+	"errors"
 	"fmt"
+
+	"github.com/jackc/pgx/v5/pgconn"
 )
 
+// SQLSTATE raised by CREATE DATABASE when the database already exists
+const duplicateDatabaseCode = "42P04"
+
 //go:embed sql/init.sql
 var initial_sql string
 
@@ -23,8 +29,10 @@ func AutoMigrateFromConnectionString(ctx context.Context, connectionString strin
 		return false, err
 	}
 
+	// init.sql is idempotent, so an existing database is still migrated to add new tables and columns
 	_, err = db.Exec(ctx, fmt.Sprintf("CREATE DATABASE %s", dbName))
-	if err != nil {
+	var pgErr *pgconn.PgError
+	if err != nil && !(errors.As(err, &pgErr) && pgErr.Code == duplicateDatabaseCode) {
 		return true, err
 	}
 
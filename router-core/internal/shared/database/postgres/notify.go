@@ -0,0 +1,72 @@
+package postgres
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	listenRetryMinDelay = 1 * time.Second
+	listenRetryMaxDelay = 30 * time.Second
+)
+
+// Notify sends payload to the LISTENers of channel (payloads are limited to 8000 bytes by Postgres).
+func Notify(ctx context.Context, db DbExecutor, channel string, payload string) error {
+	_, err := db.Exec(ctx, "SELECT pg_notify($1, $2)", channel, payload)
+	return err
+}
+
+// Listen LISTENs to channel on a dedicated connection of the pool and calls handle with the payload
+// of every notification until ctx is done. A lost connection is re-established with backoff;
+// notifications sent while reconnecting are lost.
+func Listen(ctx context.Context, pool *pgxpool.Pool, channel string, handle func(payload string)) {
+	delay := listenRetryMinDelay
+
+	for ctx.Err() == nil {
+		err := listen(ctx, pool, channel, handle, func() { delay = listenRetryMinDelay })
+		if ctx.Err() != nil {
+			return
+		}
+
+		log.Printf("Listening to %s failed, retrying in %v: %v", channel, delay, err)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+
+		delay *= 2
+		if delay > listenRetryMaxDelay {
+			delay = listenRetryMaxDelay
+		}
+	}
+}
+
+func listen(
+	ctx context.Context, pool *pgxpool.Pool, channel string, handle func(payload string), connected func(),
+) error {
+	pooledConn, err := pool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	// the connection stays in LISTEN state, so it is never handed back to the pool
+	conn := pooledConn.Hijack()
+	defer conn.Close(context.Background())
+
+	if _, err := conn.Exec(ctx, "LISTEN "+pgx.Identifier{channel}.Sanitize()); err != nil {
+		return err
+	}
+	connected()
+
+	for {
+		notification, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+		handle(notification.Payload)
+	}
+}
@@ -1,3 +1,6 @@
+-- serialize concurrent migrations of several router-core replicas
+SELECT pg_advisory_xact_lock(7242001);
+
 CREATE TABLE IF NOT EXISTS clients (
     id SERIAL PRIMARY KEY,
     name TEXT NOT NULL,
@@ -27,6 +30,9 @@ CREATE TABLE IF NOT EXISTS tools (
     updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
 );
 
+-- draft tools (e.g. imported from Lambda functions) are listed to admins but not offered to clients until published
+ALTER TABLE tools ADD COLUMN IF NOT EXISTS draft BOOLEAN NOT NULL DEFAULT FALSE;
+
 CREATE TABLE IF NOT EXISTS tool_client_permissions (
     id SERIAL PRIMARY KEY,
     tool_id INT NOT NULL,
@@ -53,4 +59,173 @@ CREATE TABLE IF NOT EXISTS tool_requests (
     FOREIGN KEY (tool_id) REFERENCES tools(id) ON DELETE CASCADE,
     FOREIGN KEY (client_id) REFERENCES clients(id) ON DELETE CASCADE
 );
-CREATE INDEX IF NOT EXISTS idx_tool_requests_client_id ON tool_requests (client_id);
\ No newline at end of file
+CREATE INDEX IF NOT EXISTS idx_tool_requests_client_id ON tool_requests (client_id);
+
+-- durable execution queue: workers claim pending requests and hold a lease while running
+ALTER TABLE tool_requests ADD COLUMN IF NOT EXISTS locked_by TEXT;
+ALTER TABLE tool_requests ADD COLUMN IF NOT EXISTS lease_expires_at TIMESTAMPTZ;
+ALTER TABLE tool_requests ADD COLUMN IF NOT EXISTS heartbeat_at TIMESTAMPTZ;
+ALTER TABLE tool_requests ADD COLUMN IF NOT EXISTS claim_count INT NOT NULL DEFAULT 0;
+CREATE INDEX IF NOT EXISTS idx_tool_requests_status_created_at ON tool_requests (status, created_at);
+
+-- invocation attempts of a tool request (JSON array), recorded by the retry policy
+ALTER TABLE tool_requests ADD COLUMN IF NOT EXISTS attempts TEXT;
+
+-- an invoked async-event request stays running without lease between its status checks,
+-- a worker claims it again at its next status check
+ALTER TABLE tool_requests ADD COLUMN IF NOT EXISTS next_poll_at TIMESTAMPTZ;
+CREATE INDEX IF NOT EXISTS idx_tool_requests_next_poll_at ON tool_requests (next_poll_at)
+    WHERE status = 'running' AND locked_by IS NULL;
+
+-- completion webhooks
+CREATE TABLE IF NOT EXISTS webhook_secrets (
+    client_id INT PRIMARY KEY,
+    secret TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    FOREIGN KEY (client_id) REFERENCES clients(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS webhook_endpoints (
+    id SERIAL PRIMARY KEY,
+    client_id INT NOT NULL,
+    url TEXT NOT NULL,
+    description TEXT,
+    is_active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    FOREIGN KEY (client_id) REFERENCES clients(id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_webhook_endpoints_client_id ON webhook_endpoints (client_id);
+
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id SERIAL PRIMARY KEY,
+    client_id INT NOT NULL,
+    tool_request_id INT NOT NULL,
+    webhook_endpoint_id INT,
+    url TEXT NOT NULL,
+    event VARCHAR(255) NOT NULL,
+    payload TEXT NOT NULL,
+    status VARCHAR(255) NOT NULL,
+    attempt_count INT NOT NULL DEFAULT 0,
+    attempts TEXT,
+    next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    locked_by TEXT,
+    lease_expires_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    FOREIGN KEY (client_id) REFERENCES clients(id) ON DELETE CASCADE,
+    FOREIGN KEY (tool_request_id) REFERENCES tool_requests(id) ON DELETE CASCADE,
+    FOREIGN KEY (webhook_endpoint_id) REFERENCES webhook_endpoints(id) ON DELETE SET NULL
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_webhook_deliveries_tool_request_id_url ON webhook_deliveries (tool_request_id, url);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_client_id ON webhook_deliveries (client_id);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_status_next_attempt_at ON webhook_deliveries (status, next_attempt_at);
+
+-- finished tool requests are swept for the webhook deliveries which were not created
+CREATE INDEX IF NOT EXISTS idx_tool_requests_status_updated_at ON tool_requests (status, updated_at);
+
+-- result cache of cacheable tools, keyed by the hash of the tool id / version and the canonical payload
+CREATE TABLE IF NOT EXISTS tool_result_cache (
+    id SERIAL PRIMARY KEY,
+    tool_id INT NOT NULL,
+    tool_version VARCHAR(255) NOT NULL,
+    cache_key VARCHAR(64) NOT NULL,
+    tool_request_id INT NOT NULL,
+    hit_count INT NOT NULL DEFAULT 0,
+    last_hit_at TIMESTAMPTZ,
+    expires_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    FOREIGN KEY (tool_id) REFERENCES tools(id) ON DELETE CASCADE,
+    FOREIGN KEY (tool_request_id) REFERENCES tool_requests(id) ON DELETE CASCADE
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_tool_result_cache_tool_id_cache_key ON tool_result_cache (tool_id, cache_key);
+CREATE INDEX IF NOT EXISTS idx_tool_result_cache_tool_request_id ON tool_result_cache (tool_request_id);
+
+-- schedules creating tool requests on a cron expression, claimed by a single replica when due
+CREATE TABLE IF NOT EXISTS tool_schedules (
+    id SERIAL PRIMARY KEY,
+    tool_id INT NOT NULL,
+    client_id INT NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    cron_expression VARCHAR(255) NOT NULL,
+    timezone VARCHAR(255) NOT NULL DEFAULT 'UTC',
+    payload TEXT NOT NULL,
+    webhook_url TEXT,
+    missed_run_policy VARCHAR(255) NOT NULL,
+    status VARCHAR(255) NOT NULL,
+    next_run_at TIMESTAMPTZ,
+    last_run_at TIMESTAMPTZ,
+    last_tool_request_id INT,
+    last_error TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    FOREIGN KEY (tool_id) REFERENCES tools(id) ON DELETE CASCADE,
+    FOREIGN KEY (client_id) REFERENCES clients(id) ON DELETE CASCADE,
+    FOREIGN KEY (last_tool_request_id) REFERENCES tool_requests(id) ON DELETE SET NULL
+);
+CREATE INDEX IF NOT EXISTS idx_tool_schedules_client_id ON tool_schedules (client_id);
+CREATE INDEX IF NOT EXISTS idx_tool_schedules_status_next_run_at ON tool_schedules (status, next_run_at);
+
+-- current health of each tool, maintained by the health prober which claims due tools with health_probe_at
+ALTER TABLE tools ADD COLUMN IF NOT EXISTS health_status VARCHAR(32) NOT NULL DEFAULT 'unknown';
+ALTER TABLE tools ADD COLUMN IF NOT EXISTS health_message TEXT NOT NULL DEFAULT '';
+ALTER TABLE tools ADD COLUMN IF NOT EXISTS health_failures INT NOT NULL DEFAULT 0;
+ALTER TABLE tools ADD COLUMN IF NOT EXISTS health_checked_at TIMESTAMPTZ;
+ALTER TABLE tools ADD COLUMN IF NOT EXISTS health_changed_at TIMESTAMPTZ;
+ALTER TABLE tools ADD COLUMN IF NOT EXISTS health_probe_at TIMESTAMPTZ;
+CREATE INDEX IF NOT EXISTS idx_tools_health_probe_at ON tools (health_probe_at);
+
+-- health check history, the most recent checks of each tool are kept
+CREATE TABLE IF NOT EXISTS tool_health_checks (
+    id SERIAL PRIMARY KEY,
+    tool_id INT NOT NULL,
+    healthy BOOLEAN NOT NULL,
+    status VARCHAR(32) NOT NULL,
+    message TEXT NOT NULL DEFAULT '',
+    latency_ms BIGINT NOT NULL DEFAULT 0,
+    checked_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    FOREIGN KEY (tool_id) REFERENCES tools(id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_tool_health_checks_tool_id ON tool_health_checks (tool_id, id);
+
+-- circuit breakers of the tools shared by the replicas: the last trip (by any replica) and its closing or reset
+CREATE TABLE IF NOT EXISTS tool_circuit_breakers (
+    tool_id INT PRIMARY KEY,
+    state VARCHAR(32) NOT NULL,
+    opened_at TIMESTAMPTZ,
+    open_until TIMESTAMPTZ,
+    trips INT NOT NULL DEFAULT 0,
+    last_error TEXT NOT NULL DEFAULT '',
+    reset_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    FOREIGN KEY (tool_id) REFERENCES tools(id) ON DELETE CASCADE
+);
+
+-- token buckets of the rate limits per client and endpoint class, shared by the replicas
+CREATE TABLE IF NOT EXISTS client_rate_limits (
+    client_id INT NOT NULL,
+    class VARCHAR(32) NOT NULL,
+    tokens DOUBLE PRECISION NOT NULL,
+    allowed BOOLEAN NOT NULL DEFAULT TRUE,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    PRIMARY KEY (client_id, class),
+    FOREIGN KEY (client_id) REFERENCES clients(id) ON DELETE CASCADE
+);
+
+-- executions accepted per client and UTC day, counted against the daily and monthly quotas
+CREATE TABLE IF NOT EXISTS client_execution_usage (
+    client_id INT NOT NULL,
+    day DATE NOT NULL,
+    executions INT NOT NULL DEFAULT 0,
+
+    PRIMARY KEY (client_id, day),
+    FOREIGN KEY (client_id) REFERENCES clients(id) ON DELETE CASCADE
+);
@@ -0,0 +1,191 @@
+// Package exec_wrapper runs commands on the router host with bounded resources:
+// wall clock (the context), CPU time and the size of the captured output.
+package exec_wrapper
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// waitDelay bounds the wait for the output pipes once the process is gone,
+// a background child keeping them open does not hold the run.
+const waitDelay = 2 * time.Second
+
+type ExecWrapperClient struct{}
+
+func NewExecWrapperClient() ExecWrapperClient {
+	return ExecWrapperClient{}
+}
+
+type RunInput struct {
+	// Command is the path of the executable, Args its arguments (without the command itself).
+	Command string
+	Args    []string
+	Dir     string
+	// Env is the whole environment of the process, nothing is inherited from the router.
+	Env   []string
+	Stdin []byte
+
+	// CPUSeconds limits the CPU time of the process (RLIMIT_CPU), unlimited when zero.
+	CPUSeconds int
+	// MaxStdoutBytes kills the process once its stdout exceeds it, unlimited when zero.
+	MaxStdoutBytes int64
+	// MaxStderrBytes keeps the last bytes of stderr only, unlimited when zero.
+	MaxStderrBytes int64
+}
+
+type RunOutput struct {
+	// ExitCode is -1 when the process was killed by a signal.
+	ExitCode int
+	Signal   string
+	Stdout   []byte
+	Stderr   []byte
+
+	StdoutExceeded  bool
+	StderrTruncated bool
+	// CPULimitExceeded reports a process killed for using up CPUSeconds.
+	CPULimitExceeded bool
+	// ContextErr is the error of the context which ended the run, if any.
+	ContextErr error
+
+	Duration time.Duration
+	CPUTime  time.Duration
+}
+
+// Run starts the command and waits for it. The process and its children are killed when ctx is done
+// or when stdout exceeds its cap. The error reports a command which could not be started, every
+// other outcome (non zero exit code, signal, limits) is described by the output.
+func (wrapper *ExecWrapperClient) Run(ctx context.Context, input RunInput) (*RunOutput, error) {
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	name, args := input.Command, input.Args
+	if input.CPUSeconds > 0 {
+		// the limit is set by a shell replaced by the command, so that it applies from its first instruction.
+		// The process gets SIGXCPU at the soft limit and SIGKILL one second later.
+		name = "/bin/sh"
+		args = append([]string{"-c", `ulimit -St "$0" && ulimit -Ht "$(($0 + 1))" && exec "$@"`,
+			strconv.Itoa(input.CPUSeconds), input.Command}, input.Args...)
+	}
+
+	cmd := exec.CommandContext(runCtx, name, args...)
+	cmd.Dir = input.Dir
+	cmd.Env = input.Env
+	if cmd.Env == nil {
+		cmd.Env = []string{}
+	}
+	cmd.Stdin = bytes.NewReader(input.Stdin)
+	setProcessGroup(cmd)
+	cmd.Cancel = func() error {
+		return killProcessGroup(cmd)
+	}
+	cmd.WaitDelay = waitDelay
+
+	stdout := &headBuffer{limit: input.MaxStdoutBytes, onExceed: cancel}
+	stderr := &tailBuffer{limit: input.MaxStderrBytes}
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+
+	startedAt := time.Now()
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start %s: %w", input.Command, err)
+	}
+	waitErr := cmd.Wait()
+
+	output := &RunOutput{
+		ExitCode:        cmd.ProcessState.ExitCode(),
+		Stdout:          stdout.Bytes(),
+		Stderr:          stderr.Bytes(),
+		StdoutExceeded:  stdout.Exceeded(),
+		StderrTruncated: stderr.Truncated(),
+		ContextErr:      ctx.Err(),
+		Duration:        time.Since(startedAt),
+		CPUTime:         cmd.ProcessState.UserTime() + cmd.ProcessState.SystemTime(),
+	}
+	output.Signal, output.CPULimitExceeded = terminationSignal(cmd.ProcessState)
+	// killed at the hard limit after handling SIGXCPU
+	if input.CPUSeconds > 0 && output.Signal != "" && output.CPUTime >= time.Duration(input.CPUSeconds)*time.Second {
+		output.CPULimitExceeded = true
+	}
+
+	var exitErr *exec.ExitError
+	if waitErr != nil && !errors.As(waitErr, &exitErr) && !errors.Is(waitErr, exec.ErrWaitDelay) && output.ContextErr == nil {
+		return nil, fmt.Errorf("failed to wait for %s: %w", input.Command, waitErr)
+	}
+
+	return output, nil
+}
+
+// headBuffer keeps the first limit bytes written and reports when more were written.
+type headBuffer struct {
+	mu       sync.Mutex
+	buf      bytes.Buffer
+	limit    int64
+	exceeded bool
+	onExceed func()
+}
+
+func (b *headBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.limit > 0 && int64(b.buf.Len()+len(p)) > b.limit {
+		b.buf.Write(p[:b.limit-int64(b.buf.Len())])
+		if !b.exceeded {
+			b.exceeded = true
+			b.onExceed()
+		}
+		return len(p), nil
+	}
+	return b.buf.Write(p)
+}
+
+func (b *headBuffer) Bytes() []byte {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return bytes.Clone(b.buf.Bytes())
+}
+
+func (b *headBuffer) Exceeded() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.exceeded
+}
+
+// tailBuffer keeps the last limit bytes written.
+type tailBuffer struct {
+	mu        sync.Mutex
+	buf       []byte
+	limit     int64
+	truncated bool
+}
+
+func (b *tailBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.buf = append(b.buf, p...)
+	if b.limit > 0 && int64(len(b.buf)) > b.limit {
+		b.buf = append(b.buf[:0], b.buf[int64(len(b.buf))-b.limit:]...)
+		b.truncated = true
+	}
+	return len(p), nil
+}
+
+func (b *tailBuffer) Bytes() []byte {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return bytes.Clone(b.buf)
+}
+
+func (b *tailBuffer) Truncated() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.truncated
+}
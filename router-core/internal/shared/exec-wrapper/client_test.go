@@ -0,0 +1,94 @@
+package exec_wrapper
+
+import "testing"
+
+func TestHeadBuffer(t *testing.T) {
+	tests := []struct {
+		name         string
+		limit        int64
+		writes       []string
+		want         string
+		wantExceeded bool
+	}{
+		{name: "unlimited", writes: []string{"hello ", "world"}, want: "hello world"},
+		{name: "under the limit", limit: 16, writes: []string{"hello ", "world"}, want: "hello world"},
+		{name: "exactly the limit", limit: 11, writes: []string{"hello ", "world"}, want: "hello world"},
+		{name: "one write over the limit", limit: 4, writes: []string{"hello"}, want: "hell", wantExceeded: true},
+		{name: "second write over the limit", limit: 8, writes: []string{"hello ", "world"}, want: "hello wo", wantExceeded: true},
+		{name: "writes after the limit", limit: 5, writes: []string{"hello", " ", "world"}, want: "hello", wantExceeded: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exceeds := 0
+			b := &headBuffer{limit: tt.limit, onExceed: func() { exceeds++ }}
+			for _, w := range tt.writes {
+				// writes past the limit are discarded without failing the writer
+				if n, err := b.Write([]byte(w)); n != len(w) || err != nil {
+					t.Fatalf("Write(%q) = %d, %v", w, n, err)
+				}
+			}
+
+			if got := string(b.Bytes()); got != tt.want {
+				t.Fatalf("Bytes() = %q, want %q", got, tt.want)
+			}
+			if b.Exceeded() != tt.wantExceeded {
+				t.Fatalf("Exceeded() = %v, want %v", b.Exceeded(), tt.wantExceeded)
+			}
+			if tt.wantExceeded && exceeds != 1 {
+				t.Fatalf("onExceed called %d times, want once", exceeds)
+			}
+			if !tt.wantExceeded && exceeds != 0 {
+				t.Fatalf("onExceed called %d times under the limit", exceeds)
+			}
+		})
+	}
+}
+
+func TestTailBuffer(t *testing.T) {
+	tests := []struct {
+		name          string
+		limit         int64
+		writes        []string
+		want          string
+		wantTruncated bool
+	}{
+		{name: "unlimited", writes: []string{"hello ", "world"}, want: "hello world"},
+		{name: "under the limit", limit: 16, writes: []string{"hello ", "world"}, want: "hello world"},
+		{name: "exactly the limit", limit: 11, writes: []string{"hello ", "world"}, want: "hello world"},
+		{name: "one write over the limit", limit: 3, writes: []string{"hello"}, want: "llo", wantTruncated: true},
+		{name: "keeps the last bytes", limit: 8, writes: []string{"hello ", "world"}, want: "lo world", wantTruncated: true},
+		{name: "many small writes", limit: 4, writes: []string{"a", "b", "c", "d", "e", "f"}, want: "cdef", wantTruncated: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := &tailBuffer{limit: tt.limit}
+			for _, w := range tt.writes {
+				if n, err := b.Write([]byte(w)); n != len(w) || err != nil {
+					t.Fatalf("Write(%q) = %d, %v", w, n, err)
+				}
+			}
+
+			if got := string(b.Bytes()); got != tt.want {
+				t.Fatalf("Bytes() = %q, want %q", got, tt.want)
+			}
+			if b.Truncated() != tt.wantTruncated {
+				t.Fatalf("Truncated() = %v, want %v", b.Truncated(), tt.wantTruncated)
+			}
+		})
+	}
+}
+
+func TestBuffersReturnCopies(t *testing.T) {
+	head := &headBuffer{onExceed: func() {}}
+	tail := &tailBuffer{}
+	_, _ = head.Write([]byte("abc"))
+	_, _ = tail.Write([]byte("abc"))
+
+	head.Bytes()[0] = 'x'
+	tail.Bytes()[0] = 'x'
+	if string(head.Bytes()) != "abc" || string(tail.Bytes()) != "abc" {
+		t.Fatalf("Bytes() returned the buffers themselves: %q, %q", head.Bytes(), tail.Bytes())
+	}
+}
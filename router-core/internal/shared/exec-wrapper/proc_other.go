@@ -0,0 +1,20 @@
+//go:build !unix
+
+package exec_wrapper
+
+import (
+	"os"
+	"os/exec"
+)
+
+// Process groups and CPU limits are not supported, only the command itself is killed.
+
+func setProcessGroup(cmd *exec.Cmd) {}
+
+func killProcessGroup(cmd *exec.Cmd) error {
+	return cmd.Process.Kill()
+}
+
+func terminationSignal(state *os.ProcessState) (string, bool) {
+	return "", false
+}
@@ -0,0 +1,33 @@
+//go:build unix
+
+package exec_wrapper
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// setProcessGroup runs the command in its own process group, so that its children are killed with it.
+func setProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+}
+
+func killProcessGroup(cmd *exec.Cmd) error {
+	err := syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	if errors.Is(err, syscall.ESRCH) {
+		return os.ErrProcessDone
+	}
+	return err
+}
+
+// terminationSignal returns the signal which killed the process, if any,
+// and whether it was sent for exceeding the CPU time limit.
+func terminationSignal(state *os.ProcessState) (string, bool) {
+	status, ok := state.Sys().(syscall.WaitStatus)
+	if !ok || !status.Signaled() {
+		return "", false
+	}
+	return status.Signal().String(), status.Signal() == syscall.SIGXCPU
+}
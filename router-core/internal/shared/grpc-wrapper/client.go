@@ -0,0 +1,194 @@
+// Package grpc_wrapper invokes unary gRPC methods of servers whose API is only known at runtime.
+// Methods are described by server reflection or by a FileDescriptorSet, and the request and response
+// messages are transcoded from and to JSON.
+package grpc_wrapper
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	_ "google.golang.org/grpc/encoding/gzip" // accept gzip compressed responses
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// MaxMessageBytes bounds the size of a received message, once decompressed.
+const MaxMessageBytes = 64 << 20
+
+var ErrInvalidPayload = errors.New("invalid payload")
+
+// Target is a gRPC server. TLS is nil for plaintext (h2c) connections.
+type Target struct {
+	Address string
+	TLS     *TLSConfig
+}
+
+// TLSConfig holds PEM encoded certificates; empty fields use the system defaults.
+type TLSConfig struct {
+	ServerName         string
+	CACertPEM          string
+	ClientCertPEM      string
+	ClientKeyPEM       string
+	InsecureSkipVerify bool
+}
+
+type GRPCWrapperClient struct {
+	mu    sync.Mutex
+	conns map[string]*grpc.ClientConn
+}
+
+func NewGRPCWrapperClient() *GRPCWrapperClient {
+	return &GRPCWrapperClient{conns: map[string]*grpc.ClientConn{}}
+}
+
+type InvokeInput struct {
+	Target   Target
+	Method   *Method
+	Metadata map[string]string
+	Payload  map[string]any
+}
+
+type InvokeOutput struct {
+	Payload map[string]any
+	Header  metadata.MD
+	Trailer metadata.MD
+}
+
+// Invoke calls a unary method. The payload is decoded as the JSON form of the request message
+// (field names or JSON names), the response message is returned in its JSON form with the field names
+// of the .proto file and unpopulated fields included.
+// A call ending with a non OK status returns a *StatusError.
+func (c *GRPCWrapperClient) Invoke(ctx context.Context, input InvokeInput) (*InvokeOutput, error) {
+	method := input.Method
+
+	encodedPayload, err := json.Marshal(input.Payload)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPayload, err)
+	}
+	request := dynamicpb.NewMessage(method.Descriptor.Input())
+	if err := (protojson.UnmarshalOptions{Resolver: method.types}).Unmarshal(encodedPayload, request); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPayload, err)
+	}
+
+	conn, err := c.conn(input.Target)
+	if err != nil {
+		return nil, err
+	}
+
+	var header, trailer metadata.MD
+	response := dynamicpb.NewMessage(method.Descriptor.Output())
+	err = conn.Invoke(outgoingContext(ctx, input.Metadata), method.Path(), request, response,
+		grpc.Header(&header), grpc.Trailer(&trailer))
+	if err != nil {
+		return nil, statusErrorOf(err)
+	}
+
+	responseJSON, err := protojson.MarshalOptions{
+		UseProtoNames:   true,
+		EmitUnpopulated: true,
+		Resolver:        method.types,
+	}.Marshal(response)
+	if err != nil {
+		return nil, &StatusError{Code: CodeInternal, Message: fmt.Sprintf("failed to encode response: %v", err)}
+	}
+
+	var payload map[string]any
+	if err := json.Unmarshal(responseJSON, &payload); err != nil {
+		return nil, &StatusError{Code: CodeInternal, Message: fmt.Sprintf("failed to encode response: %v", err)}
+	}
+
+	return &InvokeOutput{Payload: payload, Header: header, Trailer: trailer}, nil
+}
+
+// outgoingContext attaches the metadata sent with a call to ctx.
+func outgoingContext(ctx context.Context, md map[string]string) context.Context {
+	if len(md) == 0 {
+		return ctx
+	}
+	return metadata.NewOutgoingContext(ctx, metadata.New(md))
+}
+
+// statusErrorOf converts the status of a failed call to a *StatusError.
+// Transport failures (connection refused, etc.) are reported by grpc with CodeUnavailable.
+func statusErrorOf(err error) error {
+	s, ok := status.FromError(err)
+	if !ok {
+		return err
+	}
+	return &StatusError{Code: Code(s.Code()), Message: s.Message()}
+}
+
+// conn returns the connection to a target, created on first use. Connections are established lazily
+// and reconnect by themselves, so they are kept for the lifetime of the client.
+func (c *GRPCWrapperClient) conn(target Target) (*grpc.ClientConn, error) {
+	key := target.Address
+	if tlsConfig := target.TLS; tlsConfig != nil {
+		fingerprint := sha256.Sum256(fmt.Appendf(nil, "%q|%q|%q|%q|%t", tlsConfig.ServerName, tlsConfig.CACertPEM,
+			tlsConfig.ClientCertPEM, tlsConfig.ClientKeyPEM, tlsConfig.InsecureSkipVerify))
+		key += "|" + hex.EncodeToString(fingerprint[:])
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if conn, ok := c.conns[key]; ok {
+		return conn, nil
+	}
+
+	transportCredentials := insecure.NewCredentials()
+	if target.TLS != nil {
+		config, err := target.TLS.build()
+		if err != nil {
+			return nil, err
+		}
+		transportCredentials = credentials.NewTLS(config)
+	}
+
+	conn, err := grpc.NewClient(target.Address,
+		grpc.WithTransportCredentials(transportCredentials),
+		grpc.WithDefaultCallOptions(grpc.MaxCallRecvMsgSize(MaxMessageBytes)),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("invalid grpc target %s: %w", target.Address, err)
+	}
+	c.conns[key] = conn
+	return conn, nil
+}
+
+func (t *TLSConfig) build() (*tls.Config, error) {
+	config := &tls.Config{
+		ServerName:         t.ServerName,
+		InsecureSkipVerify: t.InsecureSkipVerify,
+		MinVersion:         tls.VersionTLS12,
+	}
+
+	if t.CACertPEM != "" {
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM([]byte(t.CACertPEM)) {
+			return nil, errors.New("invalid CA certificate")
+		}
+		config.RootCAs = pool
+	}
+
+	if t.ClientCertPEM != "" || t.ClientKeyPEM != "" {
+		certificate, err := tls.X509KeyPair([]byte(t.ClientCertPEM), []byte(t.ClientKeyPEM))
+		if err != nil {
+			return nil, fmt.Errorf("invalid client certificate: %w", err)
+		}
+		config.Certificates = []tls.Certificate{certificate}
+	}
+
+	return config, nil
+}
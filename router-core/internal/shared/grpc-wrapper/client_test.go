@@ -0,0 +1,116 @@
+package grpc_wrapper
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+)
+
+// startServer serves the health service, with server reflection, on a loopback port.
+func startServer(t *testing.T) (Target, *health.Server) {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	server := grpc.NewServer()
+	healthServer := health.NewServer()
+	healthpb.RegisterHealthServer(server, healthServer)
+	reflection.Register(server)
+
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	return Target{Address: listener.Addr().String()}, healthServer
+}
+
+func TestInvokeResolvedMethod(t *testing.T) {
+	target, healthServer := startServer(t)
+	healthServer.SetServingStatus("router", healthpb.HealthCheckResponse_NOT_SERVING)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	client := NewGRPCWrapperClient()
+	method, err := client.ResolveMethod(ctx, target, nil, "grpc.health.v1.Health", "Check")
+	if err != nil {
+		t.Fatalf("ResolveMethod() = %v", err)
+	}
+	if method.Path() != "/grpc.health.v1.Health/Check" {
+		t.Fatalf("Path() = %s", method.Path())
+	}
+
+	output, err := client.Invoke(ctx, InvokeInput{Target: target, Method: method, Payload: map[string]any{"service": "router"}})
+	if err != nil {
+		t.Fatalf("Invoke() = %v", err)
+	}
+	if output.Payload["status"] != "NOT_SERVING" {
+		t.Fatalf("Invoke() payload = %v, want status NOT_SERVING", output.Payload)
+	}
+
+	_, err = client.Invoke(ctx, InvokeInput{Target: target, Method: method, Payload: map[string]any{"service": "unknown"}})
+	var statusErr *StatusError
+	if !errors.As(err, &statusErr) || statusErr.Code != CodeNotFound {
+		t.Fatalf("Invoke() of an unknown service = %v, want NOT_FOUND", err)
+	}
+
+	_, err = client.Invoke(ctx, InvokeInput{Target: target, Method: method, Payload: map[string]any{"unknown_field": 1}})
+	if !errors.Is(err, ErrInvalidPayload) {
+		t.Fatalf("Invoke() with an unknown field = %v, want ErrInvalidPayload", err)
+	}
+}
+
+func TestResolveMethodNotFound(t *testing.T) {
+	target, _ := startServer(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	client := NewGRPCWrapperClient()
+	for _, tt := range []struct{ service, method string }{
+		{"grpc.health.v1.Health", "Missing"},
+		// streaming methods are not supported
+		{"grpc.health.v1.Health", "Watch"},
+	} {
+		if _, err := client.ResolveMethod(ctx, target, nil, tt.service, tt.method); !errors.Is(err, ErrMethodNotFound) {
+			t.Fatalf("ResolveMethod(%s, %s) = %v, want ErrMethodNotFound", tt.service, tt.method, err)
+		}
+	}
+
+	// the reflection service reports the unknown symbol
+	_, err := client.ResolveMethod(ctx, target, nil, "missing.Service", "Call")
+	var statusErr *StatusError
+	if !errors.As(err, &statusErr) || statusErr.Code != CodeNotFound {
+		t.Fatalf("ResolveMethod() of an unknown service = %v, want NOT_FOUND", err)
+	}
+}
+
+func TestCheckHealth(t *testing.T) {
+	target, healthServer := startServer(t)
+	healthServer.SetServingStatus("router", healthpb.HealthCheckResponse_SERVING)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	client := NewGRPCWrapperClient()
+	status, err := client.CheckHealth(ctx, target, map[string]string{"x-probe": "1"}, "router")
+	if err != nil || status != HealthStatusServing {
+		t.Fatalf("CheckHealth() = %s, %v, want SERVING", status, err)
+	}
+
+	// nothing listens on port 1
+	unreachable := Target{Address: "127.0.0.1:1"}
+	_, err = client.CheckHealth(ctx, unreachable, nil, "")
+	var statusErr *StatusError
+	if !errors.As(err, &statusErr) || statusErr.Code != CodeUnavailable {
+		t.Fatalf("CheckHealth() of an unreachable server = %v, want UNAVAILABLE", err)
+	}
+}
@@ -0,0 +1,50 @@
+package grpc_wrapper
+
+import (
+	"context"
+	"fmt"
+
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// HealthStatus is the serving status reported by the health service of a server.
+type HealthStatus int
+
+const (
+	HealthStatusUnknown        HealthStatus = 0
+	HealthStatusServing        HealthStatus = 1
+	HealthStatusNotServing     HealthStatus = 2
+	HealthStatusServiceUnknown HealthStatus = 3
+)
+
+var healthStatusNames = map[HealthStatus]string{
+	HealthStatusUnknown:        "UNKNOWN",
+	HealthStatusServing:        "SERVING",
+	HealthStatusNotServing:     "NOT_SERVING",
+	HealthStatusServiceUnknown: "SERVICE_UNKNOWN",
+}
+
+func (s HealthStatus) String() string {
+	if name, ok := healthStatusNames[s]; ok {
+		return name
+	}
+	return fmt.Sprintf("STATUS(%d)", int(s))
+}
+
+// CheckHealth asks the health service of the server for the status of service, or of the whole server when
+// service is empty. Servers without a health service return a *StatusError with CodeUnimplemented.
+func (c *GRPCWrapperClient) CheckHealth(
+	ctx context.Context, target Target, metadata map[string]string, service string,
+) (HealthStatus, error) {
+	conn, err := c.conn(target)
+	if err != nil {
+		return HealthStatusUnknown, err
+	}
+
+	response, err := healthpb.NewHealthClient(conn).Check(outgoingContext(ctx, metadata),
+		&healthpb.HealthCheckRequest{Service: service})
+	if err != nil {
+		return HealthStatusUnknown, statusErrorOf(err)
+	}
+	return HealthStatus(response.GetStatus()), nil
+}
@@ -0,0 +1,186 @@
+package grpc_wrapper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"google.golang.org/grpc"
+	reflectionpb "google.golang.org/grpc/reflection/grpc_reflection_v1"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+var ErrMethodNotFound = errors.New("grpc method not found")
+
+// reflection services, v1alpha is still the only one of older servers.
+// Their messages are the same, the v1 types are used on both.
+const (
+	reflectionV1      = "/grpc.reflection.v1.ServerReflection/ServerReflectionInfo"
+	reflectionV1Alpha = "/grpc.reflection.v1alpha.ServerReflection/ServerReflectionInfo"
+
+	// rounds of dependency requests before giving up on an incomplete descriptor graph
+	maxReflectionRounds = 8
+)
+
+// Method is a resolved unary method with the types of its files, used to transcode its messages.
+type Method struct {
+	Descriptor protoreflect.MethodDescriptor
+	types      *dynamicpb.Types
+}
+
+// Path returns the full method name of the calls ("/package.Service/Method").
+func (m *Method) Path() string {
+	return fmt.Sprintf("/%s/%s", m.Descriptor.Parent().FullName(), m.Descriptor.Name())
+}
+
+// MethodFromDescriptorSet resolves a method from a serialized FileDescriptorSet
+// (protoc --include_imports --descriptor_set_out).
+func MethodFromDescriptorSet(descriptorSet []byte, service string, method string) (*Method, error) {
+	var set descriptorpb.FileDescriptorSet
+	if err := proto.Unmarshal(descriptorSet, &set); err != nil {
+		return nil, fmt.Errorf("invalid descriptor set: %w", err)
+	}
+	return findMethod(set.File, service, method)
+}
+
+// ResolveMethod resolves a method with the reflection service of the server.
+func (c *GRPCWrapperClient) ResolveMethod(
+	ctx context.Context, target Target, metadata map[string]string, service string, method string,
+) (*Method, error) {
+	conn, err := c.conn(target)
+	if err != nil {
+		return nil, err
+	}
+	ctx = outgoingContext(ctx, metadata)
+
+	files, err := reflect(ctx, conn, reflectionV1, service)
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) && statusErr.Code == CodeUnimplemented {
+		files, err = reflect(ctx, conn, reflectionV1Alpha, service)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("server reflection failed: %w", err)
+	}
+	return findMethod(files, service, method)
+}
+
+func findMethod(files []*descriptorpb.FileDescriptorProto, service string, method string) (*Method, error) {
+	registry, err := protodesc.NewFiles(&descriptorpb.FileDescriptorSet{File: files})
+	if err != nil {
+		return nil, fmt.Errorf("invalid descriptors: %w", err)
+	}
+
+	descriptor, err := registry.FindDescriptorByName(protoreflect.FullName(service))
+	if err != nil {
+		return nil, fmt.Errorf("%w: service %s: %v", ErrMethodNotFound, service, err)
+	}
+	serviceDescriptor, ok := descriptor.(protoreflect.ServiceDescriptor)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s is not a service", ErrMethodNotFound, service)
+	}
+	methodDescriptor := serviceDescriptor.Methods().ByName(protoreflect.Name(method))
+	if methodDescriptor == nil {
+		return nil, fmt.Errorf("%w: service %s has no method %s", ErrMethodNotFound, service, method)
+	}
+	if methodDescriptor.IsStreamingClient() || methodDescriptor.IsStreamingServer() {
+		return nil, fmt.Errorf("%w: %s/%s is a streaming method, only unary methods are supported",
+			ErrMethodNotFound, service, method)
+	}
+
+	return &Method{Descriptor: methodDescriptor, types: dynamicpb.NewTypes(registry)}, nil
+}
+
+// reflect fetches the file defining symbol and the files it depends on, on a single reflection stream.
+// Servers usually send the dependencies with the file, the missing ones are requested by name.
+func reflect(
+	ctx context.Context, conn *grpc.ClientConn, path string, symbol string,
+) ([]*descriptorpb.FileDescriptorProto, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	stream, err := conn.NewStream(ctx, &grpc.StreamDesc{ClientStreams: true, ServerStreams: true}, path)
+	if err != nil {
+		return nil, statusErrorOf(err)
+	}
+	defer stream.CloseSend()
+
+	files := map[string]*descriptorpb.FileDescriptorProto{}
+	requests := []*reflectionpb.ServerReflectionRequest{{
+		MessageRequest: &reflectionpb.ServerReflectionRequest_FileContainingSymbol{FileContainingSymbol: symbol},
+	}}
+
+	for round := 0; len(requests) > 0; round++ {
+		if round == maxReflectionRounds {
+			return nil, errors.New("too many rounds of dependency requests")
+		}
+
+		for _, request := range requests {
+			descriptors, err := reflectionRoundTrip(stream, request)
+			if err != nil {
+				return nil, err
+			}
+			for _, descriptor := range descriptors {
+				files[descriptor.GetName()] = descriptor
+			}
+		}
+
+		requests = nil
+		requested := map[string]bool{}
+		for _, file := range files {
+			for _, dependency := range file.GetDependency() {
+				if files[dependency] == nil && !requested[dependency] {
+					requested[dependency] = true
+					requests = append(requests, &reflectionpb.ServerReflectionRequest{
+						MessageRequest: &reflectionpb.ServerReflectionRequest_FileByFilename{FileByFilename: dependency},
+					})
+				}
+			}
+		}
+	}
+
+	list := make([]*descriptorpb.FileDescriptorProto, 0, len(files))
+	for _, file := range files {
+		list = append(list, file)
+	}
+	return list, nil
+}
+
+// reflectionRoundTrip sends a request on the reflection stream and returns the files of its response,
+// or the error of an error response.
+func reflectionRoundTrip(
+	stream grpc.ClientStream, request *reflectionpb.ServerReflectionRequest,
+) ([]*descriptorpb.FileDescriptorProto, error) {
+	// on a failed send the status of the stream is returned by RecvMsg
+	if err := stream.SendMsg(request); err != nil && !errors.Is(err, io.EOF) {
+		return nil, statusErrorOf(err)
+	}
+	response := &reflectionpb.ServerReflectionResponse{}
+	if err := stream.RecvMsg(response); err != nil {
+		return nil, statusErrorOf(err)
+	}
+
+	switch message := response.GetMessageResponse().(type) {
+	case *reflectionpb.ServerReflectionResponse_FileDescriptorResponse:
+		var files []*descriptorpb.FileDescriptorProto
+		for _, encoded := range message.FileDescriptorResponse.GetFileDescriptorProto() {
+			file := &descriptorpb.FileDescriptorProto{}
+			if err := proto.Unmarshal(encoded, file); err != nil {
+				return nil, fmt.Errorf("invalid file descriptor: %w", err)
+			}
+			files = append(files, file)
+		}
+		return files, nil
+	case *reflectionpb.ServerReflectionResponse_ErrorResponse:
+		return nil, &StatusError{
+			Code:    Code(message.ErrorResponse.GetErrorCode()),
+			Message: message.ErrorResponse.GetErrorMessage(),
+		}
+	default:
+		return nil, errors.New("invalid reflection response: no file descriptor")
+	}
+}
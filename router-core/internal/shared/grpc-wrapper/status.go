@@ -0,0 +1,66 @@
+package grpc_wrapper
+
+import "fmt"
+
+// Code is a gRPC status code.
+type Code int
+
+const (
+	CodeOK                 Code = 0
+	CodeCanceled           Code = 1
+	CodeUnknown            Code = 2
+	CodeInvalidArgument    Code = 3
+	CodeDeadlineExceeded   Code = 4
+	CodeNotFound           Code = 5
+	CodeAlreadyExists      Code = 6
+	CodePermissionDenied   Code = 7
+	CodeResourceExhausted  Code = 8
+	CodeFailedPrecondition Code = 9
+	CodeAborted            Code = 10
+	CodeOutOfRange         Code = 11
+	CodeUnimplemented      Code = 12
+	CodeInternal           Code = 13
+	CodeUnavailable        Code = 14
+	CodeDataLoss           Code = 15
+	CodeUnauthenticated    Code = 16
+)
+
+var codeNames = map[Code]string{
+	CodeOK:                 "OK",
+	CodeCanceled:           "CANCELLED",
+	CodeUnknown:            "UNKNOWN",
+	CodeInvalidArgument:    "INVALID_ARGUMENT",
+	CodeDeadlineExceeded:   "DEADLINE_EXCEEDED",
+	CodeNotFound:           "NOT_FOUND",
+	CodeAlreadyExists:      "ALREADY_EXISTS",
+	CodePermissionDenied:   "PERMISSION_DENIED",
+	CodeResourceExhausted:  "RESOURCE_EXHAUSTED",
+	CodeFailedPrecondition: "FAILED_PRECONDITION",
+	CodeAborted:            "ABORTED",
+	CodeOutOfRange:         "OUT_OF_RANGE",
+	CodeUnimplemented:      "UNIMPLEMENTED",
+	CodeInternal:           "INTERNAL",
+	CodeUnavailable:        "UNAVAILABLE",
+	CodeDataLoss:           "DATA_LOSS",
+	CodeUnauthenticated:    "UNAUTHENTICATED",
+}
+
+func (c Code) String() string {
+	if name, ok := codeNames[c]; ok {
+		return name
+	}
+	return fmt.Sprintf("CODE(%d)", int(c))
+}
+
+// StatusError is a call which ended with a non OK status.
+type StatusError struct {
+	Code    Code
+	Message string
+}
+
+func (e *StatusError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("grpc status %s", e.Code)
+	}
+	return fmt.Sprintf("grpc status %s: %s", e.Code, e.Message)
+}
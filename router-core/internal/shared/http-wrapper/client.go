@@ -0,0 +1,240 @@
+package http_wrapper
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	ContentTypeJSON           = "application/json"
+	ContentTypeFormURLEncoded = "application/x-www-form-urlencoded"
+	ContentTypeTextPlain      = "text/plain"
+)
+
+// DefaultMaxResponseBytes bounds the size of a response body (EXECUTOR_HTTP_MAX_RESPONSE_BYTES).
+const DefaultMaxResponseBytes = 32 << 20
+
+const (
+	dialTimeout         = 10 * time.Second
+	tlsHandshakeTimeout = 10 * time.Second
+	idleConnTimeout     = 90 * time.Second
+	maxIdleConnsPerHost = 32
+)
+
+var ErrResponseTooLarge = errors.New("response body too large")
+
+// ClientOptions
+//
+// - MaxResponseBytes: Size of the largest response body read, DefaultMaxResponseBytes when zero.
+type ClientOptions struct {
+	MaxResponseBytes int64
+}
+
+type HTTPWrapperClient struct {
+	httpClient       *http.Client
+	maxResponseBytes int64
+}
+
+// NewHTTPWrapperClient returns a client whose requests are bounded by their context: the transport only times out
+// the connection and the TLS handshake, so long synchronous tools are not cut short.
+func NewHTTPWrapperClient(options ClientOptions) HTTPWrapperClient {
+	maxResponseBytes := options.MaxResponseBytes
+	if maxResponseBytes <= 0 {
+		maxResponseBytes = DefaultMaxResponseBytes
+	}
+
+	transport := &http.Transport{
+		Proxy:                 http.ProxyFromEnvironment,
+		DialContext:           (&net.Dialer{Timeout: dialTimeout, KeepAlive: 30 * time.Second}).DialContext,
+		ForceAttemptHTTP2:     true,
+		TLSHandshakeTimeout:   tlsHandshakeTimeout,
+		IdleConnTimeout:       idleConnTimeout,
+		MaxIdleConns:          100,
+		MaxIdleConnsPerHost:   maxIdleConnsPerHost,
+		ExpectContinueTimeout: 1 * time.Second,
+	}
+
+	return HTTPWrapperClient{
+		httpClient:       &http.Client{Transport: transport},
+		maxResponseBytes: maxResponseBytes,
+	}
+}
+
+type InvokeInput struct {
+	Method      string
+	URL         string
+	ContentType string
+	Query       map[string]any
+	Header      map[string]any
+	Body        map[string]any
+}
+
+type InvokeOutput struct {
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+}
+
+func (wrapper *HTTPWrapperClient) Invoke(ctx context.Context, input InvokeInput) (*InvokeOutput, error) {
+	method := strings.ToUpper(input.Method)
+	if method == "" {
+		method = http.MethodPost
+	}
+
+	requestURL, err := url.Parse(input.URL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid url %s: %w", input.URL, err)
+	}
+
+	query := requestURL.Query()
+	for key, value := range input.Query {
+		query.Set(key, stringifyValue(value))
+	}
+
+	// GET requests carry no body, so body elements are sent as query parameters
+	var body io.Reader
+	if method == http.MethodGet {
+		for key, value := range input.Body {
+			query.Set(key, stringifyValue(value))
+		}
+	} else {
+		encoded, err := EncodeBody(input.ContentType, input.Body)
+		if err != nil {
+			return nil, err
+		}
+		body = bytes.NewReader(encoded)
+	}
+	requestURL.RawQuery = query.Encode()
+
+	request, err := http.NewRequestWithContext(ctx, method, requestURL.String(), body)
+	if err != nil {
+		return nil, err
+	}
+
+	if body != nil {
+		request.Header.Set("Content-Type", input.ContentType)
+	}
+	for key, value := range input.Header {
+		request.Header.Set(key, stringifyValue(value))
+	}
+
+	response, err := wrapper.httpClient.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	// one byte past the limit tells a body of exactly maxResponseBytes from a larger one
+	responseBody, err := io.ReadAll(io.LimitReader(response.Body, wrapper.maxResponseBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(responseBody)) > wrapper.maxResponseBytes {
+		return nil, fmt.Errorf("%w: %s %s returned more than %d bytes (status code %d)",
+			ErrResponseTooLarge, method, requestURL.Redacted(), wrapper.maxResponseBytes, response.StatusCode)
+	}
+
+	return &InvokeOutput{
+		StatusCode: response.StatusCode,
+		Header:     response.Header,
+		Body:       responseBody,
+	}, nil
+}
+
+// EncodeBody serializes body according to contentType.
+// Unknown content types fall back to JSON.
+func EncodeBody(contentType string, body map[string]any) ([]byte, error) {
+	switch mediaType(contentType) {
+	case ContentTypeFormURLEncoded:
+		form := url.Values{}
+		for key, value := range body {
+			form.Set(key, stringifyValue(value))
+		}
+		return []byte(form.Encode()), nil
+	case ContentTypeTextPlain:
+		if len(body) == 1 {
+			for _, value := range body {
+				return []byte(stringifyValue(value)), nil
+			}
+		}
+		return json.Marshal(body)
+	default:
+		if body == nil {
+			body = map[string]any{}
+		}
+		return json.Marshal(body)
+	}
+}
+
+// DecodeBody parses body according to contentType.
+// JSON objects are returned as is, any other JSON value is wrapped under "result",
+// and non JSON content is returned as a string under "body".
+func DecodeBody(contentType string, body []byte) (map[string]any, error) {
+	if len(bytes.TrimSpace(body)) == 0 {
+		return map[string]any{}, nil
+	}
+
+	mt := mediaType(contentType)
+	if mt == ContentTypeJSON || strings.HasSuffix(mt, "+json") {
+		var decoded any
+		if err := json.Unmarshal(body, &decoded); err != nil {
+			return nil, fmt.Errorf("failed to decode json response: %w", err)
+		}
+		if object, ok := decoded.(map[string]any); ok {
+			return object, nil
+		}
+		return map[string]any{"result": decoded}, nil
+	}
+
+	if mt == ContentTypeFormURLEncoded {
+		form, err := url.ParseQuery(string(body))
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode form response: %w", err)
+		}
+		decoded := make(map[string]any, len(form))
+		for key := range form {
+			decoded[key] = form.Get(key)
+		}
+		return decoded, nil
+	}
+
+	return map[string]any{"body": string(body)}, nil
+}
+
+func mediaType(contentType string) string {
+	mt, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return strings.ToLower(strings.TrimSpace(contentType))
+	}
+	return mt
+}
+
+func stringifyValue(value any) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case nil:
+		return ""
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool, int, int64, json.Number:
+		return fmt.Sprint(v)
+	default:
+		encoded, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprint(v)
+		}
+		return string(encoded)
+	}
+}
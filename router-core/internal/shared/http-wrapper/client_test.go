@@ -0,0 +1,273 @@
+package http_wrapper
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+type receivedRequest struct {
+	method      string
+	query       url.Values
+	contentType string
+	header      http.Header
+	body        string
+}
+
+func TestInvokeRoutesElements(t *testing.T) {
+	tests := []struct {
+		name            string
+		input           InvokeInput
+		wantMethod      string
+		wantQuery       url.Values
+		wantContentType string
+		wantHeader      map[string]string
+		wantBody        string
+	}{
+		{
+			name: "json body",
+			input: InvokeInput{
+				Method:      "post",
+				ContentType: ContentTypeJSON,
+				Body:        map[string]any{"smiles": "CCO", "top_k": float64(5)},
+			},
+			wantMethod:      http.MethodPost,
+			wantQuery:       url.Values{},
+			wantContentType: ContentTypeJSON,
+			wantBody:        `{"smiles":"CCO","top_k":5}`,
+		},
+		{
+			name: "method defaults to post",
+			input: InvokeInput{
+				ContentType: ContentTypeJSON,
+			},
+			wantMethod:      http.MethodPost,
+			wantQuery:       url.Values{},
+			wantContentType: ContentTypeJSON,
+			wantBody:        `{}`,
+		},
+		{
+			name: "form body",
+			input: InvokeInput{
+				Method:      http.MethodPut,
+				ContentType: ContentTypeFormURLEncoded,
+				Body:        map[string]any{"name": "a b", "count": float64(2), "debug": true},
+			},
+			wantMethod:      http.MethodPut,
+			wantQuery:       url.Values{},
+			wantContentType: ContentTypeFormURLEncoded,
+			wantBody:        "count=2&debug=true&name=a+b",
+		},
+		{
+			name: "query and header elements",
+			input: InvokeInput{
+				Method:      http.MethodPost,
+				ContentType: ContentTypeJSON,
+				Query:       map[string]any{"version": "2", "ids": []any{float64(1), float64(2)}},
+				Header:      map[string]any{"X-Trace-Id": "abc", "X-Retry": float64(3)},
+				Body:        map[string]any{"a": float64(1)},
+			},
+			wantMethod:      http.MethodPost,
+			wantQuery:       url.Values{"version": {"2"}, "ids": {"[1,2]"}},
+			wantContentType: ContentTypeJSON,
+			wantHeader:      map[string]string{"X-Trace-Id": "abc", "X-Retry": "3"},
+			wantBody:        `{"a":1}`,
+		},
+		{
+			name: "get sends body elements as query parameters",
+			input: InvokeInput{
+				Method:      http.MethodGet,
+				ContentType: ContentTypeJSON,
+				Query:       map[string]any{"version": "2"},
+				Body:        map[string]any{"smiles": "CCO", "top_k": float64(5), "filter": nil},
+			},
+			wantMethod: http.MethodGet,
+			wantQuery:  url.Values{"version": {"2"}, "smiles": {"CCO"}, "top_k": {"5"}, "filter": {""}},
+		},
+		{
+			name: "header element overrides the content type",
+			input: InvokeInput{
+				Method:      http.MethodPost,
+				ContentType: ContentTypeJSON,
+				Header:      map[string]any{"Content-Type": "application/vnd.api+json"},
+			},
+			wantMethod:      http.MethodPost,
+			wantQuery:       url.Values{},
+			wantContentType: "application/vnd.api+json",
+			wantBody:        `{}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got receivedRequest
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ := io.ReadAll(r.Body)
+				got = receivedRequest{
+					method:      r.Method,
+					query:       r.URL.Query(),
+					contentType: r.Header.Get("Content-Type"),
+					header:      r.Header,
+					body:        string(body),
+				}
+				w.Header().Set("Content-Type", ContentTypeJSON)
+				_, _ = w.Write([]byte(`{"ok":true}`))
+			}))
+			defer server.Close()
+
+			input := tt.input
+			input.URL = server.URL + "/predict"
+			client := NewHTTPWrapperClient(ClientOptions{})
+			output, err := client.Invoke(context.Background(), input)
+			if err != nil {
+				t.Fatalf("Invoke() error = %v", err)
+			}
+			if output.StatusCode != http.StatusOK || string(output.Body) != `{"ok":true}` {
+				t.Fatalf("Invoke() = %d %s, want 200 {\"ok\":true}", output.StatusCode, output.Body)
+			}
+
+			if got.method != tt.wantMethod {
+				t.Fatalf("method = %s, want %s", got.method, tt.wantMethod)
+			}
+			if !reflect.DeepEqual(got.query, tt.wantQuery) {
+				t.Fatalf("query = %v, want %v", got.query, tt.wantQuery)
+			}
+			if got.contentType != tt.wantContentType {
+				t.Fatalf("content type = %q, want %q", got.contentType, tt.wantContentType)
+			}
+			for key, value := range tt.wantHeader {
+				if got.header.Get(key) != value {
+					t.Fatalf("header %s = %q, want %q", key, got.header.Get(key), value)
+				}
+			}
+			if got.body != tt.wantBody {
+				t.Fatalf("body = %s, want %s", got.body, tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestInvokeKeepsURLQuery(t *testing.T) {
+	var got url.Values
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.URL.Query()
+	}))
+	defer server.Close()
+
+	client := NewHTTPWrapperClient(ClientOptions{})
+	_, err := client.Invoke(context.Background(), InvokeInput{
+		Method: http.MethodGet,
+		URL:    server.URL + "/predict?api_key=k&version=1",
+		Query:  map[string]any{"version": "2"},
+	})
+	if err != nil {
+		t.Fatalf("Invoke() error = %v", err)
+	}
+
+	want := url.Values{"api_key": {"k"}, "version": {"2"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("query = %v, want %v", got, want)
+	}
+}
+
+func TestInvokeLimitsResponseBody(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		size, _ := strconv.Atoi(r.URL.Query().Get("size"))
+		_, _ = w.Write([]byte(strings.Repeat("x", size)))
+	}))
+	defer server.Close()
+
+	client := NewHTTPWrapperClient(ClientOptions{MaxResponseBytes: 16})
+	for _, tt := range []struct {
+		size    int
+		wantErr bool
+	}{
+		{size: 0},
+		{size: 16},
+		{size: 17, wantErr: true},
+		{size: 1 << 20, wantErr: true},
+	} {
+		output, err := client.Invoke(context.Background(), InvokeInput{
+			Method: http.MethodGet,
+			URL:    server.URL,
+			Query:  map[string]any{"size": tt.size},
+		})
+		if tt.wantErr {
+			if !errors.Is(err, ErrResponseTooLarge) {
+				t.Fatalf("Invoke() of %d bytes error = %v, want ErrResponseTooLarge", tt.size, err)
+			}
+			continue
+		}
+		if err != nil || len(output.Body) != tt.size {
+			t.Fatalf("Invoke() of %d bytes = %d bytes, %v", tt.size, len(output.Body), err)
+		}
+	}
+}
+
+func TestEncodeBody(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        map[string]any
+		want        string
+	}{
+		{name: "json", contentType: ContentTypeJSON, body: map[string]any{"a": float64(1)}, want: `{"a":1}`},
+		{name: "nil json body", contentType: ContentTypeJSON, want: `{}`},
+		{name: "json with charset", contentType: "application/json; charset=utf-8", body: map[string]any{"a": "b"}, want: `{"a":"b"}`},
+		{name: "unknown content type", contentType: "application/xml", body: map[string]any{"a": true}, want: `{"a":true}`},
+		{name: "form", contentType: ContentTypeFormURLEncoded, body: map[string]any{"b": "x&y", "a": float64(1.5)}, want: "a=1.5&b=x%26y"},
+		{name: "form object value", contentType: ContentTypeFormURLEncoded, body: map[string]any{"o": map[string]any{"k": "v"}}, want: "o=%7B%22k%22%3A%22v%22%7D"},
+		{name: "text with one element", contentType: ContentTypeTextPlain, body: map[string]any{"prompt": "hello"}, want: "hello"},
+		{name: "text with several elements", contentType: ContentTypeTextPlain, body: map[string]any{"a": "x", "b": "y"}, want: `{"a":"x","b":"y"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := EncodeBody(tt.contentType, tt.body)
+			if err != nil {
+				t.Fatalf("EncodeBody() error = %v", err)
+			}
+			if string(got) != tt.want {
+				t.Fatalf("EncodeBody() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodeBody(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		want        map[string]any
+		wantErr     bool
+	}{
+		{name: "json object", contentType: ContentTypeJSON, body: `{"score": 0.5}`, want: map[string]any{"score": 0.5}},
+		{name: "json array", contentType: ContentTypeJSON, body: `[1, 2]`, want: map[string]any{"result": []any{float64(1), float64(2)}}},
+		{name: "json scalar", contentType: "application/problem+json", body: `"done"`, want: map[string]any{"result": "done"}},
+		{name: "invalid json", contentType: ContentTypeJSON, body: `{"score"`, wantErr: true},
+		{name: "empty body", contentType: ContentTypeJSON, body: " \n", want: map[string]any{}},
+		{name: "form", contentType: ContentTypeFormURLEncoded, body: "a=1&b=x+y", want: map[string]any{"a": "1", "b": "x y"}},
+		{name: "text", contentType: "text/plain; charset=utf-8", body: "hello", want: map[string]any{"body": "hello"}},
+		{name: "missing content type", body: `{"a":1}`, want: map[string]any{"body": `{"a":1}`}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := DecodeBody(tt.contentType, []byte(tt.body))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("DecodeBody() error = %v, want error %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("DecodeBody() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
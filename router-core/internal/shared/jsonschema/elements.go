@@ -0,0 +1,49 @@
+package jsonschema
+
+// ValueTypeFile is the value type of a file element, a string with the "file" format.
+const ValueTypeFile = "file"
+
+// Element is the flat shorthand of an interface property: a key with a scalar value type.
+type Element struct {
+	Key       string
+	Location  string
+	ValueType string
+	Required  bool
+	Title     string
+}
+
+// FromElements builds the object schema equivalent to a flat element list.
+// Optional elements also accept null, and keys which are not listed are rejected.
+func FromElements(elements []Element) *Schema {
+	schema := &Schema{
+		Type:                 Types{TypeObject},
+		Properties:           make(map[string]*Schema, len(elements)),
+		AdditionalProperties: &AdditionalProperties{Allowed: false},
+	}
+
+	for _, element := range elements {
+		property := &Schema{
+			Title:    element.Title,
+			Location: element.Location,
+		}
+		if element.ValueType == ValueTypeFile {
+			property.Type = Types{TypeString}
+			property.Format = FormatFile
+		} else if element.ValueType != "" {
+			property.Type = Types{element.ValueType}
+		}
+		if len(property.Type) > 0 && !element.Required {
+			property.Type = append(property.Type, TypeNull)
+		}
+		if property.Location == LocationBody {
+			property.Location = ""
+		}
+
+		schema.Properties[element.Key] = property
+		if element.Required {
+			schema.Required = append(schema.Required, element.Key)
+		}
+	}
+
+	return schema
+}
@@ -0,0 +1,297 @@
+// Package jsonschema describes JSON documents with a subset of JSON Schema (2020-12) and validates them.
+//
+// Supported keywords:
+// type, enum, const, properties, required, additionalProperties, items, minItems, maxItems, uniqueItems,
+// minimum, maximum, exclusiveMinimum, exclusiveMaximum, multipleOf, minLength, maxLength, pattern,
+// allOf, anyOf, oneOf, not and local references ($ref to "#", "#/$defs/..." or "#/definitions/...").
+// Annotations (title, description, default, examples, format) are kept for docs and forms but not validated.
+// The "file" format marks a top-level string property uploaded as a file, which the tool receives as a reference.
+package jsonschema
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// Types of JSON values.
+const (
+	TypeString  = "string"
+	TypeNumber  = "number"
+	TypeInteger = "integer"
+	TypeBoolean = "boolean"
+	TypeObject  = "object"
+	TypeArray   = "array"
+	TypeNull    = "null"
+)
+
+// FormatFile is the format of a string property uploaded as a file (multipart execution).
+const FormatFile = "file"
+
+// Locations of a property of a tool interface (x-location), where the value is sent or read.
+const (
+	LocationBody   = "body"
+	LocationQuery  = "query"
+	LocationHeader = "header"
+)
+
+type Schema struct {
+	Ref         string             `json:"$ref,omitempty"`
+	Defs        map[string]*Schema `json:"$defs,omitempty"`
+	Definitions map[string]*Schema `json:"definitions,omitempty"`
+
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+	Default     any    `json:"default,omitempty"`
+	Examples    []any  `json:"examples,omitempty"`
+	Format      string `json:"format,omitempty"`
+
+	Type  Types `json:"type,omitempty"`
+	Enum  []any `json:"enum,omitempty"`
+	Const any   `json:"const,omitempty"`
+
+	Properties           map[string]*Schema    `json:"properties,omitempty"`
+	Required             []string              `json:"required,omitempty"`
+	AdditionalProperties *AdditionalProperties `json:"additionalProperties,omitempty"`
+
+	Items       *Schema `json:"items,omitempty"`
+	MinItems    *int    `json:"minItems,omitempty"`
+	MaxItems    *int    `json:"maxItems,omitempty"`
+	UniqueItems bool    `json:"uniqueItems,omitempty"`
+
+	Minimum          *float64 `json:"minimum,omitempty"`
+	Maximum          *float64 `json:"maximum,omitempty"`
+	ExclusiveMinimum *float64 `json:"exclusiveMinimum,omitempty"`
+	ExclusiveMaximum *float64 `json:"exclusiveMaximum,omitempty"`
+	MultipleOf       *float64 `json:"multipleOf,omitempty"`
+
+	MinLength *int   `json:"minLength,omitempty"`
+	MaxLength *int   `json:"maxLength,omitempty"`
+	Pattern   string `json:"pattern,omitempty"`
+
+	AllOf []*Schema `json:"allOf,omitempty"`
+	AnyOf []*Schema `json:"anyOf,omitempty"`
+	OneOf []*Schema `json:"oneOf,omitempty"`
+	Not   *Schema   `json:"not,omitempty"`
+
+	// Location tells where a property of a tool interface is sent or read (body, query or header), body by default.
+	Location string `json:"x-location,omitempty"`
+}
+
+// Types is the "type" keyword, a single type or a list of types.
+type Types []string
+
+func (t Types) MarshalJSON() ([]byte, error) {
+	if len(t) == 1 {
+		return json.Marshal(t[0])
+	}
+	return json.Marshal([]string(t))
+}
+
+func (t *Types) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*t = Types{single}
+		return nil
+	}
+
+	var multiple []string
+	if err := json.Unmarshal(data, &multiple); err != nil {
+		return fmt.Errorf("type must be a string or an array of strings")
+	}
+	*t = Types(multiple)
+	return nil
+}
+
+func (t Types) Has(name string) bool {
+	for _, typ := range t {
+		if typ == name {
+			return true
+		}
+	}
+	return false
+}
+
+// AdditionalProperties is the "additionalProperties" keyword, a boolean or a schema.
+type AdditionalProperties struct {
+	Allowed bool
+	Schema  *Schema
+}
+
+func (a AdditionalProperties) MarshalJSON() ([]byte, error) {
+	if a.Schema != nil {
+		return json.Marshal(a.Schema)
+	}
+	return json.Marshal(a.Allowed)
+}
+
+func (a *AdditionalProperties) UnmarshalJSON(data []byte) error {
+	var allowed bool
+	if err := json.Unmarshal(data, &allowed); err == nil {
+		*a = AdditionalProperties{Allowed: allowed}
+		return nil
+	}
+
+	var schema Schema
+	if err := json.Unmarshal(data, &schema); err != nil {
+		return fmt.Errorf("additionalProperties must be a boolean or a schema")
+	}
+	*a = AdditionalProperties{Allowed: true, Schema: &schema}
+	return nil
+}
+
+// AllowsAdditionalProperties reports whether the object accepts properties it does not declare.
+func (s *Schema) AllowsAdditionalProperties() bool {
+	return s.AdditionalProperties == nil || s.AdditionalProperties.Allowed
+}
+
+// PropertyLocation returns the x-location of a property, body by default.
+func (s *Schema) PropertyLocation(name string) string {
+	if property, ok := s.Properties[name]; ok && property != nil && property.Location != "" {
+		return property.Location
+	}
+	return LocationBody
+}
+
+// IsFileProperty reports whether a property is a file (string with the "file" format).
+func (s *Schema) IsFileProperty(name string) bool {
+	if s == nil {
+		return false
+	}
+	property, ok := s.Properties[name]
+	if !ok || property == nil {
+		return false
+	}
+	if property.Ref != "" {
+		resolved, err := s.resolve(property.Ref)
+		if err != nil {
+			return false
+		}
+		property = resolved
+	}
+	return property.Format == FormatFile
+}
+
+// Check reports schema errors which would make validation meaningless
+// (unknown types, invalid patterns, unresolvable references).
+func (s *Schema) Check() error {
+	return s.check(s, "#", 0)
+}
+
+func (s *Schema) check(root *Schema, path string, depth int) error {
+	if s == nil {
+		return nil
+	}
+	if depth > maxDepth {
+		return fmt.Errorf("%s: schema is nested too deeply", path)
+	}
+
+	for _, typ := range s.Type {
+		switch typ {
+		case TypeString, TypeNumber, TypeInteger, TypeBoolean, TypeObject, TypeArray, TypeNull:
+		default:
+			return fmt.Errorf("%s: unknown type %q", path, typ)
+		}
+	}
+	if s.Pattern != "" {
+		if _, err := compilePattern(s.Pattern); err != nil {
+			return fmt.Errorf("%s: invalid pattern: %v", path, err)
+		}
+	}
+	if s.Ref != "" {
+		if _, err := root.resolve(s.Ref); err != nil {
+			return fmt.Errorf("%s: %v", path, err)
+		}
+	}
+	if s.Format == FormatFile && len(s.Type) > 0 && !s.Type.Has(TypeString) {
+		return fmt.Errorf("%s: file properties must be strings", path)
+	}
+	switch s.Location {
+	case "", LocationBody, LocationQuery, LocationHeader:
+	default:
+		return fmt.Errorf("%s: unknown x-location %q", path, s.Location)
+	}
+
+	for name, property := range s.Properties {
+		if err := property.check(root, path+"/properties/"+name, depth+1); err != nil {
+			return err
+		}
+	}
+	if s.AdditionalProperties != nil {
+		if err := s.AdditionalProperties.Schema.check(root, path+"/additionalProperties", depth+1); err != nil {
+			return err
+		}
+	}
+	if err := s.Items.check(root, path+"/items", depth+1); err != nil {
+		return err
+	}
+	if err := s.Not.check(root, path+"/not", depth+1); err != nil {
+		return err
+	}
+	for keyword, schemas := range map[string][]*Schema{"allOf": s.AllOf, "anyOf": s.AnyOf, "oneOf": s.OneOf} {
+		for i, sub := range schemas {
+			if err := sub.check(root, fmt.Sprintf("%s/%s/%d", path, keyword, i), depth+1); err != nil {
+				return err
+			}
+		}
+	}
+	for name, def := range s.Defs {
+		if err := def.check(root, path+"/$defs/"+name, depth+1); err != nil {
+			return err
+		}
+	}
+	for name, def := range s.Definitions {
+		if err := def.check(root, path+"/definitions/"+name, depth+1); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// resolve returns the schema referenced by a local reference.
+func (s *Schema) resolve(ref string) (*Schema, error) {
+	if ref == "#" {
+		return s, nil
+	}
+
+	var defs map[string]*Schema
+	var name string
+	switch {
+	case strings.HasPrefix(ref, "#/$defs/"):
+		defs, name = s.Defs, strings.TrimPrefix(ref, "#/$defs/")
+	case strings.HasPrefix(ref, "#/definitions/"):
+		defs, name = s.Definitions, strings.TrimPrefix(ref, "#/definitions/")
+	default:
+		return nil, fmt.Errorf("unsupported reference %q (only local $defs and definitions)", ref)
+	}
+
+	def, ok := defs[name]
+	if !ok || def == nil {
+		return nil, fmt.Errorf("unresolvable reference %q", ref)
+	}
+	return def, nil
+}
+
+// compiled patterns, schemas are validated against every execution
+var (
+	patternsMu sync.Mutex
+	patterns   = map[string]*regexp.Regexp{}
+)
+
+func compilePattern(pattern string) (*regexp.Regexp, error) {
+	patternsMu.Lock()
+	defer patternsMu.Unlock()
+
+	if re, ok := patterns[pattern]; ok {
+		return re, nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	patterns[pattern] = re
+	return re, nil
+}
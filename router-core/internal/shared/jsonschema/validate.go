@@ -0,0 +1,377 @@
+package jsonschema
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// Codes of ValidationError.
+const (
+	// a required property is absent
+	CodeMissing = "missing"
+
+	// the value does not have the expected type
+	CodeTypeMismatch = "type_mismatch"
+
+	// the property is not declared and additional properties are not allowed
+	CodeUnknown = "unknown"
+
+	// the value is not one of enum / const
+	CodeEnum = "enum"
+
+	// minimum, maximum, exclusiveMinimum, exclusiveMaximum or multipleOf
+	CodeRange = "range"
+
+	// minLength, maxLength, minItems or maxItems
+	CodeLength = "length"
+
+	// the string does not match pattern
+	CodePattern = "pattern"
+
+	// uniqueItems
+	CodeUnique = "unique"
+
+	// allOf, anyOf, oneOf or not
+	CodeComposition = "composition"
+)
+
+// maximum nesting of schemas and values, guards against recursive references
+const maxDepth = 64
+
+// ValidationError is a single violation of a schema.
+// Path locates the value in the document ("params.grid[2]"), empty for the document itself.
+type ValidationError struct {
+	Path     string `json:"path"`
+	Code     string `json:"code"`
+	Expected string `json:"expected,omitempty"`
+	Actual   string `json:"actual,omitempty"`
+	Message  string `json:"message"`
+}
+
+func (e *ValidationError) Error() string {
+	return e.Message
+}
+
+// Validate checks a decoded JSON value (as produced by encoding/json into any) against the schema
+// and returns every violation found, nil when the value is valid.
+func (s *Schema) Validate(value any) []*ValidationError {
+	v := &validator{root: s}
+	v.validate(s, value, "", 0)
+	return v.errors
+}
+
+type validator struct {
+	root   *Schema
+	errors []*ValidationError
+}
+
+func (v *validator) fail(path string, code string, expected string, actual string, format string, args ...any) {
+	v.errors = append(v.errors, &ValidationError{
+		Path:     path,
+		Code:     code,
+		Expected: expected,
+		Actual:   actual,
+		Message:  displayPath(path) + " " + fmt.Sprintf(format, args...),
+	})
+}
+
+// valid reports whether value satisfies schema without recording the violations.
+func (v *validator) valid(schema *Schema, value any, path string, depth int) bool {
+	sub := &validator{root: v.root}
+	sub.validate(schema, value, path, depth)
+	return len(sub.errors) == 0
+}
+
+func (v *validator) validate(schema *Schema, value any, path string, depth int) {
+	if schema == nil {
+		return
+	}
+	if depth > maxDepth {
+		v.fail(path, CodeComposition, "", "", "is nested too deeply")
+		return
+	}
+
+	if schema.Ref != "" {
+		resolved, err := v.root.resolve(schema.Ref)
+		if err != nil {
+			v.fail(path, CodeComposition, "", "", "cannot be validated: %v", err)
+			return
+		}
+		v.validate(resolved, value, path, depth+1)
+	}
+
+	actual := TypeOf(value)
+	if len(schema.Type) > 0 && !matchesType(schema.Type, value) {
+		expected := strings.Join(schema.Type, " or ")
+		v.fail(path, CodeTypeMismatch, expected, actual, "must be %s %s, got %s", article(expected), expected, actual)
+		return
+	}
+
+	if len(schema.Enum) > 0 && !containsValue(schema.Enum, value) {
+		v.fail(path, CodeEnum, formatValues(schema.Enum), formatValue(value), "must be one of %s", formatValues(schema.Enum))
+	}
+	if schema.Const != nil && !equalValues(schema.Const, value) {
+		v.fail(path, CodeEnum, formatValue(schema.Const), formatValue(value), "must be %s", formatValue(schema.Const))
+	}
+
+	switch typed := value.(type) {
+	case string:
+		v.validateString(schema, typed, path)
+	case map[string]any:
+		v.validateObject(schema, typed, path, depth)
+	case []any:
+		v.validateArray(schema, typed, path, depth)
+	default:
+		if number, ok := toFloat(value); ok {
+			v.validateNumber(schema, number, path)
+		}
+	}
+
+	for _, sub := range schema.AllOf {
+		v.validate(sub, value, path, depth+1)
+	}
+	if len(schema.AnyOf) > 0 {
+		matched := false
+		for _, sub := range schema.AnyOf {
+			if v.valid(sub, value, path, depth+1) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			v.fail(path, CodeComposition, "", "", "must match at least one of the allowed schemas (anyOf)")
+		}
+	}
+	if len(schema.OneOf) > 0 {
+		matched := 0
+		for _, sub := range schema.OneOf {
+			if v.valid(sub, value, path, depth+1) {
+				matched++
+			}
+		}
+		if matched != 1 {
+			v.fail(path, CodeComposition, "", "", "must match exactly one of the allowed schemas (oneOf), matched %d", matched)
+		}
+	}
+	if schema.Not != nil && v.valid(schema.Not, value, path, depth+1) {
+		v.fail(path, CodeComposition, "", "", "must not match the excluded schema (not)")
+	}
+}
+
+func (v *validator) validateString(schema *Schema, value string, path string) {
+	length := utf8.RuneCountInString(value)
+	if schema.MinLength != nil && length < *schema.MinLength {
+		v.fail(path, CodeLength, fmt.Sprintf(">= %d", *schema.MinLength), fmt.Sprint(length),
+			"must be at least %d characters long, got %d", *schema.MinLength, length)
+	}
+	if schema.MaxLength != nil && length > *schema.MaxLength {
+		v.fail(path, CodeLength, fmt.Sprintf("<= %d", *schema.MaxLength), fmt.Sprint(length),
+			"must be at most %d characters long, got %d", *schema.MaxLength, length)
+	}
+	if schema.Pattern != "" {
+		re, err := compilePattern(schema.Pattern)
+		if err != nil {
+			v.fail(path, CodePattern, schema.Pattern, "", "cannot be validated: invalid pattern: %v", err)
+		} else if !re.MatchString(value) {
+			v.fail(path, CodePattern, schema.Pattern, formatValue(value), "must match pattern %s", schema.Pattern)
+		}
+	}
+}
+
+func (v *validator) validateNumber(schema *Schema, value float64, path string) {
+	actual := formatValue(value)
+	if schema.Minimum != nil && value < *schema.Minimum {
+		v.fail(path, CodeRange, fmt.Sprintf(">= %v", *schema.Minimum), actual, "must be >= %v, got %s", *schema.Minimum, actual)
+	}
+	if schema.Maximum != nil && value > *schema.Maximum {
+		v.fail(path, CodeRange, fmt.Sprintf("<= %v", *schema.Maximum), actual, "must be <= %v, got %s", *schema.Maximum, actual)
+	}
+	if schema.ExclusiveMinimum != nil && value <= *schema.ExclusiveMinimum {
+		v.fail(path, CodeRange, fmt.Sprintf("> %v", *schema.ExclusiveMinimum), actual, "must be > %v, got %s", *schema.ExclusiveMinimum, actual)
+	}
+	if schema.ExclusiveMaximum != nil && value >= *schema.ExclusiveMaximum {
+		v.fail(path, CodeRange, fmt.Sprintf("< %v", *schema.ExclusiveMaximum), actual, "must be < %v, got %s", *schema.ExclusiveMaximum, actual)
+	}
+	if schema.MultipleOf != nil && *schema.MultipleOf > 0 {
+		quotient := value / *schema.MultipleOf
+		if math.Abs(quotient-math.Round(quotient)) > 1e-9 {
+			v.fail(path, CodeRange, fmt.Sprintf("multiple of %v", *schema.MultipleOf), actual, "must be a multiple of %v, got %s", *schema.MultipleOf, actual)
+		}
+	}
+}
+
+func (v *validator) validateObject(schema *Schema, value map[string]any, path string, depth int) {
+	for _, name := range schema.Required {
+		if _, ok := value[name]; !ok {
+			v.fail(joinPath(path, name), CodeMissing, "", "", "is required")
+		}
+	}
+
+	names := make([]string, 0, len(value))
+	for name := range value {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if property, ok := schema.Properties[name]; ok {
+			v.validate(property, value[name], joinPath(path, name), depth+1)
+			continue
+		}
+
+		switch {
+		case schema.AdditionalProperties == nil:
+		case !schema.AdditionalProperties.Allowed:
+			v.fail(joinPath(path, name), CodeUnknown, "", "", "is not a declared property")
+		default:
+			v.validate(schema.AdditionalProperties.Schema, value[name], joinPath(path, name), depth+1)
+		}
+	}
+}
+
+func (v *validator) validateArray(schema *Schema, value []any, path string, depth int) {
+	if schema.MinItems != nil && len(value) < *schema.MinItems {
+		v.fail(path, CodeLength, fmt.Sprintf(">= %d items", *schema.MinItems), fmt.Sprintf("%d items", len(value)),
+			"must have at least %d items, got %d", *schema.MinItems, len(value))
+	}
+	if schema.MaxItems != nil && len(value) > *schema.MaxItems {
+		v.fail(path, CodeLength, fmt.Sprintf("<= %d items", *schema.MaxItems), fmt.Sprintf("%d items", len(value)),
+			"must have at most %d items, got %d", *schema.MaxItems, len(value))
+	}
+	if schema.UniqueItems {
+		seen := make(map[string]int, len(value))
+		for i, item := range value {
+			key := canonical(item)
+			if first, ok := seen[key]; ok {
+				v.fail(fmt.Sprintf("%s[%d]", path, i), CodeUnique, "", "", "duplicates item %d", first)
+				continue
+			}
+			seen[key] = i
+		}
+	}
+	if schema.Items != nil {
+		for i, item := range value {
+			v.validate(schema.Items, item, fmt.Sprintf("%s[%d]", path, i), depth+1)
+		}
+	}
+}
+
+// TypeOf returns the JSON type of a decoded value ("number" for every number).
+func TypeOf(value any) string {
+	switch value.(type) {
+	case nil:
+		return TypeNull
+	case string:
+		return TypeString
+	case bool:
+		return TypeBoolean
+	case map[string]any:
+		return TypeObject
+	case []any:
+		return TypeArray
+	}
+	if _, ok := toFloat(value); ok {
+		return TypeNumber
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+func matchesType(types Types, value any) bool {
+	actual := TypeOf(value)
+	for _, typ := range types {
+		if typ == actual {
+			return true
+		}
+		if typ == TypeInteger && actual == TypeNumber {
+			if number, _ := toFloat(value); number == math.Trunc(number) && !math.IsInf(number, 0) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func toFloat(value any) (float64, bool) {
+	switch n := value.(type) {
+	case float64:
+		return n, true
+	case float32:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case json.Number:
+		f, err := n.Float64()
+		return f, err == nil
+	}
+	return 0, false
+}
+
+func canonical(value any) string {
+	if number, ok := toFloat(value); ok {
+		value = number
+	}
+	// encoding/json sorts map keys, so equal documents have equal encodings
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(encoded)
+}
+
+func equalValues(a any, b any) bool {
+	return canonical(a) == canonical(b)
+}
+
+func containsValue(values []any, value any) bool {
+	for _, candidate := range values {
+		if equalValues(candidate, value) {
+			return true
+		}
+	}
+	return false
+}
+
+func formatValue(value any) string {
+	formatted := canonical(value)
+	if len(formatted) > 64 {
+		formatted = formatted[:64] + "..."
+	}
+	return formatted
+}
+
+func formatValues(values []any) string {
+	formatted := make([]string, len(values))
+	for i, value := range values {
+		formatted[i] = formatValue(value)
+	}
+	return "[" + strings.Join(formatted, ", ") + "]"
+}
+
+func joinPath(path string, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+func displayPath(path string) string {
+	if path == "" {
+		return "value"
+	}
+	return path
+}
+
+func article(word string) string {
+	if word != "" && strings.ContainsAny(word[:1], "aeiou") {
+		return "an"
+	}
+	return "a"
+}
@@ -0,0 +1,208 @@
+package jsonschema
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func mustSchema(t *testing.T, source string) *Schema {
+	t.Helper()
+	var schema Schema
+	if err := json.Unmarshal([]byte(source), &schema); err != nil {
+		t.Fatalf("invalid schema %s: %v", source, err)
+	}
+	return &schema
+}
+
+func mustValue(t *testing.T, source string) any {
+	t.Helper()
+	var value any
+	if err := json.Unmarshal([]byte(source), &value); err != nil {
+		t.Fatalf("invalid value %s: %v", source, err)
+	}
+	return value
+}
+
+func TestSchemaValidate(t *testing.T) {
+	// each violation as "path:code"
+	tests := []struct {
+		name   string
+		schema string
+		value  string
+		want   []string
+	}{
+		{
+			name:   "valid object",
+			schema: `{"type": "object", "required": ["name"], "properties": {"name": {"type": "string"}, "count": {"type": "integer"}}}`,
+			value:  `{"name": "aspirin", "count": 3}`,
+		},
+		{
+			name:   "missing required properties",
+			schema: `{"type": "object", "required": ["name", "smiles"]}`,
+			value:  `{}`,
+			want:   []string{"name:missing", "smiles:missing"},
+		},
+		{
+			name:   "type mismatch stops at the value",
+			schema: `{"type": "string", "minLength": 3}`,
+			value:  `12`,
+			want:   []string{":type_mismatch"},
+		},
+		{
+			name:   "integer accepts whole numbers only",
+			schema: `{"type": "object", "properties": {"a": {"type": "integer"}, "b": {"type": "integer"}}}`,
+			value:  `{"a": 2.0, "b": 2.5}`,
+			want:   []string{"b:type_mismatch"},
+		},
+		{
+			name:   "type list",
+			schema: `{"type": ["string", "null"]}`,
+			value:  `null`,
+		},
+		{
+			name:   "undeclared property",
+			schema: `{"type": "object", "properties": {"a": {}}, "additionalProperties": false}`,
+			value:  `{"a": 1, "b": 2}`,
+			want:   []string{"b:unknown"},
+		},
+		{
+			name:   "additional properties schema",
+			schema: `{"type": "object", "additionalProperties": {"type": "number"}}`,
+			value:  `{"a": 1, "b": "x"}`,
+			want:   []string{"b:type_mismatch"},
+		},
+		{
+			name:   "enum and const",
+			schema: `{"type": "object", "properties": {"mode": {"enum": ["fast", "exact"]}, "version": {"const": 2}}}`,
+			value:  `{"mode": "slow", "version": 2.0}`,
+			want:   []string{"mode:enum"},
+		},
+		{
+			name:   "numeric bounds",
+			schema: `{"type": "array", "items": {"type": "number", "minimum": 0, "exclusiveMaximum": 1, "multipleOf": 0.25}}`,
+			value:  `[0, 0.5, -1, 1, 0.3]`,
+			want:   []string{"[2]:range", "[3]:range", "[4]:range"},
+		},
+		{
+			name:   "string length counts characters",
+			schema: `{"type": "string", "maxLength": 2}`,
+			value:  `"αβ"`,
+		},
+		{
+			name:   "pattern",
+			schema: `{"type": "string", "pattern": "^[A-Z]{3}$"}`,
+			value:  `"abc"`,
+			want:   []string{":pattern"},
+		},
+		{
+			name:   "array length and uniqueness",
+			schema: `{"type": "array", "minItems": 4, "uniqueItems": true}`,
+			value:  `[1, {"a": 1, "b": 2}, {"b": 2, "a": 1}]`,
+			want:   []string{":length", "[2]:unique"},
+		},
+		{
+			name:   "nested paths",
+			schema: `{"type": "object", "properties": {"params": {"type": "object", "properties": {"grid": {"type": "array", "items": {"type": "integer"}}}}}}`,
+			value:  `{"params": {"grid": [1, 2, "3"]}}`,
+			want:   []string{"params.grid[2]:type_mismatch"},
+		},
+		{
+			name:   "anyOf",
+			schema: `{"anyOf": [{"type": "string"}, {"type": "integer"}]}`,
+			value:  `true`,
+			want:   []string{":composition"},
+		},
+		{
+			name:   "oneOf matching both",
+			schema: `{"oneOf": [{"type": "number"}, {"type": "integer"}]}`,
+			value:  `1`,
+			want:   []string{":composition"},
+		},
+		{
+			name:   "not",
+			schema: `{"not": {"type": "null"}}`,
+			value:  `null`,
+			want:   []string{":composition"},
+		},
+		{
+			name:   "allOf reports each violation",
+			schema: `{"allOf": [{"type": "string", "minLength": 5}, {"pattern": "^a"}]}`,
+			value:  `"bcd"`,
+			want:   []string{":length", ":pattern"},
+		},
+		{
+			name:   "local reference",
+			schema: `{"$defs": {"positive": {"type": "number", "exclusiveMinimum": 0}}, "type": "object", "properties": {"dose": {"$ref": "#/$defs/positive"}}}`,
+			value:  `{"dose": 0}`,
+			want:   []string{"dose:range"},
+		},
+		{
+			name:   "recursive reference",
+			schema: `{"type": "object", "properties": {"name": {"type": "string"}, "children": {"type": "array", "items": {"$ref": "#"}}}}`,
+			value:  `{"name": "root", "children": [{"name": "leaf", "children": [{"name": 1}]}]}`,
+			want:   []string{"children[0].children[0].name:type_mismatch"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errs := mustSchema(t, tt.schema).Validate(mustValue(t, tt.value))
+
+			got := make([]string, len(errs))
+			for i, err := range errs {
+				got[i] = err.Path + ":" + err.Code
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Fatalf("Validate() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidationErrorMessage(t *testing.T) {
+	schema := mustSchema(t, `{"type": "object", "properties": {"count": {"type": "integer"}, "items": {"type": "array"}}}`)
+
+	errs := schema.Validate(mustValue(t, `{"count": "3", "items": {}}`))
+	want := []string{"count must be an integer, got string", "items must be an array, got object"}
+	if len(errs) != len(want) {
+		t.Fatalf("Validate() returned %d errors, want %d", len(errs), len(want))
+	}
+	for i, err := range errs {
+		if err.Error() != want[i] {
+			t.Errorf("error %d = %q, want %q", i, err.Error(), want[i])
+		}
+	}
+}
+
+func TestSchemaCheck(t *testing.T) {
+	tests := []struct {
+		name    string
+		schema  string
+		wantErr string
+	}{
+		{name: "valid", schema: `{"type": "object", "properties": {"file": {"type": "string", "format": "file"}}}`},
+		{name: "unknown type", schema: `{"properties": {"a": {"type": "float"}}}`, wantErr: `#/properties/a: unknown type "float"`},
+		{name: "invalid pattern", schema: `{"pattern": "("}`, wantErr: "#: invalid pattern"},
+		{name: "unresolvable reference", schema: `{"items": {"$ref": "#/$defs/missing"}}`, wantErr: "#/items: unresolvable reference"},
+		{name: "remote reference", schema: `{"$ref": "https://example.com/schema.json"}`, wantErr: "unsupported reference"},
+		{name: "file property not a string", schema: `{"properties": {"f": {"type": "integer", "format": "file"}}}`, wantErr: "file properties must be strings"},
+		{name: "unknown location", schema: `{"properties": {"q": {"x-location": "cookie"}}}`, wantErr: `unknown x-location "cookie"`},
+		{name: "nested in composition", schema: `{"anyOf": [{"type": "string"}, {"type": "decimal"}]}`, wantErr: `#/anyOf/1: unknown type "decimal"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := mustSchema(t, tt.schema).Check()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Check() error = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("Check() error = %v, want containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package lambda_wrapper
 
 import (
+	"cmp"
 	"context"
 	"encoding/json"
 
@@ -14,15 +15,13 @@ type LambdaWrapperClient struct {
 	lambdaClient *lambda.Client
 }
 
-func NewLambdaWrapperClient(config *config.Config) LambdaWrapperClient {
-	client := lambda.NewFromConfig(aws.Config{
-		Region: config.AWS.Region,
-		Credentials: aws.CredentialsProviderFunc(func(ctx context.Context) (aws.Credentials, error) {
-			return aws.Credentials{
-				AccessKeyID:     config.AWS.AccessKeyID,
-				SecretAccessKey: config.AWS.SecretAccessKey,
-			}, nil
-		}),
+// newLambdaWrapperClient builds a Lambda client of the region and credentials of awsConfig (see LambdaClientPool).
+// AWS_LAMBDA_ENDPOINT, or AWS_ENDPOINT_URL, points the client to a local emulator (e.g. LocalStack).
+func newLambdaWrapperClient(config *config.Config, awsConfig aws.Config) LambdaWrapperClient {
+	client := lambda.NewFromConfig(awsConfig, func(o *lambda.Options) {
+		if endpoint := cmp.Or(config.AWS.LambdaEndpoint, config.AWS.EndpointURL); endpoint != "" {
+			o.BaseEndpoint = aws.String(endpoint)
+		}
 	})
 
 	return LambdaWrapperClient{
@@ -30,10 +29,11 @@ func NewLambdaWrapperClient(config *config.Config) LambdaWrapperClient {
 	}
 }
 
-func (wrapper *LambdaWrapperClient) GetFunction(ctx context.Context, functionName string) (*types.State, error) {
+func (wrapper *LambdaWrapperClient) GetFunction(ctx context.Context, function LambdaFunctionImpl) (*types.State, error) {
 	var state types.State
 	funcOutput, err := wrapper.lambdaClient.GetFunction(ctx, &lambda.GetFunctionInput{
-		FunctionName: aws.String(functionName),
+		FunctionName: aws.String(function.FunctionName),
+		Qualifier:    qualifier(function),
 	})
 	if err != nil {
 		return nil, err
@@ -44,7 +44,7 @@ func (wrapper *LambdaWrapperClient) GetFunction(ctx context.Context, functionNam
 }
 
 func (wrapper *LambdaWrapperClient) Invoke(
-	ctx context.Context, functionName string, parameters any, invocationType types.InvocationType, getLog bool,
+	ctx context.Context, function LambdaFunctionImpl, parameters any, invocationType types.InvocationType, getLog bool,
 ) (*lambda.InvokeOutput, error) {
 	logType := types.LogTypeNone
 	if getLog {
@@ -55,7 +55,8 @@ func (wrapper *LambdaWrapperClient) Invoke(
 		return nil, err
 	}
 	invokeOutput, err := wrapper.lambdaClient.Invoke(ctx, &lambda.InvokeInput{
-		FunctionName:   aws.String(functionName),
+		FunctionName:   aws.String(function.FunctionName),
+		Qualifier:      qualifier(function),
 		LogType:        logType,
 		Payload:        payload,
 		InvocationType: invocationType,
@@ -79,3 +80,34 @@ func (wrapper *LambdaWrapperClient) ListFunctions(ctx context.Context, maxItems
 	}
 	return functions, nil
 }
+
+// DescribeFunction returns the configuration and the tags of a function.
+func (wrapper *LambdaWrapperClient) DescribeFunction(
+	ctx context.Context, functionName string,
+) (*types.FunctionConfiguration, map[string]string, error) {
+	funcOutput, err := wrapper.lambdaClient.GetFunction(ctx, &lambda.GetFunctionInput{
+		FunctionName: aws.String(functionName),
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return funcOutput.Configuration, funcOutput.Tags, nil
+}
+
+// ListTags returns the tags of a function, given by its ARN.
+func (wrapper *LambdaWrapperClient) ListTags(ctx context.Context, functionARN string) (map[string]string, error) {
+	tagsOutput, err := wrapper.lambdaClient.ListTags(ctx, &lambda.ListTagsInput{
+		Resource: aws.String(functionARN),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return tagsOutput.Tags, nil
+}
+
+func qualifier(function LambdaFunctionImpl) *string {
+	if function.Qualifier == "" {
+		return nil
+	}
+	return aws.String(function.Qualifier)
+}
@@ -1,5 +1,16 @@
 package lambda_wrapper
 
+// LambdaFunctionImpl is a function invoked by a tool.
+//
+// - FunctionName: Name, ARN or partial ARN of the function.
+// - Qualifier: Alias or version invoked, $LATEST when empty.
+// - Region: Region of the function, AWS_REGION when empty.
+// - RoleARN / ExternalID: Role assumed to reach the function (e.g. in another account), the router credentials
+// are used when empty.
 type LambdaFunctionImpl struct {
 	FunctionName string
+	Qualifier    string
+	Region       string
+	RoleARN      string
+	ExternalID   string
 }
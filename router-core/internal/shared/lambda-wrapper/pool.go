@@ -0,0 +1,67 @@
+package lambda_wrapper
+
+import (
+	"cmp"
+	"sync"
+
+	"aigendrug.com/router-core/internal/config"
+	aws_wrapper "aigendrug.com/router-core/internal/shared/aws-wrapper"
+	"github.com/aws/aws-sdk-go-v2/aws"
+)
+
+type lambdaClientKey struct {
+	region     string
+	roleARN    string
+	externalID string
+}
+
+// LambdaClientPool keeps a Lambda client per region and assumed role, built on first use.
+// The clients of assumed roles share the router credentials, their sessions are refreshed before they expire.
+type LambdaClientPool struct {
+	config    *config.Config
+	awsConfig aws.Config
+
+	mu      sync.Mutex
+	clients map[lambdaClientKey]LambdaWrapperClient
+}
+
+func NewLambdaClientPool(config *config.Config, awsConfig aws.Config) *LambdaClientPool {
+	return &LambdaClientPool{
+		config:    config,
+		awsConfig: awsConfig,
+		clients:   map[lambdaClientKey]LambdaWrapperClient{},
+	}
+}
+
+// Default returns the client of AWS_REGION with the router credentials.
+func (p *LambdaClientPool) Default() LambdaWrapperClient {
+	return p.Client(LambdaFunctionImpl{})
+}
+
+// Client returns the client reaching function, in its region and with its role.
+func (p *LambdaClientPool) Client(function LambdaFunctionImpl) LambdaWrapperClient {
+	key := lambdaClientKey{
+		region:     cmp.Or(function.Region, p.config.AWS.Region),
+		roleARN:    function.RoleARN,
+		externalID: function.ExternalID,
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if client, ok := p.clients[key]; ok {
+		return client
+	}
+
+	awsConfig := p.awsConfig.Copy()
+	awsConfig.Region = key.region
+	if key.roleARN != "" {
+		awsConfig.Credentials = aws_wrapper.NewAssumeRoleProvider(p.awsConfig, p.config, key.region, aws_wrapper.AssumeRoleOptions{
+			RoleARN:    key.roleARN,
+			ExternalID: key.externalID,
+		})
+	}
+	client := newLambdaWrapperClient(p.config, awsConfig)
+	p.clients[key] = client
+	return client
+}
@@ -0,0 +1,73 @@
+package lambda_wrapper
+
+import (
+	"encoding/base64"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Report holds the metrics of the REPORT line written by Lambda at the end of each invocation.
+// Durations are in milliseconds, rounded up; fields missing from the line are zero.
+type Report struct {
+	RequestID        string
+	DurationMs       int64
+	BilledDurationMs int64
+	InitDurationMs   int64
+	MemorySizeMB     int
+	MaxMemoryUsedMB  int
+}
+
+var (
+	reportRequestID = regexp.MustCompile(`RequestId:\s*([0-9a-fA-F-]+)`)
+	reportField     = regexp.MustCompile(`(Billed Duration|Init Duration|Duration|Max Memory Used|Memory Size):\s*([0-9.]+)\s*(ms|MB)`)
+)
+
+// DecodeLogResult decodes the base64 log tail (last 4 KB of the execution log) returned with LogTypeTail.
+func DecodeLogResult(logResult *string) string {
+	if logResult == nil || *logResult == "" {
+		return ""
+	}
+	decoded, err := base64.StdEncoding.DecodeString(*logResult)
+	if err != nil {
+		return ""
+	}
+	return strings.ToValidUTF8(string(decoded), "�")
+}
+
+// ParseReport parses the last REPORT line of a log tail. It returns nil when the tail has none
+// (e.g. it was cut by the 4 KB limit).
+func ParseReport(logTail string) *Report {
+	index := strings.LastIndex(logTail, "REPORT ")
+	if index < 0 {
+		return nil
+	}
+	line, _, _ := strings.Cut(logTail[index:], "\n")
+
+	report := &Report{}
+	if match := reportRequestID.FindStringSubmatch(line); match != nil {
+		report.RequestID = match[1]
+	}
+	for _, match := range reportField.FindAllStringSubmatch(line, -1) {
+		value, err := strconv.ParseFloat(match[2], 64)
+		if err != nil {
+			continue
+		}
+		rounded := int64(math.Ceil(value))
+
+		switch match[1] {
+		case "Duration":
+			report.DurationMs = rounded
+		case "Billed Duration":
+			report.BilledDurationMs = rounded
+		case "Init Duration":
+			report.InitDurationMs = rounded
+		case "Memory Size":
+			report.MemorySizeMB = int(rounded)
+		case "Max Memory Used":
+			report.MaxMemoryUsedMB = int(rounded)
+		}
+	}
+	return report
+}
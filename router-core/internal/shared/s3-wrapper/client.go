@@ -0,0 +1,140 @@
+package s3_wrapper
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"aigendrug.com/router-core/internal/config"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/smithy-go"
+)
+
+// MaxObjectSize bounds the size of objects read into memory by GetObject.
+const MaxObjectSize = 64 << 20
+
+type S3WrapperClient struct {
+	s3Client      *s3.Client
+	presignClient *s3.PresignClient
+}
+
+// NewS3WrapperClient builds an S3 client using the same credentials as the Lambda client.
+// AWS_S3_ENDPOINT, or AWS_ENDPOINT_URL, points the client to an S3-compatible stand-in (e.g. MinIO, LocalStack),
+// which usually requires AWS_S3_USE_PATH_STYLE as well.
+func NewS3WrapperClient(config *config.Config, awsConfig aws.Config) S3WrapperClient {
+	client := s3.NewFromConfig(awsConfig, func(o *s3.Options) {
+		if endpoint := cmp.Or(config.AWS.S3Endpoint, config.AWS.EndpointURL); endpoint != "" {
+			o.BaseEndpoint = aws.String(endpoint)
+		}
+		o.UsePathStyle = config.AWS.S3UsePathStyle
+	})
+
+	return S3WrapperClient{
+		s3Client:      client,
+		presignClient: s3.NewPresignClient(client),
+	}
+}
+
+// HeadObject reports whether the object exists.
+// A missing object is not an error.
+func (wrapper *S3WrapperClient) HeadObject(ctx context.Context, bucket string, key string) (*s3.HeadObjectOutput, bool, error) {
+	output, err := wrapper.s3Client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		if IsNotFound(err) {
+			return nil, false, nil
+		}
+		return nil, false, err
+	}
+	return output, true, nil
+}
+
+// GetObject downloads the object and returns its content with its content type.
+func (wrapper *S3WrapperClient) GetObject(ctx context.Context, bucket string, key string) ([]byte, string, error) {
+	output, err := wrapper.s3Client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	defer output.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(output.Body, MaxObjectSize+1))
+	if err != nil {
+		return nil, "", err
+	}
+	if len(body) > MaxObjectSize {
+		return nil, "", fmt.Errorf("object s3://%s/%s exceeds %d bytes", bucket, key, MaxObjectSize)
+	}
+
+	return body, aws.ToString(output.ContentType), nil
+}
+
+// OpenObject streams the object, the caller closes the returned body.
+func (wrapper *S3WrapperClient) OpenObject(ctx context.Context, bucket string, key string) (*s3.GetObjectOutput, error) {
+	return wrapper.s3Client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	})
+}
+
+// PutObject uploads body, which is seekable so that the SDK can sign and retry the upload.
+func (wrapper *S3WrapperClient) PutObject(
+	ctx context.Context, bucket string, key string, body io.ReadSeeker, size int64, contentType string,
+) error {
+	input := &s3.PutObjectInput{
+		Bucket:        aws.String(bucket),
+		Key:           aws.String(key),
+		Body:          body,
+		ContentLength: aws.Int64(size),
+	}
+	if contentType != "" {
+		input.ContentType = aws.String(contentType)
+	}
+	_, err := wrapper.s3Client.PutObject(ctx, input)
+	return err
+}
+
+// DeleteObject removes the object. A missing object is not an error.
+func (wrapper *S3WrapperClient) DeleteObject(ctx context.Context, bucket string, key string) error {
+	_, err := wrapper.s3Client.DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil && !IsNotFound(err) {
+		return err
+	}
+	return nil
+}
+
+// PresignGetObject returns a URL downloading the object without credentials until ttl elapses.
+func (wrapper *S3WrapperClient) PresignGetObject(
+	ctx context.Context, bucket string, key string, ttl time.Duration,
+) (string, error) {
+	request, err := wrapper.presignClient.PresignGetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	}, s3.WithPresignExpires(ttl))
+	if err != nil {
+		return "", err
+	}
+	return request.URL, nil
+}
+
+func IsNotFound(err error) bool {
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) {
+		switch apiErr.ErrorCode() {
+		case "NotFound", "NoSuchKey":
+			return true
+		}
+	}
+	return false
+}
@@ -1,7 +1,13 @@
 package selector
 
+// SelectorRequest
+//
+// ExcludedToolIDs: Tools which must not be selected (e.g. unhealthy tools).
+// DemotedToolIDs: Tools ranked after the others, offered only when too few other tools match the prompt.
 type SelectorRequest struct {
-	UserPrompt string `json:"user_prompt"`
+	UserPrompt      string `json:"user_prompt"`
+	ExcludedToolIDs []int  `json:"excluded_tool_ids,omitempty"`
+	DemotedToolIDs  []int  `json:"demoted_tool_ids,omitempty"`
 }
 
 type SelectorResponse struct {
@@ -4,12 +4,16 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 
 	"aigendrug.com/router-core/internal/config"
 )
 
+// ErrNoCandidateTool is returned by Select when no tool is left to select once the excluded tools are removed.
+var ErrNoCandidateTool = errors.New("no candidate tool left to select")
+
 type selectorService struct {
 	selectorURL string
 }
@@ -37,6 +41,17 @@ func (s *selectorService) Select(ctx context.Context, request SelectorRequest) (
 
 	defer response.Body.Close()
 
+	if response.StatusCode != http.StatusOK {
+		var errorResponse struct {
+			Detail string `json:"detail"`
+		}
+		_ = json.NewDecoder(response.Body).Decode(&errorResponse)
+		if response.StatusCode == http.StatusNotFound {
+			return SelectorResponse{}, fmt.Errorf("%w: %s", ErrNoCandidateTool, errorResponse.Detail)
+		}
+		return SelectorResponse{}, fmt.Errorf("failed to select tool: %s: %s", response.Status, errorResponse.Detail)
+	}
+
 	var selectorResponse SelectorResponse
 	if err := json.NewDecoder(response.Body).Decode(&selectorResponse); err != nil {
 		return SelectorResponse{}, fmt.Errorf("failed to decode response: %w", err)
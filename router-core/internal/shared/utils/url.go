@@ -0,0 +1,64 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"net/url"
+	"syscall"
+)
+
+// ErrPrivateAddress reports a connection to an address which is not publicly routable.
+var ErrPrivateAddress = errors.New("address is not public")
+
+// ValidateHTTPURL checks that rawURL is an absolute http(s) URL.
+func ValidateHTTPURL(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("unsupported scheme %q (expected http or https)", u.Scheme)
+	}
+	if u.Host == "" {
+		return fmt.Errorf("missing host")
+	}
+	return nil
+}
+
+// IsPublicAddr reports whether addr is publicly routable: loopback, private (RFC 1918, RFC 4193),
+// link-local (e.g. the 169.254.169.254 metadata endpoint), multicast and unspecified addresses are not.
+func IsPublicAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	return addr.IsValid() &&
+		!addr.IsLoopback() &&
+		!addr.IsPrivate() &&
+		!addr.IsLinkLocalUnicast() &&
+		!addr.IsLinkLocalMulticast() &&
+		!addr.IsInterfaceLocalMulticast() &&
+		!addr.IsMulticast() &&
+		!addr.IsUnspecified() &&
+		!sharedAddressSpace.Contains(addr)
+}
+
+// carrier-grade NAT range (RFC 6598), not reachable from the internet either
+var sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+
+// PublicDialControl is a net.Dialer Control refusing connections to addresses which are not public.
+// It runs after the host is resolved, for every connection (including redirects), so a name resolving
+// to a private address is refused as well.
+func PublicDialControl(network string, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return err
+	}
+	if !IsPublicAddr(addr) {
+		return fmt.Errorf("%w: %s", ErrPrivateAddress, addr)
+	}
+	return nil
+}
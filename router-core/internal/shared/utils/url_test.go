@@ -0,0 +1,103 @@
+package utils
+
+import (
+	"errors"
+	"net/netip"
+	"testing"
+)
+
+func TestValidateHTTPURL(t *testing.T) {
+	tests := []struct {
+		name    string
+		url     string
+		wantErr bool
+	}{
+		{name: "https", url: "https://hooks.example.com/tool-requests"},
+		{name: "http with port and query", url: "http://example.com:8080/hook?token=abc"},
+		{name: "ip address", url: "https://203.0.113.10/hook"},
+		{name: "empty", url: "", wantErr: true},
+		{name: "relative", url: "/hook", wantErr: true},
+		{name: "missing host", url: "https:///hook", wantErr: true},
+		{name: "ftp", url: "ftp://example.com/hook", wantErr: true},
+		{name: "file", url: "file:///etc/passwd", wantErr: true},
+		{name: "no scheme", url: "example.com/hook", wantErr: true},
+		{name: "invalid", url: "https://exa mple.com/%zz", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateHTTPURL(tt.url)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ValidateHTTPURL(%q) error = %v, want error %v", tt.url, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestIsPublicAddr(t *testing.T) {
+	tests := []struct {
+		addr string
+		want bool
+	}{
+		{addr: "93.184.216.34", want: true},
+		{addr: "2606:2800:220:1:248:1893:25c8:1946", want: true},
+		{addr: "127.0.0.1"},
+		{addr: "::1"},
+		{addr: "10.1.2.3"},
+		{addr: "172.16.0.1"},
+		{addr: "192.168.1.1"},
+		{addr: "fd00::1"},
+		{addr: "169.254.169.254"},
+		{addr: "fe80::1"},
+		{addr: "100.64.0.1"},
+		{addr: "100.127.255.255"},
+		{addr: "100.128.0.1", want: true},
+		{addr: "224.0.0.1"},
+		{addr: "ff02::1"},
+		{addr: "0.0.0.0"},
+		{addr: "::"},
+		{addr: "::ffff:127.0.0.1"},
+		{addr: "::ffff:8.8.8.8", want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.addr, func(t *testing.T) {
+			if got := IsPublicAddr(netip.MustParseAddr(tt.addr)); got != tt.want {
+				t.Fatalf("IsPublicAddr(%s) = %v, want %v", tt.addr, got, tt.want)
+			}
+		})
+	}
+
+	if IsPublicAddr(netip.Addr{}) {
+		t.Fatal("IsPublicAddr(invalid address) = true, want false")
+	}
+}
+
+func TestPublicDialControl(t *testing.T) {
+	tests := []struct {
+		name        string
+		address     string
+		wantErr     bool
+		wantPrivate bool
+	}{
+		{name: "public", address: "93.184.216.34:443"},
+		{name: "public ipv6", address: "[2606:2800:220:1:248:1893:25c8:1946]:443"},
+		{name: "loopback", address: "127.0.0.1:8080", wantErr: true, wantPrivate: true},
+		{name: "metadata endpoint", address: "169.254.169.254:80", wantErr: true, wantPrivate: true},
+		{name: "private ipv6", address: "[fd00::1]:443", wantErr: true, wantPrivate: true},
+		{name: "missing port", address: "93.184.216.34", wantErr: true},
+		{name: "unresolved name", address: "example.com:443", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := PublicDialControl("tcp", tt.address, nil)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("PublicDialControl(%q) error = %v, want error %v", tt.address, err, tt.wantErr)
+			}
+			if errors.Is(err, ErrPrivateAddress) != tt.wantPrivate {
+				t.Fatalf("PublicDialControl(%q) error = %v, want ErrPrivateAddress %v", tt.address, err, tt.wantPrivate)
+			}
+		})
+	}
+}
@@ -3,11 +3,20 @@ package dto
 import (
 	"time"
 
+	"aigendrug.com/router-core/internal/shared/jsonschema"
 	"aigendrug.com/router-core/internal/tool/domain/shared_type"
 	"aigendrug.com/router-core/internal/tool/domain/valueobject"
 	"github.com/google/uuid"
 )
 
+// ReadToolDTO
+//
+// RequestSchema / ResponseSchema: JSON Schema of the tool input / output, resolved from the provider interface
+// (its schemas, or the schemas equivalent to its element lists). Used to document the tool and build forms.
+//
+// Draft: The tool is not offered to clients (listing, selection, execution) until it is published.
+//
+// Health: Current health of the tool, maintained by the health prober. Unhealthy tools are left out of selection.
 type ReadToolDTO struct {
 	ID                int                           `json:"id" example:"1"`
 	UUID              uuid.UUID                     `json:"uuid" example:"123e4567-e89b-12d3-a456-426614174000"`
@@ -16,6 +25,10 @@ type ReadToolDTO struct {
 	Description       string                        `json:"description" example:"Tool Description"`
 	EngineInterface   shared_type.EngineInterface   `json:"engine_interface"`
 	ProviderInterface shared_type.ProviderInterface `json:"provider_interface"`
+	Draft             bool                          `json:"draft" example:"false"`
+	Health            shared_type.ToolHealth        `json:"health"`
+	RequestSchema     *jsonschema.Schema            `json:"request_schema,omitempty" swaggertype:"object"`
+	ResponseSchema    *jsonschema.Schema            `json:"response_schema,omitempty" swaggertype:"object"`
 }
 
 type CreateToolDTO struct {
@@ -24,6 +37,7 @@ type CreateToolDTO struct {
 	Description       string                        `json:"description" example:"Tool Description"`
 	EngineInterface   shared_type.EngineInterface   `json:"engine_interface"`
 	ProviderInterface shared_type.ProviderInterface `json:"provider_interface"`
+	Draft             bool                          `json:"draft" example:"false"`
 }
 
 type UpdateToolDTO struct {
@@ -32,6 +46,87 @@ type UpdateToolDTO struct {
 	ProviderInterface shared_type.ProviderInterface `json:"provider_interface"`
 }
 
+// ReadToolHealthDTO is the current health of a tool with its recent health checks, most recent first.
+type ReadToolHealthDTO struct {
+	ToolID   int                       `json:"tool_id" example:"1"`
+	ToolName string                    `json:"tool_name" example:"Tool Name"`
+	Health   shared_type.ToolHealth    `json:"health"`
+	Checks   []*ReadToolHealthCheckDTO `json:"checks"`
+}
+
+// ReadToolHealthCheckDTO
+//
+// Healthy: Result of the check. Status is the health of the tool after the check.
+type ReadToolHealthCheckDTO struct {
+	ID        int                          `json:"id" example:"1"`
+	ToolID    int                          `json:"tool_id" example:"1"`
+	Healthy   bool                         `json:"healthy" example:"true"`
+	Status    valueobject.ToolHealthStatus `json:"status" example:"healthy"`
+	Message   string                       `json:"message,omitempty" example:"function state is Failed"`
+	LatencyMs int64                        `json:"latency_ms" example:"120"`
+	CheckedAt time.Time                    `json:"checked_at" example:"2021-01-01T00:00:00Z"`
+}
+
+// ReadCircuitBreakerDTO is the circuit breaker of a tool on the replica serving the request.
+// Each replica counts the invocations of its workers, and the trips are shared through the database: a breaker
+// tripped by a replica opens on the others within a few seconds, and closes on all of them when a trial succeeds
+// or the breaker is reset on any replica.
+//
+// Replica: Worker ID of the replica (the locked_by of the tool requests it claims).
+// Requests / Failures: Invocations counted by the replica in the current window (closed state).
+// RetryAt: End of the open state, the next execution is a trial.
+// Trips: Times the breaker opened on any replica since its last reset.
+type ReadCircuitBreakerDTO struct {
+	Replica     string                          `json:"replica" example:"router-core-7d9f-0b6c1a2e-5f3d-4c8e-9a71-2e4b6d8f0c13"`
+	ToolID      int                             `json:"tool_id" example:"1"`
+	ToolName    string                          `json:"tool_name" example:"Tool Name"`
+	State       valueobject.CircuitBreakerState `json:"state" example:"open"`
+	Requests    int                             `json:"requests" example:"12"`
+	Failures    int                             `json:"failures" example:"9"`
+	FailureRate float64                         `json:"failure_rate" example:"0.75"`
+	OpenedAt    *time.Time                      `json:"opened_at,omitempty" example:"2021-01-01T00:00:00Z"`
+	RetryAt     *time.Time                      `json:"retry_at,omitempty" example:"2021-01-01T00:00:30Z"`
+	Trips       int                             `json:"trips" example:"1"`
+	LastError   string                          `json:"last_error,omitempty" example:"execution timeout after 10s"`
+}
+
+// ReadLambdaFunctionDTO is a Lambda function of the configured account, listed to import it as a tool.
+//
+// Tags: Tags of the function, the "router:*" tags describe the tool created by the import (see ImportLambdaTools).
+//
+// ToolIDs: Tools already invoking the function (engine impl "function_name" is its name or ARN).
+type ReadLambdaFunctionDTO struct {
+	FunctionName   string            `json:"function_name" example:"tool-calculator"`
+	FunctionARN    string            `json:"function_arn" example:"arn:aws:lambda:ap-northeast-2:123456789012:function:tool-calculator"`
+	Description    string            `json:"description" example:"Performs a basic arithmetic operation"`
+	Runtime        string            `json:"runtime" example:"python3.12"`
+	Handler        string            `json:"handler" example:"app.handler"`
+	MemorySizeMB   int               `json:"memory_size_mb" example:"128"`
+	TimeoutSeconds int               `json:"timeout_seconds" example:"30"`
+	LastModified   string            `json:"last_modified" example:"2021-01-01T00:00:00.000+0000"`
+	Tags           map[string]string `json:"tags"`
+	ToolIDs        []int             `json:"tool_ids"`
+}
+
+type ImportLambdaToolsDTO struct {
+	FunctionNames []string `json:"function_names" example:"tool-calculator"`
+}
+
+// ImportLambdaToolsResultDTO
+//
+// Created: Draft tools created by the import, one per function.
+//
+// Skipped: Functions which were not imported, with the reason (already registered, not found, invalid tags, etc.).
+type ImportLambdaToolsResultDTO struct {
+	Created []*ReadToolDTO         `json:"created"`
+	Skipped []SkippedLambdaToolDTO `json:"skipped"`
+}
+
+type SkippedLambdaToolDTO struct {
+	FunctionName string `json:"function_name" example:"tool-calculator"`
+	Reason       string `json:"reason" example:"already registered as tool 1"`
+}
+
 type ReadToolClientPermissionDTO struct {
 	ID              int                                   `json:"id" example:"1"`
 	ToolID          int                                   `json:"tool_id" example:"1"`
@@ -51,16 +146,39 @@ type UpdateToolClientPermissionDTO struct {
 	PermissionLevel valueobject.ToolClientPermissionLevel `json:"permission_level" example:"read"`
 }
 
+// ReadToolRequestDTO
+//
+// CacheHit: The response was served from the result cache of the tool (details in response_data.cache_hit).
+// QueuePosition: Position (from 1) of a pending request among the older pending requests competing for the same
+// concurrency limits (of its client, its tool or the pair, any request when none is set), which run first. It is an
+// upper bound: requests ahead may be held by limits of their own. Reported when reading a single tool request,
+// and by executions waiting for their result.
 type ReadToolRequestDTO struct {
-	ID           int                                 `json:"id" example:"1"`
-	ToolID       int                                 `json:"tool_id" example:"1"`
-	ToolName     string                              `json:"tool_name" example:"Tool Name"`
-	ClientID     int                                 `json:"client_id" example:"1"`
-	RequestData  shared_type.ToolRequestData         `json:"request_data"`
-	ResponseData shared_type.ToolRequestResponseData `json:"response_data"`
-	Status       valueobject.ToolRequestStatus       `json:"status" example:"pending"`
-	CreatedAt    time.Time                           `json:"created_at" example:"2021-01-01T00:00:00Z"`
-	UpdatedAt    time.Time                           `json:"updated_at" example:"2021-01-01T00:00:00Z"`
+	ID            int                                 `json:"id" example:"1"`
+	ToolID        int                                 `json:"tool_id" example:"1"`
+	ToolName      string                              `json:"tool_name" example:"Tool Name"`
+	ClientID      int                                 `json:"client_id" example:"1"`
+	RequestData   shared_type.ToolRequestData         `json:"request_data"`
+	ResponseData  shared_type.ToolRequestResponseData `json:"response_data"`
+	Status        valueobject.ToolRequestStatus       `json:"status" example:"pending"`
+	Attempts      []shared_type.ToolRequestAttempt    `json:"attempts"`
+	AttemptCount  int                                 `json:"attempt_count" example:"1"`
+	CacheHit      bool                                `json:"cache_hit" example:"false"`
+	QueuePosition int                                 `json:"queue_position,omitempty" example:"3"`
+	CreatedAt     time.Time                           `json:"created_at" example:"2021-01-01T00:00:00Z"`
+	UpdatedAt     time.Time                           `json:"updated_at" example:"2021-01-01T00:00:00Z"`
+}
+
+// ReadToolRequestLogsDTO
+//
+// Attempts of a tool request with the full diagnostics reported by the engine, including the log tail of aws-lambda
+// tools (diagnostics.log_tail) and the stderr of local-exec tools (diagnostics.stderr), which other endpoints leave out.
+type ReadToolRequestLogsDTO struct {
+	ToolRequestID int                              `json:"tool_request_id" example:"1"`
+	ToolID        int                              `json:"tool_id" example:"1"`
+	ToolName      string                           `json:"tool_name" example:"Tool Name"`
+	Status        valueobject.ToolRequestStatus    `json:"status" example:"failed"`
+	Attempts      []shared_type.ToolRequestAttempt `json:"attempts"`
 }
 
 type CreateToolRequestDTO struct {
@@ -76,6 +194,93 @@ type UpdateToolRequestDTO struct {
 	Status       valueobject.ToolRequestStatus       `json:"status" example:"pending"`
 }
 
+// ReadToolResultCacheDTO is a cached result of a cacheable tool.
+//
+// ToolRequestID: Tool request which produced the result, served again for payloads hashing to CacheKey.
+//
+// Expired: The entry is past ExpiresAt, it is no longer served and is replaced by the next result.
+type ReadToolResultCacheDTO struct {
+	ID            int        `json:"id" example:"1"`
+	ToolID        int        `json:"tool_id" example:"1"`
+	ToolVersion   string     `json:"tool_version" example:"1.0.0"`
+	CacheKey      string     `json:"cache_key" example:"9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"`
+	ToolRequestID int        `json:"tool_request_id" example:"1"`
+	HitCount      int        `json:"hit_count" example:"3"`
+	LastHitAt     *time.Time `json:"last_hit_at,omitempty" example:"2021-01-01T00:00:00Z"`
+	ExpiresAt     time.Time  `json:"expires_at" example:"2021-01-02T00:00:00Z"`
+	Expired       bool       `json:"expired" example:"false"`
+	CreatedAt     time.Time  `json:"created_at" example:"2021-01-01T00:00:00Z"`
+}
+
+// ReadToolScheduleDTO
+//
+// CronExpression: Five-field cron expression (minute hour day-of-month month day-of-week) or a macro (@daily, etc.),
+// evaluated in Timezone (IANA name).
+//
+// MissedRunPolicy: Runs missed while no replica was running are dropped (skip), replaced by a single run (run_once)
+// or all fired (run_all).
+//
+// NextRunAt: Next run of the schedule, null while it is paused.
+//
+// LastToolRequestID: Tool request created by the last run. LastError is set instead when the run could not create it
+// (e.g. the permission of the client was revoked or the payload no longer matches the tool).
+type ReadToolScheduleDTO struct {
+	ID                int                                     `json:"id" example:"1"`
+	ToolID            int                                     `json:"tool_id" example:"1"`
+	ToolName          string                                  `json:"tool_name" example:"Tool Name"`
+	ClientID          int                                     `json:"client_id" example:"1"`
+	Name              string                                  `json:"name" example:"Nightly re-scoring"`
+	CronExpression    string                                  `json:"cron_expression" example:"0 2 * * *"`
+	Timezone          string                                  `json:"timezone" example:"Asia/Seoul"`
+	Payload           map[string]any                          `json:"payload"`
+	WebhookURL        string                                  `json:"webhook_url,omitempty" example:"https://example.com/hooks/tool-requests"`
+	MissedRunPolicy   valueobject.ToolScheduleMissedRunPolicy `json:"missed_run_policy" example:"run_once"`
+	Status            valueobject.ToolScheduleStatus          `json:"status" example:"active"`
+	NextRunAt         *time.Time                              `json:"next_run_at" example:"2021-01-02T02:00:00Z"`
+	LastRunAt         *time.Time                              `json:"last_run_at" example:"2021-01-01T02:00:00Z"`
+	LastToolRequestID *int                                    `json:"last_tool_request_id" example:"1"`
+	LastError         string                                  `json:"last_error,omitempty"`
+	CreatedAt         time.Time                               `json:"created_at" example:"2021-01-01T00:00:00Z"`
+	UpdatedAt         time.Time                               `json:"updated_at" example:"2021-01-01T00:00:00Z"`
+}
+
+// CreateToolScheduleDTO
+//
+// Timezone defaults to UTC and MissedRunPolicy to run_once.
+type CreateToolScheduleDTO struct {
+	ToolID          int                                     `json:"tool_id" example:"1"`
+	Name            string                                  `json:"name" example:"Nightly re-scoring"`
+	CronExpression  string                                  `json:"cron_expression" example:"0 2 * * *"`
+	Timezone        string                                  `json:"timezone,omitempty" example:"Asia/Seoul"`
+	Payload         map[string]any                          `json:"payload"`
+	WebhookURL      string                                  `json:"webhook_url,omitempty" example:"https://example.com/hooks/tool-requests"`
+	MissedRunPolicy valueobject.ToolScheduleMissedRunPolicy `json:"missed_run_policy,omitempty" example:"run_once"`
+}
+
+type UpdateToolScheduleDTO struct {
+	Name            string                                  `json:"name" example:"Nightly re-scoring"`
+	CronExpression  string                                  `json:"cron_expression" example:"0 2 * * *"`
+	Timezone        string                                  `json:"timezone,omitempty" example:"Asia/Seoul"`
+	Payload         map[string]any                          `json:"payload"`
+	WebhookURL      string                                  `json:"webhook_url,omitempty" example:"https://example.com/hooks/tool-requests"`
+	MissedRunPolicy valueobject.ToolScheduleMissedRunPolicy `json:"missed_run_policy,omitempty" example:"run_once"`
+}
+
+// PreviewToolScheduleDTO asks for the next runs of a cron expression before a schedule is created.
+// Count defaults to 5.
+type PreviewToolScheduleDTO struct {
+	CronExpression string `json:"cron_expression" example:"0 2 * * *"`
+	Timezone       string `json:"timezone,omitempty" example:"Asia/Seoul"`
+	Count          int    `json:"count,omitempty" example:"5"`
+}
+
+// ToolScheduleRunsDTO lists the next runs of a schedule, in its timezone.
+type ToolScheduleRunsDTO struct {
+	CronExpression string      `json:"cron_expression" example:"0 2 * * *"`
+	Timezone       string      `json:"timezone" example:"Asia/Seoul"`
+	NextRuns       []time.Time `json:"next_runs"`
+}
+
 type SelectToolRequestDTO struct {
 	UserPrompt string `json:"user_prompt" example:"i want to add two numbers"`
 }
@@ -86,12 +291,64 @@ type SelectToolResponseDTO struct {
 	Message         string                                `json:"message"`
 }
 
+// ToolExecutionRequestDTO
+//
+// WebhookURL: Optional URL receiving a signed POST when the tool request finishes,
+// in addition to the webhook endpoints registered by the client.
 type ToolExecutionRequestDTO struct {
-	Payload map[string]any `json:"payload"`
+	Payload    map[string]any `json:"payload"`
+	WebhookURL string         `json:"webhook_url,omitempty" example:"https://example.com/hooks/tool-requests"`
+}
+
+// PayloadFieldErrorDTO describes a payload field rejected by the tool's request schema.
+//
+// Field is the path of the value in the payload (e.g. "params.grid[2]").
+//
+// Code is one of:
+// - "missing": A required field is absent.
+// - "type_mismatch": The value does not have the declared type (Expected and Actual are set).
+// - "unknown": The field is not declared by the tool.
+// - "enum", "range", "length", "pattern", "unique", "composition": The value violates a constraint of the schema.
+type PayloadFieldErrorDTO struct {
+	Field    string `json:"field" example:"smiles"`
+	Code     string `json:"code" example:"type_mismatch"`
+	Expected string `json:"expected,omitempty" example:"string"`
+	Actual   string `json:"actual,omitempty" example:"number"`
+	Message  string `json:"message" example:"smiles must be a string, got number"`
 }
 
+// PayloadValidationErrorDTO is returned with 422 when the payload of ExecuteTool does not match the tool's request schema.
+type PayloadValidationErrorDTO struct {
+	Msg    string                  `json:"msg"`
+	Errors []*PayloadFieldErrorDTO `json:"errors"`
+}
+
+// ToolExecutionResponseDTO
+//
+// ToolRequest is only set in wait mode (?wait=...), with the state of the tool request when the wait ended.
+//
+// RetryAt is set with the circuit_open status, when the circuit breaker of the tool lets a trial execution through,
+// and with the quota_exceeded status, when the exceeded quota of the client resets.
 type ToolExecutionResponseDTO struct {
 	Status        valueobject.ToolExecutionStatus `json:"status"`
 	Message       string                          `json:"message"`
 	ToolRequestID int                             `json:"tool_request_id"`
+	ToolRequest   *ReadToolRequestDTO             `json:"tool_request,omitempty"`
+	RetryAt       *time.Time                      `json:"retry_at,omitempty" example:"2021-01-01T00:00:00Z"`
+}
+
+// ToolRequestEventDTO is a server-sent event of a tool request stream.
+//
+// Event is the SSE event name:
+// - "snapshot": Current state of the tool request when the stream starts (ToolRequest is set).
+// - "status": Status transition of the tool request.
+// - "progress": Progress of a running tool request (retry, provider progress, etc.) described by Message.
+// - "result": Final state of the tool request including its payload (ToolRequest is set).
+// - "ping": Keep-alive.
+type ToolRequestEventDTO struct {
+	Event         string                        `json:"event" example:"status"`
+	ToolRequestID int                           `json:"tool_request_id,omitempty" example:"1"`
+	Status        valueobject.ToolRequestStatus `json:"status,omitempty" example:"running"`
+	Message       string                        `json:"message,omitempty"`
+	ToolRequest   *ReadToolRequestDTO           `json:"tool_request,omitempty"`
 }
@@ -0,0 +1,199 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"strings"
+	"time"
+
+	"aigendrug.com/router-core/internal/shared/blobstore"
+	"aigendrug.com/router-core/internal/shared/jsonschema"
+	"aigendrug.com/router-core/internal/tool/application/dto"
+	"aigendrug.com/router-core/internal/tool/domain/entity"
+	"aigendrug.com/router-core/internal/tool/domain/shared_type"
+	"aigendrug.com/router-core/internal/tool/domain/valueobject"
+	"github.com/google/uuid"
+)
+
+var ErrBlobStoreUnavailable = errors.New("blob store is not configured")
+
+// ToolExecutionFile is a file uploaded with the multipart variant of ExecuteTool.
+// Field is the payload key of the file input, Open reads its content.
+type ToolExecutionFile struct {
+	Field       string
+	FileName    string
+	ContentType string
+	Open        func() (io.ReadSeekCloser, error)
+}
+
+// planArtifacts assigns a blob store key to each uploaded file and references it from the payload,
+// so that the payload can be validated before anything is stored.
+// Files must be given for the file inputs of the request schema (any key when the tool declares no schema).
+func planArtifacts(
+	providerInterface shared_type.ProviderInterface, clientID int, payload map[string]any, files []ToolExecutionFile,
+) (map[string]any, []shared_type.ToolRequestArtifact, error) {
+	if len(files) == 0 {
+		return payload, nil, nil
+	}
+
+	schema := providerInterface.ResolvedRequestSchema()
+	uploadID := uuid.NewString()
+
+	planned := make(map[string]any, len(payload)+len(files))
+	maps.Copy(planned, payload)
+
+	var fieldErrors []*dto.PayloadFieldErrorDTO
+	artifacts := make([]shared_type.ToolRequestArtifact, 0, len(files))
+	for _, file := range files {
+		if schema != nil && !schema.IsFileProperty(file.Field) {
+			fieldErrors = append(fieldErrors, fileFieldError(schema, file.Field))
+			continue
+		}
+
+		artifact := shared_type.ToolRequestArtifact{
+			Field:       file.Field,
+			FileName:    file.FileName,
+			ContentType: file.ContentType,
+			Key: fmt.Sprintf("tool-requests/%d/%s/%s/%s",
+				clientID, uploadID, sanitizeKeySegment(file.Field), sanitizeKeySegment(file.FileName)),
+		}
+		planned[file.Field] = artifact.Reference()
+		artifacts = append(artifacts, artifact)
+	}
+	if len(fieldErrors) > 0 {
+		return nil, nil, &PayloadValidationError{Errors: fieldErrors}
+	}
+
+	return planned, artifacts, nil
+}
+
+func fileFieldError(schema *jsonschema.Schema, field string) *dto.PayloadFieldErrorDTO {
+	property, declared := schema.Properties[field]
+	if !declared || property == nil {
+		return &dto.PayloadFieldErrorDTO{
+			Field:   field,
+			Code:    jsonschema.CodeUnknown,
+			Message: fmt.Sprintf("%s is not a file input of the tool", field),
+		}
+	}
+
+	expected := "any"
+	if len(property.Type) > 0 {
+		expected = strings.Join(property.Type, " or ")
+	}
+	return &dto.PayloadFieldErrorDTO{
+		Field:    field,
+		Code:     jsonschema.CodeTypeMismatch,
+		Expected: expected,
+		Actual:   jsonschema.FormatFile,
+		Message:  fmt.Sprintf("%s must be %s, got a file", field, expected),
+	}
+}
+
+// sanitizeKeySegment keeps a file or field name usable as a segment of a blob store key.
+func sanitizeKeySegment(name string) string {
+	name = name[strings.LastIndexAny(name, `/\`)+1:]
+
+	var builder strings.Builder
+	for _, r := range name {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_', r == '.':
+			builder.WriteRune(r)
+		default:
+			builder.WriteRune('_')
+		}
+	}
+
+	sanitized := strings.TrimLeft(builder.String(), ".")
+	if sanitized == "" {
+		return "file"
+	}
+	return sanitized
+}
+
+// storeArtifacts uploads the files to the blob store and completes their artifacts with size and checksum.
+// Files stored before a failure are removed.
+func (s *toolService) storeArtifacts(
+	ctx context.Context, artifacts []shared_type.ToolRequestArtifact, files []ToolExecutionFile,
+) error {
+	if len(artifacts) == 0 {
+		return nil
+	}
+	if s.blobStore == nil {
+		return ErrBlobStoreUnavailable
+	}
+
+	for i := range artifacts {
+		object, err := s.storeArtifact(ctx, artifacts[i].Key, files[i])
+		if err != nil {
+			s.deleteArtifacts(ctx, artifacts[:i])
+			return fmt.Errorf("failed to store file %s: %w", artifacts[i].Field, err)
+		}
+
+		artifacts[i].Size = object.Size
+		artifacts[i].SHA256 = object.SHA256
+		artifacts[i].Backend = s.blobStore.Backend()
+		artifacts[i].UploadedAt = time.Now()
+	}
+	return nil
+}
+
+func (s *toolService) storeArtifact(ctx context.Context, key string, file ToolExecutionFile) (*blobstore.Object, error) {
+	body, err := file.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	return s.blobStore.Put(ctx, key, body, file.ContentType)
+}
+
+// deleteArtifacts removes stored files, failures are only logged (the files are orphaned).
+func (s *toolService) deleteArtifacts(ctx context.Context, artifacts []shared_type.ToolRequestArtifact) {
+	if s.blobStore == nil {
+		return
+	}
+	for _, artifact := range artifacts {
+		if err := s.blobStore.Delete(ctx, artifact.Key); err != nil {
+			fmt.Printf("failed to delete artifact %s: %v\n", artifact.Key, err)
+		}
+	}
+}
+
+// resolveArtifacts replaces the references to the artifacts of the request in payload by the path or
+// presigned URL of the stored files, issued again for every attempt so that presigned URLs
+// do not expire while the request waits for a retry.
+func (e *functionExecutor) resolveArtifacts(
+	ctx context.Context, toolRequest *entity.ToolRequest, payload map[string]any,
+) (map[string]any, error) {
+	artifacts := toolRequest.RequestData.Artifacts
+	if len(artifacts) == 0 {
+		return payload, nil
+	}
+	if e.blobStore == nil {
+		return nil, newExecutionError(valueobject.ExecutionErrorClassConfiguration,
+			"tool request has artifacts but the blob store is not configured")
+	}
+
+	payload = maps.Clone(payload)
+	for _, artifact := range artifacts {
+		if payload[artifact.Field] != artifact.Reference() {
+			continue
+		}
+
+		reference, err := e.blobStore.Reference(ctx, artifact.Key)
+		if err != nil {
+			if errors.Is(err, blobstore.ErrNotFound) {
+				return nil, newExecutionError(valueobject.ExecutionErrorClassConfiguration,
+					"file %s of field %s is missing from the blob store", artifact.FileName, artifact.Field)
+			}
+			return nil, newExecutionError(valueobject.ExecutionErrorClassServerError,
+				"failed to resolve file %s of field %s: %v", artifact.FileName, artifact.Field, err)
+		}
+		payload[artifact.Field] = reference
+	}
+	return payload, nil
+}
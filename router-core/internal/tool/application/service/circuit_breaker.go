@@ -0,0 +1,494 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"aigendrug.com/router-core/internal/config"
+	"aigendrug.com/router-core/internal/tool/domain"
+	"aigendrug.com/router-core/internal/tool/domain/entity"
+	"aigendrug.com/router-core/internal/tool/domain/valueobject"
+)
+
+// Default circuit breaker settings, overridden by config (EXECUTOR_BREAKER_*).
+const (
+	DefaultBreakerFailureRate    = 0.5
+	DefaultBreakerMinRequests    = 10
+	DefaultBreakerWindow         = 1 * time.Minute
+	DefaultBreakerOpenDuration   = 30 * time.Second
+	DefaultBreakerHalfOpenTrials = 1
+
+	// delay before a breaker tripped, closed or reset by a replica applies to the others
+	breakerSyncInterval = 5 * time.Second
+)
+
+// breakerFailureClasses are the failures of the provider counted by the breakers.
+// Other errors (client errors, function errors, etc.) show that the provider answers and count as successes,
+// cancelled and misconfigured invocations are not counted.
+var breakerFailureClasses = []valueobject.ExecutionErrorClass{
+	valueobject.ExecutionErrorClassThrottled,
+	valueobject.ExecutionErrorClassTimeout,
+	valueobject.ExecutionErrorClassNetwork,
+	valueobject.ExecutionErrorClassServerError,
+	valueobject.ExecutionErrorClassUnknown,
+}
+
+// CircuitBreakerSnapshot is the state of the circuit breaker of a tool. Requests, Failures and FailureRate are
+// counted by this replica, Trips by every replica.
+type CircuitBreakerSnapshot struct {
+	ToolID      int
+	State       valueobject.CircuitBreakerState
+	Requests    int
+	Failures    int
+	FailureRate float64
+	OpenedAt    time.Time
+	RetryAt     time.Time
+	Trips       int
+	LastError   string
+}
+
+type breakerOutcome struct {
+	at     time.Time
+	failed bool
+}
+
+type circuitBreaker struct {
+	state    valueobject.CircuitBreakerState
+	outcomes []breakerOutcome
+	openedAt time.Time
+
+	// trial invocations in flight and successful trials while half-open
+	trials    int
+	successes int
+
+	trips     int
+	lastError string
+}
+
+// circuitBreakers keeps a circuit breaker per tool. Each replica counts the invocations of its workers in memory,
+// and shares the trips of its breakers through Postgres (tool_circuit_breakers): the breakers of the other replicas
+// open with it, and close when a trial succeeds or the breaker is reset on any replica.
+//
+// A closed breaker opens when at least minRequests invocations of the window failed at failureRate or above.
+// An open breaker rejects invocations until openDuration elapses, then lets halfOpenTrials trial invocations
+// through: they close the breaker when they all succeed, the first failure opens it again.
+type circuitBreakers struct {
+	enabled        bool
+	failureRate    float64
+	minRequests    int
+	window         time.Duration
+	openDuration   time.Duration
+	halfOpenTrials int
+
+	// nil keeps the breakers of this replica to itself
+	toolRepo domain.ToolRepository
+
+	mu       sync.Mutex
+	breakers map[int]*circuitBreaker
+	// last reset of the breaker of each tool applied by this replica
+	resets map[int]time.Time
+}
+
+// breakerWrite shares a change of a breaker with the other replicas.
+type breakerWrite func(ctx context.Context, toolRepo domain.ToolRepository) error
+
+func newCircuitBreakers(config *config.Config, toolRepo domain.ToolRepository) *circuitBreakers {
+	b := &circuitBreakers{
+		failureRate:    DefaultBreakerFailureRate,
+		minRequests:    DefaultBreakerMinRequests,
+		window:         DefaultBreakerWindow,
+		openDuration:   DefaultBreakerOpenDuration,
+		halfOpenTrials: DefaultBreakerHalfOpenTrials,
+		toolRepo:       toolRepo,
+		breakers:       map[int]*circuitBreaker{},
+		resets:         map[int]time.Time{},
+	}
+
+	if config != nil {
+		b.enabled = config.Executor.BreakerEnabled
+		if v := config.Executor.BreakerFailureRate; v > 0 && v <= 1 {
+			b.failureRate = v
+		}
+		if v := config.Executor.BreakerMinRequests; v > 0 {
+			b.minRequests = v
+		}
+		if v := config.Executor.BreakerWindowSeconds; v > 0 {
+			b.window = secondsToDuration(v)
+		}
+		if v := config.Executor.BreakerOpenSeconds; v > 0 {
+			b.openDuration = secondsToDuration(v)
+		}
+		if v := config.Executor.BreakerHalfOpenTrials; v > 0 {
+			b.halfOpenTrials = v
+		}
+	}
+
+	return b
+}
+
+// retryAt returns when an open breaker lets the next trial through.
+func (b *circuitBreakers) retryAt(breaker *circuitBreaker) time.Time {
+	return breaker.openedAt.Add(b.openDuration)
+}
+
+// allow reserves an invocation of the tool. It fails with a circuit_open ExecutionError while the breaker is open,
+// or half-open with all its trials in flight. A reserved invocation must be followed by record.
+func (b *circuitBreakers) allow(toolID int) error {
+	if !b.enabled {
+		return nil
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	breaker, ok := b.breakers[toolID]
+	if !ok {
+		return nil
+	}
+
+	now := time.Now()
+	if breaker.state == valueobject.CircuitBreakerStateOpen && !now.Before(b.retryAt(breaker)) {
+		breaker.state = valueobject.CircuitBreakerStateHalfOpen
+		breaker.trials, breaker.successes = 0, 0
+	}
+
+	switch breaker.state {
+	case valueobject.CircuitBreakerStateOpen:
+		return newExecutionError(valueobject.ExecutionErrorClassCircuitOpen,
+			"circuit breaker of the tool is open until %s (last error: %s)",
+			b.retryAt(breaker).Format(time.RFC3339), breaker.lastError)
+	case valueobject.CircuitBreakerStateHalfOpen:
+		if breaker.trials >= b.halfOpenTrials {
+			return newExecutionError(valueobject.ExecutionErrorClassCircuitOpen,
+				"circuit breaker of the tool is half-open, waiting for its trial invocations")
+		}
+		breaker.trials++
+	}
+	return nil
+}
+
+// record counts the outcome of an invocation reserved by allow, err is nil when it succeeded.
+func (b *circuitBreakers) record(toolID int, err error) {
+	if !b.enabled {
+		return
+	}
+
+	failed := false
+	if err != nil {
+		execErr := classifyError(err)
+		switch {
+		case execErr.Class == valueobject.ExecutionErrorClassCancelled,
+			execErr.Class == valueobject.ExecutionErrorClassConfiguration:
+			b.release(toolID)
+			return
+		case slices.Contains(breakerFailureClasses, execErr.Class):
+			failed = true
+		}
+	}
+
+	b.share(toolID, b.count(toolID, err, failed))
+}
+
+// count records the outcome in the breaker of the tool. It returns the write sharing the change of state it caused,
+// if any.
+func (b *circuitBreakers) count(toolID int, err error, failed bool) breakerWrite {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	breaker, ok := b.breakers[toolID]
+	if !ok {
+		breaker = &circuitBreaker{state: valueobject.CircuitBreakerStateClosed}
+		b.breakers[toolID] = breaker
+	}
+
+	now := time.Now()
+	if failed {
+		breaker.lastError = truncate(err.Error(), 500)
+	}
+
+	switch breaker.state {
+	case valueobject.CircuitBreakerStateClosed:
+		breaker.outcomes = append(b.prune(breaker.outcomes, now), breakerOutcome{at: now, failed: failed})
+		requests, failures := countOutcomes(breaker.outcomes)
+		if requests >= b.minRequests && float64(failures)/float64(requests) >= b.failureRate {
+			return b.trip(toolID, breaker, now, fmt.Sprintf("%d of %d invocations failed", failures, requests))
+		}
+	case valueobject.CircuitBreakerStateHalfOpen:
+		breaker.trials = max(breaker.trials-1, 0)
+		if failed {
+			return b.trip(toolID, breaker, now, "trial invocation failed")
+		}
+		breaker.successes++
+		if breaker.successes >= b.halfOpenTrials {
+			breaker.state = valueobject.CircuitBreakerStateClosed
+			breaker.outcomes = nil
+			fmt.Printf("circuit breaker of tool %d closed\n", toolID)
+
+			openedAt := breaker.openedAt
+			return func(ctx context.Context, toolRepo domain.ToolRepository) error {
+				return toolRepo.CloseToolCircuitBreaker(ctx, toolID, openedAt)
+			}
+		}
+	}
+	return nil
+}
+
+// share writes a change of the breaker of the tool for the other replicas.
+func (b *circuitBreakers) share(toolID int, write breakerWrite) {
+	if write == nil || b.toolRepo == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := write(ctx, b.toolRepo); err != nil {
+		fmt.Printf("failed to share the circuit breaker of tool %d: %v\n", toolID, err)
+	}
+}
+
+// release gives back the trial reserved by an invocation which is not counted.
+func (b *circuitBreakers) release(toolID int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if breaker, ok := b.breakers[toolID]; ok && breaker.state == valueobject.CircuitBreakerStateHalfOpen {
+		breaker.trials = max(breaker.trials-1, 0)
+	}
+}
+
+func (b *circuitBreakers) trip(toolID int, breaker *circuitBreaker, now time.Time, reason string) breakerWrite {
+	breaker.state = valueobject.CircuitBreakerStateOpen
+	// at the precision of Postgres, to be compared with the trips of the other replicas
+	breaker.openedAt = now.Truncate(time.Microsecond)
+	breaker.outcomes = nil
+	breaker.trials, breaker.successes = 0, 0
+	breaker.trips++
+	fmt.Printf("circuit breaker of tool %d opened until %s: %s (last error: %s)\n",
+		toolID, b.retryAt(breaker).Format(time.RFC3339), reason, breaker.lastError)
+
+	openedAt, openUntil, lastError := breaker.openedAt, b.retryAt(breaker), breaker.lastError
+	return func(ctx context.Context, toolRepo domain.ToolRepository) error {
+		return toolRepo.TripToolCircuitBreaker(ctx, toolID, openedAt, openUntil, lastError)
+	}
+}
+
+// run syncs the breakers with the other replicas until ctx is done.
+func (b *circuitBreakers) run(ctx context.Context) {
+	if !b.enabled || b.toolRepo == nil {
+		return
+	}
+
+	ticker := time.NewTicker(breakerSyncInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := b.sync(ctx); err != nil && ctx.Err() == nil {
+			fmt.Printf("failed to sync circuit breakers: %v\n", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// sync applies the breakers shared by the other replicas.
+func (b *circuitBreakers) sync(ctx context.Context) error {
+	if !b.enabled || b.toolRepo == nil {
+		return nil
+	}
+
+	shared, err := b.toolRepo.FindAllToolCircuitBreakers(ctx)
+	if err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	for _, breaker := range shared {
+		b.apply(breaker, now)
+	}
+	return nil
+}
+
+// apply updates the breaker of a tool with its shared state: a reset forgets the breaker, a trip of another replica
+// opens it until the end of the trip, and the closing of the trip it is open for closes it.
+func (b *circuitBreakers) apply(shared *entity.ToolCircuitBreaker, now time.Time) {
+	toolID := shared.ToolID
+	breaker := b.breakers[toolID]
+
+	if shared.ResetAt.After(b.resets[toolID]) {
+		b.resets[toolID] = shared.ResetAt
+		if breaker != nil {
+			breaker = nil
+			fmt.Printf("circuit breaker of tool %d reset by another replica\n", toolID)
+		}
+	}
+	if breaker == nil {
+		breaker = &circuitBreaker{state: valueobject.CircuitBreakerStateClosed}
+		b.breakers[toolID] = breaker
+	}
+	breaker.trips = shared.Trips
+
+	switch shared.State {
+	case valueobject.CircuitBreakerStateOpen:
+		tripped := breaker.state == valueobject.CircuitBreakerStateClosed || breaker.openedAt.Before(shared.OpenedAt)
+		if tripped && now.Before(shared.OpenUntil) {
+			breaker.state = valueobject.CircuitBreakerStateOpen
+			breaker.openedAt = shared.OpenedAt
+			breaker.outcomes = nil
+			breaker.trials, breaker.successes = 0, 0
+			breaker.lastError = shared.LastError
+			fmt.Printf("circuit breaker of tool %d opened by another replica until %s\n",
+				toolID, b.retryAt(breaker).Format(time.RFC3339))
+		}
+	case valueobject.CircuitBreakerStateClosed:
+		if breaker.state != valueobject.CircuitBreakerStateClosed && !breaker.openedAt.After(shared.OpenedAt) {
+			breaker.state = valueobject.CircuitBreakerStateClosed
+			breaker.outcomes = nil
+			breaker.trials, breaker.successes = 0, 0
+			fmt.Printf("circuit breaker of tool %d closed by another replica\n", toolID)
+		}
+	}
+}
+
+// prune drops the outcomes older than the window.
+func (b *circuitBreakers) prune(outcomes []breakerOutcome, now time.Time) []breakerOutcome {
+	start := now.Add(-b.window)
+	index, _ := slices.BinarySearchFunc(outcomes, start, func(outcome breakerOutcome, start time.Time) int {
+		return outcome.at.Compare(start)
+	})
+	return outcomes[index:]
+}
+
+func countOutcomes(outcomes []breakerOutcome) (requests int, failures int) {
+	for _, outcome := range outcomes {
+		if outcome.failed {
+			failures++
+		}
+	}
+	return len(outcomes), failures
+}
+
+// openUntil reports whether the breaker of the tool rejects invocations, and until when.
+// Unlike allow, it reserves no trial: a half-open breaker is not reported.
+func (b *circuitBreakers) openUntil(toolID int) (time.Time, bool) {
+	if !b.enabled {
+		return time.Time{}, false
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	breaker, ok := b.breakers[toolID]
+	if !ok || breaker.state != valueobject.CircuitBreakerStateOpen {
+		return time.Time{}, false
+	}
+	retryAt := b.retryAt(breaker)
+	return retryAt, time.Now().Before(retryAt)
+}
+
+func (b *circuitBreakers) snapshot(toolID int, breaker *circuitBreaker, now time.Time) CircuitBreakerSnapshot {
+	snapshot := CircuitBreakerSnapshot{ToolID: toolID, State: valueobject.CircuitBreakerStateClosed}
+	if breaker == nil {
+		return snapshot
+	}
+
+	snapshot.State = breaker.state
+	snapshot.Trips = breaker.trips
+	snapshot.LastError = breaker.lastError
+	if breaker.state == valueobject.CircuitBreakerStateClosed {
+		snapshot.Requests, snapshot.Failures = countOutcomes(b.prune(breaker.outcomes, now))
+		if snapshot.Requests > 0 {
+			snapshot.FailureRate = float64(snapshot.Failures) / float64(snapshot.Requests)
+		}
+	} else {
+		snapshot.OpenedAt = breaker.openedAt
+		snapshot.RetryAt = b.retryAt(breaker)
+	}
+	return snapshot
+}
+
+// Snapshot returns the circuit breaker of a tool, closed when the tool was neither executed by the replica
+// nor tripped by another one.
+func (b *circuitBreakers) Snapshot(toolID int) CircuitBreakerSnapshot {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.snapshot(toolID, b.breakers[toolID], time.Now())
+}
+
+// Snapshots returns the circuit breakers of the tools executed since the replica started, or tripped by any replica
+// since their last reset, by tool id.
+func (b *circuitBreakers) Snapshots() []CircuitBreakerSnapshot {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	snapshots := make([]CircuitBreakerSnapshot, 0, len(b.breakers))
+	for toolID, breaker := range b.breakers {
+		snapshots = append(snapshots, b.snapshot(toolID, breaker, now))
+	}
+	slices.SortFunc(snapshots, func(a, b CircuitBreakerSnapshot) int {
+		return a.ToolID - b.ToolID
+	})
+	return snapshots
+}
+
+// Reset closes the circuit breaker of a tool and forgets its counts and trips, on every replica: the others
+// apply the reset at their next sync.
+func (b *circuitBreakers) Reset(ctx context.Context, toolID int) error {
+	resetAt := time.Now().Truncate(time.Microsecond)
+	if b.enabled && b.toolRepo != nil {
+		if err := b.toolRepo.ResetToolCircuitBreaker(ctx, toolID, resetAt); err != nil {
+			return err
+		}
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.resets[toolID] = resetAt
+	if _, ok := b.breakers[toolID]; ok {
+		delete(b.breakers, toolID)
+		fmt.Printf("circuit breaker of tool %d reset\n", toolID)
+	}
+	return nil
+}
+
+func (e *functionExecutor) Start(ctx context.Context) {
+	if e.breakers.enabled && e.breakers.toolRepo != nil {
+		fmt.Printf("starting circuit breaker sync (interval %v)\n", breakerSyncInterval)
+	}
+
+	go e.breakers.run(ctx)
+}
+
+func (e *functionExecutor) CircuitBreaker(ctx context.Context, toolID int) (CircuitBreakerSnapshot, error) {
+	if err := e.breakers.sync(ctx); err != nil {
+		return CircuitBreakerSnapshot{}, err
+	}
+	return e.breakers.Snapshot(toolID), nil
+}
+
+func (e *functionExecutor) CircuitBreakers(ctx context.Context) ([]CircuitBreakerSnapshot, error) {
+	if err := e.breakers.sync(ctx); err != nil {
+		return nil, err
+	}
+	return e.breakers.Snapshots(), nil
+}
+
+func (e *functionExecutor) ResetCircuitBreaker(ctx context.Context, toolID int) error {
+	return e.breakers.Reset(ctx, toolID)
+}
+
+func (e *functionExecutor) CircuitOpenUntil(toolID int) (time.Time, bool) {
+	return e.breakers.openUntil(toolID)
+}
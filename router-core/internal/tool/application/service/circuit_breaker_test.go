@@ -0,0 +1,370 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"aigendrug.com/router-core/internal/config"
+	"aigendrug.com/router-core/internal/tool/domain"
+	"aigendrug.com/router-core/internal/tool/domain/entity"
+	"aigendrug.com/router-core/internal/tool/domain/valueobject"
+)
+
+func breakerConfig(halfOpenTrials int) *config.Config {
+	cfg := &config.Config{}
+	cfg.Executor.BreakerEnabled = true
+	cfg.Executor.BreakerFailureRate = 0.5
+	cfg.Executor.BreakerMinRequests = 4
+	cfg.Executor.BreakerWindowSeconds = 60
+	cfg.Executor.BreakerOpenSeconds = 30
+	cfg.Executor.BreakerHalfOpenTrials = halfOpenTrials
+	return cfg
+}
+
+var (
+	errServer        = newExecutionError(valueobject.ExecutionErrorClassServerError, "503 service unavailable")
+	errClient        = newExecutionError(valueobject.ExecutionErrorClassClientError, "400 bad request")
+	errCancelled     = newExecutionError(valueobject.ExecutionErrorClassCancelled, "execution aborted")
+	errConfiguration = newExecutionError(valueobject.ExecutionErrorClassConfiguration, "url is not configured")
+)
+
+// invokeThrough reserves and records an invocation of the tool for each outcome, nil for a success.
+func invokeThrough(t *testing.T, b *circuitBreakers, toolID int, outcomes ...error) {
+	t.Helper()
+	for i, outcome := range outcomes {
+		if err := b.allow(toolID); err != nil {
+			t.Fatalf("invocation %d rejected: %v", i+1, err)
+		}
+		b.record(toolID, outcome)
+	}
+}
+
+// expireOpenBreaker moves the opening of the breaker back past its open duration.
+func expireOpenBreaker(b *circuitBreakers, toolID int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.breakers[toolID].openedAt = time.Now().Add(-b.openDuration)
+}
+
+func TestCircuitBreakerRecord(t *testing.T) {
+	tests := []struct {
+		name         string
+		outcomes     []error
+		wantState    valueobject.CircuitBreakerState
+		wantRequests int
+		wantFailures int
+		wantTrips    int
+	}{
+		{
+			name:      "trips at the failure rate",
+			outcomes:  []error{nil, errServer, nil, errServer},
+			wantState: valueobject.CircuitBreakerStateOpen, wantTrips: 1,
+		},
+		{
+			name:      "throttling and unknown errors are failures",
+			outcomes:  []error{errors.New("boom"), errServer, newExecutionError(valueobject.ExecutionErrorClassThrottled, "429"), nil},
+			wantState: valueobject.CircuitBreakerStateOpen, wantTrips: 1,
+		},
+		{
+			name:      "closed below the minimum requests",
+			outcomes:  []error{errServer, errServer, errServer},
+			wantState: valueobject.CircuitBreakerStateClosed, wantRequests: 3, wantFailures: 3,
+		},
+		{
+			name:      "closed below the failure rate",
+			outcomes:  []error{nil, nil, errServer, nil, nil},
+			wantState: valueobject.CircuitBreakerStateClosed, wantRequests: 5, wantFailures: 1,
+		},
+		{
+			name:      "client errors count as successes",
+			outcomes:  []error{errClient, errClient, errClient, errClient},
+			wantState: valueobject.CircuitBreakerStateClosed, wantRequests: 4,
+		},
+		{
+			name:      "cancelled and misconfigured invocations are not counted",
+			outcomes:  []error{errCancelled, errConfiguration, errCancelled, errConfiguration, errServer},
+			wantState: valueobject.CircuitBreakerStateClosed, wantRequests: 1, wantFailures: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := newCircuitBreakers(breakerConfig(1), nil)
+			invokeThrough(t, b, 1, tt.outcomes...)
+
+			snapshot := b.Snapshot(1)
+			if snapshot.State != tt.wantState || snapshot.Trips != tt.wantTrips {
+				t.Fatalf("breaker %s after %d trips, want %s after %d", snapshot.State, snapshot.Trips, tt.wantState, tt.wantTrips)
+			}
+			if snapshot.Requests != tt.wantRequests || snapshot.Failures != tt.wantFailures {
+				t.Fatalf("breaker counted %d failures of %d requests, want %d of %d",
+					snapshot.Failures, snapshot.Requests, tt.wantFailures, tt.wantRequests)
+			}
+
+			err := b.allow(1)
+			var execErr *ExecutionError
+			rejected := errors.As(err, &execErr) && execErr.Class == valueobject.ExecutionErrorClassCircuitOpen
+			if rejected != (tt.wantState == valueobject.CircuitBreakerStateOpen) {
+				t.Fatalf("allow() = %v with the breaker %s", err, snapshot.State)
+			}
+			if _, open := b.openUntil(1); open != rejected {
+				t.Fatalf("openUntil() = %v, allow() rejected %v", open, rejected)
+			}
+			if snapshot := b.Snapshot(2); snapshot.State != valueobject.CircuitBreakerStateClosed || snapshot.Requests != 0 {
+				t.Fatalf("breaker of another tool = %+v, want closed", snapshot)
+			}
+		})
+	}
+}
+
+func TestCircuitBreakerHalfOpen(t *testing.T) {
+	tests := []struct {
+		name           string
+		halfOpenTrials int
+		trials         []error
+		wantState      valueobject.CircuitBreakerState
+		wantTrips      int
+	}{
+		{
+			name:           "closes when the trial succeeds",
+			halfOpenTrials: 1,
+			trials:         []error{nil},
+			wantState:      valueobject.CircuitBreakerStateClosed, wantTrips: 1,
+		},
+		{
+			name:           "opens again when the trial fails",
+			halfOpenTrials: 1,
+			trials:         []error{errServer},
+			wantState:      valueobject.CircuitBreakerStateOpen, wantTrips: 2,
+		},
+		{
+			name:           "stays half-open until every trial succeeds",
+			halfOpenTrials: 3,
+			trials:         []error{nil, nil},
+			wantState:      valueobject.CircuitBreakerStateHalfOpen, wantTrips: 1,
+		},
+		{
+			name:           "client errors close the breaker",
+			halfOpenTrials: 2,
+			trials:         []error{errClient, nil},
+			wantState:      valueobject.CircuitBreakerStateClosed, wantTrips: 1,
+		},
+		{
+			name:           "cancelled trials are given back",
+			halfOpenTrials: 1,
+			trials:         []error{errCancelled, errCancelled, nil},
+			wantState:      valueobject.CircuitBreakerStateClosed, wantTrips: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := newCircuitBreakers(breakerConfig(tt.halfOpenTrials), nil)
+			invokeThrough(t, b, 1, errServer, errServer, errServer, errServer)
+			if err := b.allow(1); err == nil {
+				t.Fatal("allow() let an invocation through the open breaker")
+			}
+
+			expireOpenBreaker(b, 1)
+			invokeThrough(t, b, 1, tt.trials...)
+
+			snapshot := b.Snapshot(1)
+			if snapshot.State != tt.wantState || snapshot.Trips != tt.wantTrips {
+				t.Fatalf("breaker %s after %d trips, want %s after %d", snapshot.State, snapshot.Trips, tt.wantState, tt.wantTrips)
+			}
+		})
+	}
+}
+
+func TestCircuitBreakerHalfOpenLimitsTrials(t *testing.T) {
+	b := newCircuitBreakers(breakerConfig(2), nil)
+	invokeThrough(t, b, 1, errServer, errServer, errServer, errServer)
+	expireOpenBreaker(b, 1)
+
+	for i := range 2 {
+		if err := b.allow(1); err != nil {
+			t.Fatalf("trial %d rejected: %v", i+1, err)
+		}
+	}
+	if err := b.allow(1); err == nil {
+		t.Fatal("allow() let a third invocation through with two trials in flight")
+	}
+	// a half-open breaker is not reported as open
+	if _, open := b.openUntil(1); open {
+		t.Fatal("openUntil() reported the half-open breaker as open")
+	}
+
+	b.record(1, nil)
+	if err := b.allow(1); err != nil {
+		t.Fatalf("allow() rejected an invocation after a trial finished: %v", err)
+	}
+}
+
+func TestCircuitBreakerReset(t *testing.T) {
+	b := newCircuitBreakers(breakerConfig(1), nil)
+	invokeThrough(t, b, 1, errServer, errServer, errServer, errServer)
+	invokeThrough(t, b, 2, nil)
+
+	if err := b.Reset(context.Background(), 1); err != nil {
+		t.Fatalf("Reset() = %v", err)
+	}
+	if err := b.allow(1); err != nil {
+		t.Fatalf("allow() after Reset() = %v", err)
+	}
+	b.record(1, nil)
+
+	snapshots := b.Snapshots()
+	if len(snapshots) != 2 || snapshots[0].ToolID != 1 || snapshots[1].ToolID != 2 {
+		t.Fatalf("Snapshots() = %+v, want tools 1 and 2", snapshots)
+	}
+	if snapshots[0].State != valueobject.CircuitBreakerStateClosed || snapshots[0].Trips != 0 || snapshots[0].Requests != 1 {
+		t.Fatalf("breaker after Reset() = %+v, want closed with one request", snapshots[0])
+	}
+}
+
+func TestCircuitBreakerDisabled(t *testing.T) {
+	b := newCircuitBreakers(&config.Config{}, nil)
+	invokeThrough(t, b, 1, errServer, errServer, errServer, errServer, errServer)
+
+	if err := b.allow(1); err != nil {
+		t.Fatalf("allow() with breakers disabled = %v", err)
+	}
+	if snapshots := b.Snapshots(); len(snapshots) != 0 {
+		t.Fatalf("Snapshots() with breakers disabled = %+v", snapshots)
+	}
+}
+
+// sharedBreakers keeps the tool_circuit_breakers rows of the replicas in memory.
+type sharedBreakers struct {
+	domain.ToolRepository
+
+	mu   sync.Mutex
+	rows map[int]*entity.ToolCircuitBreaker
+}
+
+func (r *sharedBreakers) FindAllToolCircuitBreakers(_ context.Context) ([]*entity.ToolCircuitBreaker, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	breakers := make([]*entity.ToolCircuitBreaker, 0, len(r.rows))
+	for _, row := range r.rows {
+		breaker := *row
+		breakers = append(breakers, &breaker)
+	}
+	return breakers, nil
+}
+
+func (r *sharedBreakers) TripToolCircuitBreaker(
+	_ context.Context, toolID int, openedAt, openUntil time.Time, lastError string,
+) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	row, ok := r.rows[toolID]
+	if !ok {
+		row = &entity.ToolCircuitBreaker{ToolID: toolID}
+		r.rows[toolID] = row
+	}
+	row.State = valueobject.CircuitBreakerStateOpen
+	row.OpenedAt, row.OpenUntil, row.LastError = openedAt, openUntil, lastError
+	row.Trips++
+	return nil
+}
+
+func (r *sharedBreakers) CloseToolCircuitBreaker(_ context.Context, toolID int, openedAt time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if row, ok := r.rows[toolID]; ok && row.State == valueobject.CircuitBreakerStateOpen && !row.OpenedAt.After(openedAt) {
+		row.State = valueobject.CircuitBreakerStateClosed
+	}
+	return nil
+}
+
+func (r *sharedBreakers) ResetToolCircuitBreaker(_ context.Context, toolID int, resetAt time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.rows[toolID] = &entity.ToolCircuitBreaker{
+		ToolID: toolID, State: valueobject.CircuitBreakerStateClosed, ResetAt: resetAt,
+	}
+	return nil
+}
+
+// expireSharedBreaker moves the opening of the shared breaker, and of the replicas open with it, back past its
+// open duration.
+func expireSharedBreaker(shared *sharedBreakers, toolID int, replicas ...*circuitBreakers) {
+	openedAt := time.Now().Add(-replicas[0].openDuration).Truncate(time.Microsecond)
+	for _, b := range replicas {
+		b.mu.Lock()
+		b.breakers[toolID].openedAt = openedAt
+		b.mu.Unlock()
+	}
+
+	shared.mu.Lock()
+	defer shared.mu.Unlock()
+	shared.rows[toolID].OpenedAt = openedAt
+	shared.rows[toolID].OpenUntil = openedAt.Add(replicas[0].openDuration)
+}
+
+func TestCircuitBreakerSync(t *testing.T) {
+	ctx := context.Background()
+	shared := &sharedBreakers{rows: map[int]*entity.ToolCircuitBreaker{}}
+	a := newCircuitBreakers(breakerConfig(1), shared)
+	b := newCircuitBreakers(breakerConfig(1), shared)
+
+	sync := func(replicas ...*circuitBreakers) {
+		t.Helper()
+		for _, replica := range replicas {
+			if err := replica.sync(ctx); err != nil {
+				t.Fatalf("sync() = %v", err)
+			}
+		}
+	}
+
+	// a trip of replica a opens the breaker of replica b
+	invokeThrough(t, a, 1, errServer, errServer, errServer, errServer)
+	invokeThrough(t, b, 1, nil)
+	sync(b)
+	if err := b.allow(1); err == nil {
+		t.Fatal("allow() let an invocation through the breaker tripped by another replica")
+	}
+	if snapshot := b.Snapshot(1); snapshot.Trips != 1 || snapshot.LastError != errServer.Error() {
+		t.Fatalf("breaker synced from another replica = %+v, want its trip and last error", snapshot)
+	}
+
+	// a successful trial of replica a closes the breaker of replica b
+	expireSharedBreaker(shared, 1, a, b)
+	invokeThrough(t, a, 1, nil)
+	sync(b)
+	if snapshot := b.Snapshot(1); snapshot.State != valueobject.CircuitBreakerStateClosed {
+		t.Fatalf("breaker after a trial succeeded on another replica = %+v, want closed", snapshot)
+	}
+
+	// a reset on replica b closes the breaker of replica a and forgets its trips
+	invokeThrough(t, a, 1, errServer, errServer, errServer, errServer)
+	sync(b)
+	if err := b.Reset(ctx, 1); err != nil {
+		t.Fatalf("Reset() = %v", err)
+	}
+	sync(a, b)
+	for _, replica := range []*circuitBreakers{a, b} {
+		if err := replica.allow(1); err != nil {
+			t.Fatalf("allow() after a reset = %v", err)
+		}
+		replica.record(1, nil)
+		if snapshot := replica.Snapshot(1); snapshot.Trips != 0 || snapshot.Requests != 1 {
+			t.Fatalf("breaker after a reset = %+v, want closed with one request", snapshot)
+		}
+	}
+
+	// a trip synced after the reset is applied again
+	invokeThrough(t, a, 1, errServer, errServer, errServer)
+	sync(b)
+	if _, open := b.openUntil(1); !open {
+		t.Fatal("openUntil() did not report the breaker tripped after the reset")
+	}
+}
@@ -0,0 +1,158 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Helpers to read dynamic EngineImpl fields.
+// Values may arrive as JSON numbers or as strings (e.g. custom fields from the console),
+// so every reader accepts both representations.
+
+func implString(impl map[string]any, key string) (string, bool) {
+	value, ok := impl[key]
+	if !ok || value == nil {
+		return "", false
+	}
+	switch v := value.(type) {
+	case string:
+		return v, v != ""
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), true
+	default:
+		return fmt.Sprint(v), true
+	}
+}
+
+func implFloat(impl map[string]any, key string) (float64, bool) {
+	value, ok := impl[key]
+	if !ok || value == nil {
+		return 0, false
+	}
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case json.Number:
+		f, err := v.Float64()
+		return f, err == nil
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		return f, err == nil
+	default:
+		return 0, false
+	}
+}
+
+func implBool(impl map[string]any, key string) bool {
+	value, ok := impl[key]
+	if !ok || value == nil {
+		return false
+	}
+	switch v := value.(type) {
+	case bool:
+		return v
+	case string:
+		b, err := strconv.ParseBool(strings.TrimSpace(v))
+		return err == nil && b
+	case float64:
+		return v != 0
+	default:
+		return false
+	}
+}
+
+func implMap(impl map[string]any, key string) (map[string]any, bool) {
+	value, ok := impl[key]
+	if !ok || value == nil {
+		return nil, false
+	}
+	switch v := value.(type) {
+	case map[string]any:
+		return v, true
+	case string:
+		var m map[string]any
+		if err := json.Unmarshal([]byte(v), &m); err != nil {
+			return nil, false
+		}
+		return m, true
+	default:
+		return nil, false
+	}
+}
+
+// implStringSlice reads a list of strings, given as a JSON array or as a string
+// holding a JSON array or whitespace separated values.
+func implStringSlice(impl map[string]any, key string) ([]string, bool) {
+	value, ok := impl[key]
+	if !ok || value == nil {
+		return nil, false
+	}
+	switch v := value.(type) {
+	case []any:
+		values := make([]string, 0, len(v))
+		for _, item := range v {
+			switch item := item.(type) {
+			case string:
+				values = append(values, item)
+			case float64:
+				values = append(values, strconv.FormatFloat(item, 'f', -1, 64))
+			default:
+				values = append(values, fmt.Sprint(item))
+			}
+		}
+		return values, true
+	case []string:
+		return v, true
+	case string:
+		if strings.HasPrefix(strings.TrimSpace(v), "[") {
+			var values []string
+			if err := json.Unmarshal([]byte(v), &values); err != nil {
+				return nil, false
+			}
+			return values, true
+		}
+		values := strings.Fields(v)
+		return values, len(values) > 0
+	default:
+		return nil, false
+	}
+}
+
+// renderTemplate replaces "{name}" placeholders in template with values from vars.
+// Unknown placeholders are left untouched.
+func renderTemplate(template string, vars map[string]string) string {
+	if !strings.Contains(template, "{") {
+		return template
+	}
+	replacements := make([]string, 0, len(vars)*2)
+	for name, value := range vars {
+		replacements = append(replacements, "{"+name+"}", value)
+	}
+	return strings.NewReplacer(replacements...).Replace(template)
+}
+
+// templateVars collects scalar top-level fields of an invocation response
+// so that they can be referenced from EngineImpl templates (e.g. "{job_id}").
+func templateVars(base map[string]string, response map[string]any) map[string]string {
+	vars := make(map[string]string, len(base)+len(response))
+	for key, value := range response {
+		switch v := value.(type) {
+		case string:
+			vars[key] = v
+		case float64:
+			vars[key] = strconv.FormatFloat(v, 'f', -1, 64)
+		case bool:
+			vars[key] = strconv.FormatBool(v)
+		}
+	}
+	for key, value := range base {
+		vars[key] = value
+	}
+	return vars
+}
@@ -0,0 +1,53 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// ExecutionQuota reserves an execution of the client against its daily and monthly quotas (RATE_LIMIT_*).
+// When a quota is reached nothing is reserved, and retryAfter is the time until the exceeded quota resets.
+// release gives back a reserved execution which ended up not being created.
+//
+// Both ExecuteTool and the runs of tool schedules go through it, so that scheduled runs are counted as well.
+type ExecutionQuota func(ctx context.Context, clientID int) (release func(context.Context), retryAfter time.Duration, reserved bool, err error)
+
+// executionReservation is the outcome of reserveExecution: the executions to give back when they are not created,
+// or the time until the quota resets when it is reached.
+type executionReservation struct {
+	releases   []func(context.Context)
+	exceeded   bool
+	retryAfter time.Duration
+}
+
+// reserveExecution reserves an execution of the client. Executions are let through rather than failed when the
+// quota can not be read.
+func reserveExecution(ctx context.Context, quota ExecutionQuota, clientID int, reservation *executionReservation) {
+	if quota == nil {
+		return
+	}
+
+	release, retryAfter, reserved, err := quota(ctx, clientID)
+	if err != nil {
+		fmt.Printf("failed to apply execution quota of client %d: %v\n", clientID, err)
+		return
+	}
+	if !reserved {
+		reservation.exceeded = true
+		reservation.retryAfter = retryAfter
+		return
+	}
+	if release != nil {
+		reservation.releases = append(reservation.releases, release)
+	}
+}
+
+// release gives back the reserved executions, even when ctx is done meanwhile.
+func (r *executionReservation) release(ctx context.Context) {
+	ctx = context.WithoutCancel(ctx)
+	for _, release := range r.releases {
+		release(ctx)
+	}
+	r.releases = nil
+}
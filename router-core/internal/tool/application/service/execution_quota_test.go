@@ -0,0 +1,79 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestReserveExecution(t *testing.T) {
+	tests := []struct {
+		name           string
+		quota          func(released *int) ExecutionQuota
+		wantExceeded   bool
+		wantRetryAfter time.Duration
+		wantReleased   int
+	}{
+		{
+			name:  "no quota",
+			quota: func(*int) ExecutionQuota { return nil },
+		},
+		{
+			name: "reserved",
+			quota: func(released *int) ExecutionQuota {
+				return func(context.Context, int) (func(context.Context), time.Duration, bool, error) {
+					return func(context.Context) { *released++ }, 0, true, nil
+				}
+			},
+			wantReleased: 1,
+		},
+		{
+			name: "reserved without release",
+			quota: func(*int) ExecutionQuota {
+				return func(context.Context, int) (func(context.Context), time.Duration, bool, error) {
+					return nil, 0, true, nil
+				}
+			},
+		},
+		{
+			name: "quota reached",
+			quota: func(*int) ExecutionQuota {
+				return func(context.Context, int) (func(context.Context), time.Duration, bool, error) {
+					return nil, time.Hour, false, nil
+				}
+			},
+			wantExceeded:   true,
+			wantRetryAfter: time.Hour,
+		},
+		{
+			name: "quota unavailable lets the execution through",
+			quota: func(*int) ExecutionQuota {
+				return func(context.Context, int) (func(context.Context), time.Duration, bool, error) {
+					return nil, 0, false, errors.New("connection refused")
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			released := 0
+			var reservation executionReservation
+			reserveExecution(context.Background(), tt.quota(&released), 1, &reservation)
+
+			if reservation.exceeded != tt.wantExceeded || reservation.retryAfter != tt.wantRetryAfter {
+				t.Fatalf("reservation exceeded = %v retry after %v, want %v retry after %v",
+					reservation.exceeded, reservation.retryAfter, tt.wantExceeded, tt.wantRetryAfter)
+			}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			reservation.release(ctx)
+			reservation.release(ctx)
+			if released != tt.wantReleased {
+				t.Fatalf("released %d executions, want %d", released, tt.wantReleased)
+			}
+		})
+	}
+}
@@ -3,83 +3,353 @@ package service
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"strings"
 	"time"
 
+	"aigendrug.com/router-core/internal/config"
+	"aigendrug.com/router-core/internal/shared/blobstore"
+	exec_wrapper "aigendrug.com/router-core/internal/shared/exec-wrapper"
+	grpc_wrapper "aigendrug.com/router-core/internal/shared/grpc-wrapper"
+	http_wrapper "aigendrug.com/router-core/internal/shared/http-wrapper"
+	"aigendrug.com/router-core/internal/shared/jsonschema"
 	lambda_wrapper "aigendrug.com/router-core/internal/shared/lambda-wrapper"
-	"aigendrug.com/router-core/internal/tool/application/dto"
+	s3_wrapper "aigendrug.com/router-core/internal/shared/s3-wrapper"
 	"aigendrug.com/router-core/internal/tool/domain"
 	"aigendrug.com/router-core/internal/tool/domain/entity"
+	"aigendrug.com/router-core/internal/tool/domain/shared_type"
 	"aigendrug.com/router-core/internal/tool/domain/valueobject"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsmiddleware "github.com/aws/aws-sdk-go-v2/aws/middleware"
 	"github.com/aws/aws-sdk-go-v2/service/lambda/types"
 	"github.com/google/uuid"
 )
 
 const DefaultFunctionSyncExecutionTimeout = 10 * time.Second
 
+// errToolRequestReleased reports that the claimed tool request was cancelled or released to another worker.
+var errToolRequestReleased = errors.New("tool request released")
+
+// Default polling policy of asynchronous invocations.
+// Overridden by config (EXECUTOR_POLL_*) and per tool by EngineImpl fields
+// "poll_interval_seconds", "poll_max_interval_seconds", "poll_backoff_multiplier" and "poll_timeout_seconds".
+const (
+	DefaultPollInterval          = 5 * time.Second
+	DefaultPollMaxInterval       = 1 * time.Minute
+	DefaultPollBackoffMultiplier = 1.5
+	DefaultPollTimeout           = 2 * time.Hour
+
+	// consecutive transient status check errors tolerated before the request fails
+	maxConsecutiveStatusCheckErrors = 5
+
+	// keys of check_status_impl holding the time of the invocation, the number of status checks made,
+	// the consecutive transient errors and the last progress published
+	checkStatusInvokedAtKey = "invoked_at"
+	checkStatusChecksKey    = "status_checks"
+	checkStatusErrorsKey    = "status_check_errors"
+	checkStatusProgressKey  = "last_progress"
+)
+
 type FunctionExecutor interface {
-	Sync(ctx context.Context, tool *entity.Tool, requestID int, executionRequest dto.ToolExecutionRequestDTO)
+	Sync(ctx context.Context, tool *entity.Tool, toolRequest *entity.ToolRequest)
+	Async(ctx context.Context, tool *entity.Tool, toolRequest *entity.ToolRequest)
+	// Cancel asks the provider to stop an async-event invocation, when the tool declares a cancel endpoint.
+	Cancel(ctx context.Context, tool *entity.Tool, toolRequest *entity.ToolRequest) error
+	// Probe checks the health of a tool, without creating a tool request.
+	Probe(ctx context.Context, tool *entity.Tool) error
+	// Start launches the sync of the circuit breakers with the other replicas. It stops when ctx is done.
+	Start(ctx context.Context)
+	// CircuitBreaker returns the state of the circuit breaker of a tool, synced with the other replicas.
+	CircuitBreaker(ctx context.Context, toolID int) (CircuitBreakerSnapshot, error)
+	// CircuitBreakers returns the circuit breakers of the tools executed by this replica or tripped by any replica.
+	CircuitBreakers(ctx context.Context) ([]CircuitBreakerSnapshot, error)
+	// ResetCircuitBreaker closes the circuit breaker of a tool on every replica.
+	ResetCircuitBreaker(ctx context.Context, toolID int) error
+	// CircuitOpenUntil reports whether the circuit breaker of a tool rejects invocations, and until when.
+	CircuitOpenUntil(toolID int) (time.Time, bool)
 }
 
 type functionExecutor struct {
-	baseCtx      context.Context
-	toolRepo     domain.ToolRepository
-	lambdaClient lambda_wrapper.LambdaWrapperClient
+	config         *config.Config
+	toolRepo       domain.ToolRepository
+	lambdaClients  *lambda_wrapper.LambdaClientPool
+	httpClient     http_wrapper.HTTPWrapperClient
+	execClient     exec_wrapper.ExecWrapperClient
+	grpcClient     *grpc_wrapper.GRPCWrapperClient
+	grpcMethods    *grpcMethodCache
+	statusCheckers map[valueobject.EngineInterfaceCheckStatusType]StatusChecker
+	notifier       ToolRequestNotifier
+	blobStore      blobstore.BlobStore
+	payloadStore   *PayloadStore
+	breakers       *circuitBreakers
 	// inject other engine providers here (Azure, GCP, etc.)
 }
 
 func NewFunctionExecutor(
-	baseCtx context.Context,
+	config *config.Config,
 	toolRepo domain.ToolRepository,
-	lambdaClient lambda_wrapper.LambdaWrapperClient,
+	lambdaClients *lambda_wrapper.LambdaClientPool,
+	httpClient http_wrapper.HTTPWrapperClient,
+	execClient exec_wrapper.ExecWrapperClient,
+	grpcClient *grpc_wrapper.GRPCWrapperClient,
+	s3Client s3_wrapper.S3WrapperClient,
+	notifier ToolRequestNotifier,
+	blobStore blobstore.BlobStore,
+	payloadStore *PayloadStore,
 ) FunctionExecutor {
 	return &functionExecutor{
-		baseCtx:      baseCtx,
-		toolRepo:     toolRepo,
-		lambdaClient: lambdaClient,
+		config:        config,
+		toolRepo:      toolRepo,
+		lambdaClients: lambdaClients,
+		httpClient:    httpClient,
+		execClient:    execClient,
+		grpcClient:    grpcClient,
+		grpcMethods:   newGRPCMethodCache(),
+		statusCheckers: map[valueobject.EngineInterfaceCheckStatusType]StatusChecker{
+			valueobject.EngineInterfaceCheckStatusTypePollHTTP:     NewHTTPStatusChecker(httpClient),
+			valueobject.EngineInterfaceCheckStatusTypeAWSS3Trigger: NewS3StatusChecker(s3Client),
+		},
+		notifier:     notifier,
+		blobStore:    blobStore,
+		payloadStore: payloadStore,
+		breakers:     newCircuitBreakers(config, toolRepo),
+	}
+}
+
+// lambdaFunction reads the function of an aws-lambda tool from its EngineImpl:
+// "function_name", "qualifier" (alias or version), "region", "role_arn" and "external_id".
+func lambdaFunction(engineImpl map[string]any) (lambda_wrapper.LambdaFunctionImpl, error) {
+	var function lambda_wrapper.LambdaFunctionImpl
+	var ok bool
+	if function.FunctionName, ok = implString(engineImpl, "function_name"); !ok {
+		return function, newExecutionError(valueobject.ExecutionErrorClassConfiguration, "engine impl has no function_name")
 	}
+	function.Qualifier, _ = implString(engineImpl, "qualifier")
+	function.Region, _ = implString(engineImpl, "region")
+	function.RoleARN, _ = implString(engineImpl, "role_arn")
+	function.ExternalID, _ = implString(engineImpl, "external_id")
+	return function, nil
 }
 
+// InvokeLambdaFunction invokes a Lambda function. The diagnostics hold the request id, the executed version and
+// the function error of the invocation, and the decoded log tail with the metrics of its REPORT line when logTail
+// is set (sync invocations only, Lambda returns no log for event invocations).
 func (e *functionExecutor) InvokeLambdaFunction(
-	ctx context.Context, functionName string, payload map[string]any, sync bool,
-) (map[string]any, error) {
+	ctx context.Context, function lambda_wrapper.LambdaFunctionImpl, payload map[string]any, sync bool, logTail bool,
+) (map[string]any, *shared_type.InvocationDiagnostics, error) {
 	invocationType := types.InvocationTypeRequestResponse
 	if !sync {
 		invocationType = types.InvocationTypeEvent
 	}
-	output, err := e.lambdaClient.Invoke(ctx, functionName, payload, invocationType, false)
+	functionName := function.FunctionName
+	if function.Qualifier != "" {
+		functionName += ":" + function.Qualifier
+	}
+
+	lambdaClient := e.lambdaClients.Client(function)
+	output, err := lambdaClient.Invoke(ctx, function, payload, invocationType, sync && logTail)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
+	}
+
+	awsRequestID, _ := awsmiddleware.GetRequestIDMetadata(output.ResultMetadata)
+	diagnostics := &shared_type.InvocationDiagnostics{
+		AWSRequestID:    awsRequestID,
+		ExecutedVersion: aws.ToString(output.ExecutedVersion),
+		FunctionError:   aws.ToString(output.FunctionError),
+		LogTail:         lambda_wrapper.DecodeLogResult(output.LogResult),
+	}
+	if report := lambda_wrapper.ParseReport(diagnostics.LogTail); report != nil {
+		diagnostics.DurationMs = report.DurationMs
+		diagnostics.BilledDurationMs = report.BilledDurationMs
+		diagnostics.InitDurationMs = report.InitDurationMs
+		diagnostics.MemorySizeMB = report.MemorySizeMB
+		diagnostics.MaxMemoryUsedMB = report.MaxMemoryUsedMB
+	}
+
+	// event invocations are only queued by Lambda and carry no payload
+	if !sync {
+		if output.StatusCode != http.StatusAccepted {
+			return nil, diagnostics, newExecutionError(valueobject.ExecutionErrorClassServerError,
+				"lambda function %s returned status code %d", functionName, output.StatusCode)
+		}
+		return map[string]any{"aws_request_id": awsRequestID}, diagnostics, nil
 	}
 
 	if output.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("lambda function %s returned status code %d", functionName, output.StatusCode)
+		return nil, diagnostics, newExecutionError(valueobject.ExecutionErrorClassServerError,
+			"lambda function %s returned status code %d", functionName, output.StatusCode)
 	}
 
 	if output.FunctionError != nil {
-		return nil, fmt.Errorf("lambda function %s returned error: %s", functionName, *output.FunctionError)
+		// the payload of a function error describes it: {"errorMessage": ..., "errorType": ..., "stackTrace": [...]}
+		var functionError struct {
+			ErrorMessage string `json:"errorMessage"`
+			ErrorType    string `json:"errorType"`
+		}
+		_ = json.Unmarshal(output.Payload, &functionError)
+		diagnostics.ErrorType = functionError.ErrorType
+
+		message := *output.FunctionError
+		if functionError.ErrorType != "" {
+			message += ", " + functionError.ErrorType
+		}
+		if functionError.ErrorMessage != "" {
+			message += ": " + truncate(functionError.ErrorMessage, 512)
+		}
+		return nil, diagnostics, newExecutionError(valueobject.ExecutionErrorClassFunctionError,
+			"lambda function %s returned error: %s", functionName, message)
 	}
 
 	var outputRes map[string]any
 	err = json.Unmarshal(output.Payload, &outputRes)
+	if err != nil {
+		return nil, diagnostics, newExecutionError(valueobject.ExecutionErrorClassInvalidResponse,
+			"lambda function %s returned invalid payload: %v", functionName, err)
+	}
+
+	return outputRes, diagnostics, nil
+}
+
+// InvokeHTTPServer builds the outbound request from the tool's ProviderInterface.
+// Each payload value is routed to body, query or header by the location of its property in the
+// request schema; undeclared keys are sent in the body.
+// Header properties of the response schema are copied from the response headers into the result.
+func (e *functionExecutor) InvokeHTTPServer(
+	ctx context.Context, providerInterface shared_type.ProviderInterface, engineImpl map[string]any, payload map[string]any,
+) (map[string]any, error) {
+	targetURL := providerInterface.URL
+	if targetURL == "" {
+		targetURL, _ = engineImpl["url"].(string)
+	}
+	if targetURL == "" {
+		return nil, newExecutionError(valueobject.ExecutionErrorClassConfiguration, "http server url is not configured")
+	}
+
+	input := http_wrapper.InvokeInput{
+		Method:      providerInterface.RequestMethod,
+		URL:         targetURL,
+		ContentType: providerInterface.RequestContentType,
+		Query:       map[string]any{},
+		Header:      map[string]any{},
+		Body:        map[string]any{},
+	}
+	if input.ContentType == "" {
+		input.ContentType = http_wrapper.ContentTypeJSON
+	}
+
+	requestSchema := providerInterface.ResolvedRequestSchema()
+	for key, value := range payload {
+		location := jsonschema.LocationBody
+		if requestSchema != nil {
+			location = requestSchema.PropertyLocation(key)
+		}
+
+		switch location {
+		case jsonschema.LocationQuery:
+			input.Query[key] = value
+		case jsonschema.LocationHeader:
+			input.Header[key] = value
+		default:
+			input.Body[key] = value
+		}
+	}
+
+	output, err := e.httpClient.Invoke(ctx, input)
 	if err != nil {
 		return nil, err
 	}
 
+	if output.StatusCode < http.StatusOK || output.StatusCode >= http.StatusMultipleChoices {
+		return nil, &ExecutionError{
+			Class:      errorClassForStatusCode(output.StatusCode),
+			StatusCode: output.StatusCode,
+			Err: fmt.Errorf("http server %s returned status code %d: %s",
+				targetURL, output.StatusCode, truncate(string(output.Body), 512)),
+		}
+	}
+
+	responseContentType := providerInterface.ResponseContentType
+	if responseContentType == "" {
+		responseContentType = output.Header.Get("Content-Type")
+	}
+
+	outputRes, err := http_wrapper.DecodeBody(responseContentType, output.Body)
+	if err != nil {
+		return nil, newExecutionError(valueobject.ExecutionErrorClassInvalidResponse,
+			"http server %s returned invalid body: %v", targetURL, err)
+	}
+
+	if responseSchema := providerInterface.ResolvedResponseSchema(); responseSchema != nil {
+		for key := range responseSchema.Properties {
+			if responseSchema.PropertyLocation(key) != jsonschema.LocationHeader {
+				continue
+			}
+			if value := output.Header.Get(key); value != "" {
+				outputRes[key] = value
+			}
+		}
+	}
+
 	return outputRes, nil
 }
 
+// isFireAndForget reports whether the tool is invoked without tracking its completion.
+func isFireAndForget(tool *entity.Tool) bool {
+	return tool.EngineInterface.EngineInterfaceInvokeType == valueobject.EngineInterfaceInvokeTypeAsyncEvent &&
+		tool.EngineInterface.EngineInterfaceCheckStatusType == valueobject.EngineInterfaceCheckStatusTypeNone
+}
+
+func truncate(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	return s[:max] + "..."
+}
+
+// invoke calls the engine of tool once.
+// sync selects between a request-response invocation and a fire-and-forget event invocation.
+// Engines reporting diagnostics (local-exec) return them whether the invocation failed or not.
+func (e *functionExecutor) invoke(
+	ctx context.Context, tool *entity.Tool, payload map[string]any, sync bool,
+) (map[string]any, *shared_type.InvocationDiagnostics, error) {
+	switch tool.EngineInterface.EngineInterfaceType {
+	case valueobject.EngineInterfaceAWSLambda:
+		function, err := lambdaFunction(tool.EngineInterface.EngineImpl)
+		if err != nil {
+			return nil, nil, err
+		}
+		logType, _ := implString(tool.EngineInterface.EngineImpl, "log_type")
+		logTail := strings.EqualFold(logType, string(types.LogTypeTail))
+		return e.InvokeLambdaFunction(ctx, function, payload, sync, logTail)
+	case valueobject.EngineInterfaceHTTPServer:
+		result, err := e.InvokeHTTPServer(ctx, tool.ProviderInterface, tool.EngineInterface.EngineImpl, payload)
+		return result, nil, err
+	case valueobject.EngineInterfaceLocalExec:
+		return e.InvokeLocalExec(ctx, tool, payload)
+	case valueobject.EngineInterfaceGRPC:
+		result, err := e.InvokeGRPC(ctx, tool, payload)
+		return result, nil, err
+	default:
+		// not implemented
+		return nil, nil, newExecutionError(valueobject.ExecutionErrorClassConfiguration, "engine interface type not implemented")
+	}
+}
+
+// Sync invokes the tool and waits for its result within the timeout of its check status type.
+// toolRequest must be claimed by the caller; it is completed with the result,
+// or with the cached result when the tool is cacheable.
 func (e *functionExecutor) Sync(
-	ctx context.Context, tool *entity.Tool, requestID int, executionRequest dto.ToolExecutionRequestDTO,
+	ctx context.Context, tool *entity.Tool, toolRequest *entity.ToolRequest,
 ) {
-	requestIdentifier, err := uuid.NewRandom()
-	if err != nil {
+	if err := ensureIdentifiers(toolRequest); err != nil {
+		e.finishToolRequest(ctx, tool, toolRequest, nil, valueobject.ToolRequestStatusFailed, err.Error())
 		return
 	}
 
-	responseIdentifier, err := uuid.NewRandom()
-	if err != nil {
+	if e.serveFromCache(ctx, tool, toolRequest) {
 		return
 	}
 
@@ -89,89 +359,501 @@ func (e *functionExecutor) Sync(
 	case valueobject.EngineInterfaceCheckStatusTypeNone:
 		timeoutDuration = DefaultFunctionSyncExecutionTimeout
 	case valueobject.EngineInterfaceCheckStatusTypeDelayed:
-		delaySeconds := tool.EngineInterface.EngineImpl["delay_seconds"].(float64)
-		timeoutDuration = time.Duration(delaySeconds) * time.Second
+		timeoutDuration = DefaultFunctionSyncExecutionTimeout
+		if delaySeconds, ok := implFloat(tool.EngineInterface.EngineImpl, "delay_seconds"); ok && delaySeconds > 0 {
+			timeoutDuration = secondsToDuration(delaySeconds)
+		}
 	default:
-		// not implemented
+		e.finishToolRequest(ctx, tool, toolRequest, nil, valueobject.ToolRequestStatusFailed,
+			fmt.Sprintf("check status type %s is not supported for sync-wait invocation",
+				tool.EngineInterface.EngineInterfaceCheckStatusType))
 		return
 	}
 
-	// Create timeout context for lambda execution only
-	lambdaTimeoutCtx, cancel := context.WithTimeout(e.baseCtx, timeoutDuration)
-	defer cancel()
+	fmt.Printf("executing tool request %d with timeout duration: %v\n", toolRequest.ID, timeoutDuration)
 
-	fmt.Printf("executing tool with timeout duration: %v\n", timeoutDuration)
-
-	// Channel to receive execution result
-	resultChan := make(chan map[string]any, 1)
-	errorChan := make(chan error, 1)
-
-	// Execute in goroutine with timeout
-	go func() {
-		var res map[string]any
-		var err error
-
-		switch tool.EngineInterface.EngineInterfaceType {
-		case valueobject.EngineInterfaceAWSLambda:
-			res, err = e.InvokeLambdaFunction(
-				lambdaTimeoutCtx,
-				tool.EngineInterface.EngineImpl["function_name"].(string),
-				executionRequest.Payload,
-				true,
-			)
-			if err != nil {
-				errorChan <- err
-				return
-			}
-		default:
-			// not implemented
-			errorChan <- fmt.Errorf("engine interface type not implemented")
+	result, err := e.invokeWithRetry(ctx, tool, toolRequest, true, timeoutDuration)
+
+	status := valueobject.ToolRequestStatusSuccess
+	reason := ""
+	if err != nil {
+		fmt.Printf("execution error: %v\n", err)
+		status = valueobject.ToolRequestStatusFailed
+		reason = err.Error()
+	} else {
+		fmt.Printf("execution completed successfully: %v\n", result)
+	}
+
+	e.finishToolRequest(ctx, tool, toolRequest, result, status, reason)
+}
+
+// Async fires an async-event invocation and tracks its completion
+// 1. Invoke the tool without waiting for the result
+// 2. Resolve check_status_impl from the invocation response or EngineImpl, with the time of the invocation
+// 3. Release the tool request (and the worker) until its first status check, when it is claimed again
+// 4. Check the status once per claim with the StatusChecker of the tool's check status type, releasing the request
+// until the next check (backing off between checks) while the job runs
+// 5. Move the tool request to success / failed with the fetched payload
+//
+// A tool request which already has a persisted check_status_impl was invoked by a previous claim, so its status
+// is checked without invoking the tool again. Otherwise the request is completed from the result cache when the
+// tool is cacheable.
+func (e *functionExecutor) Async(
+	ctx context.Context, tool *entity.Tool, toolRequest *entity.ToolRequest,
+) {
+	fail := func(reason string) {
+		fmt.Printf("async execution error: %s\n", reason)
+		e.finishToolRequest(ctx, tool, toolRequest, nil, valueobject.ToolRequestStatusFailed, reason)
+	}
+
+	if err := ensureIdentifiers(toolRequest); err != nil {
+		fail(err.Error())
+		return
+	}
+
+	checkStatusType := tool.EngineInterface.EngineInterfaceCheckStatusType
+	checker, ok := e.statusCheckers[checkStatusType]
+	if !ok && checkStatusType != valueobject.EngineInterfaceCheckStatusTypeNone {
+		fail(fmt.Sprintf("check status type %s is not supported for async-event invocation", checkStatusType))
+		return
+	}
+
+	checkStatusImpl := toolRequest.ResponseData.CheckStatusImpl
+	if len(checkStatusImpl) == 0 {
+		if e.serveFromCache(ctx, tool, toolRequest) {
 			return
 		}
 
-		resultChan <- res
-	}()
+		invocationResult, err := e.invokeWithRetry(ctx, tool, toolRequest, false, DefaultFunctionSyncExecutionTimeout)
+		if err != nil {
+			fail(err.Error())
+			return
+		}
 
-	// Wait for result or timeout
-	var result map[string]any
-	var status valueobject.ToolRequestStatus
+		// fire-and-forget: nothing to check, the accepted invocation is the result
+		if checkStatusType == valueobject.EngineInterfaceCheckStatusTypeNone {
+			e.finishToolRequest(ctx, tool, toolRequest, invocationResult, valueobject.ToolRequestStatusSuccess, "")
+			return
+		}
 
-	select {
-	case result = <-resultChan:
-		fmt.Printf("execution completed successfully: %v\n", result)
-		status = valueobject.ToolRequestStatusSuccess
-	case err := <-errorChan:
-		fmt.Printf("execution error: %v\n", err)
-		status = valueobject.ToolRequestStatusFailed
-	case <-lambdaTimeoutCtx.Done():
-		fmt.Printf("execution timeout after %v\n", timeoutDuration)
+		invokedAt := time.Now()
+		checkStatusImpl = e.resolveCheckStatusImpl(tool, toolRequest.ID, toolRequest.RequestData.RequestIdentifier, invocationResult)
+		checkStatusImpl[checkStatusInvokedAtKey] = invokedAt.UTC().Format(time.RFC3339Nano)
+		toolRequest.ResponseData.CheckStatusImpl = checkStatusImpl
+
+		policy := e.pollPolicy(tool.EngineInterface.EngineImpl)
+		if err := e.waitStatusCheck(ctx, toolRequest, invokedAt.Add(policy.interval)); err != nil {
+			fmt.Printf("failed to persist check status impl: %v\n", err)
+
+			if errors.Is(err, errToolRequestReleased) {
+				e.cancelReleasedInvocation(ctx, tool, toolRequest)
+			}
+			return
+		}
+		e.notifier.Publish(newToolRequestEvent(ToolRequestEventTypeProgress, toolRequest,
+			fmt.Sprintf("invoked, waiting for completion (%s)", checkStatusType)))
+		return
+	}
+
+	result, nextCheckAt := e.checkStatus(ctx, tool, toolRequest, checker, checkStatusImpl)
+	if ctx.Err() != nil {
+		// cancelled, or released to another worker: the request is no longer claimed by this one
+		return
+	}
+	if !result.Done {
+		if err := e.waitStatusCheck(ctx, toolRequest, nextCheckAt); err != nil {
+			fmt.Printf("failed to release tool request %d until its next status check: %v\n", toolRequest.ID, err)
+		}
+		return
+	}
+
+	status := valueobject.ToolRequestStatusSuccess
+	if result.Failed {
 		status = valueobject.ToolRequestStatusFailed
 	}
 
-	// Use separate context for DB operations (with reasonable timeout)
-	dbCtx, dbCancel := context.WithTimeout(e.baseCtx, 30*time.Second)
-	defer dbCancel()
+	e.finishToolRequest(ctx, tool, toolRequest, result.Payload, status, result.Reason)
+}
+
+// ensureIdentifiers assigns request / response identifiers on the first execution of a tool request.
+// Identifiers of a re-claimed tool request are kept, so they stay stable across claims.
+func ensureIdentifiers(toolRequest *entity.ToolRequest) error {
+	if toolRequest.RequestData.RequestIdentifier == "" {
+		requestIdentifier, err := uuid.NewRandom()
+		if err != nil {
+			return err
+		}
+		toolRequest.RequestData.RequestIdentifier = requestIdentifier.String()
+	}
+
+	if toolRequest.ResponseData.ResponseIdentifier == "" {
+		responseIdentifier, err := uuid.NewRandom()
+		if err != nil {
+			return err
+		}
+		toolRequest.ResponseData.ResponseIdentifier = responseIdentifier.String()
+	}
+
+	return nil
+}
+
+// resolveCheckStatusImpl prefers a "check_status_impl" object returned by the invocation.
+// Otherwise it is built from EngineImpl, rendering "{name}" placeholders with
+// request_id, tool_request_id, aws_request_id and scalar fields of the invocation response.
+func (e *functionExecutor) resolveCheckStatusImpl(
+	tool *entity.Tool, requestID int, requestIdentifier string, invocationResult map[string]any,
+) map[string]any {
+	checkStatusType := string(tool.EngineInterface.EngineInterfaceCheckStatusType)
+
+	if impl, ok := implMap(invocationResult, "check_status_impl"); ok {
+		if _, ok := impl["type"]; !ok {
+			impl["type"] = checkStatusType
+		}
+		return impl
+	}
+
+	vars := templateVars(map[string]string{
+		"request_id":      requestIdentifier,
+		"tool_request_id": fmt.Sprint(requestID),
+	}, invocationResult)
+
+	engineImpl := tool.EngineInterface.EngineImpl
+	impl := map[string]any{"type": checkStatusType}
+
+	switch tool.EngineInterface.EngineInterfaceCheckStatusType {
+	case valueobject.EngineInterfaceCheckStatusTypePollHTTP:
+		statusURL, ok := implString(invocationResult, "status_url")
+		if !ok {
+			statusURL, _ = implString(engineImpl, "status_url")
+		}
+		impl["url"] = renderTemplate(statusURL, vars)
+		if headers, ok := implMap(engineImpl, "status_headers"); ok {
+			impl["headers"] = headers
+		}
+	case valueobject.EngineInterfaceCheckStatusTypeAWSS3Trigger:
+		for _, key := range []string{"aws_s3_bucket", "aws_s3_key", "aws_s3_error_key"} {
+			value, ok := implString(invocationResult, key)
+			if !ok {
+				value, ok = implString(engineImpl, key)
+			}
+			if ok {
+				impl[key] = renderTemplate(value, vars)
+			}
+		}
+	}
+
+	// the cancel endpoint usually refers to the same job, so it is rendered with the same variables
+	cancelURL, ok := implString(invocationResult, "cancel_url")
+	if !ok {
+		cancelURL, ok = implString(engineImpl, "cancel_url")
+	}
+	if ok {
+		impl["cancel_url"] = renderTemplate(cancelURL, vars)
+	}
+
+	return impl
+}
 
-	// Update tool request with results
-	toolRequest, err := e.toolRepo.FindToolRequestByID(dbCtx, requestID)
+// Cancel calls "cancel_url" of the tool request's check_status_impl (rendered when the tool was invoked)
+// with "cancel_method" (default POST) and "cancel_headers" of EngineImpl.
+// Tools without a cancel endpoint, sync-wait tools and requests which were not invoked yet are left alone.
+func (e *functionExecutor) Cancel(
+	ctx context.Context, tool *entity.Tool, toolRequest *entity.ToolRequest,
+) error {
+	if tool.EngineInterface.EngineInterfaceInvokeType != valueobject.EngineInterfaceInvokeTypeAsyncEvent {
+		return nil
+	}
+
+	cancelURL, ok := implString(toolRequest.ResponseData.CheckStatusImpl, "cancel_url")
+	if !ok {
+		return nil
+	}
+
+	method, ok := implString(tool.EngineInterface.EngineImpl, "cancel_method")
+	if !ok {
+		method = http.MethodPost
+	}
+	headers, _ := implMap(tool.EngineInterface.EngineImpl, "cancel_headers")
+
+	cancelCtx, cancel := context.WithTimeout(ctx, DefaultFunctionSyncExecutionTimeout)
+	defer cancel()
+
+	output, err := e.httpClient.Invoke(cancelCtx, http_wrapper.InvokeInput{
+		Method:      method,
+		URL:         cancelURL,
+		ContentType: http_wrapper.ContentTypeJSON,
+		Header:      headers,
+		Body: map[string]any{
+			"request_id":      toolRequest.RequestData.RequestIdentifier,
+			"tool_request_id": toolRequest.ID,
+		},
+	})
 	if err != nil {
-		fmt.Printf("failed to find tool request: %v\n", err)
+		return err
+	}
+
+	// the job may already be gone on the provider side
+	if output.StatusCode == http.StatusNotFound || output.StatusCode == http.StatusGone {
+		return nil
+	}
+	if output.StatusCode < http.StatusOK || output.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("cancel url %s returned status code %d: %s",
+			cancelURL, output.StatusCode, truncate(string(output.Body), 512))
+	}
+
+	return nil
+}
+
+// cancelReleasedInvocation forwards the cancellation of a tool request cancelled between its invocation
+// and the persistence of its check_status_impl, as the canceller could not see the cancel endpoint yet.
+func (e *functionExecutor) cancelReleasedInvocation(
+	ctx context.Context, tool *entity.Tool, toolRequest *entity.ToolRequest,
+) {
+	dbCtx, dbCancel := context.WithTimeout(context.WithoutCancel(ctx), 30*time.Second)
+	defer dbCancel()
+
+	current, err := e.toolRepo.FindToolRequestByID(dbCtx, toolRequest.ID)
+	if err != nil || current.Status != valueobject.ToolRequestStatusCancelled {
 		return
 	}
 
-	toolRequest.RequestData.RequestIdentifier = requestIdentifier.String()
-	toolRequest.RequestData.Payload = executionRequest.Payload
+	if err := e.Cancel(dbCtx, tool, toolRequest); err != nil {
+		fmt.Printf("failed to cancel tool request %d at provider: %v\n", toolRequest.ID, err)
+	}
+}
+
+// checkStatus makes the next status check of an invoked request. It returns the result once the request is done
+// (or failed: the poll timeout elapsed, or too many consecutive transient errors occurred), otherwise the time of
+// the next check. The checks made so far, the consecutive errors and the last progress are kept in checkStatusImpl.
+// The poll timeout runs from the invocation ("invoked_at" of checkStatusImpl); it runs from now for a request
+// invoked before it was recorded. Progress reported by the provider is published as it changes.
+func (e *functionExecutor) checkStatus(
+	ctx context.Context, tool *entity.Tool, toolRequest *entity.ToolRequest,
+	checker StatusChecker, checkStatusImpl map[string]any,
+) (*CheckStatusResult, time.Time) {
+	policy := e.pollPolicy(tool.EngineInterface.EngineImpl)
+
+	now := time.Now()
+	invokedAt, ok := invocationTime(checkStatusImpl)
+	if !ok {
+		invokedAt = now
+		checkStatusImpl[checkStatusInvokedAtKey] = invokedAt.UTC().Format(time.RFC3339Nano)
+	}
+	deadline := invokedAt.Add(policy.timeout)
+	if !now.Before(deadline) {
+		return &CheckStatusResult{
+			Done: true, Failed: true,
+			Reason: fmt.Sprintf("status check timeout after %v", policy.timeout),
+		}, time.Time{}
+	}
+
+	checks, _ := implFloat(checkStatusImpl, checkStatusChecksKey)
+	consecutiveErrors, _ := implFloat(checkStatusImpl, checkStatusErrorsKey)
+
+	checkCtx, checkCancel := context.WithDeadline(ctx, earliest(deadline, now.Add(DefaultFunctionSyncExecutionTimeout)))
+	result, err := checker.Check(checkCtx, checkStatusImpl)
+	checkCancel()
+
+	checks++
+	checkStatusImpl[checkStatusChecksKey] = int(checks)
+
+	if err != nil {
+		consecutiveErrors++
+		fmt.Printf("status check error of tool request %d (%d/%d): %v\n",
+			toolRequest.ID, int(consecutiveErrors), maxConsecutiveStatusCheckErrors, err)
+		if int(consecutiveErrors) >= maxConsecutiveStatusCheckErrors {
+			return &CheckStatusResult{Done: true, Failed: true, Reason: err.Error()}, time.Time{}
+		}
+		checkStatusImpl[checkStatusErrorsKey] = int(consecutiveErrors)
+	} else {
+		if result.Done {
+			return result, time.Time{}
+		}
+		checkStatusImpl[checkStatusErrorsKey] = 0
+		if lastProgress, _ := implString(checkStatusImpl, checkStatusProgressKey); result.Progress != "" && result.Progress != lastProgress {
+			checkStatusImpl[checkStatusProgressKey] = result.Progress
+			e.notifier.Publish(newToolRequestEvent(ToolRequestEventTypeProgress, toolRequest, result.Progress))
+		}
+	}
+
+	// a check due past the deadline is made at the deadline, which fails the request
+	return &CheckStatusResult{}, earliest(time.Now().Add(policy.intervalAfter(int(checks))), deadline)
+}
+
+func earliest(a time.Time, b time.Time) time.Time {
+	if a.Before(b) {
+		return a
+	}
+	return b
+}
+
+// invocationTime reads the time of the invocation recorded in check_status_impl.
+func invocationTime(checkStatusImpl map[string]any) (time.Time, bool) {
+	value, ok := implString(checkStatusImpl, checkStatusInvokedAtKey)
+	if !ok {
+		return time.Time{}, false
+	}
+	invokedAt, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return invokedAt, true
+}
+
+type pollPolicy struct {
+	interval    time.Duration
+	maxInterval time.Duration
+	multiplier  float64
+	timeout     time.Duration
+}
+
+// intervalAfter returns the interval between a status check and the next one, after checks checks.
+func (p pollPolicy) intervalAfter(checks int) time.Duration {
+	interval := p.interval
+	for range checks {
+		interval = time.Duration(float64(interval) * p.multiplier)
+		if interval >= p.maxInterval {
+			return p.maxInterval
+		}
+	}
+	return interval
+}
+
+func (e *functionExecutor) pollPolicy(engineImpl map[string]any) pollPolicy {
+	policy := pollPolicy{
+		interval:    DefaultPollInterval,
+		maxInterval: DefaultPollMaxInterval,
+		multiplier:  DefaultPollBackoffMultiplier,
+		timeout:     DefaultPollTimeout,
+	}
+
+	if e.config != nil {
+		if v := e.config.Executor.PollIntervalSeconds; v > 0 {
+			policy.interval = secondsToDuration(v)
+		}
+		if v := e.config.Executor.PollMaxIntervalSeconds; v > 0 {
+			policy.maxInterval = secondsToDuration(v)
+		}
+		if v := e.config.Executor.PollBackoffMultiplier; v >= 1 {
+			policy.multiplier = v
+		}
+		if v := e.config.Executor.PollTimeoutSeconds; v > 0 {
+			policy.timeout = secondsToDuration(v)
+		}
+	}
+
+	if v, ok := implFloat(engineImpl, "poll_interval_seconds"); ok && v > 0 {
+		policy.interval = secondsToDuration(v)
+	}
+	if v, ok := implFloat(engineImpl, "poll_max_interval_seconds"); ok && v > 0 {
+		policy.maxInterval = secondsToDuration(v)
+	}
+	if v, ok := implFloat(engineImpl, "poll_backoff_multiplier"); ok && v >= 1 {
+		policy.multiplier = v
+	}
+	if v, ok := implFloat(engineImpl, "poll_timeout_seconds"); ok && v > 0 {
+		policy.timeout = secondsToDuration(v)
+	}
+
+	if policy.maxInterval < policy.interval {
+		policy.maxInterval = policy.interval
+	}
+
+	return policy
+}
+
+func secondsToDuration(seconds float64) time.Duration {
+	return time.Duration(seconds * float64(time.Second))
+}
+
+// waitStatusCheck persists the claimed request, with its check_status_impl, and releases it until its next
+// status check at nextCheckAt, when a worker claims it again. The status location of a long-running invocation
+// is visible meanwhile.
+func (e *functionExecutor) waitStatusCheck(
+	ctx context.Context, toolRequest *entity.ToolRequest, nextCheckAt time.Time,
+) error {
+	dbCtx, dbCancel := context.WithTimeout(context.WithoutCancel(ctx), 30*time.Second)
+	defer dbCancel()
+
+	waiting, err := e.toolRepo.WaitToolRequestStatusCheck(dbCtx, toolRequest, nextCheckAt)
+	if err != nil {
+		return err
+	}
+	if !waiting {
+		return fmt.Errorf("tool request %d is no longer claimed by %s: %w", toolRequest.ID, toolRequest.LockedBy, errToolRequestReleased)
+	}
+
+	return nil
+}
+
+// saveAttempts persists the attempts made so far while the request is being retried.
+func (e *functionExecutor) saveAttempts(ctx context.Context, toolRequest *entity.ToolRequest) error {
+	dbCtx, dbCancel := context.WithTimeout(context.WithoutCancel(ctx), 30*time.Second)
+	defer dbCancel()
+
+	updated, err := e.toolRepo.UpdateClaimedToolRequest(dbCtx, toolRequest)
+	if err != nil {
+		return err
+	}
+	if !updated {
+		return fmt.Errorf("tool request %d is no longer claimed by %s: %w", toolRequest.ID, toolRequest.LockedBy, errToolRequestReleased)
+	}
+
+	return nil
+}
+
+// finishToolRequest completes the claimed tool request.
+// The provider response is kept as the raw payload; a successful response is mapped to the
+// tool's response schema, and the request fails when it does not satisfy it.
+// Payloads exceeding the offload threshold are moved to the blob store, and the result of a cacheable tool is cached.
+// The update is discarded when the lease was lost in the meantime
+// (the request was cancelled or released to another worker).
+func (e *functionExecutor) finishToolRequest(
+	ctx context.Context, tool *entity.Tool, toolRequest *entity.ToolRequest, result map[string]any,
+	status valueobject.ToolRequestStatus, reason string,
+) {
+	// Use separate context for DB operations (with reasonable timeout),
+	// the result is still recorded when the execution context was cancelled
+	dbCtx, dbCancel := context.WithTimeout(context.WithoutCancel(ctx), 30*time.Second)
+	defer dbCancel()
 
-	toolRequest.ResponseData.ResponseIdentifier = responseIdentifier.String()
 	if result != nil {
+		toolRequest.ResponseData.RawPayload = result
+	}
+	switch {
+	case status != valueobject.ToolRequestStatusSuccess:
+	case isFireAndForget(tool):
+		// the tool produces no output, the acknowledgement of the invocation is kept as is
 		toolRequest.ResponseData.Payload = result
+	default:
+		output, err := mapOutput(tool.ProviderInterface, result)
+		if err != nil {
+			fmt.Printf("tool request %d: %v\n", toolRequest.ID, err)
+			status = valueobject.ToolRequestStatusFailed
+			reason = err.Error()
+		} else {
+			toolRequest.ResponseData.Payload = output
+		}
 	}
+	toolRequest.ResponseData.Error = reason
 
 	toolRequest.Status = status
 
-	err = e.toolRepo.UpdateToolRequest(dbCtx, toolRequest)
+	e.payloadStore.offloadResponse(dbCtx, toolRequest)
+
+	completed, err := e.toolRepo.CompleteToolRequest(dbCtx, toolRequest)
 	if err != nil {
 		fmt.Printf("failed to update tool request: %v\n", err)
+		e.payloadStore.delete(dbCtx, responseRefs(toolRequest)...)
 		return
 	}
+	if !completed {
+		fmt.Printf("tool request %d is no longer claimed by %s, result discarded\n", toolRequest.ID, toolRequest.LockedBy)
+		e.payloadStore.delete(dbCtx, responseRefs(toolRequest)...)
+		return
+	}
+
+	e.cacheResult(dbCtx, tool, toolRequest)
+
+	e.notifier.Publish(newToolRequestEvent(ToolRequestEventTypeStatus, toolRequest, ""))
 }
@@ -0,0 +1,197 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"aigendrug.com/router-core/internal/tool/domain/entity"
+	"aigendrug.com/router-core/internal/tool/domain/shared_type"
+)
+
+// fakeStatusChecker answers every check with result, or err when set, and counts the checks.
+type fakeStatusChecker struct {
+	result *CheckStatusResult
+	err    error
+	checks int
+}
+
+func (c *fakeStatusChecker) Check(_ context.Context, _ map[string]any) (*CheckStatusResult, error) {
+	c.checks++
+	return c.result, c.err
+}
+
+func TestCheckStatus(t *testing.T) {
+	now := time.Now()
+	invokedAt := func(ago time.Duration) string {
+		return now.Add(-ago).UTC().Format(time.RFC3339Nano)
+	}
+
+	tests := []struct {
+		name            string
+		checkStatusImpl map[string]any
+		result          *CheckStatusResult
+		err             error
+		wantChecks      int
+		wantDone        bool
+		wantReason      string
+		// delay of the next check from now, when not done
+		wantNextCheckIn time.Duration
+		wantImpl        map[string]any
+		wantProgress    []string
+	}{
+		{
+			name:            "timeout elapsed since the invocation",
+			checkStatusImpl: map[string]any{checkStatusInvokedAtKey: invokedAt(2 * time.Hour)},
+			result:          &CheckStatusResult{},
+			wantDone:        true,
+			wantReason:      "status check timeout after 1h0m0s",
+		},
+		{
+			name:            "done",
+			checkStatusImpl: map[string]any{checkStatusInvokedAtKey: invokedAt(time.Minute)},
+			result:          &CheckStatusResult{Done: true, Payload: map[string]any{"score": 0.5}},
+			wantChecks:      1,
+			wantDone:        true,
+		},
+		{
+			name:            "running backs off",
+			checkStatusImpl: map[string]any{checkStatusInvokedAtKey: invokedAt(time.Minute)},
+			result:          &CheckStatusResult{},
+			wantChecks:      1,
+			wantNextCheckIn: 20 * time.Second,
+			wantImpl:        map[string]any{checkStatusChecksKey: 1, checkStatusErrorsKey: 0},
+		},
+		{
+			name: "interval capped",
+			checkStatusImpl: map[string]any{
+				checkStatusInvokedAtKey: invokedAt(time.Minute), checkStatusChecksKey: float64(9),
+			},
+			result:          &CheckStatusResult{},
+			wantChecks:      1,
+			wantNextCheckIn: time.Minute,
+			wantImpl:        map[string]any{checkStatusChecksKey: 10},
+		},
+		{
+			name:            "next check at the deadline",
+			checkStatusImpl: map[string]any{checkStatusInvokedAtKey: invokedAt(time.Hour - 5*time.Second)},
+			result:          &CheckStatusResult{},
+			wantChecks:      1,
+			wantNextCheckIn: 5 * time.Second,
+		},
+		{
+			name:            "invocation time not recorded",
+			checkStatusImpl: map[string]any{},
+			result:          &CheckStatusResult{},
+			wantChecks:      1,
+			wantNextCheckIn: 20 * time.Second,
+		},
+		{
+			name: "progress published when it changes",
+			checkStatusImpl: map[string]any{
+				checkStatusInvokedAtKey: invokedAt(time.Minute), checkStatusProgressKey: "running 10%",
+			},
+			result:          &CheckStatusResult{Progress: "running 20%"},
+			wantChecks:      1,
+			wantNextCheckIn: 20 * time.Second,
+			wantImpl:        map[string]any{checkStatusProgressKey: "running 20%"},
+			wantProgress:    []string{"running 20%"},
+		},
+		{
+			name: "same progress not published again",
+			checkStatusImpl: map[string]any{
+				checkStatusInvokedAtKey: invokedAt(time.Minute), checkStatusProgressKey: "running 10%",
+			},
+			result:          &CheckStatusResult{Progress: "running 10%"},
+			wantChecks:      1,
+			wantNextCheckIn: 20 * time.Second,
+		},
+		{
+			name: "transient error",
+			checkStatusImpl: map[string]any{
+				checkStatusInvokedAtKey: invokedAt(time.Minute), checkStatusErrorsKey: float64(1),
+			},
+			err:             errors.New("connection reset"),
+			wantChecks:      1,
+			wantNextCheckIn: 20 * time.Second,
+			wantImpl:        map[string]any{checkStatusErrorsKey: 2},
+		},
+		{
+			name: "too many consecutive errors",
+			checkStatusImpl: map[string]any{
+				checkStatusInvokedAtKey: invokedAt(time.Minute),
+				checkStatusErrorsKey:    float64(maxConsecutiveStatusCheckErrors - 1),
+			},
+			err:        errors.New("connection reset"),
+			wantChecks: 1,
+			wantDone:   true,
+			wantReason: "connection reset",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			notifier := &fakeNotifier{}
+			e := &functionExecutor{notifier: notifier}
+			tool := &entity.Tool{EngineInterface: shared_type.EngineInterface{EngineImpl: map[string]any{
+				"poll_interval_seconds":     10,
+				"poll_max_interval_seconds": 60,
+				"poll_backoff_multiplier":   2,
+				"poll_timeout_seconds":      3600,
+			}}}
+			checker := &fakeStatusChecker{result: tt.result, err: tt.err}
+
+			result, nextCheckAt := e.checkStatus(context.Background(), tool, &entity.ToolRequest{ID: 1}, checker, tt.checkStatusImpl)
+
+			if checker.checks != tt.wantChecks {
+				t.Fatalf("checkStatus() made %d checks, want %d", checker.checks, tt.wantChecks)
+			}
+			if result.Done != tt.wantDone || !strings.Contains(result.Reason, tt.wantReason) {
+				t.Fatalf("checkStatus() = %+v, want done %v with reason %q", result, tt.wantDone, tt.wantReason)
+			}
+			if !tt.wantDone {
+				if delay := time.Until(nextCheckAt); delay > tt.wantNextCheckIn || delay < tt.wantNextCheckIn-time.Second {
+					t.Fatalf("next check in %v, want %v", delay, tt.wantNextCheckIn)
+				}
+			}
+			if _, ok := invocationTime(tt.checkStatusImpl); !ok {
+				t.Fatalf("check_status_impl has no invocation time: %v", tt.checkStatusImpl)
+			}
+			for key, want := range tt.wantImpl {
+				if got := tt.checkStatusImpl[key]; got != want {
+					t.Fatalf("check_status_impl[%s] = %v, want %v", key, got, want)
+				}
+			}
+			var progress []string
+			for _, event := range notifier.events {
+				progress = append(progress, event.Message)
+			}
+			if strings.Join(progress, ",") != strings.Join(tt.wantProgress, ",") {
+				t.Fatalf("published progress %v, want %v", progress, tt.wantProgress)
+			}
+		})
+	}
+}
+
+func TestPollPolicyIntervalAfter(t *testing.T) {
+	policy := pollPolicy{interval: 5 * time.Second, maxInterval: time.Minute, multiplier: 1.5}
+
+	tests := []struct {
+		checks int
+		want   time.Duration
+	}{
+		{checks: 0, want: 5 * time.Second},
+		{checks: 1, want: 7500 * time.Millisecond},
+		{checks: 2, want: 11250 * time.Millisecond},
+		{checks: 7, want: time.Minute},
+		{checks: 1000, want: time.Minute},
+	}
+
+	for _, tt := range tests {
+		if got := policy.intervalAfter(tt.checks); got != tt.want {
+			t.Errorf("intervalAfter(%d) = %v, want %v", tt.checks, got, tt.want)
+		}
+	}
+}
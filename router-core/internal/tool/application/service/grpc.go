@@ -0,0 +1,204 @@
+package service
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	grpc_wrapper "aigendrug.com/router-core/internal/shared/grpc-wrapper"
+	"aigendrug.com/router-core/internal/tool/domain/entity"
+	"aigendrug.com/router-core/internal/tool/domain/valueobject"
+)
+
+// DefaultGRPCReflectionTTL is how long a method resolved by server reflection is reused,
+// so that a redeployed server with a new API is picked up without updating the tool.
+// Methods of a descriptor set are kept until the tool is updated.
+const DefaultGRPCReflectionTTL = 5 * time.Minute
+
+// InvokeGRPC calls a unary method of a grpc tool. The payload is transcoded into the request message
+// and the response message back into JSON, with the field names of the .proto file.
+// The deadline of ctx, the execution timeout of the tool, is sent to the server.
+//
+// EngineImpl fields:
+// - "address": host:port of the server.
+// - "service", "method": Fully qualified service name (e.g. "inference.v1.Predictor") and method name.
+// - "descriptor_set": Base64 encoded FileDescriptorSet (protoc --include_imports --descriptor_set_out).
+// The method is resolved with server reflection when it is empty.
+// - "metadata": Metadata (headers) sent with every call, reflection calls included.
+// - "tls": Use TLS, with "tls_server_name", "tls_ca_cert", "tls_client_cert", "tls_client_key" (PEM)
+// and "tls_insecure_skip_verify" as options.
+func (e *functionExecutor) InvokeGRPC(ctx context.Context, tool *entity.Tool, payload map[string]any) (map[string]any, error) {
+	engineImpl := tool.EngineInterface.EngineImpl
+
+	target, err := grpcTarget(engineImpl)
+	if err != nil {
+		return nil, err
+	}
+	metadata := grpcMetadata(engineImpl)
+
+	method, err := e.grpcMethod(ctx, tool, target, metadata)
+	if err != nil {
+		return nil, err
+	}
+
+	output, err := e.grpcClient.Invoke(ctx, grpc_wrapper.InvokeInput{
+		Target:   target,
+		Method:   method,
+		Metadata: metadata,
+		Payload:  payload,
+	})
+	if err != nil {
+		return nil, grpcExecutionError(target, method.Path(), err)
+	}
+
+	return output.Payload, nil
+}
+
+func grpcTarget(engineImpl map[string]any) (grpc_wrapper.Target, error) {
+	address, ok := implString(engineImpl, "address")
+	if !ok {
+		return grpc_wrapper.Target{}, newExecutionError(valueobject.ExecutionErrorClassConfiguration,
+			"engine impl has no address")
+	}
+
+	target := grpc_wrapper.Target{Address: address}
+	if implBool(engineImpl, "tls") {
+		target.TLS = &grpc_wrapper.TLSConfig{InsecureSkipVerify: implBool(engineImpl, "tls_insecure_skip_verify")}
+		target.TLS.ServerName, _ = implString(engineImpl, "tls_server_name")
+		target.TLS.CACertPEM, _ = implString(engineImpl, "tls_ca_cert")
+		target.TLS.ClientCertPEM, _ = implString(engineImpl, "tls_client_cert")
+		target.TLS.ClientKeyPEM, _ = implString(engineImpl, "tls_client_key")
+	}
+	return target, nil
+}
+
+func grpcMetadata(engineImpl map[string]any) map[string]string {
+	fields, ok := implMap(engineImpl, "metadata")
+	if !ok {
+		return nil
+	}
+	metadata := make(map[string]string, len(fields))
+	for key, value := range fields {
+		if s, ok := value.(string); ok {
+			metadata[key] = s
+		} else {
+			metadata[key] = fmt.Sprint(value)
+		}
+	}
+	return metadata
+}
+
+type grpcMethodCacheEntry struct {
+	method    *grpc_wrapper.Method
+	updatedAt time.Time
+	expiresAt time.Time
+}
+
+// grpcMethodCache keeps the resolved methods of grpc tools by tool id.
+// An entry is dropped when the tool is updated or, for reflection, when it expires.
+type grpcMethodCache struct {
+	mu      sync.Mutex
+	entries map[int]grpcMethodCacheEntry
+}
+
+func newGRPCMethodCache() *grpcMethodCache {
+	return &grpcMethodCache{entries: map[int]grpcMethodCacheEntry{}}
+}
+
+func (c *grpcMethodCache) get(tool *entity.Tool) *grpc_wrapper.Method {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[tool.ID]
+	if !ok || !entry.updatedAt.Equal(tool.UpdatedAt) || (!entry.expiresAt.IsZero() && time.Now().After(entry.expiresAt)) {
+		return nil
+	}
+	return entry.method
+}
+
+func (c *grpcMethodCache) put(tool *entity.Tool, method *grpc_wrapper.Method, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry := grpcMethodCacheEntry{method: method, updatedAt: tool.UpdatedAt}
+	if ttl > 0 {
+		entry.expiresAt = time.Now().Add(ttl)
+	}
+	c.entries[tool.ID] = entry
+}
+
+// grpcMethod resolves the method of a grpc tool, from its descriptor set or with server reflection.
+func (e *functionExecutor) grpcMethod(
+	ctx context.Context, tool *entity.Tool, target grpc_wrapper.Target, metadata map[string]string,
+) (*grpc_wrapper.Method, error) {
+	if method := e.grpcMethods.get(tool); method != nil {
+		return method, nil
+	}
+
+	engineImpl := tool.EngineInterface.EngineImpl
+	serviceName, ok := implString(engineImpl, "service")
+	if !ok {
+		return nil, newExecutionError(valueobject.ExecutionErrorClassConfiguration, "engine impl has no service")
+	}
+	methodName, ok := implString(engineImpl, "method")
+	if !ok {
+		return nil, newExecutionError(valueobject.ExecutionErrorClassConfiguration, "engine impl has no method")
+	}
+
+	if encoded, ok := implString(engineImpl, "descriptor_set"); ok {
+		descriptorSet, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, newExecutionError(valueobject.ExecutionErrorClassConfiguration,
+				"descriptor_set is not valid base64: %v", err)
+		}
+		method, err := grpc_wrapper.MethodFromDescriptorSet(descriptorSet, serviceName, methodName)
+		if err != nil {
+			return nil, newExecutionError(valueobject.ExecutionErrorClassConfiguration, "%v", err)
+		}
+		e.grpcMethods.put(tool, method, 0)
+		return method, nil
+	}
+
+	method, err := e.grpcClient.ResolveMethod(ctx, target, metadata, serviceName, methodName)
+	if err != nil {
+		if errors.Is(err, grpc_wrapper.ErrMethodNotFound) {
+			return nil, newExecutionError(valueobject.ExecutionErrorClassConfiguration, "%v", err)
+		}
+		return nil, grpcExecutionError(target, "server reflection", err)
+	}
+	e.grpcMethods.put(tool, method, DefaultGRPCReflectionTTL)
+	return method, nil
+}
+
+// grpcExecutionError classifies a failed call by its status code.
+// Transport failures (connection refused, reset, etc.) are reported with UNAVAILABLE, a network error.
+func grpcExecutionError(target grpc_wrapper.Target, call string, err error) error {
+	if errors.Is(err, grpc_wrapper.ErrInvalidPayload) {
+		return newExecutionError(valueobject.ExecutionErrorClassClientError, "%v", err)
+	}
+
+	var statusErr *grpc_wrapper.StatusError
+	if !errors.As(err, &statusErr) {
+		return fmt.Errorf("grpc server %s: %w", target.Address, err)
+	}
+
+	class := valueobject.ExecutionErrorClassServerError
+	switch statusErr.Code {
+	case grpc_wrapper.CodeCanceled:
+		class = valueobject.ExecutionErrorClassCancelled
+	case grpc_wrapper.CodeDeadlineExceeded:
+		class = valueobject.ExecutionErrorClassTimeout
+	case grpc_wrapper.CodeResourceExhausted:
+		class = valueobject.ExecutionErrorClassThrottled
+	case grpc_wrapper.CodeUnavailable:
+		class = valueobject.ExecutionErrorClassNetwork
+	case grpc_wrapper.CodeInvalidArgument, grpc_wrapper.CodeNotFound, grpc_wrapper.CodeAlreadyExists,
+		grpc_wrapper.CodePermissionDenied, grpc_wrapper.CodeFailedPrecondition, grpc_wrapper.CodeOutOfRange,
+		grpc_wrapper.CodeUnimplemented, grpc_wrapper.CodeUnauthenticated:
+		class = valueobject.ExecutionErrorClassClientError
+	}
+	return newExecutionError(class, "grpc server %s returned %v (%s)", target.Address, statusErr, call)
+}
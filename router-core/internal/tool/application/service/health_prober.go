@@ -0,0 +1,332 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"aigendrug.com/router-core/internal/config"
+	"aigendrug.com/router-core/internal/shared/database/postgres"
+	grpc_wrapper "aigendrug.com/router-core/internal/shared/grpc-wrapper"
+	http_wrapper "aigendrug.com/router-core/internal/shared/http-wrapper"
+	"aigendrug.com/router-core/internal/tool/domain"
+	"aigendrug.com/router-core/internal/tool/domain/entity"
+	"aigendrug.com/router-core/internal/tool/domain/shared_type"
+	"aigendrug.com/router-core/internal/tool/domain/valueobject"
+	"github.com/aws/aws-sdk-go-v2/service/lambda/types"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Default health prober settings.
+// Overridden by config (HEALTH_*) and the interval per tool by EngineImpl "health_interval_seconds".
+const (
+	DefaultHealthInterval           = 1 * time.Minute
+	DefaultHealthTimeout            = 10 * time.Second
+	DefaultHealthUnhealthyThreshold = 3
+	DefaultHealthHistoryLimit       = 100
+	DefaultHealthConcurrency        = 4
+
+	// delay between two scans for due tools
+	healthPollInterval = 5 * time.Second
+)
+
+// HealthSelectionPolicy defines how SelectTool treats unhealthy tools
+type HealthSelectionPolicy string
+
+const (
+	// unhealthy tools are excluded from selection (default)
+	HealthSelectionPolicySkip HealthSelectionPolicy = "skip"
+
+	// unhealthy tools can be selected, the selection message warns about them
+	HealthSelectionPolicyDemote HealthSelectionPolicy = "demote"
+
+	// health is not considered
+	HealthSelectionPolicyOff HealthSelectionPolicy = "off"
+)
+
+// errHealthCheckNotConfigured reports a tool which declares no way to check its health, its health stays unknown.
+var errHealthCheckNotConfigured = errors.New("no health check configured")
+
+// ToolHealthProber periodically checks the health of every tool and records it on the tool, with a history
+// of its recent checks.
+//
+// Every replica scans for due tools, a due tool is claimed by pushing its next check time forward
+// (SELECT ... FOR UPDATE SKIP LOCKED), so that each tool is checked by a single replica per interval.
+type ToolHealthProber interface {
+	// Start launches the prober, unless it is disabled. It stops when ctx is done.
+	Start(ctx context.Context)
+	// Check checks a tool now and records the result.
+	Check(ctx context.Context, tool *entity.Tool) error
+	// SelectionPolicy is the treatment of unhealthy tools by SelectTool.
+	SelectionPolicy() HealthSelectionPolicy
+}
+
+type toolHealthProber struct {
+	db       *pgxpool.Pool
+	toolRepo domain.ToolRepository
+	executor FunctionExecutor
+
+	enabled            bool
+	interval           time.Duration
+	timeout            time.Duration
+	unhealthyThreshold int
+	historyLimit       int
+	concurrency        int
+	selectionPolicy    HealthSelectionPolicy
+}
+
+func NewToolHealthProber(
+	config *config.Config,
+	db *pgxpool.Pool,
+	toolRepo domain.ToolRepository,
+	executor FunctionExecutor,
+) ToolHealthProber {
+	p := &toolHealthProber{
+		db:                 db,
+		toolRepo:           toolRepo,
+		executor:           executor,
+		interval:           DefaultHealthInterval,
+		timeout:            DefaultHealthTimeout,
+		unhealthyThreshold: DefaultHealthUnhealthyThreshold,
+		historyLimit:       DefaultHealthHistoryLimit,
+		concurrency:        DefaultHealthConcurrency,
+		selectionPolicy:    HealthSelectionPolicySkip,
+	}
+
+	if config != nil {
+		p.enabled = config.Health.Enabled
+		if v := config.Health.IntervalSeconds; v > 0 {
+			p.interval = secondsToDuration(v)
+		}
+		if v := config.Health.TimeoutSeconds; v > 0 {
+			p.timeout = secondsToDuration(v)
+		}
+		if v := config.Health.UnhealthyThreshold; v > 0 {
+			p.unhealthyThreshold = v
+		}
+		if v := config.Health.HistoryLimit; v > 0 {
+			p.historyLimit = v
+		}
+		if v := config.Health.Concurrency; v > 0 {
+			p.concurrency = v
+		}
+		switch policy := HealthSelectionPolicy(config.Health.SelectionPolicy); policy {
+		case HealthSelectionPolicySkip, HealthSelectionPolicyDemote, HealthSelectionPolicyOff:
+			p.selectionPolicy = policy
+		case "":
+		default:
+			fmt.Printf("unknown health selection policy %q, using %s\n", policy, p.selectionPolicy)
+		}
+	}
+
+	return p
+}
+
+func (p *toolHealthProber) SelectionPolicy() HealthSelectionPolicy {
+	return p.selectionPolicy
+}
+
+func (p *toolHealthProber) Start(ctx context.Context) {
+	if !p.enabled {
+		fmt.Printf("tool health prober disabled (HEALTH_ENABLED)\n")
+		return
+	}
+	fmt.Printf("starting tool health prober (interval %v, timeout %v, unhealthy after %d failures)\n",
+		p.interval, p.timeout, p.unhealthyThreshold)
+
+	go p.run(ctx)
+}
+
+func (p *toolHealthProber) run(ctx context.Context) {
+	ticker := time.NewTicker(min(healthPollInterval, p.interval))
+	defer ticker.Stop()
+
+	for {
+		p.checkDueTools(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// checkDueTools claims and checks the due tools, concurrency at a time, until none is due.
+func (p *toolHealthProber) checkDueTools(ctx context.Context) {
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	semaphore := make(chan struct{}, p.concurrency)
+	for ctx.Err() == nil {
+		semaphore <- struct{}{}
+
+		// the claim holds the tool until its check is recorded, or gives it back to the next scan if this replica dies
+		tool, err := p.toolRepo.ClaimDueToolHealthCheck(ctx, p.timeout+healthPollInterval)
+		if err != nil || tool == nil {
+			if err != nil && ctx.Err() == nil {
+				fmt.Printf("failed to claim tool health check: %v\n", err)
+			}
+			<-semaphore
+			return
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+
+			if err := p.Check(ctx, tool); err != nil {
+				fmt.Printf("failed to record health check of tool %d: %v\n", tool.ID, err)
+			}
+		}()
+	}
+}
+
+func (p *toolHealthProber) Check(ctx context.Context, tool *entity.Tool) error {
+	probeCtx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+
+	start := time.Now()
+	err := p.executor.Probe(probeCtx, tool)
+	checkedAt := time.Now()
+	if err != nil && probeCtx.Err() == context.DeadlineExceeded && ctx.Err() == nil {
+		err = fmt.Errorf("health check timeout after %v", p.timeout)
+	}
+
+	previous := tool.Health
+	health := shared_type.ToolHealth{CheckedAt: &checkedAt, ChangedAt: previous.ChangedAt}
+	switch {
+	case errors.Is(err, errHealthCheckNotConfigured):
+		health.Status = valueobject.ToolHealthStatusUnknown
+		health.Message = err.Error()
+	case err == nil:
+		health.Status = valueobject.ToolHealthStatusHealthy
+	default:
+		health.ConsecutiveFailures = previous.ConsecutiveFailures + 1
+		health.Message = truncate(err.Error(), 1000)
+		health.Status = valueobject.ToolHealthStatusDegraded
+		if health.ConsecutiveFailures >= p.unhealthyThreshold {
+			health.Status = valueobject.ToolHealthStatusUnhealthy
+		}
+	}
+	if health.Status != previous.Status {
+		health.ChangedAt = &checkedAt
+		fmt.Printf("tool %d (%s) is %s (was %s) %s\n", tool.ID, tool.Name, health.Status, previous.Status, health.Message)
+	}
+
+	interval := p.interval
+	if v, ok := implFloat(tool.EngineInterface.EngineImpl, "health_interval_seconds"); ok && v > 0 {
+		interval = secondsToDuration(v)
+	}
+	nextCheckAt := checkedAt.Add(interval)
+
+	if errors.Is(err, errHealthCheckNotConfigured) {
+		return p.toolRepo.UpdateToolHealth(ctx, tool.ID, health, nextCheckAt)
+	}
+
+	check := &entity.ToolHealthCheck{
+		ToolID:    tool.ID,
+		Healthy:   err == nil,
+		Status:    health.Status,
+		Message:   health.Message,
+		LatencyMs: checkedAt.Sub(start).Milliseconds(),
+		CheckedAt: checkedAt,
+	}
+	return postgres.WithTx(ctx, p.db, func(tx pgx.Tx) error {
+		txRepo := p.toolRepo.WithTx(ctx, tx)
+		if err := txRepo.CreateToolHealthCheck(ctx, check, p.historyLimit); err != nil {
+			return err
+		}
+		return txRepo.UpdateToolHealth(ctx, tool.ID, health, nextCheckAt)
+	})
+}
+
+// Probe checks that the tool is able to serve requests, without executing it:
+// - aws-lambda: the function state is Active (or Inactive, reactivated by the next invocation).
+// - http-server: GET "health_url" (with "health_headers") returns a 2xx or 3xx status. Not probed without it.
+// - grpc: the health service of the server reports the service as SERVING. Servers without a health service
+// are checked by resolving the method.
+// - local-exec: the engine is enabled on the router and the command resolves.
+// A tool declaring "health_payload" is invoked with it instead, the invocation must succeed.
+func (e *functionExecutor) Probe(ctx context.Context, tool *entity.Tool) error {
+	engineImpl := tool.EngineInterface.EngineImpl
+	if payload, ok := implMap(engineImpl, "health_payload"); ok {
+		_, _, err := e.invoke(ctx, tool, payload, true)
+		return err
+	}
+
+	switch tool.EngineInterface.EngineInterfaceType {
+	case valueobject.EngineInterfaceAWSLambda:
+		function, err := lambdaFunction(engineImpl)
+		if err != nil {
+			return err
+		}
+		lambdaClient := e.lambdaClients.Client(function)
+		state, err := lambdaClient.GetFunction(ctx, function)
+		if err != nil {
+			return err
+		}
+		if *state != types.StateActive && *state != types.StateInactive {
+			return fmt.Errorf("function state is %s", *state)
+		}
+		return nil
+	case valueobject.EngineInterfaceHTTPServer:
+		healthURL, ok := implString(engineImpl, "health_url")
+		if !ok {
+			return fmt.Errorf("%w (health_url)", errHealthCheckNotConfigured)
+		}
+		headers, _ := implMap(engineImpl, "health_headers")
+		output, err := e.httpClient.Invoke(ctx, http_wrapper.InvokeInput{
+			Method: http.MethodGet,
+			URL:    healthURL,
+			Header: headers,
+		})
+		if err != nil {
+			return err
+		}
+		if output.StatusCode >= http.StatusBadRequest {
+			return fmt.Errorf("health url returned status %d: %s", output.StatusCode, truncate(string(output.Body), 200))
+		}
+		return nil
+	case valueobject.EngineInterfaceGRPC:
+		return e.probeGRPC(ctx, tool)
+	case valueobject.EngineInterfaceLocalExec:
+		if e.config == nil || !e.config.LocalExec.Enabled {
+			return errors.New("local-exec engine is disabled on this router (LOCAL_EXEC_ENABLED)")
+		}
+		_, err := e.localExecCommand(engineImpl)
+		return err
+	default:
+		return fmt.Errorf("%w for engine interface type %s", errHealthCheckNotConfigured,
+			tool.EngineInterface.EngineInterfaceType)
+	}
+}
+
+func (e *functionExecutor) probeGRPC(ctx context.Context, tool *entity.Tool) error {
+	engineImpl := tool.EngineInterface.EngineImpl
+	target, err := grpcTarget(engineImpl)
+	if err != nil {
+		return err
+	}
+	metadata := grpcMetadata(engineImpl)
+	service, _ := implString(engineImpl, "service")
+
+	status, err := e.grpcClient.CheckHealth(ctx, target, metadata, service)
+	var statusErr *grpc_wrapper.StatusError
+	if errors.As(err, &statusErr) && statusErr.Code == grpc_wrapper.CodeUnimplemented {
+		_, err := e.grpcMethod(ctx, tool, target, metadata)
+		return err
+	}
+	if err != nil {
+		return err
+	}
+	if status != grpc_wrapper.HealthStatusServing {
+		return fmt.Errorf("health service reports %s", status)
+	}
+	return nil
+}
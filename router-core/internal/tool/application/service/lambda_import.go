@@ -0,0 +1,348 @@
+package service
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	"aigendrug.com/router-core/internal/shared/jsonschema"
+	"aigendrug.com/router-core/internal/tool/application/dto"
+	"aigendrug.com/router-core/internal/tool/domain/entity"
+	"aigendrug.com/router-core/internal/tool/domain/shared_type"
+	"aigendrug.com/router-core/internal/tool/domain/valueobject"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/lambda/types"
+)
+
+// MaxLambdaFunctions bounds the functions listed by ListLambdaFunctions.
+// lambdaTagsConcurrency is the number of concurrent ListTags calls made while listing.
+const (
+	MaxLambdaFunctions    = 1000
+	lambdaTagsConcurrency = 8
+)
+
+var ErrLambdaDiscovery = errors.New("failed to list lambda functions")
+
+// Tags of a Lambda function read when it is imported as a tool. All of them are optional.
+// - "router:name", "router:version", "router:description": Tool name (defaults to the function name),
+// version (defaults to 1.0.0) and description (defaults to the description of the function).
+// - "router:invoke-type", "router:check-status-type": Engine interface invoke type (defaults to sync-wait)
+// and check status type (defaults to none).
+// - "router:request-interface", "router:response-interface": Scalar keys of the body, as "key:type" separated
+// by commas or spaces (e.g. "smiles:string, count:number?"). The type is string (default), number, boolean or file,
+// a trailing "?" marks an optional key.
+// - "router:request-schema", "router:response-schema": JSON Schema of the input / output, taking precedence over
+// the interfaces. A schema longer than a tag value (256 characters) is continued in "router:request-schema.1",
+// "router:request-schema.2", etc.
+const (
+	lambdaTagName              = "router:name"
+	lambdaTagVersion           = "router:version"
+	lambdaTagDescription       = "router:description"
+	lambdaTagInvokeType        = "router:invoke-type"
+	lambdaTagCheckStatusType   = "router:check-status-type"
+	lambdaTagRequestInterface  = "router:request-interface"
+	lambdaTagResponseInterface = "router:response-interface"
+	lambdaTagRequestSchema     = "router:request-schema"
+	lambdaTagResponseSchema    = "router:response-schema"
+)
+
+// ListLambdaFunctions lists the functions of the configured account with their tags,
+// and the tools already invoking each of them.
+func (s *toolService) ListLambdaFunctions(ctx context.Context) ([]*dto.ReadLambdaFunctionDTO, error) {
+	functions, err := s.lambdaClient.ListFunctions(ctx, MaxLambdaFunctions)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrLambdaDiscovery, err)
+	}
+	if len(functions) > MaxLambdaFunctions {
+		functions = functions[:MaxLambdaFunctions]
+	}
+
+	tools, err := s.toolRepo.FindAllTools(ctx)
+	if err != nil {
+		return nil, err
+	}
+	registered := registeredLambdaFunctions(tools)
+
+	functionsDTO := make([]*dto.ReadLambdaFunctionDTO, len(functions))
+	for i, function := range functions {
+		functionsDTO[i] = lambdaFunctionDTO(&function, registered)
+	}
+
+	// tags are not part of the listing, they are fetched per function
+	var wg sync.WaitGroup
+	semaphore := make(chan struct{}, lambdaTagsConcurrency)
+	for i, function := range functions {
+		if function.FunctionArn == nil {
+			continue
+		}
+		wg.Add(1)
+		semaphore <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+
+			tags, err := s.lambdaClient.ListTags(ctx, *function.FunctionArn)
+			if err != nil {
+				fmt.Printf("failed to list tags of lambda function %s: %v\n", *function.FunctionArn, err)
+				return
+			}
+			if tags != nil {
+				functionsDTO[i].Tags = tags
+			}
+		}()
+	}
+	wg.Wait()
+
+	return functionsDTO, nil
+}
+
+// ImportLambdaTools creates a draft tool for each function, described by its configuration and its "router:*" tags.
+// Functions already invoked by a tool, missing or with invalid tags are skipped.
+func (s *toolService) ImportLambdaTools(
+	ctx context.Context, request *dto.ImportLambdaToolsDTO,
+) (*dto.ImportLambdaToolsResultDTO, error) {
+	tools, err := s.toolRepo.FindAllTools(ctx)
+	if err != nil {
+		return nil, err
+	}
+	registered := registeredLambdaFunctions(tools)
+
+	result := &dto.ImportLambdaToolsResultDTO{
+		Created: []*dto.ReadToolDTO{},
+		Skipped: []dto.SkippedLambdaToolDTO{},
+	}
+	skip := func(functionName string, format string, args ...any) {
+		result.Skipped = append(result.Skipped, dto.SkippedLambdaToolDTO{
+			FunctionName: functionName,
+			Reason:       fmt.Sprintf(format, args...),
+		})
+	}
+
+	seen := map[string]bool{}
+	for _, functionName := range request.FunctionNames {
+		functionName = strings.TrimSpace(functionName)
+		if functionName == "" || seen[functionName] {
+			continue
+		}
+		seen[functionName] = true
+
+		configuration, tags, err := s.lambdaClient.DescribeFunction(ctx, functionName)
+		if err != nil {
+			var notFound *types.ResourceNotFoundException
+			if errors.As(err, &notFound) {
+				skip(functionName, "function not found")
+			} else {
+				skip(functionName, "failed to get function: %v", err)
+			}
+			continue
+		}
+
+		name := aws.ToString(configuration.FunctionName)
+		if toolIDs := registered[name]; len(toolIDs) > 0 {
+			skip(functionName, "already registered as tool %d", toolIDs[0])
+			continue
+		}
+
+		tool, err := lambdaToolDTO(configuration, tags)
+		if err != nil {
+			skip(functionName, "%v", err)
+			continue
+		}
+
+		createdTool, err := s.CreateTool(ctx, tool)
+		if err != nil {
+			skip(functionName, "failed to create tool: %v", err)
+			continue
+		}
+		registered[name] = append(registered[name], createdTool.ID)
+		result.Created = append(result.Created, createdTool)
+	}
+
+	return result, nil
+}
+
+// PublishTool turns a draft tool into a regular tool, offered to the clients it is permitted to.
+func (s *toolService) PublishTool(ctx context.Context, id int) error {
+	updated, err := s.toolRepo.UpdateToolDraft(ctx, id, false)
+	if err != nil {
+		return err
+	}
+	if !updated {
+		return ErrToolNotFound
+	}
+	return nil
+}
+
+// registeredLambdaFunctions maps function names to the IDs of the aws-lambda tools invoking them.
+func registeredLambdaFunctions(tools []*entity.Tool) map[string][]int {
+	registered := map[string][]int{}
+	for _, tool := range tools {
+		if tool.EngineInterface.EngineInterfaceType != valueobject.EngineInterfaceAWSLambda {
+			continue
+		}
+		functionName, ok := implString(tool.EngineInterface.EngineImpl, "function_name")
+		if !ok {
+			continue
+		}
+		name := lambdaFunctionName(functionName)
+		registered[name] = append(registered[name], tool.ID)
+	}
+	return registered
+}
+
+// lambdaFunctionName returns the name of a function given by its name, ARN or partial ARN, with or without qualifier.
+func lambdaFunctionName(function string) string {
+	if _, name, ok := strings.Cut(function, "function:"); ok {
+		function = name
+	}
+	name, _, _ := strings.Cut(function, ":")
+	return name
+}
+
+func lambdaFunctionDTO(function *types.FunctionConfiguration, registered map[string][]int) *dto.ReadLambdaFunctionDTO {
+	name := aws.ToString(function.FunctionName)
+	toolIDs := registered[name]
+	if toolIDs == nil {
+		toolIDs = []int{}
+	}
+
+	return &dto.ReadLambdaFunctionDTO{
+		FunctionName:   name,
+		FunctionARN:    aws.ToString(function.FunctionArn),
+		Description:    aws.ToString(function.Description),
+		Runtime:        string(function.Runtime),
+		Handler:        aws.ToString(function.Handler),
+		MemorySizeMB:   int(aws.ToInt32(function.MemorySize)),
+		TimeoutSeconds: int(aws.ToInt32(function.Timeout)),
+		LastModified:   aws.ToString(function.LastModified),
+		Tags:           map[string]string{},
+		ToolIDs:        toolIDs,
+	}
+}
+
+// lambdaToolDTO describes the draft tool of a function (see the "router:*" tags).
+func lambdaToolDTO(function *types.FunctionConfiguration, tags map[string]string) (*dto.CreateToolDTO, error) {
+	functionName := aws.ToString(function.FunctionName)
+
+	tool := &dto.CreateToolDTO{
+		Name:        cmp.Or(tags[lambdaTagName], functionName),
+		Version:     cmp.Or(tags[lambdaTagVersion], "1.0.0"),
+		Description: cmp.Or(tags[lambdaTagDescription], aws.ToString(function.Description)),
+		EngineInterface: shared_type.EngineInterface{
+			EngineInterfaceType: valueobject.EngineInterfaceAWSLambda,
+			EngineInterfaceInvokeType: valueobject.EngineInterfaceInvokeType(
+				cmp.Or(tags[lambdaTagInvokeType], string(valueobject.EngineInterfaceInvokeTypeSyncWait))),
+			EngineInterfaceCheckStatusType: valueobject.EngineInterfaceCheckStatusType(
+				cmp.Or(tags[lambdaTagCheckStatusType], string(valueobject.EngineInterfaceCheckStatusTypeNone))),
+			EngineImpl: map[string]any{"function_name": functionName},
+		},
+		ProviderInterface: shared_type.ProviderInterface{
+			AuthStrategy:        "none",
+			RequestMethod:       "POST",
+			RequestContentType:  "application/json",
+			ResponseContentType: "application/json",
+		},
+		Draft: true,
+	}
+
+	switch tool.EngineInterface.EngineInterfaceInvokeType {
+	case valueobject.EngineInterfaceInvokeTypeSyncWait, valueobject.EngineInterfaceInvokeTypeAsyncEvent:
+	default:
+		return nil, fmt.Errorf("invalid %s tag %q", lambdaTagInvokeType, tags[lambdaTagInvokeType])
+	}
+	switch tool.EngineInterface.EngineInterfaceCheckStatusType {
+	case valueobject.EngineInterfaceCheckStatusTypeNone, valueobject.EngineInterfaceCheckStatusTypeDelayed,
+		valueobject.EngineInterfaceCheckStatusTypePollHTTP, valueobject.EngineInterfaceCheckStatusTypeAWSS3Trigger:
+	default:
+		return nil, fmt.Errorf("invalid %s tag %q", lambdaTagCheckStatusType, tags[lambdaTagCheckStatusType])
+	}
+
+	var err error
+	provider := &tool.ProviderInterface
+	if provider.RequestInterface, err = lambdaTagInterface(tags, lambdaTagRequestInterface, "request"); err != nil {
+		return nil, err
+	}
+	if provider.ResponseInterface, err = lambdaTagInterface(tags, lambdaTagResponseInterface, "response"); err != nil {
+		return nil, err
+	}
+	if provider.RequestSchema, err = lambdaTagSchema(tags, lambdaTagRequestSchema); err != nil {
+		return nil, err
+	}
+	if provider.ResponseSchema, err = lambdaTagSchema(tags, lambdaTagResponseSchema); err != nil {
+		return nil, err
+	}
+
+	return tool, nil
+}
+
+// lambdaTagInterface parses an interface tag ("key:type" separated by commas or spaces).
+func lambdaTagInterface(tags map[string]string, tag string, idPrefix string) ([]shared_type.InterfaceElement, error) {
+	fields := strings.FieldsFunc(tags[tag], func(r rune) bool { return r == ',' || r == ' ' })
+	elements := make([]shared_type.InterfaceElement, 0, len(fields))
+	for _, field := range fields {
+		key, valueType, _ := strings.Cut(field, ":")
+		required := true
+		if trimmed, ok := strings.CutSuffix(valueType, "?"); ok {
+			valueType, required = trimmed, false
+		} else if trimmed, ok := strings.CutSuffix(key, "?"); ok {
+			key, required = trimmed, false
+		}
+		if valueType == "" {
+			valueType = "string"
+		}
+
+		htmlElementType, ok := lambdaHTMLElementTypes[valueType]
+		if key == "" || !ok {
+			return nil, fmt.Errorf("invalid %s tag: %q is not key:type (string, number, boolean or file)", tag, field)
+		}
+
+		elements = append(elements, shared_type.InterfaceElement{
+			ID:        idPrefix + "-" + key,
+			Type:      "body",
+			Required:  required,
+			Key:       key,
+			ValueType: valueType,
+			BindedElementType: shared_type.BindedElementType{
+				Label:           key,
+				HTMLElementType: htmlElementType,
+				ValueType:       valueType,
+			},
+		})
+	}
+	return elements, nil
+}
+
+var lambdaHTMLElementTypes = map[string]string{
+	"string":  "input-text",
+	"number":  "input-number",
+	"boolean": "checkbox",
+	"file":    "input-file",
+}
+
+// lambdaTagSchema parses a schema tag and its continuation tags (tag.1, tag.2, ...).
+func lambdaTagSchema(tags map[string]string, tag string) (*jsonschema.Schema, error) {
+	value, ok := tags[tag]
+	if !ok {
+		return nil, nil
+	}
+	var encoded strings.Builder
+	encoded.WriteString(value)
+	for i := 1; ; i++ {
+		part, ok := tags[tag+"."+strconv.Itoa(i)]
+		if !ok {
+			break
+		}
+		encoded.WriteString(part)
+	}
+
+	schema := &jsonschema.Schema{}
+	if err := json.Unmarshal([]byte(encoded.String()), schema); err != nil {
+		return nil, fmt.Errorf("invalid %s tag: %v", tag, err)
+	}
+	return schema, nil
+}
@@ -0,0 +1,305 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	exec_wrapper "aigendrug.com/router-core/internal/shared/exec-wrapper"
+	"aigendrug.com/router-core/internal/tool/domain/entity"
+	"aigendrug.com/router-core/internal/tool/domain/shared_type"
+	"aigendrug.com/router-core/internal/tool/domain/valueobject"
+)
+
+// Defaults of the local-exec engine.
+// Overridden by config (LOCAL_EXEC_*) and per tool by EngineImpl fields "max_output_bytes" and "max_stderr_bytes".
+const (
+	DefaultLocalExecWorkDir        = "/var/lib/router-core/local-exec"
+	DefaultLocalExecMaxOutputBytes = 10 << 20
+	DefaultLocalExecMaxStderrBytes = 64 << 10
+)
+
+// Input modes of local-exec tools ("input_mode").
+const (
+	// the payload is written to the stdin of the command
+	LocalExecInputModeStdin = "stdin"
+
+	// the payload is written to a file of the working directory, given by ROUTER_INPUT_FILE and "{input_file}"
+	LocalExecInputModeFile = "file"
+)
+
+const (
+	localExecInputFileName = "input.json"
+	localExecDefaultPath   = "/usr/local/sbin:/usr/local/bin:/usr/sbin:/usr/bin:/sbin:/bin"
+)
+
+// InvokeLocalExec runs the command of a local-exec tool on the router host and decodes its stdout as the
+// JSON result. The command is run synchronously whatever the invoke type of the tool.
+//
+// EngineImpl fields:
+// - "command": Path of the executable (looked up in the PATH of the router when it has no slash).
+// - "args": Arguments, as a JSON array. "{work_dir}" and "{input_file}" are replaced by their paths.
+// - "input_mode": stdin (default) or file.
+// - "env_allowlist": Variables of the router environment passed to the command.
+// - "env": Variables set for the command.
+// - "timeout_seconds": Wall clock limit, within the execution timeout of the tool.
+// - "cpu_seconds": CPU time limit.
+// - "max_output_bytes", "max_stderr_bytes": Output caps.
+//
+// Each run gets its own working directory, removed afterwards, which is also its HOME and TMPDIR.
+// Nothing else of the router environment is inherited (PATH defaults to the system directories).
+// The diagnostics (exit code, signal, stderr) are returned whether the run failed or not.
+func (e *functionExecutor) InvokeLocalExec(
+	ctx context.Context, tool *entity.Tool, payload map[string]any,
+) (map[string]any, *shared_type.InvocationDiagnostics, error) {
+	engineImpl := tool.EngineInterface.EngineImpl
+	if e.config == nil || !e.config.LocalExec.Enabled {
+		return nil, nil, newExecutionError(valueobject.ExecutionErrorClassConfiguration,
+			"local-exec engine is disabled on this router (LOCAL_EXEC_ENABLED)")
+	}
+
+	command, err := e.localExecCommand(engineImpl)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	inputMode := LocalExecInputModeStdin
+	if v, ok := implString(engineImpl, "input_mode"); ok {
+		inputMode = v
+	}
+	if inputMode != LocalExecInputModeStdin && inputMode != LocalExecInputModeFile {
+		return nil, nil, newExecutionError(valueobject.ExecutionErrorClassConfiguration,
+			"invalid input_mode %q (expected %s or %s)", inputMode, LocalExecInputModeStdin, LocalExecInputModeFile)
+	}
+
+	input, err := json.Marshal(payload)
+	if err != nil {
+		return nil, nil, newExecutionError(valueobject.ExecutionErrorClassClientError, "failed to encode payload: %v", err)
+	}
+
+	workDir, err := e.localExecWorkDir()
+	if err != nil {
+		return nil, nil, newExecutionError(valueobject.ExecutionErrorClassServerError,
+			"failed to create working directory: %v", err)
+	}
+	defer func() {
+		if err := os.RemoveAll(workDir); err != nil {
+			fmt.Printf("failed to remove local-exec working directory %s: %v\n", workDir, err)
+		}
+	}()
+
+	vars := map[string]string{"work_dir": workDir}
+	env := localExecEnv(engineImpl, workDir)
+
+	maxOutputBytes := localExecLimit(engineImpl, "max_output_bytes",
+		e.config.LocalExec.MaxOutputBytes, DefaultLocalExecMaxOutputBytes)
+	maxStderrBytes := localExecLimit(engineImpl, "max_stderr_bytes",
+		e.config.LocalExec.MaxStderrBytes, DefaultLocalExecMaxStderrBytes)
+
+	runInput := exec_wrapper.RunInput{
+		Command:        command,
+		Dir:            workDir,
+		MaxStdoutBytes: maxOutputBytes,
+		MaxStderrBytes: maxStderrBytes,
+	}
+
+	if inputMode == LocalExecInputModeFile {
+		inputFile := filepath.Join(workDir, localExecInputFileName)
+		if err := os.WriteFile(inputFile, input, 0o600); err != nil {
+			return nil, nil, newExecutionError(valueobject.ExecutionErrorClassServerError,
+				"failed to write input file: %v", err)
+		}
+		vars["input_file"] = inputFile
+		env = append(env, "ROUTER_INPUT_FILE="+inputFile)
+	} else {
+		runInput.Stdin = input
+	}
+	runInput.Env = env
+
+	args, _ := implStringSlice(engineImpl, "args")
+	for _, arg := range args {
+		runInput.Args = append(runInput.Args, renderTemplate(arg, vars))
+	}
+
+	if v, ok := implFloat(engineImpl, "cpu_seconds"); ok && v > 0 {
+		runInput.CPUSeconds = int(math.Ceil(v))
+	}
+
+	runCtx := ctx
+	if v, ok := implFloat(engineImpl, "timeout_seconds"); ok && v > 0 {
+		var cancel context.CancelFunc
+		runCtx, cancel = context.WithTimeout(ctx, secondsToDuration(v))
+		defer cancel()
+	}
+
+	output, err := e.execClient.Run(runCtx, runInput)
+	if err != nil {
+		return nil, nil, newExecutionError(valueobject.ExecutionErrorClassConfiguration, "%v", err)
+	}
+
+	diagnostics := &shared_type.InvocationDiagnostics{
+		Signal:          output.Signal,
+		Stderr:          strings.ToValidUTF8(string(output.Stderr), "�"),
+		StderrTruncated: output.StderrTruncated,
+		DurationMs:      output.Duration.Milliseconds(),
+		CPUTimeMs:       output.CPUTime.Milliseconds(),
+	}
+	if output.Signal == "" {
+		diagnostics.ExitCode = &output.ExitCode
+	}
+
+	result, err := localExecResult(ctx, command, runInput, output)
+	return result, diagnostics, err
+}
+
+// localExecResult decodes the output of a run, or classifies its failure.
+func localExecResult(
+	ctx context.Context, command string, runInput exec_wrapper.RunInput, output *exec_wrapper.RunOutput,
+) (map[string]any, error) {
+	switch {
+	case ctx.Err() != nil:
+		return nil, fmt.Errorf("command %s interrupted: %w", command, ctx.Err())
+	case output.ContextErr != nil:
+		return nil, newExecutionError(valueobject.ExecutionErrorClassTimeout,
+			"command %s exceeded its wall clock limit after %v", command, output.Duration.Round(time.Millisecond))
+	case output.CPULimitExceeded:
+		return nil, newExecutionError(valueobject.ExecutionErrorClassTimeout,
+			"command %s exceeded its CPU time limit of %ds", command, runInput.CPUSeconds)
+	case output.StdoutExceeded:
+		return nil, newExecutionError(valueobject.ExecutionErrorClassInvalidResponse,
+			"command %s output exceeds %d bytes", command, runInput.MaxStdoutBytes)
+	case output.Signal != "":
+		return nil, newExecutionError(valueobject.ExecutionErrorClassFunctionError,
+			"command %s killed by signal %s: %s", command, output.Signal, stderrTail(output.Stderr))
+	case output.ExitCode != 0:
+		return nil, newExecutionError(valueobject.ExecutionErrorClassFunctionError,
+			"command %s exited with code %d: %s", command, output.ExitCode, stderrTail(output.Stderr))
+	}
+
+	var result map[string]any
+	if err := json.Unmarshal(output.Stdout, &result); err != nil || result == nil {
+		if err == nil {
+			err = errors.New("not a JSON object")
+		}
+		return nil, newExecutionError(valueobject.ExecutionErrorClassInvalidResponse,
+			"command %s returned invalid output: %v", command, err)
+	}
+	return result, nil
+}
+
+// stderrTail returns the end of stderr, where commands usually report why they failed.
+func stderrTail(stderr []byte) string {
+	const max = 512
+
+	tail := strings.TrimSpace(string(stderr))
+	if tail == "" {
+		return "no stderr output"
+	}
+	if len(tail) > max {
+		tail = "..." + tail[len(tail)-max:]
+	}
+	return strings.ToValidUTF8(tail, "�")
+}
+
+// localExecCommand resolves the command of a local-exec tool, which must be located in one of the
+// command dirs of the router when those are configured.
+func (e *functionExecutor) localExecCommand(engineImpl map[string]any) (string, error) {
+	command, ok := implString(engineImpl, "command")
+	if !ok {
+		return "", newExecutionError(valueobject.ExecutionErrorClassConfiguration, "engine impl has no command")
+	}
+
+	path, err := exec.LookPath(command)
+	if err != nil {
+		return "", newExecutionError(valueobject.ExecutionErrorClassConfiguration, "command %s not found: %v", command, err)
+	}
+	if path, err = filepath.Abs(path); err == nil {
+		path, err = filepath.EvalSymlinks(path)
+	}
+	if err != nil {
+		return "", newExecutionError(valueobject.ExecutionErrorClassConfiguration, "command %s not found: %v", command, err)
+	}
+
+	commandDirs := strings.FieldsFunc(e.config.LocalExec.CommandDirs, func(r rune) bool { return r == ',' })
+	if len(commandDirs) == 0 {
+		return path, nil
+	}
+	for _, dir := range commandDirs {
+		dir, err := filepath.Abs(strings.TrimSpace(dir))
+		if err != nil {
+			continue
+		}
+		if resolved, err := filepath.EvalSymlinks(dir); err == nil {
+			dir = resolved
+		}
+		if rel, err := filepath.Rel(dir, path); err == nil && rel != ".." && !strings.HasPrefix(rel, "../") {
+			return path, nil
+		}
+	}
+	return "", newExecutionError(valueobject.ExecutionErrorClassConfiguration,
+		"command %s is not located in the allowed command dirs (LOCAL_EXEC_COMMAND_DIRS)", command)
+}
+
+// localExecWorkDir creates the working directory of a run.
+func (e *functionExecutor) localExecWorkDir() (string, error) {
+	root := e.config.LocalExec.WorkDir
+	if root == "" {
+		root = DefaultLocalExecWorkDir
+	}
+	if err := os.MkdirAll(root, 0o700); err != nil {
+		return "", err
+	}
+	return os.MkdirTemp(root, "run-")
+}
+
+// localExecLimit returns an output cap of the tool, or the cap configured for the router, or the default.
+func localExecLimit(engineImpl map[string]any, key string, configured int64, fallback int64) int64 {
+	if v, ok := implFloat(engineImpl, key); ok && v > 0 {
+		return int64(v)
+	}
+	if configured > 0 {
+		return configured
+	}
+	return fallback
+}
+
+// localExecEnv builds the environment of a run: the base variables, the allowlisted variables of the
+// router environment, then the variables set by the tool.
+func localExecEnv(engineImpl map[string]any, workDir string) []string {
+	vars := map[string]string{
+		"PATH":            localExecDefaultPath,
+		"HOME":            workDir,
+		"TMPDIR":          workDir,
+		"ROUTER_WORK_DIR": workDir,
+	}
+
+	allowlist, _ := implStringSlice(engineImpl, "env_allowlist")
+	for _, name := range allowlist {
+		if value, ok := os.LookupEnv(name); ok {
+			vars[name] = value
+		}
+	}
+
+	if fixed, ok := implMap(engineImpl, "env"); ok {
+		for name, value := range fixed {
+			if s, ok := value.(string); ok {
+				vars[name] = s
+			} else {
+				vars[name] = fmt.Sprint(value)
+			}
+		}
+	}
+
+	env := make([]string, 0, len(vars))
+	for name, value := range vars {
+		env = append(env, name+"="+value)
+	}
+	return env
+}
@@ -0,0 +1,197 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"aigendrug.com/router-core/internal/shared/database/postgres"
+	"aigendrug.com/router-core/internal/tool/domain/entity"
+	"aigendrug.com/router-core/internal/tool/domain/valueobject"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ToolRequestEventChannel is the Postgres NOTIFY channel carrying tool request events between replicas.
+const ToolRequestEventChannel = "tool_request_events"
+
+type ToolRequestEventType string
+
+const (
+	// the status of the tool request changed
+	ToolRequestEventTypeStatus ToolRequestEventType = "status"
+
+	// the tool request made progress without changing its status (retry, status check, etc.)
+	ToolRequestEventTypeProgress ToolRequestEventType = "progress"
+)
+
+// ToolRequestEvent reports a change of a tool request.
+// Events are kept small (no payload) to fit into a NOTIFY, subscribers read the tool request when they need it.
+type ToolRequestEvent struct {
+	Type          ToolRequestEventType          `json:"type"`
+	ToolRequestID int                           `json:"tool_request_id"`
+	ClientID      int                           `json:"client_id"`
+	Status        valueobject.ToolRequestStatus `json:"status"`
+	Message       string                        `json:"message,omitempty"`
+}
+
+func newToolRequestEvent(
+	eventType ToolRequestEventType, toolRequest *entity.ToolRequest, message string,
+) ToolRequestEvent {
+	return ToolRequestEvent{
+		Type:          eventType,
+		ToolRequestID: toolRequest.ID,
+		ClientID:      toolRequest.ClientID,
+		Status:        toolRequest.Status,
+		Message:       message,
+	}
+}
+
+// ToolRequestNotifier fans events of tool requests out to the subscribers of every replica,
+// so that callers waiting for a tool request are woken up instead of polling the database.
+//
+// Events are published through Postgres NOTIFY and delivered to local subscribers by the LISTEN loop of each replica.
+type ToolRequestNotifier interface {
+	// Start LISTENs to the events published by every replica until ctx is done.
+	Start(ctx context.Context)
+
+	// Subscribe returns a channel receiving the events of the tool request and a function releasing it.
+	// Events are dropped when the subscriber does not keep up, the channel only signals that the request changed.
+	Subscribe(toolRequestID int) (<-chan ToolRequestEvent, func())
+
+	// SubscribeClient is Subscribe for every tool request of the client.
+	SubscribeClient(clientID int) (<-chan ToolRequestEvent, func())
+
+	Publish(event ToolRequestEvent)
+
+	// OnFinished registers a hook called once a tool request reaches a terminal status.
+	// Hooks run on the replica publishing the status, outside of the caller's goroutine.
+	OnFinished(hook func(ctx context.Context, toolRequestID int))
+}
+
+type subscriptionKey struct {
+	client bool
+	id     int
+}
+
+type toolRequestNotifier struct {
+	db *pgxpool.Pool
+
+	mu            sync.Mutex
+	subscribers   map[subscriptionKey]map[chan ToolRequestEvent]struct{}
+	finishedHooks []func(ctx context.Context, toolRequestID int)
+}
+
+// NewToolRequestNotifier returns a notifier fanning events out through db.
+// Without db, events are only delivered to the subscribers of this replica.
+func NewToolRequestNotifier(db *pgxpool.Pool) ToolRequestNotifier {
+	return &toolRequestNotifier{
+		db:          db,
+		subscribers: make(map[subscriptionKey]map[chan ToolRequestEvent]struct{}),
+	}
+}
+
+func (n *toolRequestNotifier) Start(ctx context.Context) {
+	if n.db == nil {
+		return
+	}
+
+	go postgres.Listen(ctx, n.db, ToolRequestEventChannel, func(payload string) {
+		var event ToolRequestEvent
+		if err := json.Unmarshal([]byte(payload), &event); err != nil {
+			fmt.Printf("invalid tool request event %q: %v\n", payload, err)
+			return
+		}
+		n.dispatch(event)
+	})
+}
+
+func (n *toolRequestNotifier) Subscribe(toolRequestID int) (<-chan ToolRequestEvent, func()) {
+	return n.subscribe(subscriptionKey{id: toolRequestID})
+}
+
+func (n *toolRequestNotifier) SubscribeClient(clientID int) (<-chan ToolRequestEvent, func()) {
+	return n.subscribe(subscriptionKey{client: true, id: clientID})
+}
+
+func (n *toolRequestNotifier) subscribe(key subscriptionKey) (<-chan ToolRequestEvent, func()) {
+	ch := make(chan ToolRequestEvent, 16)
+
+	n.mu.Lock()
+	if n.subscribers[key] == nil {
+		n.subscribers[key] = make(map[chan ToolRequestEvent]struct{})
+	}
+	n.subscribers[key][ch] = struct{}{}
+	n.mu.Unlock()
+
+	var once sync.Once
+	unsubscribe := func() {
+		once.Do(func() {
+			n.mu.Lock()
+			delete(n.subscribers[key], ch)
+			if len(n.subscribers[key]) == 0 {
+				delete(n.subscribers, key)
+			}
+			n.mu.Unlock()
+		})
+	}
+
+	return ch, unsubscribe
+}
+
+func (n *toolRequestNotifier) OnFinished(hook func(ctx context.Context, toolRequestID int)) {
+	n.mu.Lock()
+	n.finishedHooks = append(n.finishedHooks, hook)
+	n.mu.Unlock()
+}
+
+// Publish sends the event to every replica. When NOTIFY fails the event is still delivered locally.
+func (n *toolRequestNotifier) Publish(event ToolRequestEvent) {
+	if event.Type == ToolRequestEventTypeStatus && event.Status.IsTerminal() {
+		n.runFinishedHooks(event.ToolRequestID)
+	}
+
+	if n.db != nil {
+		payload, err := json.Marshal(event)
+		if err == nil {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			err = postgres.Notify(ctx, n.db, ToolRequestEventChannel, string(payload))
+			cancel()
+		}
+		if err == nil {
+			return
+		}
+		fmt.Printf("failed to notify tool request event: %v\n", err)
+	}
+
+	n.dispatch(event)
+}
+
+func (n *toolRequestNotifier) runFinishedHooks(toolRequestID int) {
+	n.mu.Lock()
+	hooks := append([]func(ctx context.Context, toolRequestID int){}, n.finishedHooks...)
+	n.mu.Unlock()
+
+	for _, hook := range hooks {
+		go func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			defer cancel()
+			hook(ctx, toolRequestID)
+		}()
+	}
+}
+
+func (n *toolRequestNotifier) dispatch(event ToolRequestEvent) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	for _, key := range []subscriptionKey{{id: event.ToolRequestID}, {client: true, id: event.ClientID}} {
+		for ch := range n.subscribers[key] {
+			select {
+			case ch <- event:
+			default:
+			}
+		}
+	}
+}
@@ -0,0 +1,184 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"aigendrug.com/router-core/internal/shared/jsonschema"
+	"aigendrug.com/router-core/internal/tool/domain/shared_type"
+)
+
+// OutputMappingError is returned when the provider response does not satisfy the tool's response schema.
+type OutputMappingError struct {
+	Problems []string
+}
+
+func (e *OutputMappingError) Error() string {
+	return "provider response does not match the response schema: " + strings.Join(e.Problems, "; ")
+}
+
+// mapOutput extracts the properties declared by the tool's response schema (its ResponseSchema or the schema
+// of its ResponseInterface elements) from the raw provider response, coerces scalar properties to their
+// declared type and validates the result, so every tool exposes a stable output contract.
+//
+// Body properties are read from the top level of the response, or from its "body" when the provider answered
+// with a proxy-style document ({"statusCode", "headers", "body"}); header properties (x-location: header)
+// are read from the headers copied into the response by the http-server engine, or from its "headers".
+//
+// Undeclared keys are dropped unless the schema allows additional properties explicitly.
+// A tool declaring no response schema returns the raw response as is.
+func mapOutput(providerInterface shared_type.ProviderInterface, raw map[string]any) (map[string]any, error) {
+	schema := providerInterface.ResolvedResponseSchema()
+	if schema == nil {
+		return raw, nil
+	}
+
+	body := raw
+	if proxyBody, ok := proxyResponseBody(raw); ok {
+		body = proxyBody
+	}
+	if body == nil {
+		body = map[string]any{}
+	}
+
+	output := make(map[string]any, len(schema.Properties))
+	if schema.AdditionalProperties != nil && schema.AdditionalProperties.Allowed {
+		for key, value := range body {
+			if _, declared := schema.Properties[key]; !declared {
+				output[key] = value
+			}
+		}
+	}
+
+	var problems []string
+	for key, property := range schema.Properties {
+		var value any
+		var ok bool
+		if schema.PropertyLocation(key) == jsonschema.LocationHeader {
+			value, ok = responseHeader(raw, key)
+		} else {
+			value, ok = body[key]
+		}
+		if !ok {
+			continue
+		}
+
+		coerced, err := coerceValue(value, property)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("output %s: %v", key, err))
+			continue
+		}
+		output[key] = coerced
+	}
+
+	for _, violation := range schema.Validate(output) {
+		problems = append(problems, violation.Message)
+	}
+
+	if len(problems) > 0 {
+		sort.Strings(problems)
+		return nil, &OutputMappingError{Problems: problems}
+	}
+	return output, nil
+}
+
+// proxyResponseBody returns the decoded "body" of a proxy-style response (Lambda behind API Gateway, etc.).
+func proxyResponseBody(raw map[string]any) (map[string]any, bool) {
+	if _, ok := raw["statusCode"]; !ok {
+		return nil, false
+	}
+
+	switch body := raw["body"].(type) {
+	case map[string]any:
+		return body, true
+	case string:
+		decoded := map[string]any{}
+		if err := json.Unmarshal([]byte(body), &decoded); err != nil {
+			return nil, false
+		}
+		return decoded, true
+	default:
+		return nil, false
+	}
+}
+
+// responseHeader looks a header output up case-insensitively.
+func responseHeader(raw map[string]any, key string) (any, bool) {
+	if value, ok := raw[key]; ok {
+		return value, true
+	}
+
+	headers, ok := raw["headers"].(map[string]any)
+	if !ok {
+		return nil, false
+	}
+	for name, value := range headers {
+		if strings.EqualFold(name, key) {
+			return value, true
+		}
+	}
+	return nil, false
+}
+
+// coerceValue converts a decoded JSON value to the scalar type declared by the property schema
+// (e.g. "42" to 42 for a number). Values which already match, null, and properties without
+// a single scalar type are returned unchanged, the schema validation reports them.
+func coerceValue(value any, property *jsonschema.Schema) (any, error) {
+	if value == nil || property == nil {
+		return value, nil
+	}
+
+	var target string
+	for _, typ := range property.Type {
+		if typ == jsonschema.TypeNull {
+			continue
+		}
+		if target != "" {
+			return value, nil
+		}
+		target = typ
+	}
+
+	actual := jsonschema.TypeOf(value)
+	if actual == target || (target == jsonschema.TypeInteger && actual == jsonschema.TypeNumber) {
+		return value, nil
+	}
+
+	switch target {
+	case jsonschema.TypeString:
+		switch v := value.(type) {
+		case bool:
+			return strconv.FormatBool(v), nil
+		case float64:
+			return strconv.FormatFloat(v, 'f', -1, 64), nil
+		case json.Number:
+			return v.String(), nil
+		}
+	case jsonschema.TypeNumber, jsonschema.TypeInteger:
+		if v, ok := value.(string); ok {
+			number, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+			if err == nil && !math.IsNaN(number) && !math.IsInf(number, 0) {
+				return number, nil
+			}
+		}
+	case jsonschema.TypeBoolean:
+		switch v := value.(type) {
+		case string:
+			if boolean, err := strconv.ParseBool(strings.TrimSpace(v)); err == nil {
+				return boolean, nil
+			}
+		case float64:
+			if v == 0 || v == 1 {
+				return v == 1, nil
+			}
+		}
+	default:
+		return value, nil
+	}
+
+	return nil, fmt.Errorf("cannot convert %s %v to %s", actual, truncate(fmt.Sprint(value), 64), target)
+}
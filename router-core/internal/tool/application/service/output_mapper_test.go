@@ -0,0 +1,176 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"aigendrug.com/router-core/internal/shared/jsonschema"
+	"aigendrug.com/router-core/internal/tool/domain/shared_type"
+)
+
+func decodeObject(t *testing.T, source string) map[string]any {
+	t.Helper()
+	if source == "" {
+		return nil
+	}
+	var object map[string]any
+	if err := json.Unmarshal([]byte(source), &object); err != nil {
+		t.Fatalf("invalid JSON object %s: %v", source, err)
+	}
+	return object
+}
+
+func TestMapOutput(t *testing.T) {
+	responseInterface := []shared_type.InterfaceElement{
+		{Key: "score", Type: "body", Required: true, ValueType: "number"},
+		{Key: "label", Type: "body", ValueType: "string"},
+		{Key: "X-Request-Id", Type: "header", ValueType: "string"},
+	}
+
+	tests := []struct {
+		name              string
+		responseSchema    string
+		responseInterface []shared_type.InterfaceElement
+		raw               string
+		want              string
+		wantProblems      []string
+	}{
+		{
+			name: "no response schema returns the raw response",
+			raw:  `{"anything": [1, 2]}`,
+			want: `{"anything": [1, 2]}`,
+		},
+		{
+			name:              "undeclared keys are dropped",
+			responseInterface: responseInterface,
+			raw:               `{"score": 0.9, "label": "active", "debug": true}`,
+			want:              `{"score": 0.9, "label": "active"}`,
+		},
+		{
+			name:              "scalars are coerced to their declared type",
+			responseInterface: responseInterface,
+			raw:               `{"score": " 0.25 ", "label": 3}`,
+			want:              `{"score": 0.25, "label": "3"}`,
+		},
+		{
+			name:              "proxy response body",
+			responseInterface: responseInterface,
+			raw:               `{"statusCode": 200, "headers": {"x-request-id": "abc"}, "body": "{\"score\": 1}"}`,
+			want:              `{"score": 1, "X-Request-Id": "abc"}`,
+		},
+		{
+			name:              "header copied at the top level",
+			responseInterface: responseInterface,
+			raw:               `{"score": 1, "X-Request-Id": "abc"}`,
+			want:              `{"score": 1, "X-Request-Id": "abc"}`,
+		},
+		{
+			name:              "optional outputs accept null",
+			responseInterface: responseInterface,
+			raw:               `{"score": 1, "label": null}`,
+			want:              `{"score": 1, "label": null}`,
+		},
+		{
+			name:              "missing required output",
+			responseInterface: responseInterface,
+			raw:               `{"label": "active"}`,
+			wantProblems:      []string{"score is required"},
+		},
+		{
+			name:              "value which can not be coerced",
+			responseInterface: responseInterface,
+			raw:               `{"score": "high"}`,
+			wantProblems:      []string{"output score: cannot convert string high to number", "score is required"},
+		},
+		{
+			name:           "additional properties kept when allowed",
+			responseSchema: `{"type": "object", "properties": {"n": {"type": "integer"}}, "additionalProperties": true}`,
+			raw:            `{"n": "4", "extra": {"a": 1}}`,
+			want:           `{"n": 4, "extra": {"a": 1}}`,
+		},
+		{
+			name:           "nested values validated against the schema",
+			responseSchema: `{"type": "object", "properties": {"items": {"type": "array", "items": {"type": "string"}}}}`,
+			raw:            `{"items": ["a", 2]}`,
+			wantProblems:   []string{"items[1] must be a string, got number"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			providerInterface := shared_type.ProviderInterface{ResponseInterface: tt.responseInterface}
+			if tt.responseSchema != "" {
+				providerInterface.ResponseSchema = &jsonschema.Schema{}
+				if err := json.Unmarshal([]byte(tt.responseSchema), providerInterface.ResponseSchema); err != nil {
+					t.Fatalf("invalid response schema: %v", err)
+				}
+			}
+
+			output, err := mapOutput(providerInterface, decodeObject(t, tt.raw))
+			if tt.wantProblems != nil {
+				var mappingErr *OutputMappingError
+				if !errors.As(err, &mappingErr) {
+					t.Fatalf("mapOutput() error = %v, want an OutputMappingError", err)
+				}
+				if !reflect.DeepEqual(mappingErr.Problems, tt.wantProblems) {
+					t.Fatalf("mapOutput() problems = %q, want %q", mappingErr.Problems, tt.wantProblems)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("mapOutput() error = %v", err)
+			}
+			if want := decodeObject(t, tt.want); !reflect.DeepEqual(output, want) {
+				t.Fatalf("mapOutput() = %v, want %v", output, want)
+			}
+		})
+	}
+}
+
+func TestCoerceValue(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   any
+		types   jsonschema.Types
+		want    any
+		wantErr string
+	}{
+		{name: "number to string", value: 1.5, types: jsonschema.Types{"string"}, want: "1.5"},
+		{name: "large number to string", value: 1e21, types: jsonschema.Types{"string"}, want: "1000000000000000000000"},
+		{name: "boolean to string", value: true, types: jsonschema.Types{"string"}, want: "true"},
+		{name: "string to number", value: "42", types: jsonschema.Types{"number"}, want: 42.0},
+		{name: "string to integer", value: "7", types: jsonschema.Types{"integer"}, want: 7.0},
+		{name: "number kept for integer", value: 2.5, types: jsonschema.Types{"integer"}, want: 2.5},
+		{name: "string to boolean", value: "FALSE", types: jsonschema.Types{"boolean"}, want: false},
+		{name: "one to boolean", value: 1.0, types: jsonschema.Types{"boolean"}, want: true},
+		{name: "nullable type", value: "3", types: jsonschema.Types{"number", "null"}, want: 3.0},
+		{name: "null unchanged", value: nil, types: jsonschema.Types{"number"}, want: nil},
+		{name: "several types unchanged", value: "3", types: jsonschema.Types{"number", "boolean"}, want: "3"},
+		{name: "no type unchanged", value: "3", want: "3"},
+		{name: "object type unchanged", value: "3", types: jsonschema.Types{"object"}, want: "3"},
+		{name: "NaN refused", value: "NaN", types: jsonschema.Types{"number"}, wantErr: "cannot convert string NaN to number"},
+		{name: "two to boolean refused", value: 2.0, types: jsonschema.Types{"boolean"}, wantErr: "cannot convert number 2 to boolean"},
+		{name: "object to string refused", value: map[string]any{}, types: jsonschema.Types{"string"}, wantErr: "cannot convert object"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := coerceValue(tt.value, &jsonschema.Schema{Type: tt.types})
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("coerceValue() error = %v, want containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("coerceValue() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("coerceValue() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
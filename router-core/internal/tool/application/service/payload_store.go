@@ -0,0 +1,168 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"aigendrug.com/router-core/internal/config"
+	"aigendrug.com/router-core/internal/shared/blobstore"
+	"aigendrug.com/router-core/internal/tool/domain/entity"
+	"aigendrug.com/router-core/internal/tool/domain/shared_type"
+	"aigendrug.com/router-core/internal/tool/domain/valueobject"
+	"github.com/google/uuid"
+)
+
+// DefaultPayloadOffloadThreshold is the encoded size above which a payload is moved to the blob store.
+// Overridden by config (BLOB_STORE_OFFLOAD_THRESHOLD_BYTES).
+const DefaultPayloadOffloadThreshold = 256 << 10
+
+// Parts of a tool request holding a payload, downloaded with GET /v1/tool-requests/{id}/payload/{part}.
+const (
+	PayloadPartRequest     = "request"
+	PayloadPartResponse    = "response"
+	PayloadPartRawResponse = "raw_response"
+)
+
+// PayloadStore moves the payloads of tool requests which exceed the offload threshold to the blob store,
+// so that tool_requests rows (and the list endpoints reading them) stay small.
+// The tool request keeps a BlobReference in place of the payload.
+//
+// Without a blob store, payloads are kept inline whatever their size.
+type PayloadStore struct {
+	blobStore blobstore.BlobStore
+	threshold int
+}
+
+func NewPayloadStore(config *config.Config, blobStore blobstore.BlobStore) *PayloadStore {
+	p := &PayloadStore{
+		blobStore: blobStore,
+		threshold: DefaultPayloadOffloadThreshold,
+	}
+	if config != nil && config.BlobStore.OffloadThresholdBytes > 0 {
+		p.threshold = config.BlobStore.OffloadThresholdBytes
+	}
+	return p
+}
+
+// offload stores the payload when its encoding exceeds the threshold and returns its reference,
+// or nil when the payload stays inline. A payload which cannot be stored stays inline as well.
+func (p *PayloadStore) offload(ctx context.Context, clientID int, part string, payload map[string]any) *shared_type.BlobReference {
+	if p == nil || p.blobStore == nil || payload == nil {
+		return nil
+	}
+
+	encoded, err := json.Marshal(payload)
+	if err != nil || len(encoded) <= p.threshold {
+		return nil
+	}
+
+	key := fmt.Sprintf("payloads/%d/%s/%s.json", clientID, uuid.NewString(), part)
+	object, err := p.blobStore.Put(ctx, key, bytes.NewReader(encoded), "application/json")
+	if err != nil {
+		fmt.Printf("failed to offload %s payload (%d bytes), keeping it inline: %v\n", part, len(encoded), err)
+		return nil
+	}
+
+	return &shared_type.BlobReference{
+		Backend: p.blobStore.Backend(),
+		Key:     object.Key,
+		Size:    object.Size,
+		SHA256:  object.SHA256,
+	}
+}
+
+// offloadRequest moves the payload of the tool request to the blob store when it is too large.
+func (p *PayloadStore) offloadRequest(ctx context.Context, toolRequest *entity.ToolRequest) {
+	if ref := p.offload(ctx, toolRequest.ClientID, PayloadPartRequest, toolRequest.RequestData.Payload); ref != nil {
+		toolRequest.RequestData.Payload = nil
+		toolRequest.RequestData.PayloadRef = ref
+	}
+}
+
+// offloadResponse is offloadRequest for the mapped and raw payloads of the response.
+func (p *PayloadStore) offloadResponse(ctx context.Context, toolRequest *entity.ToolRequest) {
+	responseData := &toolRequest.ResponseData
+	if ref := p.offload(ctx, toolRequest.ClientID, PayloadPartResponse, responseData.Payload); ref != nil {
+		responseData.Payload = nil
+		responseData.PayloadRef = ref
+	}
+	if ref := p.offload(ctx, toolRequest.ClientID, PayloadPartRawResponse, responseData.RawPayload); ref != nil {
+		responseData.RawPayload = nil
+		responseData.RawPayloadRef = ref
+	}
+}
+
+// open streams an offloaded payload.
+func (p *PayloadStore) open(ctx context.Context, ref *shared_type.BlobReference) (io.ReadCloser, error) {
+	if p == nil || p.blobStore == nil {
+		return nil, ErrBlobStoreUnavailable
+	}
+	return p.blobStore.Open(ctx, ref.Key)
+}
+
+// load reads an offloaded payload back.
+func (p *PayloadStore) load(ctx context.Context, ref *shared_type.BlobReference) (map[string]any, error) {
+	body, err := p.open(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	var payload map[string]any
+	if err := json.NewDecoder(body).Decode(&payload); err != nil {
+		return nil, fmt.Errorf("invalid offloaded payload %s: %w", ref.Key, err)
+	}
+	return payload, nil
+}
+
+// delete removes offloaded payloads, failures are only logged (the blobs are orphaned).
+func (p *PayloadStore) delete(ctx context.Context, refs ...*shared_type.BlobReference) {
+	if p == nil || p.blobStore == nil {
+		return
+	}
+	for _, ref := range refs {
+		if ref == nil {
+			continue
+		}
+		if err := p.blobStore.Delete(ctx, ref.Key); err != nil {
+			fmt.Printf("failed to delete offloaded payload %s: %v\n", ref.Key, err)
+		}
+	}
+}
+
+// responseRefs returns the references of the offloaded response payloads of the tool request.
+func responseRefs(toolRequest *entity.ToolRequest) []*shared_type.BlobReference {
+	return []*shared_type.BlobReference{toolRequest.ResponseData.PayloadRef, toolRequest.ResponseData.RawPayloadRef}
+}
+
+// invocationPayload returns the payload given to the tool: the stored payload with its artifacts resolved.
+func (e *functionExecutor) invocationPayload(ctx context.Context, toolRequest *entity.ToolRequest) (map[string]any, error) {
+	payload, err := e.storedPayload(ctx, toolRequest)
+	if err != nil {
+		return nil, err
+	}
+	return e.resolveArtifacts(ctx, toolRequest, payload)
+}
+
+// storedPayload returns the payload of the tool request, read back from the blob store when it was offloaded.
+func (e *functionExecutor) storedPayload(ctx context.Context, toolRequest *entity.ToolRequest) (map[string]any, error) {
+	ref := toolRequest.RequestData.PayloadRef
+	if ref == nil {
+		return toolRequest.RequestData.Payload, nil
+	}
+
+	payload, err := e.payloadStore.load(ctx, ref)
+	if err != nil {
+		if errors.Is(err, blobstore.ErrNotFound) || errors.Is(err, ErrBlobStoreUnavailable) {
+			return nil, newExecutionError(valueobject.ExecutionErrorClassConfiguration,
+				"offloaded payload %s is not available: %v", ref.Key, err)
+		}
+		return nil, newExecutionError(valueobject.ExecutionErrorClassServerError,
+			"failed to load offloaded payload %s: %v", ref.Key, err)
+	}
+	return payload, nil
+}
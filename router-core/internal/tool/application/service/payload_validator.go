@@ -0,0 +1,99 @@
+package service
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"aigendrug.com/router-core/internal/shared/jsonschema"
+	"aigendrug.com/router-core/internal/tool/application/dto"
+	"aigendrug.com/router-core/internal/tool/domain/shared_type"
+)
+
+// PayloadValidationError is returned by ExecuteTool when the payload does not match the tool's request schema.
+type PayloadValidationError struct {
+	Errors []*dto.PayloadFieldErrorDTO
+}
+
+func (e *PayloadValidationError) Error() string {
+	messages := make([]string, len(e.Errors))
+	for i, fieldErr := range e.Errors {
+		messages[i] = fieldErr.Message
+	}
+	return "invalid payload: " + strings.Join(messages, "; ")
+}
+
+func (e *PayloadValidationError) ToDTO() *dto.PayloadValidationErrorDTO {
+	return &dto.PayloadValidationErrorDTO{
+		Msg:    fmt.Sprintf("payload does not match the request schema of the tool (%d errors)", len(e.Errors)),
+		Errors: e.Errors,
+	}
+}
+
+// validatePayload checks the payload against the request schema of the tool
+// (its RequestSchema or the schema of its RequestInterface elements) and reports every violation at once.
+// A tool declaring neither accepts any payload.
+//
+// The value of a file input must be the reference of one of the artifacts planned for the request
+// (see planArtifacts): any other string (a path, a URL, a forged reference) would be read as a blob of the store.
+func validatePayload(
+	providerInterface shared_type.ProviderInterface, payload map[string]any, artifacts []shared_type.ToolRequestArtifact,
+) error {
+	schema := providerInterface.ResolvedRequestSchema()
+	if schema == nil {
+		return nil
+	}
+
+	if payload == nil {
+		payload = map[string]any{}
+	}
+
+	violations := schema.Validate(payload)
+	fieldErrors := make([]*dto.PayloadFieldErrorDTO, 0, len(violations))
+	for _, violation := range violations {
+		fieldErrors = append(fieldErrors, &dto.PayloadFieldErrorDTO{
+			Field:    violation.Path,
+			Code:     violation.Code,
+			Expected: violation.Expected,
+			Actual:   violation.Actual,
+			Message:  violation.Message,
+		})
+	}
+	fieldErrors = append(fieldErrors, fileInputErrors(schema, payload, artifacts)...)
+
+	if len(fieldErrors) == 0 {
+		return nil
+	}
+	return &PayloadValidationError{Errors: fieldErrors}
+}
+
+// fileInputErrors reports the file inputs of the payload which do not reference a file uploaded with the request.
+func fileInputErrors(
+	schema *jsonschema.Schema, payload map[string]any, artifacts []shared_type.ToolRequestArtifact,
+) []*dto.PayloadFieldErrorDTO {
+	var fieldErrors []*dto.PayloadFieldErrorDTO
+	for _, field := range slices.Sorted(maps.Keys(payload)) {
+		// values of another type are reported by the schema
+		reference, ok := payload[field].(string)
+		if !ok || !schema.IsFileProperty(field) {
+			continue
+		}
+
+		uploaded := slices.ContainsFunc(artifacts, func(artifact shared_type.ToolRequestArtifact) bool {
+			return artifact.Field == field && reference == artifact.Reference()
+		})
+		if uploaded {
+			continue
+		}
+
+		fieldErrors = append(fieldErrors, &dto.PayloadFieldErrorDTO{
+			Field:    field,
+			Code:     jsonschema.CodeTypeMismatch,
+			Expected: jsonschema.FormatFile,
+			Actual:   jsonschema.TypeString,
+			Message:  fmt.Sprintf("%s must be uploaded as a file", field),
+		})
+	}
+	return fieldErrors
+}
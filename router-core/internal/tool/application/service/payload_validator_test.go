@@ -0,0 +1,97 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"aigendrug.com/router-core/internal/shared/jsonschema"
+	"aigendrug.com/router-core/internal/tool/domain/shared_type"
+)
+
+func TestValidatePayloadFileInputs(t *testing.T) {
+	var schema jsonschema.Schema
+	if err := json.Unmarshal([]byte(`{
+		"type": "object",
+		"properties": {
+			"structure": {"type": "string", "format": "file"},
+			"ligand": {"$ref": "#/$defs/file"},
+			"name": {"type": "string"}
+		},
+		"$defs": {"file": {"type": "string", "format": "file"}}
+	}`), &schema); err != nil {
+		t.Fatal(err)
+	}
+	providerInterface := shared_type.ProviderInterface{RequestSchema: &schema}
+
+	uploaded := shared_type.ToolRequestArtifact{Field: "structure", Key: "tool-requests/1/upload/structure/1abc.pdb"}
+	other := shared_type.ToolRequestArtifact{Field: "ligand", Key: "tool-requests/1/upload/ligand/ligand.sdf"}
+
+	tests := []struct {
+		name       string
+		payload    map[string]any
+		artifacts  []shared_type.ToolRequestArtifact
+		wantFields []string
+	}{
+		{
+			name:      "reference of an uploaded file",
+			payload:   map[string]any{"structure": uploaded.Reference(), "name": "run"},
+			artifacts: []shared_type.ToolRequestArtifact{uploaded},
+		},
+		{
+			name:    "absent file input",
+			payload: map[string]any{"name": "/etc/passwd"},
+		},
+		{
+			name:       "local path",
+			payload:    map[string]any{"structure": "/etc/passwd"},
+			wantFields: []string{"structure"},
+		},
+		{
+			name:       "URL",
+			payload:    map[string]any{"structure": "http://169.254.169.254/latest/meta-data"},
+			wantFields: []string{"structure"},
+		},
+		{
+			name:       "forged reference",
+			payload:    map[string]any{"structure": uploaded.Reference()},
+			wantFields: []string{"structure"},
+		},
+		{
+			name:       "reference of the file of another input",
+			payload:    map[string]any{"structure": uploaded.Reference(), "ligand": uploaded.Reference()},
+			artifacts:  []shared_type.ToolRequestArtifact{uploaded, other},
+			wantFields: []string{"ligand"},
+		},
+		{
+			name:       "file input declared through a reference",
+			payload:    map[string]any{"ligand": "s3://bucket/ligand.sdf"},
+			wantFields: []string{"ligand"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validatePayload(providerInterface, tt.payload, tt.artifacts)
+			if len(tt.wantFields) == 0 {
+				if err != nil {
+					t.Fatalf("validatePayload() error = %v", err)
+				}
+				return
+			}
+
+			var validationErr *PayloadValidationError
+			if !errors.As(err, &validationErr) {
+				t.Fatalf("validatePayload() error = %v, want a PayloadValidationError", err)
+			}
+			if len(validationErr.Errors) != len(tt.wantFields) {
+				t.Fatalf("validatePayload() errors = %v, want errors on %v", err, tt.wantFields)
+			}
+			for i, field := range tt.wantFields {
+				if fieldErr := validationErr.Errors[i]; fieldErr.Field != field || fieldErr.Expected != jsonschema.FormatFile {
+					t.Fatalf("error %d = %+v, want a file error on %s", i, fieldErr, field)
+				}
+			}
+		})
+	}
+}
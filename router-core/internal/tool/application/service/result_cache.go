@@ -0,0 +1,185 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"time"
+
+	"aigendrug.com/router-core/internal/tool/domain/entity"
+	"aigendrug.com/router-core/internal/tool/domain/shared_type"
+	"aigendrug.com/router-core/internal/tool/domain/valueobject"
+)
+
+// cacheTTL returns the lifetime of the cached results of the tool, 0 when its results are not cached.
+// Fire-and-forget tools produce no result and are never cached.
+func cacheTTL(tool *entity.Tool) time.Duration {
+	policy := tool.EngineInterface.CachePolicy
+	if policy == nil || policy.TTLSeconds <= 0 || isFireAndForget(tool) {
+		return 0
+	}
+	return secondsToDuration(policy.TTLSeconds)
+}
+
+// resultCacheKey hashes the tool id and version with the canonical JSON encoding of the payload
+// (object keys sorted). Uploaded files are identified by their checksum rather than their blob store key,
+// so that the same file uploaded twice hits the cache.
+func (e *functionExecutor) resultCacheKey(
+	ctx context.Context, tool *entity.Tool, toolRequest *entity.ToolRequest,
+) (string, error) {
+	payload, err := e.storedPayload(ctx, toolRequest)
+	if err != nil {
+		return "", err
+	}
+
+	if artifacts := toolRequest.RequestData.Artifacts; len(artifacts) > 0 {
+		payload = maps.Clone(payload)
+		for _, artifact := range artifacts {
+			if payload[artifact.Field] == artifact.Reference() {
+				payload[artifact.Field] = "sha256:" + artifact.SHA256
+			}
+		}
+	}
+
+	encoded, err := json.Marshal(map[string]any{
+		"tool_id":      tool.ID,
+		"tool_version": tool.Version,
+		"payload":      payload,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(encoded)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// serveFromCache completes the claimed tool request with the cached result of the tool for its payload,
+// and reports whether the request was handled. The cached payloads are copied into the request,
+// so that it does not depend on the request which produced them.
+// The tool is invoked as usual when there is no usable cached result (or it cannot be read).
+func (e *functionExecutor) serveFromCache(
+	ctx context.Context, tool *entity.Tool, toolRequest *entity.ToolRequest,
+) bool {
+	if cacheTTL(tool) <= 0 {
+		return false
+	}
+
+	dbCtx, dbCancel := context.WithTimeout(context.WithoutCancel(ctx), 30*time.Second)
+	defer dbCancel()
+
+	cacheKey, err := e.resultCacheKey(dbCtx, tool, toolRequest)
+	if err != nil {
+		fmt.Printf("tool request %d: failed to compute cache key: %v\n", toolRequest.ID, err)
+		return false
+	}
+
+	cache, err := e.toolRepo.FindToolResultCache(dbCtx, tool.ID, cacheKey)
+	if err != nil {
+		fmt.Printf("tool request %d: failed to read result cache: %v\n", toolRequest.ID, err)
+		return false
+	}
+	if cache == nil {
+		return false
+	}
+
+	source, err := e.toolRepo.FindToolRequestByID(dbCtx, cache.ToolRequestID)
+	if err != nil || source.Status != valueobject.ToolRequestStatusSuccess {
+		fmt.Printf("tool request %d: cached result of tool request %d is not available\n", toolRequest.ID, cache.ToolRequestID)
+		return false
+	}
+
+	payload, rawPayload, err := e.sourcePayloads(dbCtx, source)
+	if err != nil {
+		fmt.Printf("tool request %d: failed to read cached result of tool request %d: %v\n", toolRequest.ID, source.ID, err)
+		return false
+	}
+
+	toolRequest.ResponseData.Payload = payload
+	toolRequest.ResponseData.RawPayload = rawPayload
+	toolRequest.ResponseData.Error = ""
+	toolRequest.ResponseData.CacheHit = &shared_type.ToolRequestCacheHit{
+		CacheKey:            cache.CacheKey,
+		SourceToolRequestID: source.ID,
+		CachedAt:            cache.CreatedAt,
+		ExpiresAt:           cache.ExpiresAt,
+	}
+	toolRequest.Status = valueobject.ToolRequestStatusSuccess
+
+	e.payloadStore.offloadResponse(dbCtx, toolRequest)
+
+	completed, err := e.toolRepo.CompleteToolRequest(dbCtx, toolRequest)
+	if err != nil {
+		fmt.Printf("failed to update tool request: %v\n", err)
+		e.payloadStore.delete(dbCtx, responseRefs(toolRequest)...)
+		return true
+	}
+	if !completed {
+		fmt.Printf("tool request %d is no longer claimed by %s, cached result discarded\n", toolRequest.ID, toolRequest.LockedBy)
+		e.payloadStore.delete(dbCtx, responseRefs(toolRequest)...)
+		return true
+	}
+
+	fmt.Printf("tool request %d served from the result cache (tool request %d)\n", toolRequest.ID, source.ID)
+	if err := e.toolRepo.RecordToolResultCacheHit(dbCtx, cache.ID); err != nil {
+		fmt.Printf("failed to record result cache hit: %v\n", err)
+	}
+
+	e.notifier.Publish(newToolRequestEvent(ToolRequestEventTypeStatus, toolRequest, ""))
+	return true
+}
+
+// sourcePayloads returns the mapped and raw response payloads of a completed tool request,
+// read back from the blob store when they were offloaded.
+func (e *functionExecutor) sourcePayloads(
+	ctx context.Context, source *entity.ToolRequest,
+) (map[string]any, map[string]any, error) {
+	payload := source.ResponseData.Payload
+	if ref := source.ResponseData.PayloadRef; ref != nil {
+		loaded, err := e.payloadStore.load(ctx, ref)
+		if err != nil {
+			return nil, nil, err
+		}
+		payload = loaded
+	}
+
+	rawPayload := source.ResponseData.RawPayload
+	if ref := source.ResponseData.RawPayloadRef; ref != nil {
+		loaded, err := e.payloadStore.load(ctx, ref)
+		if err != nil {
+			return nil, nil, err
+		}
+		rawPayload = loaded
+	}
+
+	return payload, rawPayload, nil
+}
+
+// cacheResult records the successful result of the completed tool request as the cached result of the tool
+// for its payload. Failures are only logged, the next request with the same payload invokes the tool again.
+func (e *functionExecutor) cacheResult(ctx context.Context, tool *entity.Tool, toolRequest *entity.ToolRequest) {
+	ttl := cacheTTL(tool)
+	if ttl <= 0 || toolRequest.Status != valueobject.ToolRequestStatusSuccess || toolRequest.ResponseData.CacheHit != nil {
+		return
+	}
+
+	cacheKey, err := e.resultCacheKey(ctx, tool, toolRequest)
+	if err != nil {
+		fmt.Printf("tool request %d: failed to compute cache key: %v\n", toolRequest.ID, err)
+		return
+	}
+
+	err = e.toolRepo.UpsertToolResultCache(ctx, &entity.ToolResultCache{
+		ToolID:        tool.ID,
+		ToolVersion:   tool.Version,
+		CacheKey:      cacheKey,
+		ToolRequestID: toolRequest.ID,
+		ExpiresAt:     time.Now().Add(ttl),
+	})
+	if err != nil {
+		fmt.Printf("tool request %d: failed to cache result: %v\n", toolRequest.ID, err)
+	}
+}
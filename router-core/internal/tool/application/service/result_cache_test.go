@@ -0,0 +1,138 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"aigendrug.com/router-core/internal/tool/domain/entity"
+	"aigendrug.com/router-core/internal/tool/domain/shared_type"
+)
+
+type cacheKeyInput struct {
+	toolID    int
+	version   string
+	payload   string
+	artifacts []shared_type.ToolRequestArtifact
+}
+
+func artifact(field, key, sha256 string) shared_type.ToolRequestArtifact {
+	return shared_type.ToolRequestArtifact{Field: field, Key: key, SHA256: sha256}
+}
+
+func (in cacheKeyInput) key(t *testing.T) string {
+	t.Helper()
+
+	var payload map[string]any
+	if err := json.Unmarshal([]byte(in.payload), &payload); err != nil {
+		t.Fatalf("invalid payload %s: %v", in.payload, err)
+	}
+
+	e := &functionExecutor{}
+	key, err := e.resultCacheKey(context.Background(),
+		&entity.Tool{ID: in.toolID, Version: in.version},
+		&entity.ToolRequest{RequestData: shared_type.ToolRequestData{Payload: payload, Artifacts: in.artifacts}},
+	)
+	if err != nil {
+		t.Fatalf("resultCacheKey() error = %v", err)
+	}
+	if len(key) != 64 {
+		t.Fatalf("resultCacheKey() = %q, want a hex SHA-256", key)
+	}
+	return key
+}
+
+func TestResultCacheKey(t *testing.T) {
+	tests := []struct {
+		name string
+		a    cacheKeyInput
+		b    cacheKeyInput
+		same bool
+	}{
+		{
+			name: "object keys in another order",
+			a:    cacheKeyInput{toolID: 1, version: "1.0", payload: `{"smiles": "CCO", "top_k": 5}`},
+			b:    cacheKeyInput{toolID: 1, version: "1.0", payload: `{"top_k": 5, "smiles": "CCO"}`},
+			same: true,
+		},
+		{
+			name: "nested keys in another order",
+			a:    cacheKeyInput{toolID: 1, version: "1.0", payload: `{"params": {"a": 1, "b": [{"x": 1, "y": 2}]}}`},
+			b:    cacheKeyInput{toolID: 1, version: "1.0", payload: `{"params": {"b": [{"y": 2, "x": 1}], "a": 1}}`},
+			same: true,
+		},
+		{
+			name: "equal numbers written differently",
+			a:    cacheKeyInput{toolID: 1, version: "1.0", payload: `{"top_k": 5}`},
+			b:    cacheKeyInput{toolID: 1, version: "1.0", payload: `{"top_k": 5.0}`},
+			same: true,
+		},
+		{
+			name: "different value",
+			a:    cacheKeyInput{toolID: 1, version: "1.0", payload: `{"top_k": 5}`},
+			b:    cacheKeyInput{toolID: 1, version: "1.0", payload: `{"top_k": "5"}`},
+		},
+		{
+			name: "array order matters",
+			a:    cacheKeyInput{toolID: 1, version: "1.0", payload: `{"ids": [1, 2]}`},
+			b:    cacheKeyInput{toolID: 1, version: "1.0", payload: `{"ids": [2, 1]}`},
+		},
+		{
+			name: "different tool version",
+			a:    cacheKeyInput{toolID: 1, version: "1.0", payload: `{"top_k": 5}`},
+			b:    cacheKeyInput{toolID: 1, version: "1.1", payload: `{"top_k": 5}`},
+		},
+		{
+			name: "different tool",
+			a:    cacheKeyInput{toolID: 1, version: "1.0", payload: `{"top_k": 5}`},
+			b:    cacheKeyInput{toolID: 2, version: "1.0", payload: `{"top_k": 5}`},
+		},
+		{
+			name: "same file uploaded twice",
+			a: cacheKeyInput{toolID: 1, version: "1.0",
+				payload:   `{"structure": "artifact://uploads/a.pdb"}`,
+				artifacts: []shared_type.ToolRequestArtifact{artifact("structure", "uploads/a.pdb", "abc")}},
+			b: cacheKeyInput{toolID: 1, version: "1.0",
+				payload:   `{"structure": "artifact://uploads/b.pdb"}`,
+				artifacts: []shared_type.ToolRequestArtifact{artifact("structure", "uploads/b.pdb", "abc")}},
+			same: true,
+		},
+		{
+			name: "different file contents",
+			a: cacheKeyInput{toolID: 1, version: "1.0",
+				payload:   `{"structure": "artifact://uploads/a.pdb"}`,
+				artifacts: []shared_type.ToolRequestArtifact{artifact("structure", "uploads/a.pdb", "abc")}},
+			b: cacheKeyInput{toolID: 1, version: "1.0",
+				payload:   `{"structure": "artifact://uploads/a.pdb"}`,
+				artifacts: []shared_type.ToolRequestArtifact{artifact("structure", "uploads/a.pdb", "def")}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, b := tt.a.key(t), tt.b.key(t)
+			if (a == b) != tt.same {
+				t.Fatalf("keys %s and %s: same = %v, want %v", a, b, a == b, tt.same)
+			}
+		})
+	}
+}
+
+func TestResultCacheKeyKeepsPayload(t *testing.T) {
+	var payload map[string]any
+	if err := json.Unmarshal([]byte(`{"structure": "artifact://uploads/a.pdb"}`), &payload); err != nil {
+		t.Fatal(err)
+	}
+	toolRequest := &entity.ToolRequest{RequestData: shared_type.ToolRequestData{
+		Payload:   payload,
+		Artifacts: []shared_type.ToolRequestArtifact{artifact("structure", "uploads/a.pdb", "abc")},
+	}}
+
+	e := &functionExecutor{}
+	if _, err := e.resultCacheKey(context.Background(), &entity.Tool{ID: 1}, toolRequest); err != nil {
+		t.Fatalf("resultCacheKey() error = %v", err)
+	}
+	if got := toolRequest.RequestData.Payload["structure"]; got != "artifact://uploads/a.pdb" {
+		t.Fatalf("payload of the tool request changed to %v", got)
+	}
+}
@@ -0,0 +1,279 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"slices"
+	"time"
+
+	http_wrapper "aigendrug.com/router-core/internal/shared/http-wrapper"
+	"aigendrug.com/router-core/internal/tool/domain/entity"
+	"aigendrug.com/router-core/internal/tool/domain/shared_type"
+	"aigendrug.com/router-core/internal/tool/domain/valueobject"
+	"github.com/aws/smithy-go"
+)
+
+// Defaults of a retry policy whose fields are left empty.
+const (
+	DefaultRetryBaseDelay = 1 * time.Second
+	DefaultRetryMaxDelay  = 30 * time.Second
+	DefaultRetryJitter    = 0.2
+)
+
+var (
+	DefaultRetryableErrors = []valueobject.ExecutionErrorClass{
+		valueobject.ExecutionErrorClassThrottled,
+		valueobject.ExecutionErrorClassTimeout,
+		valueobject.ExecutionErrorClassNetwork,
+		valueobject.ExecutionErrorClassServerError,
+	}
+	DefaultRetryableStatusCodes = []int{
+		http.StatusRequestTimeout,
+		http.StatusTooManyRequests,
+		http.StatusInternalServerError,
+		http.StatusBadGateway,
+		http.StatusServiceUnavailable,
+		http.StatusGatewayTimeout,
+	}
+)
+
+// ExecutionError is a failed invocation classified for the retry policy.
+// StatusCode is the status code returned by an http-server tool, zero otherwise.
+type ExecutionError struct {
+	Class      valueobject.ExecutionErrorClass
+	StatusCode int
+	Err        error
+}
+
+func (e *ExecutionError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ExecutionError) Unwrap() error {
+	return e.Err
+}
+
+func newExecutionError(class valueobject.ExecutionErrorClass, format string, args ...any) *ExecutionError {
+	return &ExecutionError{Class: class, Err: fmt.Errorf(format, args...)}
+}
+
+// classifyError wraps err into an ExecutionError.
+// Errors already classified by the engine are returned as is.
+func classifyError(err error) *ExecutionError {
+	var execErr *ExecutionError
+	if errors.As(err, &execErr) {
+		return execErr
+	}
+
+	class := valueobject.ExecutionErrorClassUnknown
+
+	var apiErr smithy.APIError
+	var netErr net.Error
+	switch {
+	case errors.Is(err, context.Canceled):
+		class = valueobject.ExecutionErrorClassCancelled
+	case errors.Is(err, context.DeadlineExceeded):
+		class = valueobject.ExecutionErrorClassTimeout
+	case errors.Is(err, http_wrapper.ErrResponseTooLarge):
+		class = valueobject.ExecutionErrorClassInvalidResponse
+	case errors.As(err, &apiErr):
+		switch apiErr.ErrorCode() {
+		case "TooManyRequestsException", "ThrottlingException", "Throttling",
+			"EC2ThrottledException", "RequestLimitExceeded", "SlowDown":
+			class = valueobject.ExecutionErrorClassThrottled
+		default:
+			if apiErr.ErrorFault() == smithy.FaultServer {
+				class = valueobject.ExecutionErrorClassServerError
+			} else {
+				class = valueobject.ExecutionErrorClassClientError
+			}
+		}
+	case errors.As(err, &netErr):
+		if netErr.Timeout() {
+			class = valueobject.ExecutionErrorClassTimeout
+		} else {
+			class = valueobject.ExecutionErrorClassNetwork
+		}
+	}
+
+	return &ExecutionError{Class: class, Err: err}
+}
+
+// errorClassForStatusCode classifies a non 2xx response of an http-server tool.
+func errorClassForStatusCode(statusCode int) valueobject.ExecutionErrorClass {
+	switch {
+	case statusCode == http.StatusTooManyRequests:
+		return valueobject.ExecutionErrorClassThrottled
+	case statusCode == http.StatusRequestTimeout || statusCode == http.StatusGatewayTimeout:
+		return valueobject.ExecutionErrorClassTimeout
+	case statusCode >= http.StatusInternalServerError:
+		return valueobject.ExecutionErrorClassServerError
+	default:
+		return valueobject.ExecutionErrorClassClientError
+	}
+}
+
+type retryPolicy struct {
+	maxAttempts          int
+	baseDelay            time.Duration
+	maxDelay             time.Duration
+	jitter               float64
+	retryableErrors      []valueobject.ExecutionErrorClass
+	retryableStatusCodes []int
+}
+
+// resolveRetryPolicy fills the empty fields of the tool's retry policy with defaults.
+// A tool without a retry policy is invoked once.
+func resolveRetryPolicy(policy *shared_type.RetryPolicy) retryPolicy {
+	resolved := retryPolicy{
+		maxAttempts:          1,
+		baseDelay:            DefaultRetryBaseDelay,
+		maxDelay:             DefaultRetryMaxDelay,
+		jitter:               DefaultRetryJitter,
+		retryableErrors:      DefaultRetryableErrors,
+		retryableStatusCodes: DefaultRetryableStatusCodes,
+	}
+	if policy == nil {
+		return resolved
+	}
+
+	if policy.MaxAttempts > 0 {
+		resolved.maxAttempts = policy.MaxAttempts
+	}
+	if policy.BaseDelaySeconds > 0 {
+		resolved.baseDelay = secondsToDuration(policy.BaseDelaySeconds)
+	}
+	if policy.MaxDelaySeconds > 0 {
+		resolved.maxDelay = secondsToDuration(policy.MaxDelaySeconds)
+	}
+	if policy.Jitter > 0 && policy.Jitter <= 1 {
+		resolved.jitter = policy.Jitter
+	}
+	if len(policy.RetryableErrors) > 0 {
+		resolved.retryableErrors = policy.RetryableErrors
+	}
+	if len(policy.RetryableStatusCodes) > 0 {
+		resolved.retryableStatusCodes = policy.RetryableStatusCodes
+	}
+	if resolved.maxDelay < resolved.baseDelay {
+		resolved.maxDelay = resolved.baseDelay
+	}
+
+	return resolved
+}
+
+func (p retryPolicy) retryable(execErr *ExecutionError) bool {
+	if execErr.StatusCode != 0 {
+		return slices.Contains(p.retryableStatusCodes, execErr.StatusCode)
+	}
+	return slices.Contains(p.retryableErrors, execErr.Class)
+}
+
+// delay returns the backoff before the given retry (1 for the first retry).
+func (p retryPolicy) delay(retry int) time.Duration {
+	delay := float64(p.baseDelay) * math.Pow(2, float64(retry-1))
+	if delay > float64(p.maxDelay) {
+		delay = float64(p.maxDelay)
+	}
+	delay -= delay * p.jitter * rand.Float64()
+	return time.Duration(delay)
+}
+
+// invokeWithRetry invokes the tool until it succeeds, fails with a non retryable error
+// or the attempts of its retry policy are used up. Each attempt is bounded by timeout
+// and appended to toolRequest.Attempts; attempts of previous claims count towards the policy.
+// The payload (offloaded payload, artifacts) is resolved for each attempt.
+func (e *functionExecutor) invokeWithRetry(
+	ctx context.Context, tool *entity.Tool, toolRequest *entity.ToolRequest, sync bool, timeout time.Duration,
+) (map[string]any, error) {
+	policy := resolveRetryPolicy(tool.EngineInterface.RetryPolicy)
+
+	for {
+		attempt := shared_type.ToolRequestAttempt{
+			Attempt:   len(toolRequest.Attempts) + 1,
+			StartedAt: time.Now(),
+		}
+
+		payload, err := e.invocationPayload(ctx, toolRequest)
+		var result map[string]any
+		if err == nil {
+			err = e.breakers.allow(tool.ID)
+		}
+		if err == nil {
+			result, attempt.Diagnostics, err = e.invokeWithTimeout(ctx, tool, payload, sync, timeout)
+			e.breakers.record(tool.ID, err)
+		}
+		attempt.FinishedAt = time.Now()
+		if err == nil {
+			toolRequest.Attempts = append(toolRequest.Attempts, attempt)
+			return result, nil
+		}
+
+		execErr := classifyError(err)
+		attempt.Error = execErr.Error()
+		attempt.ErrorClass = execErr.Class
+		attempt.StatusCode = execErr.StatusCode
+		attempt.Retryable = policy.retryable(execErr) && attempt.Attempt < policy.maxAttempts
+		toolRequest.Attempts = append(toolRequest.Attempts, attempt)
+
+		if !attempt.Retryable {
+			return nil, execErr
+		}
+
+		delay := policy.delay(attempt.Attempt)
+		fmt.Printf("attempt %d/%d of tool request %d failed (%s), retrying in %v: %v\n",
+			attempt.Attempt, policy.maxAttempts, toolRequest.ID, execErr.Class, delay, execErr)
+
+		if err := e.saveAttempts(ctx, toolRequest); err != nil {
+			fmt.Printf("failed to persist attempts: %v\n", err)
+		}
+		e.notifier.Publish(newToolRequestEvent(ToolRequestEventTypeProgress, toolRequest,
+			fmt.Sprintf("attempt %d failed (%s), retrying in %v", attempt.Attempt, execErr.Class, delay.Round(time.Millisecond))))
+
+		select {
+		case <-ctx.Done():
+			return nil, newExecutionError(valueobject.ExecutionErrorClassCancelled,
+				"retry aborted after attempt %d: %v", attempt.Attempt, ctx.Err())
+		case <-time.After(delay):
+		}
+	}
+}
+
+// invokeWithTimeout invokes the tool once and gives up after timeout or when ctx is cancelled,
+// even when the engine does not honor context cancellation.
+// The diagnostics reported by the engine are returned with the result or the error.
+func (e *functionExecutor) invokeWithTimeout(
+	ctx context.Context, tool *entity.Tool, payload map[string]any, sync bool, timeout time.Duration,
+) (map[string]any, *shared_type.InvocationDiagnostics, error) {
+	executionTimeoutCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	type invocation struct {
+		result      map[string]any
+		diagnostics *shared_type.InvocationDiagnostics
+		err         error
+	}
+
+	// Channel to receive execution result
+	invocationChan := make(chan invocation, 1)
+
+	go func() {
+		res, diagnostics, err := e.invoke(executionTimeoutCtx, tool, payload, sync)
+		invocationChan <- invocation{result: res, diagnostics: diagnostics, err: err}
+	}()
+
+	select {
+	case res := <-invocationChan:
+		return res.result, res.diagnostics, res.err
+	case <-executionTimeoutCtx.Done():
+		if ctx.Err() != nil {
+			return nil, nil, newExecutionError(valueobject.ExecutionErrorClassCancelled, "execution aborted: %v", ctx.Err())
+		}
+		return nil, nil, newExecutionError(valueobject.ExecutionErrorClassTimeout, "execution timeout after %v", timeout)
+	}
+}
@@ -0,0 +1,427 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	http_wrapper "aigendrug.com/router-core/internal/shared/http-wrapper"
+	"aigendrug.com/router-core/internal/tool/domain"
+	"aigendrug.com/router-core/internal/tool/domain/entity"
+	"aigendrug.com/router-core/internal/tool/domain/shared_type"
+	"aigendrug.com/router-core/internal/tool/domain/valueobject"
+	"github.com/aws/smithy-go"
+)
+
+func TestClassifyError(t *testing.T) {
+	classified := newExecutionError(valueobject.ExecutionErrorClassFunctionError, "function failed")
+
+	tests := []struct {
+		name string
+		err  error
+		want valueobject.ExecutionErrorClass
+	}{
+		{name: "classified by the engine", err: fmt.Errorf("invoke: %w", classified), want: valueobject.ExecutionErrorClassFunctionError},
+		{name: "cancelled", err: fmt.Errorf("invoke: %w", context.Canceled), want: valueobject.ExecutionErrorClassCancelled},
+		{name: "deadline exceeded", err: fmt.Errorf("invoke: %w", context.DeadlineExceeded), want: valueobject.ExecutionErrorClassTimeout},
+		{
+			name: "response too large",
+			err:  fmt.Errorf("%w: GET http://tool returned more than 16 bytes", http_wrapper.ErrResponseTooLarge),
+			want: valueobject.ExecutionErrorClassInvalidResponse,
+		},
+		{
+			name: "lambda throttling",
+			err:  &smithy.GenericAPIError{Code: "TooManyRequestsException", Fault: smithy.FaultClient},
+			want: valueobject.ExecutionErrorClassThrottled,
+		},
+		{
+			name: "s3 slow down",
+			err:  &smithy.GenericAPIError{Code: "SlowDown", Fault: smithy.FaultServer},
+			want: valueobject.ExecutionErrorClassThrottled,
+		},
+		{
+			name: "aws server fault",
+			err:  &smithy.GenericAPIError{Code: "ServiceException", Fault: smithy.FaultServer},
+			want: valueobject.ExecutionErrorClassServerError,
+		},
+		{
+			name: "aws client fault",
+			err:  &smithy.GenericAPIError{Code: "InvalidParameterValueException", Fault: smithy.FaultClient},
+			want: valueobject.ExecutionErrorClassClientError,
+		},
+		{
+			name: "network timeout",
+			err:  &net.DNSError{Err: "i/o timeout", Name: "tool.internal", IsTimeout: true},
+			want: valueobject.ExecutionErrorClassTimeout,
+		},
+		{
+			name: "connection refused",
+			err:  &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")},
+			want: valueobject.ExecutionErrorClassNetwork,
+		},
+		{name: "anything else", err: errors.New("boom"), want: valueobject.ExecutionErrorClassUnknown},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := classifyError(tt.err)
+			if got.Class != tt.want {
+				t.Fatalf("classifyError(%v) class = %s, want %s", tt.err, got.Class, tt.want)
+			}
+			if !errors.Is(got, tt.err) && got != classified {
+				t.Fatalf("classifyError(%v) = %v, want the error wrapped", tt.err, got)
+			}
+		})
+	}
+}
+
+func TestErrorClassForStatusCode(t *testing.T) {
+	tests := []struct {
+		statusCode int
+		want       valueobject.ExecutionErrorClass
+	}{
+		{statusCode: http.StatusTooManyRequests, want: valueobject.ExecutionErrorClassThrottled},
+		{statusCode: http.StatusRequestTimeout, want: valueobject.ExecutionErrorClassTimeout},
+		{statusCode: http.StatusGatewayTimeout, want: valueobject.ExecutionErrorClassTimeout},
+		{statusCode: http.StatusInternalServerError, want: valueobject.ExecutionErrorClassServerError},
+		{statusCode: http.StatusServiceUnavailable, want: valueobject.ExecutionErrorClassServerError},
+		{statusCode: http.StatusBadRequest, want: valueobject.ExecutionErrorClassClientError},
+		{statusCode: http.StatusNotFound, want: valueobject.ExecutionErrorClassClientError},
+		{statusCode: http.StatusFound, want: valueobject.ExecutionErrorClassClientError},
+	}
+
+	for _, tt := range tests {
+		t.Run(http.StatusText(tt.statusCode), func(t *testing.T) {
+			if got := errorClassForStatusCode(tt.statusCode); got != tt.want {
+				t.Fatalf("errorClassForStatusCode(%d) = %s, want %s", tt.statusCode, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveRetryPolicy(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy *shared_type.RetryPolicy
+		want   retryPolicy
+	}{
+		{
+			name: "no policy",
+			want: retryPolicy{
+				maxAttempts: 1, baseDelay: DefaultRetryBaseDelay, maxDelay: DefaultRetryMaxDelay, jitter: DefaultRetryJitter,
+			},
+		},
+		{
+			name:   "attempts only",
+			policy: &shared_type.RetryPolicy{MaxAttempts: 3},
+			want: retryPolicy{
+				maxAttempts: 3, baseDelay: DefaultRetryBaseDelay, maxDelay: DefaultRetryMaxDelay, jitter: DefaultRetryJitter,
+			},
+		},
+		{
+			name:   "every field",
+			policy: &shared_type.RetryPolicy{MaxAttempts: 5, BaseDelaySeconds: 0.5, MaxDelaySeconds: 4, Jitter: 1},
+			want:   retryPolicy{maxAttempts: 5, baseDelay: 500 * time.Millisecond, maxDelay: 4 * time.Second, jitter: 1},
+		},
+		{
+			name:   "max delay below the base delay",
+			policy: &shared_type.RetryPolicy{MaxAttempts: 2, BaseDelaySeconds: 10, MaxDelaySeconds: 2},
+			want:   retryPolicy{maxAttempts: 2, baseDelay: 10 * time.Second, maxDelay: 10 * time.Second, jitter: DefaultRetryJitter},
+		},
+		{
+			name:   "out of range jitter",
+			policy: &shared_type.RetryPolicy{MaxAttempts: 2, Jitter: 1.5},
+			want: retryPolicy{
+				maxAttempts: 2, baseDelay: DefaultRetryBaseDelay, maxDelay: DefaultRetryMaxDelay, jitter: DefaultRetryJitter,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := resolveRetryPolicy(tt.policy)
+			if got.maxAttempts != tt.want.maxAttempts || got.baseDelay != tt.want.baseDelay ||
+				got.maxDelay != tt.want.maxDelay || got.jitter != tt.want.jitter {
+				t.Fatalf("resolveRetryPolicy() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRetryPolicyRetryable(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy *shared_type.RetryPolicy
+		err    *ExecutionError
+		want   bool
+	}{
+		{name: "default class", err: &ExecutionError{Class: valueobject.ExecutionErrorClassNetwork}, want: true},
+		{name: "default non retryable class", err: &ExecutionError{Class: valueobject.ExecutionErrorClassFunctionError}},
+		{
+			name: "default status code",
+			err:  &ExecutionError{Class: valueobject.ExecutionErrorClassServerError, StatusCode: http.StatusBadGateway},
+			want: true,
+		},
+		{
+			name: "status code decides over the class",
+			err:  &ExecutionError{Class: valueobject.ExecutionErrorClassServerError, StatusCode: http.StatusNotImplemented},
+		},
+		{
+			name:   "configured classes",
+			policy: &shared_type.RetryPolicy{RetryableErrors: []valueobject.ExecutionErrorClass{valueobject.ExecutionErrorClassFunctionError}},
+			err:    &ExecutionError{Class: valueobject.ExecutionErrorClassFunctionError},
+			want:   true,
+		},
+		{
+			name:   "configured classes replace the defaults",
+			policy: &shared_type.RetryPolicy{RetryableErrors: []valueobject.ExecutionErrorClass{valueobject.ExecutionErrorClassFunctionError}},
+			err:    &ExecutionError{Class: valueobject.ExecutionErrorClassNetwork},
+		},
+		{
+			name:   "configured status codes",
+			policy: &shared_type.RetryPolicy{RetryableStatusCodes: []int{http.StatusConflict}},
+			err:    &ExecutionError{Class: valueobject.ExecutionErrorClassClientError, StatusCode: http.StatusConflict},
+			want:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := resolveRetryPolicy(tt.policy).retryable(tt.err); got != tt.want {
+				t.Fatalf("retryable(%s, %d) = %v, want %v", tt.err.Class, tt.err.StatusCode, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRetryPolicyDelay(t *testing.T) {
+	policy := retryPolicy{baseDelay: time.Second, maxDelay: 5 * time.Second}
+
+	tests := []struct {
+		retry int
+		want  time.Duration
+	}{
+		{retry: 1, want: time.Second},
+		{retry: 2, want: 2 * time.Second},
+		{retry: 3, want: 4 * time.Second},
+		{retry: 4, want: 5 * time.Second},
+		{retry: 10, want: 5 * time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.retry), func(t *testing.T) {
+			if got := policy.delay(tt.retry); got != tt.want {
+				t.Fatalf("delay(%d) = %v, want %v", tt.retry, got, tt.want)
+			}
+		})
+	}
+
+	policy.jitter = 0.5
+	for range 100 {
+		if got := policy.delay(2); got <= time.Second || got > 2*time.Second {
+			t.Fatalf("delay(2) with jitter 0.5 = %v, want within (1s, 2s]", got)
+		}
+	}
+}
+
+// fakeToolRepository records the attempts saved between retries.
+type fakeToolRepository struct {
+	domain.ToolRepository
+	savedAttempts []int
+}
+
+func (r *fakeToolRepository) UpdateClaimedToolRequest(_ context.Context, toolRequest *entity.ToolRequest) (bool, error) {
+	r.savedAttempts = append(r.savedAttempts, len(toolRequest.Attempts))
+	return true, nil
+}
+
+// fakeNotifier records the published events.
+type fakeNotifier struct {
+	ToolRequestNotifier
+	events []ToolRequestEvent
+}
+
+func (n *fakeNotifier) Publish(event ToolRequestEvent) {
+	n.events = append(n.events, event)
+}
+
+// statusSequenceServer answers each request with the next status code, repeating the last one.
+func statusSequenceServer(statusCodes ...int) (*httptest.Server, func() int) {
+	var mu sync.Mutex
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		statusCode := statusCodes[min(calls, len(statusCodes)-1)]
+		calls++
+		mu.Unlock()
+
+		w.Header().Set("Content-Type", http_wrapper.ContentTypeJSON)
+		w.WriteHeader(statusCode)
+		_, _ = w.Write([]byte(`{"score": 1}`))
+	}))
+	return server, func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		return calls
+	}
+}
+
+func TestInvokeWithRetry(t *testing.T) {
+	tests := []struct {
+		name             string
+		policy           *shared_type.RetryPolicy
+		previousAttempts int
+		statusCodes      []int
+		wantErrClass     valueobject.ExecutionErrorClass
+		wantStatusCodes  []int
+		wantRetryable    []bool
+	}{
+		{
+			name:            "succeeds first",
+			policy:          &shared_type.RetryPolicy{MaxAttempts: 3, BaseDelaySeconds: 0.001},
+			statusCodes:     []int{http.StatusOK},
+			wantStatusCodes: []int{0},
+			wantRetryable:   []bool{false},
+		},
+		{
+			name:            "retried until success",
+			policy:          &shared_type.RetryPolicy{MaxAttempts: 3, BaseDelaySeconds: 0.001},
+			statusCodes:     []int{http.StatusServiceUnavailable, http.StatusTooManyRequests, http.StatusOK},
+			wantStatusCodes: []int{http.StatusServiceUnavailable, http.StatusTooManyRequests, 0},
+			wantRetryable:   []bool{true, true, false},
+		},
+		{
+			name:            "attempts used up",
+			policy:          &shared_type.RetryPolicy{MaxAttempts: 2, BaseDelaySeconds: 0.001},
+			statusCodes:     []int{http.StatusBadGateway, http.StatusBadGateway, http.StatusOK},
+			wantErrClass:    valueobject.ExecutionErrorClassServerError,
+			wantStatusCodes: []int{http.StatusBadGateway, http.StatusBadGateway},
+			wantRetryable:   []bool{true, false},
+		},
+		{
+			name:            "non retryable status code",
+			policy:          &shared_type.RetryPolicy{MaxAttempts: 3, BaseDelaySeconds: 0.001},
+			statusCodes:     []int{http.StatusBadRequest, http.StatusOK},
+			wantErrClass:    valueobject.ExecutionErrorClassClientError,
+			wantStatusCodes: []int{http.StatusBadRequest},
+			wantRetryable:   []bool{false},
+		},
+		{
+			name:            "no policy",
+			statusCodes:     []int{http.StatusServiceUnavailable, http.StatusOK},
+			wantErrClass:    valueobject.ExecutionErrorClassServerError,
+			wantStatusCodes: []int{http.StatusServiceUnavailable},
+			wantRetryable:   []bool{false},
+		},
+		{
+			name:             "attempts of previous claims count",
+			policy:           &shared_type.RetryPolicy{MaxAttempts: 3, BaseDelaySeconds: 0.001},
+			previousAttempts: 2,
+			statusCodes:      []int{http.StatusServiceUnavailable, http.StatusOK},
+			wantErrClass:     valueobject.ExecutionErrorClassServerError,
+			wantStatusCodes:  []int{http.StatusServiceUnavailable},
+			wantRetryable:    []bool{false},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, calls := statusSequenceServer(tt.statusCodes...)
+			defer server.Close()
+
+			repo := &fakeToolRepository{}
+			notifier := &fakeNotifier{}
+			e := &functionExecutor{
+				toolRepo:   repo,
+				httpClient: http_wrapper.NewHTTPWrapperClient(http_wrapper.ClientOptions{}),
+				notifier:   notifier,
+				breakers:   newCircuitBreakers(nil, nil),
+			}
+			tool := &entity.Tool{
+				ProviderInterface: shared_type.ProviderInterface{URL: server.URL},
+				EngineInterface: shared_type.EngineInterface{
+					EngineInterfaceType: valueobject.EngineInterfaceHTTPServer,
+					RetryPolicy:         tt.policy,
+				},
+			}
+			toolRequest := &entity.ToolRequest{ID: 1}
+			for i := range tt.previousAttempts {
+				toolRequest.Attempts = append(toolRequest.Attempts, shared_type.ToolRequestAttempt{Attempt: i + 1})
+			}
+
+			result, err := e.invokeWithRetry(context.Background(), tool, toolRequest, true, time.Second)
+			if tt.wantErrClass == "" {
+				if err != nil {
+					t.Fatalf("invokeWithRetry() error = %v", err)
+				}
+				if result["score"] != float64(1) {
+					t.Fatalf("invokeWithRetry() = %v, want the response of the tool", result)
+				}
+			} else {
+				var execErr *ExecutionError
+				if !errors.As(err, &execErr) || execErr.Class != tt.wantErrClass {
+					t.Fatalf("invokeWithRetry() error = %v, want a %s execution error", err, tt.wantErrClass)
+				}
+			}
+
+			attempts := toolRequest.Attempts[tt.previousAttempts:]
+			if calls() != len(tt.wantStatusCodes) || len(attempts) != len(tt.wantStatusCodes) {
+				t.Fatalf("made %d calls and recorded %d attempts, want %d", calls(), len(attempts), len(tt.wantStatusCodes))
+			}
+			for i, attempt := range attempts {
+				if attempt.Attempt != tt.previousAttempts+i+1 {
+					t.Fatalf("attempt %d numbered %d", tt.previousAttempts+i+1, attempt.Attempt)
+				}
+				if attempt.StatusCode != tt.wantStatusCodes[i] || attempt.Retryable != tt.wantRetryable[i] {
+					t.Fatalf("attempt %d = status %d retryable %v, want status %d retryable %v", attempt.Attempt,
+						attempt.StatusCode, attempt.Retryable, tt.wantStatusCodes[i], tt.wantRetryable[i])
+				}
+				if (attempt.Error == "") != (tt.wantStatusCodes[i] == 0) {
+					t.Fatalf("attempt %d error = %q", attempt.Attempt, attempt.Error)
+				}
+			}
+			// attempts are persisted and reported before each retry
+			if len(repo.savedAttempts) != len(attempts)-1 || len(notifier.events) != len(attempts)-1 {
+				t.Fatalf("saved attempts %d times and published %d events, want %d",
+					len(repo.savedAttempts), len(notifier.events), len(attempts)-1)
+			}
+		})
+	}
+}
+
+func TestInvokeWithRetryStopsWhenCancelled(t *testing.T) {
+	server, calls := statusSequenceServer(http.StatusServiceUnavailable)
+	defer server.Close()
+
+	e := &functionExecutor{
+		toolRepo:   &fakeToolRepository{},
+		httpClient: http_wrapper.NewHTTPWrapperClient(http_wrapper.ClientOptions{}),
+		notifier:   &fakeNotifier{},
+		breakers:   newCircuitBreakers(nil, nil),
+	}
+	tool := &entity.Tool{
+		ProviderInterface: shared_type.ProviderInterface{URL: server.URL},
+		EngineInterface: shared_type.EngineInterface{
+			EngineInterfaceType: valueobject.EngineInterfaceHTTPServer,
+			RetryPolicy:         &shared_type.RetryPolicy{MaxAttempts: 5, BaseDelaySeconds: 60},
+		},
+	}
+	toolRequest := &entity.ToolRequest{ID: 1}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err := e.invokeWithRetry(ctx, tool, toolRequest, true, time.Second)
+	var execErr *ExecutionError
+	if !errors.As(err, &execErr) || execErr.Class != valueobject.ExecutionErrorClassCancelled {
+		t.Fatalf("invokeWithRetry() error = %v, want a cancelled execution error", err)
+	}
+	if calls() != 1 || len(toolRequest.Attempts) != 1 {
+		t.Fatalf("made %d calls and recorded %d attempts while waiting to retry, want 1", calls(), len(toolRequest.Attempts))
+	}
+}
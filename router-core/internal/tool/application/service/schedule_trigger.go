@@ -0,0 +1,299 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"aigendrug.com/router-core/internal/config"
+	"aigendrug.com/router-core/internal/shared/cron"
+	"aigendrug.com/router-core/internal/shared/database/postgres"
+	"aigendrug.com/router-core/internal/tool/domain"
+	"aigendrug.com/router-core/internal/tool/domain/entity"
+	"aigendrug.com/router-core/internal/tool/domain/shared_type"
+	"aigendrug.com/router-core/internal/tool/domain/valueobject"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Default schedule trigger settings.
+// Overridden by config (SCHEDULE_*).
+const (
+	DefaultSchedulePollInterval = 10 * time.Second
+	DefaultScheduleMisfireGrace = 1 * time.Minute
+
+	// missed runs fired at once by the run_all policy, older runs are dropped
+	maxScheduleCatchUpRuns = 100
+)
+
+// ToolScheduleTrigger creates the tool requests of the due runs of tool schedules.
+//
+// Every replica polls the schedules, a due schedule is claimed with SELECT ... FOR UPDATE SKIP LOCKED and its
+// tool requests are created in the transaction moving it to its next run, so that each run fires exactly once
+// whatever the number of replicas. A run overdue by more than the misfire grace (e.g. every replica was down)
+// is a missed run, handled by the missed run policy of the schedule.
+type ToolScheduleTrigger interface {
+	// Start launches the trigger. It stops when ctx is done.
+	Start(ctx context.Context)
+}
+
+type toolScheduleTrigger struct {
+	db           *pgxpool.Pool
+	toolRepo     domain.ToolRepository
+	scheduler    ToolRequestScheduler
+	payloadStore *PayloadStore
+	quota        ExecutionQuota
+
+	pollInterval time.Duration
+	misfireGrace time.Duration
+}
+
+func NewToolScheduleTrigger(
+	config *config.Config,
+	db *pgxpool.Pool,
+	toolRepo domain.ToolRepository,
+	scheduler ToolRequestScheduler,
+	payloadStore *PayloadStore,
+	quota ExecutionQuota,
+) ToolScheduleTrigger {
+	t := &toolScheduleTrigger{
+		db:           db,
+		toolRepo:     toolRepo,
+		scheduler:    scheduler,
+		payloadStore: payloadStore,
+		quota:        quota,
+		pollInterval: DefaultSchedulePollInterval,
+		misfireGrace: DefaultScheduleMisfireGrace,
+	}
+
+	if config != nil {
+		if v := config.Schedule.PollIntervalSeconds; v > 0 {
+			t.pollInterval = secondsToDuration(v)
+		}
+		if v := config.Schedule.MisfireGraceSeconds; v > 0 {
+			t.misfireGrace = secondsToDuration(v)
+		}
+	}
+
+	// a run fired on time must not look missed because of the polling delay
+	if t.misfireGrace < 2*t.pollInterval {
+		t.misfireGrace = 2 * t.pollInterval
+	}
+
+	return t
+}
+
+func (t *toolScheduleTrigger) Start(ctx context.Context) {
+	fmt.Printf("starting tool schedule trigger (poll interval %v, misfire grace %v)\n", t.pollInterval, t.misfireGrace)
+
+	go t.run(ctx)
+}
+
+func (t *toolScheduleTrigger) run(ctx context.Context) {
+	ticker := time.NewTicker(t.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		t.fireDueSchedules(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// fireDueSchedules fires the due schedules, one transaction per schedule, until none is due.
+func (t *toolScheduleTrigger) fireDueSchedules(ctx context.Context) {
+	for ctx.Err() == nil {
+		fired, err := t.fireNext(ctx)
+		if err != nil {
+			fmt.Printf("failed to fire tool schedule: %v\n", err)
+			return
+		}
+		if !fired {
+			return
+		}
+	}
+}
+
+// fireNext claims a due schedule, creates the tool requests of its due runs and moves it to its next run.
+// It reports whether a schedule was due. Nothing is kept when the transaction fails (the reserved executions are
+// given back), the runs are fired again later.
+func (t *toolScheduleTrigger) fireNext(ctx context.Context) (bool, error) {
+	var created []*entity.ToolRequest
+	var reservation executionReservation
+
+	fired, err := postgres.WithTxResult(ctx, t.db, func(tx pgx.Tx) (bool, error) {
+		txRepo := t.toolRepo.WithTx(ctx, tx)
+
+		schedule, err := txRepo.ClaimDueToolSchedule(ctx)
+		if err != nil || schedule == nil {
+			return false, err
+		}
+
+		created, err = t.fire(ctx, txRepo, schedule, time.Now(), &reservation)
+		return true, err
+	})
+	if err != nil {
+		reservation.release(ctx)
+		for _, toolRequest := range created {
+			t.payloadStore.delete(ctx, toolRequest.RequestData.PayloadRef)
+		}
+		return false, err
+	}
+
+	if len(created) > 0 {
+		t.scheduler.Notify()
+	}
+	return fired, nil
+}
+
+// fire creates the tool requests of the due runs of the claimed schedule and records the outcome on it.
+// A run is subject to the checks of ExecuteTool: a run which the client is no longer allowed to execute,
+// or whose payload no longer matches the tool, creates no tool request and is reported as the last error.
+// Each run counts against the execution quotas of the client (into reservation), the runs left once a quota
+// is reached are skipped and reported as the last error.
+func (t *toolScheduleTrigger) fire(
+	ctx context.Context, txRepo domain.ToolRepository, schedule *entity.ToolSchedule, now time.Time,
+	reservation *executionReservation,
+) ([]*entity.ToolRequest, error) {
+	cronSchedule, loc, err := parseSchedule(schedule.CronExpression, schedule.Timezone)
+	if err != nil {
+		// not fired again until the schedule is fixed
+		schedule.NextRunAt = nil
+		schedule.LastError = err.Error()
+		return nil, txRepo.RecordToolScheduleRun(ctx, schedule)
+	}
+
+	runs, skipped, next := dueRuns(cronSchedule, loc, *schedule.NextRunAt, now, t.misfireGrace, schedule.MissedRunPolicy)
+	schedule.NextRunAt = nil
+	if !next.IsZero() {
+		schedule.NextRunAt = &next
+	}
+	if skipped > 0 {
+		fmt.Printf("tool schedule %d: %d missed runs skipped (missed run policy %s)\n",
+			schedule.ID, skipped, schedule.MissedRunPolicy)
+	}
+	if len(runs) == 0 {
+		return nil, txRepo.RecordToolScheduleRun(ctx, schedule)
+	}
+
+	lastRun := runs[len(runs)-1]
+	schedule.LastRunAt = &lastRun
+
+	tool, refusal := authorizeExecution(ctx, txRepo, schedule.ClientID, schedule.ToolID)
+	if refusal != nil {
+		schedule.LastError = refusal.Message
+		return nil, txRepo.RecordToolScheduleRun(ctx, schedule)
+	}
+	if err := validatePayload(tool.ProviderInterface, schedule.Payload, nil); err != nil {
+		schedule.LastError = err.Error()
+		return nil, txRepo.RecordToolScheduleRun(ctx, schedule)
+	}
+
+	created := make([]*entity.ToolRequest, 0, len(runs))
+	for _, run := range runs {
+		reserveExecution(ctx, t.quota, schedule.ClientID, reservation)
+		if reservation.exceeded {
+			break
+		}
+
+		toolRequest := &entity.ToolRequest{
+			ToolID:   schedule.ToolID,
+			ClientID: schedule.ClientID,
+			RequestData: shared_type.ToolRequestData{
+				Payload:    schedule.Payload,
+				WebhookURL: schedule.WebhookURL,
+				Schedule: &shared_type.ToolRequestSchedule{
+					ScheduleID:   schedule.ID,
+					ScheduledFor: run,
+				},
+			},
+			ResponseData: shared_type.ToolRequestResponseData{},
+			Status:       valueobject.ToolRequestStatusPending,
+		}
+
+		t.payloadStore.offloadRequest(ctx, toolRequest)
+		created = append(created, toolRequest)
+
+		createdToolRequest, err := txRepo.CreateToolRequest(ctx, toolRequest)
+		if err != nil {
+			return created, err
+		}
+		schedule.LastToolRequestID = &createdToolRequest.ID
+	}
+
+	schedule.LastError = ""
+	if skipped := len(runs) - len(created); skipped > 0 {
+		retryAt := now.Add(reservation.retryAfter)
+		fmt.Printf("tool schedule %d: %d runs skipped, execution quota of client %d exceeded\n",
+			schedule.ID, skipped, schedule.ClientID)
+		schedule.LastError = fmt.Sprintf("execution quota exceeded, %d runs skipped (quota resets at %s)",
+			skipped, retryAt.UTC().Format(time.RFC3339))
+	}
+	if len(created) > 0 {
+		fmt.Printf("tool schedule %d fired %d runs (last scheduled for %v)\n",
+			schedule.ID, len(created), created[len(created)-1].RequestData.Schedule.ScheduledFor)
+	}
+	return created, txRepo.RecordToolScheduleRun(ctx, schedule)
+}
+
+// dueRuns returns the runs of a schedule due at now, from its next run on, with the missed run policy applied
+// to the runs overdue by more than grace. It also returns the number of missed runs dropped by the policy
+// and the first run after now (zero when the schedule has none).
+func dueRuns(
+	cronSchedule *cron.Schedule, loc *time.Location, nextRun time.Time, now time.Time, grace time.Duration,
+	policy valueobject.ToolScheduleMissedRunPolicy,
+) ([]time.Time, int, time.Time) {
+	var missed, onTime []time.Time
+	skipped := 0
+
+	run := nextRun.In(loc)
+	for ; !run.IsZero() && !run.After(now); run = cronSchedule.Next(run) {
+		if now.Sub(run) <= grace {
+			onTime = append(onTime, run)
+			continue
+		}
+
+		missed = append(missed, run)
+		if len(missed) > maxScheduleCatchUpRuns {
+			missed = missed[1:]
+			skipped++
+		}
+	}
+
+	switch policy {
+	case valueobject.ToolScheduleMissedRunPolicySkip:
+		skipped += len(missed)
+		missed = nil
+	case valueobject.ToolScheduleMissedRunPolicyRunAll:
+	default:
+		if len(missed) > 1 {
+			skipped += len(missed) - 1
+			missed = missed[len(missed)-1:]
+		}
+	}
+
+	return append(missed, onTime...), skipped, run
+}
+
+// parseSchedule parses the cron expression of a schedule and loads its timezone (UTC when empty).
+func parseSchedule(expression string, timezone string) (*cron.Schedule, *time.Location, error) {
+	cronSchedule, err := cron.Parse(expression)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrInvalidToolSchedule, err)
+	}
+
+	if strings.TrimSpace(timezone) == "" {
+		timezone = "UTC"
+	}
+	loc, err := time.LoadLocation(timezone)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: unknown timezone %q", ErrInvalidToolSchedule, timezone)
+	}
+
+	return cronSchedule, loc, nil
+}
@@ -0,0 +1,125 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"aigendrug.com/router-core/internal/shared/cron"
+	"aigendrug.com/router-core/internal/tool/domain/valueobject"
+)
+
+func TestDueRuns(t *testing.T) {
+	hourly, err := cron.Parse("0 * * * *")
+	if err != nil {
+		t.Fatal(err)
+	}
+	daily, err := cron.Parse("0 9 * * *")
+	if err != nil {
+		t.Fatal(err)
+	}
+	seoul, err := time.LoadLocation("Asia/Seoul")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	hours := func(from int, to int) []time.Time {
+		var runs []time.Time
+		for hour := from; hour <= to; hour++ {
+			runs = append(runs, start.Add(time.Duration(hour)*time.Hour))
+		}
+		return runs
+	}
+
+	tests := []struct {
+		name        string
+		schedule    *cron.Schedule
+		loc         *time.Location
+		now         time.Time
+		policy      valueobject.ToolScheduleMissedRunPolicy
+		wantRuns    []time.Time
+		wantSkipped int
+		wantNext    time.Time
+	}{
+		{
+			name:     "not due yet",
+			schedule: hourly, loc: time.UTC,
+			now:      start.Add(-30 * time.Minute),
+			wantNext: start,
+		},
+		{
+			name:     "due within the grace period",
+			schedule: hourly, loc: time.UTC,
+			now:      start.Add(30 * time.Second),
+			wantRuns: hours(0, 0),
+			wantNext: start.Add(time.Hour),
+		},
+		{
+			name:     "missed runs run once by default",
+			schedule: hourly, loc: time.UTC,
+			now:      start.Add(3*time.Hour + 30*time.Second),
+			wantRuns: hours(2, 3), wantSkipped: 2,
+			wantNext: start.Add(4 * time.Hour),
+		},
+		{
+			name:     "missed runs run once",
+			schedule: hourly, loc: time.UTC,
+			now:      start.Add(3*time.Hour + 30*time.Second),
+			policy:   valueobject.ToolScheduleMissedRunPolicyRunOnce,
+			wantRuns: hours(2, 3), wantSkipped: 2,
+			wantNext: start.Add(4 * time.Hour),
+		},
+		{
+			name:     "missed runs skipped",
+			schedule: hourly, loc: time.UTC,
+			now:      start.Add(3*time.Hour + 30*time.Second),
+			policy:   valueobject.ToolScheduleMissedRunPolicySkip,
+			wantRuns: hours(3, 3), wantSkipped: 3,
+			wantNext: start.Add(4 * time.Hour),
+		},
+		{
+			name:     "missed runs all run",
+			schedule: hourly, loc: time.UTC,
+			now:      start.Add(3*time.Hour + 30*time.Second),
+			policy:   valueobject.ToolScheduleMissedRunPolicyRunAll,
+			wantRuns: hours(0, 3),
+			wantNext: start.Add(4 * time.Hour),
+		},
+		{
+			name:     "missed runs skipped beyond the catch-up limit",
+			schedule: hourly, loc: time.UTC,
+			now:      start.Add(200*time.Hour + 30*time.Second),
+			policy:   valueobject.ToolScheduleMissedRunPolicyRunAll,
+			wantRuns: hours(200-maxScheduleCatchUpRuns, 200), wantSkipped: 200 - maxScheduleCatchUpRuns,
+			wantNext: start.Add(201 * time.Hour),
+		},
+		{
+			name:     "schedule in its timezone",
+			schedule: daily, loc: seoul,
+			// 09:00 in Seoul
+			now:      start.Add(10 * time.Second),
+			wantRuns: []time.Time{start},
+			wantNext: start.Add(24 * time.Hour),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			runs, skipped, next := dueRuns(tt.schedule, tt.loc, start, tt.now, time.Minute, tt.policy)
+			if len(runs) != len(tt.wantRuns) {
+				t.Fatalf("dueRuns() = %d runs %v, want %d runs", len(runs), runs, len(tt.wantRuns))
+			}
+			for i := range runs {
+				if !runs[i].Equal(tt.wantRuns[i]) {
+					t.Fatalf("run %d = %v, want %v", i, runs[i], tt.wantRuns[i])
+				}
+			}
+			if skipped != tt.wantSkipped {
+				t.Fatalf("dueRuns() skipped %d runs, want %d", skipped, tt.wantSkipped)
+			}
+			if !next.Equal(tt.wantNext) {
+				t.Fatalf("dueRuns() next run = %v, want %v", next, tt.wantNext)
+			}
+		})
+	}
+}
@@ -1,34 +1,375 @@
 package service
 
 import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"aigendrug.com/router-core/internal/config"
+	"aigendrug.com/router-core/internal/shared/database/postgres"
 	"aigendrug.com/router-core/internal/tool/domain"
+	"aigendrug.com/router-core/internal/tool/domain/entity"
+	"aigendrug.com/router-core/internal/tool/domain/shared_type"
+	"aigendrug.com/router-core/internal/tool/domain/valueobject"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+// Default queue settings.
+// Overridden by config (SCHEDULER_*).
+const (
+	DefaultSchedulerWorkers      = 8
+	DefaultSchedulerLease        = 1 * time.Minute
+	DefaultSchedulerHeartbeat    = 20 * time.Second
+	DefaultSchedulerPollInterval = 2 * time.Second
+	DefaultSchedulerMaxClaims    = 3
+)
+
+// ToolRequestScheduler executes pending tool requests stored in the database.
+//
+// Every replica runs a pool of workers which claim pending requests with
+// SELECT ... FOR UPDATE SKIP LOCKED, so a request is executed by a single worker at a time.
+// A request is claimed only while its client, its tool and the pair run fewer requests than their concurrency limits,
+// the claims of all replicas being serialized so that the limits hold across replicas.
+// A claimed request holds a lease which is renewed by a heartbeat while it runs.
+// An invoked async-event request does not hold a worker while its job runs: it stays running without lease
+// between its status checks, and is claimed again (before any pending request) when its next check is due.
+// Requests whose lease expired (e.g. the replica crashed) are put back to pending,
+// or failed once they have been claimed too many times.
 type ToolRequestScheduler interface {
+	// Start launches the workers and the lease reaper. They stop when ctx is done.
+	Start(ctx context.Context)
+	// Notify wakes an idle worker of this replica up, e.g. right after a request is enqueued.
+	Notify()
+	// Cancel aborts the execution of the tool request when it runs on this replica.
+	// Executions on other replicas are aborted by their next heartbeat.
+	Cancel(toolRequestID int) bool
+	// Limits returns the concurrency limits applied to the claims, before the policies of the tools.
+	Limits() shared_type.ConcurrencyLimits
+	// WorkerID identifies this replica, it locks the tool requests it claims (locked_by).
+	WorkerID() string
 }
 
-type tooloolRequestScheduler struct {
-	db       *pgxpool.Pool
-	toolRepo domain.ToolRepository
+type toolRequestScheduler struct {
+	db               *pgxpool.Pool
+	toolRepo         domain.ToolRepository
+	functionExecutor FunctionExecutor
+	notifier         ToolRequestNotifier
+
+	workerID     string
+	workers      int
+	lease        time.Duration
+	heartbeat    time.Duration
+	pollInterval time.Duration
+	maxClaims    int
+	limits       shared_type.ConcurrencyLimits
+
+	wakeup chan struct{}
+
+	mu      sync.Mutex
+	running map[int]context.CancelFunc
 }
 
 func NewToolRequestScheduler(
+	config *config.Config,
 	db *pgxpool.Pool,
 	toolRepo domain.ToolRepository,
+	functionExecutor FunctionExecutor,
+	notifier ToolRequestNotifier,
 ) ToolRequestScheduler {
-	return &tooloolRequestScheduler{
-		db:       db,
-		toolRepo: toolRepo,
+	s := &toolRequestScheduler{
+		db:               db,
+		toolRepo:         toolRepo,
+		functionExecutor: functionExecutor,
+		notifier:         notifier,
+		workerID:         newWorkerID(),
+		workers:          DefaultSchedulerWorkers,
+		lease:            DefaultSchedulerLease,
+		heartbeat:        DefaultSchedulerHeartbeat,
+		pollInterval:     DefaultSchedulerPollInterval,
+		maxClaims:        DefaultSchedulerMaxClaims,
+		running:          make(map[int]context.CancelFunc),
+	}
+
+	if config != nil {
+		if v := config.Scheduler.Workers; v > 0 {
+			s.workers = v
+		}
+		if v := config.Scheduler.LeaseSeconds; v > 0 {
+			s.lease = secondsToDuration(v)
+		}
+		if v := config.Scheduler.HeartbeatSeconds; v > 0 {
+			s.heartbeat = secondsToDuration(v)
+		}
+		if v := config.Scheduler.PollIntervalSeconds; v > 0 {
+			s.pollInterval = secondsToDuration(v)
+		}
+		if v := config.Scheduler.MaxClaims; v > 0 {
+			s.maxClaims = v
+		}
+		s.limits = shared_type.ConcurrencyLimits{
+			PerClient:     max(config.Scheduler.MaxPerClient, 0),
+			PerTool:       max(config.Scheduler.MaxPerTool, 0),
+			PerClientTool: max(config.Scheduler.MaxPerClientTool, 0),
+		}
+	}
+
+	// the lease must survive at least one missed heartbeat
+	if s.heartbeat > s.lease/2 {
+		s.heartbeat = s.lease / 2
+	}
+
+	s.wakeup = make(chan struct{}, s.workers)
+
+	return s
+}
+
+func newWorkerID() string {
+	hostname, err := os.Hostname()
+	if err != nil || hostname == "" {
+		hostname = "router-core"
+	}
+	return fmt.Sprintf("%s-%s", hostname, uuid.NewString())
+}
+
+func (s *toolRequestScheduler) Start(ctx context.Context) {
+	fmt.Printf("starting tool request scheduler %s with %d workers (running requests per client %s, per tool %s, per client and tool %s)\n",
+		s.workerID, s.workers, limitString(s.limits.PerClient), limitString(s.limits.PerTool), limitString(s.limits.PerClientTool))
+
+	for i := 0; i < s.workers; i++ {
+		go s.runWorker(ctx)
+	}
+	go s.runReaper(ctx)
+}
+
+func (s *toolRequestScheduler) Notify() {
+	select {
+	case s.wakeup <- struct{}{}:
+	default:
+		// every worker is already about to look for work
+	}
+}
+
+func (s *toolRequestScheduler) Cancel(toolRequestID int) bool {
+	s.mu.Lock()
+	cancel, ok := s.running[toolRequestID]
+	s.mu.Unlock()
+
+	if ok {
+		cancel()
+	}
+	return ok
+}
+
+func (s *toolRequestScheduler) Limits() shared_type.ConcurrencyLimits {
+	return s.limits
+}
+
+func (s *toolRequestScheduler) WorkerID() string {
+	return s.workerID
+}
+
+// runWorker drains the queue, then sleeps until notified or until the poll interval elapses
+// (requests enqueued by other replicas are only seen by polling).
+func (s *toolRequestScheduler) runWorker(ctx context.Context) {
+	ticker := time.NewTicker(s.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		for s.claimAndExecute(ctx) {
+			if ctx.Err() != nil {
+				return
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.wakeup:
+		case <-ticker.C:
+		}
+	}
+}
+
+// claimAndExecute executes a single pending tool request.
+// It reports whether a request was claimed, so the caller knows whether the queue may hold more.
+func (s *toolRequestScheduler) claimAndExecute(ctx context.Context) bool {
+	claimCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	toolRequest, statusCheck, err := s.claim(claimCtx)
+	cancel()
+	if err != nil {
+		fmt.Printf("failed to claim tool request: %v\n", err)
+		return false
+	}
+	if toolRequest == nil {
+		return false
 	}
+
+	jobCtx, jobCancel := context.WithCancel(ctx)
+	defer jobCancel()
+
+	s.mu.Lock()
+	s.running[toolRequest.ID] = jobCancel
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(s.running, toolRequest.ID)
+		s.mu.Unlock()
+	}()
+
+	heartbeatDone := make(chan struct{})
+	defer close(heartbeatDone)
+	go s.runHeartbeat(jobCtx, jobCancel, toolRequest.ID, heartbeatDone)
+
+	s.execute(jobCtx, toolRequest, statusCheck)
+
+	return true
 }
 
-// func (s *tooloolRequestScheduler) reftechToolRequest(
-// 	ctx context.Context,
-// 	toolRequest *entity.ToolRequest,
-// ) error {
-// 	tool, err := s.toolRepo.FindToolByID(ctx, toolRequest.ToolID)
-// 	if err != nil {
-// 		return err
-// 	}
-// }
+// claim claims the running request whose status check is due, or else the next pending request within
+// the concurrency limits, holding the claims of the other replicas until the request is running.
+// It reports whether the claimed request is a status check.
+func (s *toolRequestScheduler) claim(ctx context.Context) (*entity.ToolRequest, bool, error) {
+	statusCheck := false
+	toolRequest, err := postgres.WithTxResult(ctx, s.db, func(tx pgx.Tx) (*entity.ToolRequest, error) {
+		txRepo := s.toolRepo.WithTx(ctx, tx)
+
+		// a running request already counts against the limits, its status checks are not held by the claims lock
+		toolRequest, err := txRepo.ClaimToolRequestStatusCheck(ctx, s.workerID, s.lease)
+		if err != nil || toolRequest != nil {
+			statusCheck = toolRequest != nil
+			return toolRequest, err
+		}
+
+		if err := txRepo.LockToolRequestClaims(ctx); err != nil {
+			return nil, err
+		}
+		return txRepo.ClaimToolRequest(ctx, s.workerID, s.lease, s.limits)
+	})
+	return toolRequest, statusCheck, err
+}
+
+func limitString(limit int) string {
+	if limit <= 0 {
+		return "unlimited"
+	}
+	return strconv.Itoa(limit)
+}
+
+// execute runs the claimed tool request. A status check continues a request which is already running,
+// its status is not published again.
+func (s *toolRequestScheduler) execute(ctx context.Context, toolRequest *entity.ToolRequest, statusCheck bool) {
+	toolRequest.LockedBy = s.workerID
+
+	if !statusCheck {
+		s.notifier.Publish(newToolRequestEvent(ToolRequestEventTypeStatus, toolRequest, ""))
+	}
+
+	tool, err := s.toolRepo.FindToolByID(ctx, toolRequest.ToolID)
+	if err != nil {
+		toolRequest.ResponseData.Error = fmt.Sprintf("tool not found: %v", err)
+		toolRequest.Status = valueobject.ToolRequestStatusFailed
+		if _, err := s.toolRepo.CompleteToolRequest(ctx, toolRequest); err != nil {
+			fmt.Printf("failed to update tool request: %v\n", err)
+			return
+		}
+		s.notifier.Publish(newToolRequestEvent(ToolRequestEventTypeStatus, toolRequest, ""))
+		return
+	}
+
+	if statusCheck {
+		fmt.Printf("worker %s checking status of tool request %d\n", s.workerID, toolRequest.ID)
+	} else {
+		fmt.Printf("worker %s executing tool request %d (claim %d)\n", s.workerID, toolRequest.ID, toolRequest.ClaimCount)
+	}
+
+	switch tool.EngineInterface.EngineInterfaceInvokeType {
+	case valueobject.EngineInterfaceInvokeTypeAsyncEvent:
+		s.functionExecutor.Async(ctx, tool, toolRequest)
+	default:
+		s.functionExecutor.Sync(ctx, tool, toolRequest)
+	}
+}
+
+// runHeartbeat renews the lease of the running tool request until done is closed.
+// Losing the lease cancels the job context: the request was cancelled,
+// or it now belongs to the reaper or another worker.
+func (s *toolRequestScheduler) runHeartbeat(
+	ctx context.Context, cancel context.CancelFunc, toolRequestID int, done <-chan struct{},
+) {
+	ticker := time.NewTicker(s.heartbeat)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		renewCtx, renewCancel := context.WithTimeout(ctx, s.heartbeat)
+		renewed, err := s.toolRepo.RenewToolRequestLease(renewCtx, toolRequestID, s.workerID, s.lease)
+		renewCancel()
+		if err != nil {
+			// transient, the lease outlives a missed heartbeat
+			fmt.Printf("failed to renew lease of tool request %d: %v\n", toolRequestID, err)
+			continue
+		}
+		if !renewed {
+			fmt.Printf("lease of tool request %d was lost, aborting execution\n", toolRequestID)
+			cancel()
+			return
+		}
+	}
+}
+
+// runReaper periodically releases tool requests whose lease expired.
+func (s *toolRequestScheduler) runReaper(ctx context.Context) {
+	ticker := time.NewTicker(s.lease / 2)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		reapCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+		requeued, failed, err := s.toolRepo.ReleaseExpiredToolRequests(reapCtx, s.maxClaims)
+		cancel()
+		if err != nil {
+			fmt.Printf("failed to release expired tool requests: %v\n", err)
+			continue
+		}
+		if requeued > 0 || len(failed) > 0 {
+			fmt.Printf("released expired tool requests: %d requeued, %d failed\n", requeued, len(failed))
+		}
+		if requeued > 0 {
+			s.Notify()
+		}
+		for _, id := range failed {
+			s.publishReleasedToolRequest(ctx, id)
+		}
+	}
+}
+
+// publishReleasedToolRequest publishes the status of a tool request failed by the reaper,
+// which nobody else reports since its worker is gone.
+func (s *toolRequestScheduler) publishReleasedToolRequest(ctx context.Context, toolRequestID int) {
+	findCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	toolRequest, err := s.toolRepo.FindToolRequestByID(findCtx, toolRequestID)
+	if err != nil {
+		fmt.Printf("failed to read released tool request %d: %v\n", toolRequestID, err)
+		return
+	}
+	s.notifier.Publish(newToolRequestEvent(ToolRequestEventTypeStatus, toolRequest, ""))
+}
@@ -0,0 +1,195 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	http_wrapper "aigendrug.com/router-core/internal/shared/http-wrapper"
+	s3_wrapper "aigendrug.com/router-core/internal/shared/s3-wrapper"
+)
+
+// CheckStatusResult is the outcome of a single status check of an asynchronous invocation.
+// Progress is an optional progress report of a running invocation.
+type CheckStatusResult struct {
+	Done     bool
+	Failed   bool
+	Reason   string
+	Payload  map[string]any
+	Progress string
+}
+
+// StatusChecker checks the completion of an asynchronous invocation
+// described by ToolRequestResponseData.CheckStatusImpl.
+//
+// A returned error is treated as transient: the caller keeps polling until its own limits are reached.
+// Permanent failures are reported through CheckStatusResult.Failed.
+type StatusChecker interface {
+	Check(ctx context.Context, checkStatusImpl map[string]any) (*CheckStatusResult, error)
+}
+
+type httpStatusChecker struct {
+	httpClient http_wrapper.HTTPWrapperClient
+}
+
+func NewHTTPStatusChecker(httpClient http_wrapper.HTTPWrapperClient) StatusChecker {
+	return &httpStatusChecker{httpClient: httpClient}
+}
+
+// Check polls "url" of checkStatusImpl using GET method.
+//
+// - 202 Accepted is treated as still running.
+// - 5xx responses are transient errors, other non 2xx responses fail the request.
+// - A JSON body with a "status" field is interpreted as a job status document:
+// pending / queued / running / in_progress keep polling (with an optional "progress" / "message"), success / succeeded / completed / done finish the request,
+// failed / failure / error / cancelled fail it. The result is read from "payload" or "result" when present.
+// - A 2xx body without "status" is the result itself.
+func (c *httpStatusChecker) Check(ctx context.Context, checkStatusImpl map[string]any) (*CheckStatusResult, error) {
+	statusURL, ok := implString(checkStatusImpl, "url")
+	if !ok {
+		return &CheckStatusResult{Done: true, Failed: true, Reason: "check_status_impl has no url"}, nil
+	}
+
+	headers, _ := implMap(checkStatusImpl, "headers")
+
+	output, err := c.httpClient.Invoke(ctx, http_wrapper.InvokeInput{
+		Method: http.MethodGet,
+		URL:    statusURL,
+		Header: headers,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if output.StatusCode == http.StatusAccepted {
+		return &CheckStatusResult{}, nil
+	}
+	if output.StatusCode >= http.StatusInternalServerError {
+		return nil, fmt.Errorf("status url %s returned status code %d", statusURL, output.StatusCode)
+	}
+	if output.StatusCode < http.StatusOK || output.StatusCode >= http.StatusMultipleChoices {
+		return &CheckStatusResult{
+			Done:   true,
+			Failed: true,
+			Reason: fmt.Sprintf("status url %s returned status code %d: %s",
+				statusURL, output.StatusCode, truncate(string(output.Body), 512)),
+		}, nil
+	}
+
+	body, err := http_wrapper.DecodeBody(output.Header.Get("Content-Type"), output.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	return interpretStatusDocument(body), nil
+}
+
+type s3StatusChecker struct {
+	s3Client s3_wrapper.S3WrapperClient
+}
+
+func NewS3StatusChecker(s3Client s3_wrapper.S3WrapperClient) StatusChecker {
+	return &s3StatusChecker{s3Client: s3Client}
+}
+
+// Check watches "aws_s3_bucket" / "aws_s3_key" of checkStatusImpl with HeadObject.
+// The request is still running until the result object exists; the object is then
+// downloaded and parsed by its content type (JSON documents are read as status documents, see httpStatusChecker).
+// When "aws_s3_error_key" is set and that object appears first, the request fails with its content.
+func (c *s3StatusChecker) Check(ctx context.Context, checkStatusImpl map[string]any) (*CheckStatusResult, error) {
+	bucket, ok := implString(checkStatusImpl, "aws_s3_bucket")
+	if !ok {
+		return &CheckStatusResult{Done: true, Failed: true, Reason: "check_status_impl has no aws_s3_bucket"}, nil
+	}
+	key, ok := implString(checkStatusImpl, "aws_s3_key")
+	if !ok {
+		return &CheckStatusResult{Done: true, Failed: true, Reason: "check_status_impl has no aws_s3_key"}, nil
+	}
+
+	if errorKey, ok := implString(checkStatusImpl, "aws_s3_error_key"); ok {
+		_, exists, err := c.s3Client.HeadObject(ctx, bucket, errorKey)
+		if err != nil {
+			return nil, err
+		}
+		if exists {
+			body, _, err := c.s3Client.GetObject(ctx, bucket, errorKey)
+			if err != nil {
+				return nil, err
+			}
+			return &CheckStatusResult{
+				Done: true, Failed: true,
+				Reason: fmt.Sprintf("tool reported error at s3://%s/%s: %s", bucket, errorKey, truncate(string(body), 512)),
+			}, nil
+		}
+	}
+
+	head, exists, err := c.s3Client.HeadObject(ctx, bucket, key)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return &CheckStatusResult{}, nil
+	}
+
+	body, contentType, err := c.s3Client.GetObject(ctx, bucket, key)
+	if err != nil {
+		return nil, err
+	}
+	if contentType == "" && head.ContentType != nil {
+		contentType = *head.ContentType
+	}
+	if strings.HasSuffix(strings.ToLower(key), ".json") {
+		contentType = http_wrapper.ContentTypeJSON
+	}
+
+	payload, err := http_wrapper.DecodeBody(contentType, body)
+	if err != nil {
+		return &CheckStatusResult{Done: true, Failed: true, Reason: err.Error()}, nil
+	}
+
+	return interpretStatusDocument(payload), nil
+}
+
+func interpretStatusDocument(body map[string]any) *CheckStatusResult {
+	status, ok := body["status"].(string)
+	if !ok {
+		return &CheckStatusResult{Done: true, Payload: body}
+	}
+
+	switch strings.ToLower(status) {
+	case "pending", "queued", "running", "in_progress", "in-progress", "processing":
+		return &CheckStatusResult{Progress: statusDocumentProgress(status, body)}
+	case "success", "succeeded", "completed", "complete", "done":
+		return &CheckStatusResult{Done: true, Payload: statusDocumentPayload(body)}
+	case "failed", "failure", "error", "cancelled", "canceled":
+		reason := fmt.Sprintf("tool reported status %s", status)
+		if message, ok := body["error"].(string); ok && message != "" {
+			reason = message
+		}
+		return &CheckStatusResult{Done: true, Failed: true, Reason: reason, Payload: statusDocumentPayload(body)}
+	default:
+		return &CheckStatusResult{Done: true, Payload: body}
+	}
+}
+
+func statusDocumentPayload(body map[string]any) map[string]any {
+	for _, key := range []string{"payload", "result"} {
+		if payload, ok := body[key].(map[string]any); ok {
+			return payload
+		}
+	}
+	return body
+}
+
+// statusDocumentProgress describes a running job from the "progress" and "message" fields of its status document.
+func statusDocumentProgress(status string, body map[string]any) string {
+	progress := status
+	if value, ok := implString(body, "progress"); ok {
+		progress += " " + value
+	}
+	if message, ok := implString(body, "message"); ok {
+		progress += ": " + message
+	}
+	return truncate(progress, 512)
+}
@@ -1,20 +1,74 @@
 package service
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"time"
 
+	"aigendrug.com/router-core/internal/shared/blobstore"
 	lambda_wrapper "aigendrug.com/router-core/internal/shared/lambda-wrapper"
 	"aigendrug.com/router-core/internal/shared/selector"
+	"aigendrug.com/router-core/internal/shared/utils"
 	"aigendrug.com/router-core/internal/tool/application/dto"
 	"aigendrug.com/router-core/internal/tool/domain"
 	"aigendrug.com/router-core/internal/tool/domain/entity"
 	"aigendrug.com/router-core/internal/tool/domain/shared_type"
 	"aigendrug.com/router-core/internal/tool/domain/valueobject"
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+// MaxToolRequestWait bounds the wait mode of ExecuteTool.
+// waitFallbackInterval re-reads the tool request while waiting, covering notifications lost while a replica reconnects.
+// streamPingInterval keeps idle event streams alive through proxies.
+const (
+	MaxToolRequestWait   = 2 * time.Minute
+	waitFallbackInterval = 5 * time.Second
+	streamPingInterval   = 15 * time.Second
+)
+
+var (
+	ErrToolRequestNotFound       = errors.New("tool request not found")
+	ErrToolRequestForbidden      = errors.New("you don't have permission to cancel this tool request")
+	ErrToolRequestNotCancellable = errors.New("tool request already finished")
+	ErrInvalidToolInterface      = errors.New("invalid provider interface")
+	ErrInvalidEngineInterface    = errors.New("invalid engine interface")
+	ErrToolRequestAccessDenied   = errors.New("you don't have permission to access this tool request")
+	ErrInvalidPayloadPart        = errors.New("invalid payload part (request, response or raw_response)")
+	ErrPayloadNotFound           = errors.New("payload not found")
+	ErrToolResultCacheNotFound   = errors.New("cached result not found")
+	ErrToolScheduleNotFound      = errors.New("tool schedule not found")
+	ErrToolScheduleForbidden     = errors.New("you don't have permission to access this tool schedule")
+	ErrInvalidToolSchedule       = errors.New("invalid tool schedule")
+	ErrToolExecutionRefused      = errors.New("tool execution refused")
+	ErrToolNotFound              = errors.New("tool not found")
+	ErrNoHealthyTool             = errors.New("no healthy tool available for this prompt")
+)
+
+// DefaultToolSchedulePreviewCount / MaxToolSchedulePreviewCount bound the next runs listed by the schedule previews.
+const (
+	DefaultToolSchedulePreviewCount = 5
+	MaxToolSchedulePreviewCount     = 50
+)
+
+// ToolHealthChecksListed is the number of recent health checks returned with the health of a tool.
+const ToolHealthChecksListed = 50
+
+// ToolRequestPayload is a payload of a tool request streamed by OpenToolRequestPayload, the caller closes Body.
+// SHA256 is only known for offloaded payloads.
+type ToolRequestPayload struct {
+	Body   io.ReadCloser
+	Size   int64
+	SHA256 string
+}
+
 type ToolService interface {
 	// Tool
 	GetAllTools(ctx context.Context) ([]*dto.ReadToolDTO, error)
@@ -24,6 +78,18 @@ type ToolService interface {
 	CreateTool(ctx context.Context, tool *dto.CreateToolDTO) (*dto.ReadToolDTO, error)
 	UpdateTool(ctx context.Context, id int, tool *dto.UpdateToolDTO) error
 	DeleteTool(ctx context.Context, id int) error
+	PublishTool(ctx context.Context, id int) error
+
+	// ToolHealth
+	GetToolHealth(ctx context.Context, id int) (*dto.ReadToolHealthDTO, error)
+	CheckToolHealth(ctx context.Context, id int) (*dto.ReadToolHealthDTO, error)
+	GetAllCircuitBreakers(ctx context.Context) ([]*dto.ReadCircuitBreakerDTO, error)
+	GetCircuitBreaker(ctx context.Context, id int) (*dto.ReadCircuitBreakerDTO, error)
+	ResetCircuitBreaker(ctx context.Context, id int) (*dto.ReadCircuitBreakerDTO, error)
+
+	// Lambda import
+	ListLambdaFunctions(ctx context.Context) ([]*dto.ReadLambdaFunctionDTO, error)
+	ImportLambdaTools(ctx context.Context, request *dto.ImportLambdaToolsDTO) (*dto.ImportLambdaToolsResultDTO, error)
 
 	// ToolClientPermission
 	GetAllToolClientPermissionsByToolID(ctx context.Context, toolID int) ([]*dto.ReadToolClientPermissionDTO, error)
@@ -40,12 +106,37 @@ type ToolService interface {
 	CreateToolRequest(ctx context.Context, toolRequest *dto.CreateToolRequestDTO) (*dto.ReadToolRequestDTO, error)
 	UpdateToolRequest(ctx context.Context, id int, toolRequest *dto.UpdateToolRequestDTO) error
 	DeleteToolRequest(ctx context.Context, id int) error
+	CancelToolRequest(ctx context.Context, clientID int, isAdmin bool, id int) (*dto.ReadToolRequestDTO, error)
+	WaitToolRequest(ctx context.Context, id int, wait time.Duration) (*dto.ReadToolRequestDTO, error)
+	OpenToolRequestPayload(ctx context.Context, clientID int, isAdmin bool, id int, part string) (*ToolRequestPayload, error)
+	GetToolRequestLogs(ctx context.Context, id int) (*dto.ReadToolRequestLogsDTO, error)
+	StreamToolRequestEvents(ctx context.Context, clientID int, isAdmin bool, id int, send func(dto.ToolRequestEventDTO) error) error
+	StreamClientToolRequestEvents(ctx context.Context, clientID int, send func(dto.ToolRequestEventDTO) error) error
+
+	// ToolResultCache
+	GetAllToolResultCacheByToolID(ctx context.Context, toolID int) ([]*dto.ReadToolResultCacheDTO, error)
+	InvalidateToolResultCache(ctx context.Context, toolID int) (int64, error)
+	DeleteToolResultCache(ctx context.Context, toolID int, id int) error
+
+	// ToolSchedule
+	GetAllToolSchedules(ctx context.Context) ([]*dto.ReadToolScheduleDTO, error)
+	GetAllToolSchedulesByToolID(ctx context.Context, toolID int) ([]*dto.ReadToolScheduleDTO, error)
+	GetAllToolSchedulesByClientID(ctx context.Context, clientID int) ([]*dto.ReadToolScheduleDTO, error)
+	GetToolScheduleByID(ctx context.Context, clientID int, isAdmin bool, id int) (*dto.ReadToolScheduleDTO, error)
+	CreateToolSchedule(ctx context.Context, clientID int, schedule *dto.CreateToolScheduleDTO) (*dto.ReadToolScheduleDTO, error)
+	UpdateToolSchedule(ctx context.Context, clientID int, isAdmin bool, id int, schedule *dto.UpdateToolScheduleDTO) (*dto.ReadToolScheduleDTO, error)
+	DeleteToolSchedule(ctx context.Context, clientID int, isAdmin bool, id int) error
+	PauseToolSchedule(ctx context.Context, clientID int, isAdmin bool, id int) (*dto.ReadToolScheduleDTO, error)
+	ResumeToolSchedule(ctx context.Context, clientID int, isAdmin bool, id int) (*dto.ReadToolScheduleDTO, error)
+	GetToolScheduleNextRuns(ctx context.Context, clientID int, isAdmin bool, id int, count int) (*dto.ToolScheduleRunsDTO, error)
+	PreviewToolSchedule(ctx context.Context, preview *dto.PreviewToolScheduleDTO) (*dto.ToolScheduleRunsDTO, error)
 
 	// Selector
 	SelectTool(ctx context.Context, clientID int, userPrompt string) (*dto.SelectToolResponseDTO, error)
 
 	// Tool Execution
-	ExecuteTool(ctx context.Context, clientID int, toolID int, requestData dto.ToolExecutionRequestDTO) (*dto.ToolExecutionResponseDTO, error)
+	ExecuteTool(ctx context.Context, clientID int, isAdmin bool, toolID int, requestData dto.ToolExecutionRequestDTO) (*dto.ToolExecutionResponseDTO, error)
+	ExecuteToolWithFiles(ctx context.Context, clientID int, isAdmin bool, toolID int, requestData dto.ToolExecutionRequestDTO, files []ToolExecutionFile) (*dto.ToolExecutionResponseDTO, error)
 }
 
 type toolService struct {
@@ -53,21 +144,40 @@ type toolService struct {
 	toolRepo         domain.ToolRepository
 	selectorService  selector.SelectorService
 	functionExecutor FunctionExecutor
+	lambdaClient     lambda_wrapper.LambdaWrapperClient
+	scheduler        ToolRequestScheduler
+	notifier         ToolRequestNotifier
+	blobStore        blobstore.BlobStore
+	payloadStore     *PayloadStore
+	healthProber     ToolHealthProber
+	quota            ExecutionQuota
 }
 
 func NewToolService(
 	dbPool *pgxpool.Pool,
 	toolRepo domain.ToolRepository,
 	selectorService selector.SelectorService,
+	functionExecutor FunctionExecutor,
 	lambdaClient lambda_wrapper.LambdaWrapperClient,
+	scheduler ToolRequestScheduler,
+	notifier ToolRequestNotifier,
+	blobStore blobstore.BlobStore,
+	payloadStore *PayloadStore,
+	healthProber ToolHealthProber,
+	quota ExecutionQuota,
 ) ToolService {
-	functionExecutor := NewFunctionExecutor(context.Background(), toolRepo, lambdaClient)
-
 	return &toolService{
 		db:               dbPool,
 		toolRepo:         toolRepo,
 		selectorService:  selectorService,
 		functionExecutor: functionExecutor,
+		lambdaClient:     lambdaClient,
+		scheduler:        scheduler,
+		notifier:         notifier,
+		blobStore:        blobStore,
+		payloadStore:     payloadStore,
+		healthProber:     healthProber,
+		quota:            quota,
 	}
 }
 
@@ -119,6 +229,13 @@ func (s *toolService) GetAllToolsByClientID(
 func (s *toolService) CreateTool(
 	ctx context.Context, tool *dto.CreateToolDTO,
 ) (*dto.ReadToolDTO, error) {
+	if err := tool.ProviderInterface.Check(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToolInterface, err)
+	}
+	if err := tool.EngineInterface.ConcurrencyPolicy.Check(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidEngineInterface, err)
+	}
+
 	newUUID, err := uuid.NewRandom()
 	if err != nil {
 		return nil, err
@@ -131,6 +248,7 @@ func (s *toolService) CreateTool(
 		Description:       tool.Description,
 		EngineInterface:   tool.EngineInterface,
 		ProviderInterface: tool.ProviderInterface,
+		Draft:             tool.Draft,
 	}
 
 	createdTool, err := s.toolRepo.CreateTool(ctx, toolEntity)
@@ -141,6 +259,13 @@ func (s *toolService) CreateTool(
 }
 
 func (s *toolService) UpdateTool(ctx context.Context, id int, tool *dto.UpdateToolDTO) error {
+	if err := tool.ProviderInterface.Check(); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidToolInterface, err)
+	}
+	if err := tool.EngineInterface.ConcurrencyPolicy.Check(); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidEngineInterface, err)
+	}
+
 	toolEntity := &entity.Tool{
 		ID:                id,
 		Description:       tool.Description,
@@ -148,13 +273,132 @@ func (s *toolService) UpdateTool(ctx context.Context, id int, tool *dto.UpdateTo
 		ProviderInterface: tool.ProviderInterface,
 	}
 
-	return s.toolRepo.UpdateTool(ctx, toolEntity)
+	if err := s.toolRepo.UpdateTool(ctx, toolEntity); err != nil {
+		return err
+	}
+
+	// cached results were produced by the previous definition of the tool
+	if _, err := s.toolRepo.DeleteAllToolResultCacheByToolID(ctx, id); err != nil {
+		fmt.Printf("failed to invalidate result cache of tool %d: %v\n", id, err)
+	}
+	return nil
 }
 
 func (s *toolService) DeleteTool(ctx context.Context, id int) error {
 	return s.toolRepo.DeleteTool(ctx, id)
 }
 
+func (s *toolService) GetToolHealth(ctx context.Context, id int) (*dto.ReadToolHealthDTO, error) {
+	tool, err := s.toolRepo.FindToolByID(ctx, id)
+	if err != nil {
+		return nil, ErrToolNotFound
+	}
+
+	checks, err := s.toolRepo.FindAllToolHealthChecksByToolID(ctx, tool.ID, ToolHealthChecksListed)
+	if err != nil {
+		return nil, err
+	}
+
+	checksDTO := make([]*dto.ReadToolHealthCheckDTO, len(checks))
+	for i, check := range checks {
+		checksDTO[i] = check.ToDTO()
+	}
+
+	return &dto.ReadToolHealthDTO{
+		ToolID:   tool.ID,
+		ToolName: tool.Name,
+		Health:   tool.Health,
+		Checks:   checksDTO,
+	}, nil
+}
+
+func (s *toolService) CheckToolHealth(ctx context.Context, id int) (*dto.ReadToolHealthDTO, error) {
+	tool, err := s.toolRepo.FindToolByID(ctx, id)
+	if err != nil {
+		return nil, ErrToolNotFound
+	}
+
+	if err := s.healthProber.Check(ctx, tool); err != nil {
+		return nil, err
+	}
+
+	return s.GetToolHealth(ctx, id)
+}
+
+func circuitBreakerToDTO(snapshot CircuitBreakerSnapshot, toolName string, replica string) *dto.ReadCircuitBreakerDTO {
+	breaker := &dto.ReadCircuitBreakerDTO{
+		Replica:     replica,
+		ToolID:      snapshot.ToolID,
+		ToolName:    toolName,
+		State:       snapshot.State,
+		Requests:    snapshot.Requests,
+		Failures:    snapshot.Failures,
+		FailureRate: snapshot.FailureRate,
+		Trips:       snapshot.Trips,
+		LastError:   snapshot.LastError,
+	}
+	if !snapshot.OpenedAt.IsZero() {
+		breaker.OpenedAt = &snapshot.OpenedAt
+		breaker.RetryAt = &snapshot.RetryAt
+	}
+	return breaker
+}
+
+func (s *toolService) GetAllCircuitBreakers(ctx context.Context) ([]*dto.ReadCircuitBreakerDTO, error) {
+	tools, err := s.toolRepo.FindAllTools(ctx)
+	if err != nil {
+		return nil, err
+	}
+	toolNames := make(map[int]string, len(tools))
+	for _, tool := range tools {
+		toolNames[tool.ID] = tool.Name
+	}
+
+	snapshots, err := s.functionExecutor.CircuitBreakers(ctx)
+	if err != nil {
+		return nil, err
+	}
+	breakers := make([]*dto.ReadCircuitBreakerDTO, 0, len(snapshots))
+	for _, snapshot := range snapshots {
+		toolName, ok := toolNames[snapshot.ToolID]
+		if !ok {
+			// deleted tool
+			continue
+		}
+		breakers = append(breakers, circuitBreakerToDTO(snapshot, toolName, s.scheduler.WorkerID()))
+	}
+	return breakers, nil
+}
+
+func (s *toolService) GetCircuitBreaker(ctx context.Context, id int) (*dto.ReadCircuitBreakerDTO, error) {
+	tool, err := s.toolRepo.FindToolByID(ctx, id)
+	if err != nil {
+		return nil, ErrToolNotFound
+	}
+
+	snapshot, err := s.functionExecutor.CircuitBreaker(ctx, tool.ID)
+	if err != nil {
+		return nil, err
+	}
+	return circuitBreakerToDTO(snapshot, tool.Name, s.scheduler.WorkerID()), nil
+}
+
+func (s *toolService) ResetCircuitBreaker(ctx context.Context, id int) (*dto.ReadCircuitBreakerDTO, error) {
+	tool, err := s.toolRepo.FindToolByID(ctx, id)
+	if err != nil {
+		return nil, ErrToolNotFound
+	}
+	if err := s.functionExecutor.ResetCircuitBreaker(ctx, tool.ID); err != nil {
+		return nil, err
+	}
+
+	snapshot, err := s.functionExecutor.CircuitBreaker(ctx, tool.ID)
+	if err != nil {
+		return nil, err
+	}
+	return circuitBreakerToDTO(snapshot, tool.Name, s.scheduler.WorkerID()), nil
+}
+
 func (s *toolService) GetAllToolClientPermissionsByToolID(
 	ctx context.Context, toolID int,
 ) ([]*dto.ReadToolClientPermissionDTO, error) {
@@ -275,7 +519,26 @@ func (s *toolService) GetToolRequestByID(
 		return nil, fmt.Errorf("you don't have permission to access this tool request")
 	}
 
-	return toolRequest.ToDTO(), nil
+	return s.toolRequestWithQueuePosition(ctx, toolRequest), nil
+}
+
+// toolRequestWithQueuePosition returns the DTO of a tool request with its queue position while it is pending.
+// The request is returned without it when the position can not be read.
+func (s *toolService) toolRequestWithQueuePosition(
+	ctx context.Context, toolRequest *entity.ToolRequest,
+) *dto.ReadToolRequestDTO {
+	toolRequestDTO := toolRequest.ToDTO()
+	if toolRequest.Status != valueobject.ToolRequestStatusPending {
+		return toolRequestDTO
+	}
+
+	position, err := s.toolRepo.FindToolRequestQueuePosition(ctx, toolRequest.ID, s.scheduler.Limits())
+	if err != nil {
+		fmt.Printf("failed to read queue position of tool request %d: %v\n", toolRequest.ID, err)
+		return toolRequestDTO
+	}
+	toolRequestDTO.QueuePosition = position
+	return toolRequestDTO
 }
 
 func (s *toolService) CreateToolRequest(
@@ -288,43 +551,643 @@ func (s *toolService) CreateToolRequest(
 		ResponseData: toolRequest.ResponseData,
 		Status:       toolRequest.Status,
 	}
+	s.payloadStore.offloadRequest(ctx, toolRequestEntity)
+	s.payloadStore.offloadResponse(ctx, toolRequestEntity)
 
 	createdToolRequest, err := s.toolRepo.CreateToolRequest(ctx, toolRequestEntity)
 	if err != nil {
+		s.payloadStore.delete(ctx, append(responseRefs(toolRequestEntity), toolRequestEntity.RequestData.PayloadRef)...)
 		return nil, err
 	}
 	return createdToolRequest.ToDTO(), nil
 }
 
+// UpdateToolRequest replaces the response of the tool request,
+// the payloads offloaded with the previous response are deleted.
 func (s *toolService) UpdateToolRequest(
 	ctx context.Context, id int, toolRequest *dto.UpdateToolRequestDTO,
 ) error {
+	previous, err := s.toolRepo.FindToolRequestByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrToolRequestNotFound
+		}
+		return err
+	}
+
 	toolRequestEntity := &entity.ToolRequest{
 		ID:           id,
+		ClientID:     previous.ClientID,
+		RequestData:  previous.RequestData,
 		ResponseData: toolRequest.ResponseData,
 		Status:       toolRequest.Status,
 	}
+	s.payloadStore.offloadResponse(ctx, toolRequestEntity)
 
-	return s.toolRepo.UpdateToolRequest(ctx, toolRequestEntity)
+	if err := s.toolRepo.UpdateToolRequest(ctx, toolRequestEntity); err != nil {
+		s.payloadStore.delete(ctx, responseRefs(toolRequestEntity)...)
+		return err
+	}
+
+	s.payloadStore.delete(ctx, responseRefs(previous)...)
+	return nil
 }
 
+// DeleteToolRequest deletes the tool request, the files uploaded with it and its offloaded payloads.
 func (s *toolService) DeleteToolRequest(ctx context.Context, id int) error {
-	return s.toolRepo.DeleteToolRequest(ctx, id)
+	toolRequest, err := s.toolRepo.FindToolRequestByID(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if err := s.toolRepo.DeleteToolRequest(ctx, id); err != nil {
+		return err
+	}
+
+	s.deleteArtifacts(ctx, toolRequest.RequestData.Artifacts)
+	s.payloadStore.delete(ctx, append(responseRefs(toolRequest), toolRequest.RequestData.PayloadRef)...)
+	return nil
+}
+
+// OpenToolRequestPayload streams a payload of a tool request of the client (any tool request for admins):
+// the request payload, the mapped response payload or the raw response of the provider.
+// Offloaded payloads are streamed from the blob store, inline payloads are encoded as stored.
+func (s *toolService) OpenToolRequestPayload(
+	ctx context.Context, clientID int, isAdmin bool, id int, part string,
+) (*ToolRequestPayload, error) {
+	toolRequest, err := s.toolRepo.FindToolRequestByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrToolRequestNotFound
+		}
+		return nil, err
+	}
+
+	if !isAdmin && toolRequest.ClientID != clientID {
+		return nil, ErrToolRequestAccessDenied
+	}
+
+	var payload map[string]any
+	var ref *shared_type.BlobReference
+	switch part {
+	case PayloadPartRequest:
+		payload, ref = toolRequest.RequestData.Payload, toolRequest.RequestData.PayloadRef
+	case PayloadPartResponse:
+		payload, ref = toolRequest.ResponseData.Payload, toolRequest.ResponseData.PayloadRef
+	case PayloadPartRawResponse:
+		payload, ref = toolRequest.ResponseData.RawPayload, toolRequest.ResponseData.RawPayloadRef
+	default:
+		return nil, ErrInvalidPayloadPart
+	}
+
+	if ref == nil {
+		if payload == nil {
+			return nil, ErrPayloadNotFound
+		}
+		encoded, err := json.Marshal(payload)
+		if err != nil {
+			return nil, err
+		}
+		return &ToolRequestPayload{
+			Body: io.NopCloser(bytes.NewReader(encoded)),
+			Size: int64(len(encoded)),
+		}, nil
+	}
+
+	body, err := s.payloadStore.open(ctx, ref)
+	if err != nil {
+		if errors.Is(err, blobstore.ErrNotFound) {
+			return nil, ErrPayloadNotFound
+		}
+		return nil, err
+	}
+	return &ToolRequestPayload{
+		Body:   body,
+		Size:   ref.Size,
+		SHA256: ref.SHA256,
+	}, nil
+}
+
+// GetToolRequestLogs returns the attempts of a tool request with the execution logs captured by its engine.
+func (s *toolService) GetToolRequestLogs(ctx context.Context, id int) (*dto.ReadToolRequestLogsDTO, error) {
+	toolRequest, err := s.toolRepo.FindToolRequestByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrToolRequestNotFound
+		}
+		return nil, err
+	}
+	return toolRequest.ToLogsDTO(), nil
+}
+
+// CancelToolRequest stops a pending or running tool request of the client
+// 1. Mark the tool request as cancelled, so it is not claimed anymore and its running execution cannot complete it
+// 2. Abort the execution on this replica (other replicas abort on their next heartbeat)
+// 3. Forward the cancellation to the provider of async-event tools declaring a cancel endpoint
+func (s *toolService) CancelToolRequest(
+	ctx context.Context, clientID int, isAdmin bool, id int,
+) (*dto.ReadToolRequestDTO, error) {
+	toolRequest, err := s.toolRepo.FindToolRequestByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrToolRequestNotFound
+		}
+		return nil, err
+	}
+
+	if !isAdmin && toolRequest.ClientID != clientID {
+		return nil, ErrToolRequestForbidden
+	}
+
+	toolRequest.Status = valueobject.ToolRequestStatusCancelled
+	toolRequest.ResponseData.Error = "cancelled by client"
+
+	cancelled, err := s.toolRepo.CancelToolRequest(ctx, toolRequest)
+	if err != nil {
+		return nil, err
+	}
+	if !cancelled {
+		return nil, ErrToolRequestNotCancellable
+	}
+
+	s.scheduler.Cancel(toolRequest.ID)
+	s.notifier.Publish(newToolRequestEvent(ToolRequestEventTypeStatus, toolRequest, ""))
+
+	tool, err := s.toolRepo.FindToolByID(ctx, toolRequest.ToolID)
+	if err != nil {
+		return toolRequest.ToDTO(), nil
+	}
+
+	if err := s.functionExecutor.Cancel(ctx, tool, toolRequest); err != nil {
+		fmt.Printf("failed to cancel tool request %d at provider: %v\n", toolRequest.ID, err)
+
+		toolRequest.ResponseData.Error = fmt.Sprintf("cancelled by client (provider cancel failed: %v)", err)
+		if err := s.toolRepo.UpdateToolRequest(ctx, toolRequest); err != nil {
+			return nil, err
+		}
+	}
+
+	return toolRequest.ToDTO(), nil
+}
+
+// WaitToolRequest blocks until the tool request reaches a terminal status, wait elapses or ctx is done,
+// then returns its current state. Status changes are notified by the executor of any replica,
+// with a coarse periodic re-read as a safety net.
+func (s *toolService) WaitToolRequest(
+	ctx context.Context, id int, wait time.Duration,
+) (*dto.ReadToolRequestDTO, error) {
+	if wait > MaxToolRequestWait {
+		wait = MaxToolRequestWait
+	}
+
+	// subscribe before reading, so a completion between the read and the wait is not missed
+	events, unsubscribe := s.notifier.Subscribe(id)
+	defer unsubscribe()
+
+	waitCtx, cancel := context.WithTimeout(ctx, wait)
+	defer cancel()
+
+	fallback := time.NewTicker(waitFallbackInterval)
+	defer fallback.Stop()
+
+	for {
+		toolRequest, err := s.toolRepo.FindToolRequestByID(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if toolRequest.Status.IsTerminal() {
+			return toolRequest.ToDTO(), nil
+		}
+
+		select {
+		case <-waitCtx.Done():
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			return s.toolRequestWithQueuePosition(ctx, toolRequest), nil
+		case event := <-events:
+			if !event.Status.IsTerminal() {
+				continue
+			}
+		case <-fallback.C:
+		}
+	}
+}
+
+// StreamToolRequestEvents sends a snapshot of a tool request of the client (any tool request for admins),
+// then its events until it finishes (with a final "result" event) or ctx is done.
+// Tool requests of other clients are reported as not found.
+func (s *toolService) StreamToolRequestEvents(
+	ctx context.Context, clientID int, isAdmin bool, id int, send func(dto.ToolRequestEventDTO) error,
+) error {
+	// subscribe before reading, so a completion between the read and the stream is not missed
+	events, unsubscribe := s.notifier.Subscribe(id)
+	defer unsubscribe()
+
+	request, err := s.toolRepo.FindToolRequestByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrToolRequestNotFound
+		}
+		return err
+	}
+	if !isAdmin && request.ClientID != clientID {
+		return ErrToolRequestNotFound
+	}
+	toolRequest := s.toolRequestWithQueuePosition(ctx, request)
+
+	if err := send(dto.ToolRequestEventDTO{
+		Event: "snapshot", ToolRequestID: toolRequest.ID, Status: toolRequest.Status, ToolRequest: toolRequest,
+	}); err != nil {
+		return err
+	}
+	if toolRequest.Status.IsTerminal() {
+		return s.sendToolRequestResult(ctx, id, send)
+	}
+
+	ping := time.NewTicker(streamPingInterval)
+	defer ping.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ping.C:
+			if err := send(dto.ToolRequestEventDTO{Event: "ping"}); err != nil {
+				return err
+			}
+		case event := <-events:
+			if err := send(toolRequestEventDTO(event)); err != nil {
+				return err
+			}
+			if event.Type == ToolRequestEventTypeStatus && event.Status.IsTerminal() {
+				return s.sendToolRequestResult(ctx, id, send)
+			}
+		}
+	}
+}
+
+// StreamClientToolRequestEvents sends the events of every tool request of the client until ctx is done.
+// Finished tool requests are followed by a "result" event.
+func (s *toolService) StreamClientToolRequestEvents(
+	ctx context.Context, clientID int, send func(dto.ToolRequestEventDTO) error,
+) error {
+	events, unsubscribe := s.notifier.SubscribeClient(clientID)
+	defer unsubscribe()
+
+	ping := time.NewTicker(streamPingInterval)
+	defer ping.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ping.C:
+			if err := send(dto.ToolRequestEventDTO{Event: "ping"}); err != nil {
+				return err
+			}
+		case event := <-events:
+			if err := send(toolRequestEventDTO(event)); err != nil {
+				return err
+			}
+			if event.Type == ToolRequestEventTypeStatus && event.Status.IsTerminal() {
+				if err := s.sendToolRequestResult(ctx, event.ToolRequestID, send); err != nil {
+					return err
+				}
+			}
+		}
+	}
+}
+
+func (s *toolService) sendToolRequestResult(
+	ctx context.Context, id int, send func(dto.ToolRequestEventDTO) error,
+) error {
+	toolRequest, err := s.toolRepo.FindToolRequestByID(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	toolRequestDTO := toolRequest.ToDTO()
+	return send(dto.ToolRequestEventDTO{
+		Event: "result", ToolRequestID: toolRequestDTO.ID, Status: toolRequestDTO.Status, ToolRequest: toolRequestDTO,
+	})
+}
+
+func toolRequestEventDTO(event ToolRequestEvent) dto.ToolRequestEventDTO {
+	return dto.ToolRequestEventDTO{
+		Event:         string(event.Type),
+		ToolRequestID: event.ToolRequestID,
+		Status:        event.Status,
+		Message:       event.Message,
+	}
+}
+
+func (s *toolService) GetAllToolResultCacheByToolID(
+	ctx context.Context, toolID int,
+) ([]*dto.ReadToolResultCacheDTO, error) {
+	caches, err := s.toolRepo.FindAllToolResultCacheByToolID(ctx, toolID)
+	if err != nil {
+		return nil, err
+	}
+
+	cachesDTO := make([]*dto.ReadToolResultCacheDTO, len(caches))
+	for i, cache := range caches {
+		cachesDTO[i] = cache.ToDTO()
+	}
+	return cachesDTO, nil
+}
+
+// InvalidateToolResultCache drops every cached result of the tool and returns how many were dropped.
+// The tool requests which produced them are kept.
+func (s *toolService) InvalidateToolResultCache(ctx context.Context, toolID int) (int64, error) {
+	return s.toolRepo.DeleteAllToolResultCacheByToolID(ctx, toolID)
+}
+
+func (s *toolService) DeleteToolResultCache(ctx context.Context, toolID int, id int) error {
+	deleted, err := s.toolRepo.DeleteToolResultCache(ctx, toolID, id)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrToolResultCacheNotFound
+	}
+	return nil
+}
+
+func toolSchedulesDTO(schedules []*entity.ToolSchedule) []*dto.ReadToolScheduleDTO {
+	schedulesDTO := make([]*dto.ReadToolScheduleDTO, len(schedules))
+	for i, schedule := range schedules {
+		schedulesDTO[i] = schedule.ToDTO()
+	}
+	return schedulesDTO
+}
+
+func (s *toolService) GetAllToolSchedules(ctx context.Context) ([]*dto.ReadToolScheduleDTO, error) {
+	schedules, err := s.toolRepo.FindAllToolSchedules(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return toolSchedulesDTO(schedules), nil
+}
+
+func (s *toolService) GetAllToolSchedulesByToolID(ctx context.Context, toolID int) ([]*dto.ReadToolScheduleDTO, error) {
+	schedules, err := s.toolRepo.FindAllToolSchedulesByToolID(ctx, toolID)
+	if err != nil {
+		return nil, err
+	}
+	return toolSchedulesDTO(schedules), nil
+}
+
+func (s *toolService) GetAllToolSchedulesByClientID(ctx context.Context, clientID int) ([]*dto.ReadToolScheduleDTO, error) {
+	schedules, err := s.toolRepo.FindAllToolSchedulesByClientID(ctx, clientID)
+	if err != nil {
+		return nil, err
+	}
+	return toolSchedulesDTO(schedules), nil
+}
+
+// findToolSchedule loads a schedule owned by the client (any schedule for an admin).
+func (s *toolService) findToolSchedule(
+	ctx context.Context, clientID int, isAdmin bool, id int,
+) (*entity.ToolSchedule, error) {
+	schedule, err := s.toolRepo.FindToolScheduleByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrToolScheduleNotFound
+		}
+		return nil, err
+	}
+	if !isAdmin && schedule.ClientID != clientID {
+		return nil, ErrToolScheduleForbidden
+	}
+	return schedule, nil
+}
+
+func (s *toolService) GetToolScheduleByID(
+	ctx context.Context, clientID int, isAdmin bool, id int,
+) (*dto.ReadToolScheduleDTO, error) {
+	schedule, err := s.findToolSchedule(ctx, clientID, isAdmin, id)
+	if err != nil {
+		return nil, err
+	}
+	return schedule.ToDTO(), nil
+}
+
+// defineToolSchedule checks the definition of a schedule the client creates or updates,
+// with the checks ExecuteTool applies to its payload, and sets its next run when it is active.
+func (s *toolService) defineToolSchedule(ctx context.Context, schedule *entity.ToolSchedule) error {
+	if strings.TrimSpace(schedule.Name) == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidToolSchedule)
+	}
+	if schedule.Timezone == "" {
+		schedule.Timezone = "UTC"
+	}
+	if schedule.MissedRunPolicy == "" {
+		schedule.MissedRunPolicy = valueobject.ToolScheduleMissedRunPolicyRunOnce
+	}
+	if !schedule.MissedRunPolicy.IsValid() {
+		return fmt.Errorf("%w: unknown missed run policy %q (skip, run_once or run_all)",
+			ErrInvalidToolSchedule, schedule.MissedRunPolicy)
+	}
+	if schedule.Payload == nil {
+		schedule.Payload = map[string]any{}
+	}
+	// copied to the tool request of every run, checked like the webhook_url of ExecuteTool
+	if schedule.WebhookURL != "" {
+		if err := utils.ValidateHTTPURL(schedule.WebhookURL); err != nil {
+			return fmt.Errorf("%w: invalid webhook_url: %v", ErrInvalidToolSchedule, err)
+		}
+	}
+
+	cronSchedule, loc, err := parseSchedule(schedule.CronExpression, schedule.Timezone)
+	if err != nil {
+		return err
+	}
+	next := cronSchedule.Next(time.Now().In(loc))
+	if next.IsZero() {
+		return fmt.Errorf("%w: %q has no upcoming run", ErrInvalidToolSchedule, schedule.CronExpression)
+	}
+
+	tool, refusal := authorizeExecution(ctx, s.toolRepo, schedule.ClientID, schedule.ToolID)
+	if refusal != nil {
+		return fmt.Errorf("%w: %s", ErrToolExecutionRefused, refusal.Message)
+	}
+	if err := validatePayload(tool.ProviderInterface, schedule.Payload, nil); err != nil {
+		return err
+	}
+
+	schedule.NextRunAt = nil
+	if schedule.Status == valueobject.ToolScheduleStatusActive {
+		schedule.NextRunAt = &next
+	}
+	return nil
+}
+
+func (s *toolService) CreateToolSchedule(
+	ctx context.Context, clientID int, schedule *dto.CreateToolScheduleDTO,
+) (*dto.ReadToolScheduleDTO, error) {
+	scheduleEntity := &entity.ToolSchedule{
+		ToolID:          schedule.ToolID,
+		ClientID:        clientID,
+		Name:            schedule.Name,
+		CronExpression:  schedule.CronExpression,
+		Timezone:        schedule.Timezone,
+		Payload:         schedule.Payload,
+		WebhookURL:      schedule.WebhookURL,
+		MissedRunPolicy: schedule.MissedRunPolicy,
+		Status:          valueobject.ToolScheduleStatusActive,
+	}
+	if err := s.defineToolSchedule(ctx, scheduleEntity); err != nil {
+		return nil, err
+	}
+
+	createdSchedule, err := s.toolRepo.CreateToolSchedule(ctx, scheduleEntity)
+	if err != nil {
+		return nil, err
+	}
+	return createdSchedule.ToDTO(), nil
+}
+
+// UpdateToolSchedule replaces the definition of the schedule. An active schedule moves to the next run
+// of its new definition, the runs missed in between are not fired.
+func (s *toolService) UpdateToolSchedule(
+	ctx context.Context, clientID int, isAdmin bool, id int, schedule *dto.UpdateToolScheduleDTO,
+) (*dto.ReadToolScheduleDTO, error) {
+	scheduleEntity, err := s.findToolSchedule(ctx, clientID, isAdmin, id)
+	if err != nil {
+		return nil, err
+	}
+
+	scheduleEntity.Name = schedule.Name
+	scheduleEntity.CronExpression = schedule.CronExpression
+	scheduleEntity.Timezone = schedule.Timezone
+	scheduleEntity.Payload = schedule.Payload
+	scheduleEntity.WebhookURL = schedule.WebhookURL
+	scheduleEntity.MissedRunPolicy = schedule.MissedRunPolicy
+	if err := s.defineToolSchedule(ctx, scheduleEntity); err != nil {
+		return nil, err
+	}
+
+	if err := s.toolRepo.UpdateToolSchedule(ctx, scheduleEntity); err != nil {
+		return nil, err
+	}
+	return s.GetToolScheduleByID(ctx, clientID, isAdmin, id)
+}
+
+func (s *toolService) DeleteToolSchedule(ctx context.Context, clientID int, isAdmin bool, id int) error {
+	if _, err := s.findToolSchedule(ctx, clientID, isAdmin, id); err != nil {
+		return err
+	}
+	return s.toolRepo.DeleteToolSchedule(ctx, id)
+}
+
+// PauseToolSchedule stops the runs of the schedule until it is resumed.
+func (s *toolService) PauseToolSchedule(
+	ctx context.Context, clientID int, isAdmin bool, id int,
+) (*dto.ReadToolScheduleDTO, error) {
+	schedule, err := s.findToolSchedule(ctx, clientID, isAdmin, id)
+	if err != nil {
+		return nil, err
+	}
+
+	schedule.Status = valueobject.ToolScheduleStatusPaused
+	schedule.NextRunAt = nil
+	if err := s.toolRepo.UpdateToolSchedule(ctx, schedule); err != nil {
+		return nil, err
+	}
+	return s.GetToolScheduleByID(ctx, clientID, isAdmin, id)
+}
+
+// ResumeToolSchedule restarts a paused schedule from its next run, the runs of the pause are not fired.
+func (s *toolService) ResumeToolSchedule(
+	ctx context.Context, clientID int, isAdmin bool, id int,
+) (*dto.ReadToolScheduleDTO, error) {
+	schedule, err := s.findToolSchedule(ctx, clientID, isAdmin, id)
+	if err != nil {
+		return nil, err
+	}
+
+	cronSchedule, loc, err := parseSchedule(schedule.CronExpression, schedule.Timezone)
+	if err != nil {
+		return nil, err
+	}
+	next := cronSchedule.Next(time.Now().In(loc))
+	if next.IsZero() {
+		return nil, fmt.Errorf("%w: %q has no upcoming run", ErrInvalidToolSchedule, schedule.CronExpression)
+	}
+
+	schedule.Status = valueobject.ToolScheduleStatusActive
+	schedule.NextRunAt = &next
+	if err := s.toolRepo.UpdateToolSchedule(ctx, schedule); err != nil {
+		return nil, err
+	}
+	return s.GetToolScheduleByID(ctx, clientID, isAdmin, id)
+}
+
+// GetToolScheduleNextRuns lists the next runs of the schedule, as if it was active.
+func (s *toolService) GetToolScheduleNextRuns(
+	ctx context.Context, clientID int, isAdmin bool, id int, count int,
+) (*dto.ToolScheduleRunsDTO, error) {
+	schedule, err := s.findToolSchedule(ctx, clientID, isAdmin, id)
+	if err != nil {
+		return nil, err
+	}
+	return s.PreviewToolSchedule(ctx, &dto.PreviewToolScheduleDTO{
+		CronExpression: schedule.CronExpression,
+		Timezone:       schedule.Timezone,
+		Count:          count,
+	})
+}
+
+// PreviewToolSchedule lists the next runs of a cron expression, to check it before creating a schedule.
+func (s *toolService) PreviewToolSchedule(
+	ctx context.Context, preview *dto.PreviewToolScheduleDTO,
+) (*dto.ToolScheduleRunsDTO, error) {
+	cronSchedule, loc, err := parseSchedule(preview.CronExpression, preview.Timezone)
+	if err != nil {
+		return nil, err
+	}
+
+	count := preview.Count
+	if count <= 0 {
+		count = DefaultToolSchedulePreviewCount
+	}
+	count = min(count, MaxToolSchedulePreviewCount)
+
+	return &dto.ToolScheduleRunsDTO{
+		CronExpression: preview.CronExpression,
+		Timezone:       loc.String(),
+		NextRuns:       cronSchedule.Preview(time.Now().In(loc), count),
+	}, nil
 }
 
 func (s *toolService) SelectTool(
 	ctx context.Context, clientID int, userPrompt string,
 ) (*dto.SelectToolResponseDTO, error) {
-	selectorResponse, err := s.selectorService.Select(ctx, selector.SelectorRequest{
-		UserPrompt: userPrompt,
-	})
+	selectorRequest := selector.SelectorRequest{UserPrompt: userPrompt}
+
+	policy := s.healthProber.SelectionPolicy()
+	if policy != HealthSelectionPolicyOff {
+		unhealthyToolIDs, err := s.toolRepo.FindAllToolIDsByHealthStatus(ctx, valueobject.ToolHealthStatusUnhealthy)
+		if err != nil {
+			return nil, err
+		}
+		switch policy {
+		case HealthSelectionPolicySkip:
+			selectorRequest.ExcludedToolIDs = unhealthyToolIDs
+		case HealthSelectionPolicyDemote:
+			selectorRequest.DemotedToolIDs = unhealthyToolIDs
+		}
+	}
+
+	tool, selectorResponse, err := s.selectTool(ctx, selectorRequest, policy)
 	if err != nil {
 		return nil, err
 	}
 
-	tool, err := s.toolRepo.FindToolByID(ctx, selectorResponse.ToolID)
-	if err != nil {
-		return nil, fmt.Errorf("tool not found")
+	if policy == HealthSelectionPolicyDemote && tool.Health.Status == valueobject.ToolHealthStatusUnhealthy {
+		selectorResponse.Message += "\n\nThis tool is currently unhealthy and its execution may fail: " +
+			tool.Health.Message
 	}
 
 	toolClientPermission, err := s.toolRepo.GetToolClientPermissionByToolIDAndClientID(
@@ -345,57 +1208,173 @@ func (s *toolService) SelectTool(
 	}, nil
 }
 
-// Core function to execute a tool
-// 1. Check if the client has permission to use the tool
-// 2. Check if the tool exists
-// 3. Call FunctionExecutor to execute the tool
-// 4. Create a tool request
-// 5. Return the tool request ID
-func (s *toolService) ExecuteTool(
-	ctx context.Context, clientID int, toolID int, requestData dto.ToolExecutionRequestDTO,
-) (*dto.ToolExecutionResponseDTO, error) {
-	toolClientPermission, err := s.toolRepo.GetToolClientPermissionByToolIDAndClientID(
+// maxToolSelections bounds the selections of selectTool, each one excluding the tool returned before.
+const maxToolSelections = 3
+
+// selectTool asks the selector for a tool. A draft tool, or under the skip policy an unhealthy tool, returned by
+// the selector (it changed since the selector listed it, or the selector ignored the exclusions) is excluded
+// and the selection is made again.
+func (s *toolService) selectTool(
+	ctx context.Context, selectorRequest selector.SelectorRequest, policy HealthSelectionPolicy,
+) (*entity.Tool, selector.SelectorResponse, error) {
+	unhealthyExcluded := len(selectorRequest.ExcludedToolIDs) > 0
+	for selection := 1; ; selection++ {
+		selectorResponse, err := s.selectorService.Select(ctx, selectorRequest)
+		if err != nil {
+			if errors.Is(err, selector.ErrNoCandidateTool) {
+				if unhealthyExcluded {
+					return nil, selectorResponse, fmt.Errorf("%w: every other tool is unhealthy", ErrNoHealthyTool)
+				}
+				return nil, selectorResponse, fmt.Errorf("%w: %v", ErrToolNotFound, err)
+			}
+			return nil, selectorResponse, err
+		}
+
+		tool, err := s.toolRepo.FindToolByID(ctx, selectorResponse.ToolID)
+		if err != nil {
+			return nil, selectorResponse, fmt.Errorf("%w: selected tool %d: %v", ErrToolNotFound, selectorResponse.ToolID, err)
+		}
+
+		unhealthy := policy == HealthSelectionPolicySkip && tool.Health.Status == valueobject.ToolHealthStatusUnhealthy
+		switch {
+		case !tool.Draft && !unhealthy:
+			return tool, selectorResponse, nil
+		case selection >= maxToolSelections && tool.Draft:
+			return nil, selectorResponse, fmt.Errorf("%w: selected tool %s is a draft", ErrToolNotFound, tool.Name)
+		case selection >= maxToolSelections:
+			return nil, selectorResponse, fmt.Errorf("%w: %s is unhealthy", ErrNoHealthyTool, tool.Name)
+		}
+
+		unhealthyExcluded = unhealthyExcluded || unhealthy
+		if !slices.Contains(selectorRequest.ExcludedToolIDs, tool.ID) {
+			selectorRequest.ExcludedToolIDs = append(selectorRequest.ExcludedToolIDs, tool.ID)
+		}
+	}
+}
+
+// authorizeExecution returns the tool when the client may execute it, or the response refusing the execution.
+// Shared by ExecuteTool and the runs of tool schedules.
+func authorizeExecution(
+	ctx context.Context, toolRepo domain.ToolRepository, clientID int, toolID int,
+) (*entity.Tool, *dto.ToolExecutionResponseDTO) {
+	toolClientPermission, err := toolRepo.GetToolClientPermissionByToolIDAndClientID(
 		ctx, toolID, clientID,
 	)
 	if err != nil {
-		return &dto.ToolExecutionResponseDTO{
+		return nil, &dto.ToolExecutionResponseDTO{
 			Status:  valueobject.ToolExecutionStatusUnauthorized,
 			Message: "You don't have permission to use this tool. Please contact the administrator.",
-		}, nil
+		}
 	}
 
 	if toolClientPermission.PermissionLevel != valueobject.ToolClientPermissionLevelWrite {
-		return &dto.ToolExecutionResponseDTO{
+		return nil, &dto.ToolExecutionResponseDTO{
 			Status: valueobject.ToolExecutionStatusUnauthorized,
 			Message: fmt.Sprintf("You don't have permission to use this tool. Please contact the administrator. (permission level: %d)",
 				toolClientPermission.PermissionLevel.Int()),
-		}, nil
+		}
 	}
 
-	tool, err := s.toolRepo.FindToolByID(ctx, toolID)
+	tool, err := toolRepo.FindToolByID(ctx, toolID)
 	if err != nil {
-		return &dto.ToolExecutionResponseDTO{
+		return nil, &dto.ToolExecutionResponseDTO{
 			Status:  valueobject.ToolExecutionStatusFailed,
 			Message: "Tool not found",
+		}
+	}
+	if tool.Draft {
+		return nil, &dto.ToolExecutionResponseDTO{
+			Status:  valueobject.ToolExecutionStatusFailed,
+			Message: "Tool is a draft, it cannot be executed until it is published",
+		}
+	}
+
+	return tool, nil
+}
+
+func (s *toolService) ExecuteTool(
+	ctx context.Context, clientID int, isAdmin bool, toolID int, requestData dto.ToolExecutionRequestDTO,
+) (*dto.ToolExecutionResponseDTO, error) {
+	return s.ExecuteToolWithFiles(ctx, clientID, isAdmin, toolID, requestData, nil)
+}
+
+// Core function to execute a tool
+// 1. Check if the client has permission to use the tool
+// 2. Check if the tool exists
+// 3. Reference the uploaded files from the payload and validate it
+// 4. Count the execution against the quotas of the client (admin console sessions are not counted)
+// 5. Store the files in the blob store
+// 6. Enqueue a pending tool request listing the stored files as artifacts (a large payload is offloaded)
+// 7. Wake the scheduler up, one of its workers executes the tool
+// 8. Return the tool request ID
+func (s *toolService) ExecuteToolWithFiles(
+	ctx context.Context, clientID int, isAdmin bool, toolID int, requestData dto.ToolExecutionRequestDTO,
+	files []ToolExecutionFile,
+) (*dto.ToolExecutionResponseDTO, error) {
+	tool, refusal := authorizeExecution(ctx, s.toolRepo, clientID, toolID)
+	if refusal != nil {
+		return refusal, nil
+	}
+
+	if retryAt, open := s.functionExecutor.CircuitOpenUntil(tool.ID); open {
+		return &dto.ToolExecutionResponseDTO{
+			Status: valueobject.ToolExecutionStatusCircuitOpen,
+			Message: fmt.Sprintf("The tool is failing and its executions are paused until %s. Please retry later.",
+				retryAt.Format(time.RFC3339)),
+			RetryAt: &retryAt,
+		}, nil
+	}
+
+	payload, artifacts, err := planArtifacts(tool.ProviderInterface, clientID, requestData.Payload, files)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := validatePayload(tool.ProviderInterface, payload, artifacts); err != nil {
+		return nil, err
+	}
+
+	var reservation executionReservation
+	if !isAdmin {
+		reserveExecution(ctx, s.quota, clientID, &reservation)
+	}
+	if reservation.exceeded {
+		retryAt := time.Now().Add(reservation.retryAfter)
+		return &dto.ToolExecutionResponseDTO{
+			Status:  valueobject.ToolExecutionStatusQuotaExceeded,
+			Message: "Execution quota exceeded. Please retry after the quota resets or contact the administrator.",
+			RetryAt: &retryAt,
 		}, nil
 	}
 
+	if err := s.storeArtifacts(ctx, artifacts, files); err != nil {
+		reservation.release(ctx)
+		return nil, err
+	}
+
 	toolRequestEntity := &entity.ToolRequest{
 		ToolID:   toolID,
 		ClientID: clientID,
 		RequestData: shared_type.ToolRequestData{
-			Payload: requestData.Payload,
+			Payload:    payload,
+			WebhookURL: requestData.WebhookURL,
+			Artifacts:  artifacts,
 		},
 		ResponseData: shared_type.ToolRequestResponseData{},
 		Status:       valueobject.ToolRequestStatusPending,
 	}
 
+	s.payloadStore.offloadRequest(ctx, toolRequestEntity)
+
 	createdToolRequest, err := s.toolRepo.CreateToolRequest(ctx, toolRequestEntity)
 	if err != nil {
+		reservation.release(ctx)
+		s.deleteArtifacts(ctx, artifacts)
+		s.payloadStore.delete(ctx, toolRequestEntity.RequestData.PayloadRef)
 		return nil, err
 	}
 
-	go s.functionExecutor.Sync(ctx, tool, createdToolRequest.ID, requestData)
+	s.scheduler.Notify()
 
 	return &dto.ToolExecutionResponseDTO{
 		Status:        valueobject.ToolExecutionStatusSuccess,
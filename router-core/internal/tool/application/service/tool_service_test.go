@@ -0,0 +1,234 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+
+	"aigendrug.com/router-core/internal/shared/selector"
+	"aigendrug.com/router-core/internal/tool/domain"
+	"aigendrug.com/router-core/internal/tool/domain/entity"
+	"aigendrug.com/router-core/internal/tool/domain/shared_type"
+	"aigendrug.com/router-core/internal/tool/domain/valueobject"
+)
+
+// selectionToolRepository holds the tools offered to the selector, the client may write every tool.
+// The unhealthy tools listed are given apart from the health of the tools, as a tool may turn unhealthy
+// after the listing.
+type selectionToolRepository struct {
+	domain.ToolRepository
+	tools     map[int]*entity.Tool
+	unhealthy []int
+}
+
+func (r *selectionToolRepository) FindToolByID(_ context.Context, id int) (*entity.Tool, error) {
+	tool, ok := r.tools[id]
+	if !ok {
+		return nil, fmt.Errorf("no tool %d", id)
+	}
+	return tool, nil
+}
+
+func (r *selectionToolRepository) FindAllToolIDsByHealthStatus(
+	_ context.Context, _ valueobject.ToolHealthStatus,
+) ([]int, error) {
+	return r.unhealthy, nil
+}
+
+func (r *selectionToolRepository) GetToolClientPermissionByToolIDAndClientID(
+	_ context.Context, toolID int, clientID int,
+) (*entity.ToolClientPermission, error) {
+	return &entity.ToolClientPermission{
+		ToolID: toolID, ClientID: clientID, PermissionLevel: valueobject.ToolClientPermissionLevelWrite,
+	}, nil
+}
+
+// fakeSelector returns its picks in order, skipping the excluded tools unless ignoreExclusions is set,
+// and records the requests.
+type fakeSelector struct {
+	picks            []int
+	ignoreExclusions bool
+	requests         []selector.SelectorRequest
+}
+
+func (s *fakeSelector) Select(_ context.Context, request selector.SelectorRequest) (selector.SelectorResponse, error) {
+	s.requests = append(s.requests, request)
+	for _, toolID := range s.picks {
+		if s.ignoreExclusions || !slices.Contains(request.ExcludedToolIDs, toolID) {
+			return selector.SelectorResponse{ToolID: toolID, Message: "selected"}, nil
+		}
+	}
+	return selector.SelectorResponse{}, selector.ErrNoCandidateTool
+}
+
+type fakeHealthProber struct {
+	ToolHealthProber
+	policy HealthSelectionPolicy
+}
+
+func (p *fakeHealthProber) SelectionPolicy() HealthSelectionPolicy {
+	return p.policy
+}
+
+func TestSelectTool(t *testing.T) {
+	healthy := shared_type.ToolHealth{Status: valueobject.ToolHealthStatusHealthy}
+	unhealthy := shared_type.ToolHealth{Status: valueobject.ToolHealthStatusUnhealthy, Message: "function state is Failed"}
+
+	tests := []struct {
+		name             string
+		policy           HealthSelectionPolicy
+		listedUnhealthy  []int
+		picks            []int
+		ignoreExclusions bool
+		wantToolID       int
+		wantErr          error
+		wantSelections   int
+		wantExcluded     []int
+		wantDemoted      []int
+		wantWarning      bool
+	}{
+		{
+			name:            "skip excludes unhealthy tools",
+			policy:          HealthSelectionPolicySkip,
+			listedUnhealthy: []int{1},
+			picks:           []int{1, 2},
+			wantToolID:      2,
+			wantSelections:  1,
+			wantExcluded:    []int{1},
+		},
+		{
+			name:           "skip selects again when the tool turned unhealthy after the listing",
+			policy:         HealthSelectionPolicySkip,
+			picks:          []int{1, 2},
+			wantToolID:     2,
+			wantSelections: 2,
+			wantExcluded:   []int{1},
+		},
+		{
+			name:             "skip gives up after the maximum selections",
+			policy:           HealthSelectionPolicySkip,
+			listedUnhealthy:  []int{1},
+			picks:            []int{1, 2},
+			ignoreExclusions: true,
+			wantErr:          ErrNoHealthyTool,
+			wantSelections:   maxToolSelections,
+			wantExcluded:     []int{1},
+		},
+		{
+			name:            "skip without healthy tool left",
+			policy:          HealthSelectionPolicySkip,
+			listedUnhealthy: []int{1},
+			picks:           []int{1},
+			wantErr:         ErrNoHealthyTool,
+			wantSelections:  1,
+			wantExcluded:    []int{1},
+		},
+		{
+			name:            "demote passes unhealthy tools to rank them last",
+			policy:          HealthSelectionPolicyDemote,
+			listedUnhealthy: []int{1},
+			picks:           []int{2},
+			wantToolID:      2,
+			wantSelections:  1,
+			wantDemoted:     []int{1},
+		},
+		{
+			name:            "demote warns when an unhealthy tool is selected",
+			policy:          HealthSelectionPolicyDemote,
+			listedUnhealthy: []int{1},
+			picks:           []int{1},
+			wantToolID:      1,
+			wantSelections:  1,
+			wantDemoted:     []int{1},
+			wantWarning:     true,
+		},
+		{
+			name:           "draft tools are excluded",
+			policy:         HealthSelectionPolicyOff,
+			picks:          []int{3, 2},
+			wantToolID:     2,
+			wantSelections: 2,
+			wantExcluded:   []int{3},
+		},
+		{
+			name:             "draft tool returned at every selection",
+			policy:           HealthSelectionPolicyOff,
+			picks:            []int{3},
+			ignoreExclusions: true,
+			wantErr:          ErrToolNotFound,
+			wantSelections:   maxToolSelections,
+			wantExcluded:     []int{3},
+		},
+		{
+			name:           "no tool to select",
+			policy:         HealthSelectionPolicyOff,
+			wantErr:        ErrToolNotFound,
+			wantSelections: 1,
+		},
+		{
+			name:           "unknown tool",
+			policy:         HealthSelectionPolicyOff,
+			picks:          []int{4},
+			wantErr:        ErrToolNotFound,
+			wantSelections: 1,
+		},
+		{
+			name:            "off ignores health",
+			policy:          HealthSelectionPolicyOff,
+			listedUnhealthy: []int{1},
+			picks:           []int{1},
+			wantToolID:      1,
+			wantSelections:  1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &selectionToolRepository{
+				tools: map[int]*entity.Tool{
+					1: {ID: 1, Name: "docking", Health: unhealthy},
+					2: {ID: 2, Name: "folding", Health: healthy},
+					3: {ID: 3, Name: "imported", Health: healthy, Draft: true},
+				},
+				unhealthy: tt.listedUnhealthy,
+			}
+			selectorService := &fakeSelector{picks: tt.picks, ignoreExclusions: tt.ignoreExclusions}
+			s := &toolService{
+				toolRepo:        repo,
+				selectorService: selectorService,
+				healthProber:    &fakeHealthProber{policy: tt.policy},
+			}
+
+			response, err := s.SelectTool(context.Background(), 1, "dock this ligand")
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("SelectTool() error = %v, want %v", err, tt.wantErr)
+				}
+			} else {
+				if err != nil {
+					t.Fatalf("SelectTool() error = %v", err)
+				}
+				if response.Tool.ID != tt.wantToolID {
+					t.Fatalf("SelectTool() selected tool %d, want %d", response.Tool.ID, tt.wantToolID)
+				}
+				if warned := strings.Contains(response.Message, "unhealthy"); warned != tt.wantWarning {
+					t.Fatalf("SelectTool() message = %q, want warning %v", response.Message, tt.wantWarning)
+				}
+			}
+
+			if len(selectorService.requests) != tt.wantSelections {
+				t.Fatalf("selector called %d times, want %d", len(selectorService.requests), tt.wantSelections)
+			}
+			if demoted := selectorService.requests[0].DemotedToolIDs; !slices.Equal(demoted, tt.wantDemoted) {
+				t.Fatalf("demoted tools = %v, want %v", demoted, tt.wantDemoted)
+			}
+			last := selectorService.requests[len(selectorService.requests)-1]
+			if !slices.Equal(last.ExcludedToolIDs, tt.wantExcluded) {
+				t.Fatalf("excluded tools = %v, want %v", last.ExcludedToolIDs, tt.wantExcluded)
+			}
+		})
+	}
+}
@@ -1,10 +1,21 @@
 package delivery
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
 	"net/http"
+	"slices"
 	"strconv"
+	"strings"
+	"time"
 
+	"aigendrug.com/router-core/internal/config"
+	"aigendrug.com/router-core/internal/shared/blobstore"
 	shared_types "aigendrug.com/router-core/internal/shared/types"
+	"aigendrug.com/router-core/internal/shared/utils"
 	"aigendrug.com/router-core/internal/tool/application/dto"
 	"aigendrug.com/router-core/internal/tool/application/service"
 	"aigendrug.com/router-core/internal/tool/domain/valueobject"
@@ -12,12 +23,20 @@ import (
 	"github.com/google/uuid"
 )
 
+// multipartMemory is the part of a multipart upload kept in memory, the rest is spooled to temporary files.
+const multipartMemory = 32 << 20
+
 type ToolHandler struct {
-	toolService service.ToolService
+	toolService    service.ToolService
+	maxUploadBytes int64
 }
 
-func NewToolHandler(toolService service.ToolService) *ToolHandler {
-	return &ToolHandler{toolService: toolService}
+func NewToolHandler(config *config.Config, toolService service.ToolService) *ToolHandler {
+	maxUploadBytes := int64(blobstore.DefaultMaxUploadBytes)
+	if config != nil && config.BlobStore.MaxUploadBytes > 0 {
+		maxUploadBytes = config.BlobStore.MaxUploadBytes
+	}
+	return &ToolHandler{toolService: toolService, maxUploadBytes: maxUploadBytes}
 }
 
 // GetAllTools godoc
@@ -186,6 +205,10 @@ func (h *ToolHandler) CreateTool(c *gin.Context) {
 
 	createdTool, err := h.toolService.CreateTool(c.Request.Context(), &tool)
 	if err != nil {
+		if errors.Is(err, service.ErrInvalidToolInterface) || errors.Is(err, service.ErrInvalidEngineInterface) {
+			c.JSON(http.StatusBadRequest, shared_types.HttpErrorResponse{Msg: err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, shared_types.HttpErrorResponse{Msg: err.Error()})
 		return
 	}
@@ -219,6 +242,10 @@ func (h *ToolHandler) UpdateTool(c *gin.Context) {
 	}
 
 	if err := h.toolService.UpdateTool(c.Request.Context(), id, &tool); err != nil {
+		if errors.Is(err, service.ErrInvalidToolInterface) || errors.Is(err, service.ErrInvalidEngineInterface) {
+			c.JSON(http.StatusBadRequest, shared_types.HttpErrorResponse{Msg: err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, shared_types.HttpErrorResponse{Msg: err.Error()})
 		return
 	}
@@ -250,6 +277,313 @@ func (h *ToolHandler) DeleteTool(c *gin.Context) {
 	c.JSON(http.StatusOK, shared_types.HttpSuccessResponse{Msg: "Tool deleted successfully"})
 }
 
+// PublishTool godoc
+// @Summary Publish a draft tool
+// @Description Publishes a draft tool (e.g. imported from a Lambda function), offering it to the clients it is permitted to
+// @Tags tool
+// @Produce json
+// @Param tool_id path int true "Tool ID"
+// @Success 200 {object} shared_types.HttpSuccessResponse
+// @Failure 400 {object} shared_types.HttpErrorResponse
+// @Failure 404 {object} shared_types.HttpErrorResponse
+// @Failure 500 {object} shared_types.HttpErrorResponse
+// @Router /v1/tools/{tool_id}/publish [post]
+func (h *ToolHandler) PublishTool(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("tool_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, shared_types.HttpErrorResponse{Msg: "Invalid tool ID"})
+		return
+	}
+
+	if err := h.toolService.PublishTool(c.Request.Context(), id); err != nil {
+		if errors.Is(err, service.ErrToolNotFound) {
+			c.JSON(http.StatusNotFound, shared_types.HttpErrorResponse{Msg: err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, shared_types.HttpErrorResponse{Msg: err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, shared_types.HttpSuccessResponse{Msg: "Tool published successfully"})
+}
+
+// GetToolHealth godoc
+// @Summary Get the health of a tool
+// @Description Returns the current health of a tool, maintained by the health prober, with its recent health checks
+// @Tags tool
+// @Produce json
+// @Param id path int true "Tool ID"
+// @Success 200 {object} dto.ReadToolHealthDTO
+// @Failure 400 {object} shared_types.HttpErrorResponse
+// @Failure 404 {object} shared_types.HttpErrorResponse
+// @Failure 500 {object} shared_types.HttpErrorResponse
+// @Router /v1/tools/{id}/health [get]
+func (h *ToolHandler) GetToolHealth(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, shared_types.HttpErrorResponse{Msg: "Invalid tool ID"})
+		return
+	}
+
+	health, err := h.toolService.GetToolHealth(c.Request.Context(), id)
+	if err != nil {
+		if errors.Is(err, service.ErrToolNotFound) {
+			c.JSON(http.StatusNotFound, shared_types.HttpErrorResponse{Msg: err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, shared_types.HttpErrorResponse{Msg: err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, health)
+}
+
+// CheckToolHealth godoc
+// @Summary Check the health of a tool now
+// @Description Checks the health of a tool without waiting for the health prober, records the check and returns the health of the tool
+// @Tags tool
+// @Produce json
+// @Param tool_id path int true "Tool ID"
+// @Success 200 {object} dto.ReadToolHealthDTO
+// @Failure 400 {object} shared_types.HttpErrorResponse
+// @Failure 404 {object} shared_types.HttpErrorResponse
+// @Failure 500 {object} shared_types.HttpErrorResponse
+// @Router /v1/tools/{tool_id}/health/check [post]
+func (h *ToolHandler) CheckToolHealth(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("tool_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, shared_types.HttpErrorResponse{Msg: "Invalid tool ID"})
+		return
+	}
+
+	health, err := h.toolService.CheckToolHealth(c.Request.Context(), id)
+	if err != nil {
+		if errors.Is(err, service.ErrToolNotFound) {
+			c.JSON(http.StatusNotFound, shared_types.HttpErrorResponse{Msg: err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, shared_types.HttpErrorResponse{Msg: err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, health)
+}
+
+// GetAllCircuitBreakers godoc
+// @Summary Get the circuit breakers of the tools
+// @Description Returns the circuit breakers of the tools executed by the replica serving the request or tripped by any replica, labelled with the replica.
+// @Description Trips are shared by the replicas, requests and failures are counted by the replica serving the request.
+// @Tags tool
+// @Produce json
+// @Success 200 {array} dto.ReadCircuitBreakerDTO
+// @Failure 500 {object} shared_types.HttpErrorResponse
+// @Router /v1/tools/breakers [get]
+func (h *ToolHandler) GetAllCircuitBreakers(c *gin.Context) {
+	breakers, err := h.toolService.GetAllCircuitBreakers(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, shared_types.HttpErrorResponse{Msg: err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, breakers)
+}
+
+// GetCircuitBreaker godoc
+// @Summary Get the circuit breaker of a tool
+// @Description Returns the circuit breaker of a tool on the replica serving the request, labelled with its replica
+// @Tags tool
+// @Produce json
+// @Param id path int true "Tool ID"
+// @Success 200 {object} dto.ReadCircuitBreakerDTO
+// @Failure 400 {object} shared_types.HttpErrorResponse
+// @Failure 404 {object} shared_types.HttpErrorResponse
+// @Failure 500 {object} shared_types.HttpErrorResponse
+// @Router /v1/tools/{id}/breaker [get]
+func (h *ToolHandler) GetCircuitBreaker(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, shared_types.HttpErrorResponse{Msg: "Invalid tool ID"})
+		return
+	}
+
+	breaker, err := h.toolService.GetCircuitBreaker(c.Request.Context(), id)
+	if err != nil {
+		if errors.Is(err, service.ErrToolNotFound) {
+			c.JSON(http.StatusNotFound, shared_types.HttpErrorResponse{Msg: err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, shared_types.HttpErrorResponse{Msg: err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, breaker)
+}
+
+// ResetCircuitBreaker godoc
+// @Summary Reset the circuit breaker of a tool
+// @Description Closes the circuit breaker of a tool and forgets its counts and trips, on every replica
+// @Tags tool
+// @Produce json
+// @Param tool_id path int true "Tool ID"
+// @Success 200 {object} dto.ReadCircuitBreakerDTO
+// @Failure 400 {object} shared_types.HttpErrorResponse
+// @Failure 404 {object} shared_types.HttpErrorResponse
+// @Failure 500 {object} shared_types.HttpErrorResponse
+// @Router /v1/tools/{tool_id}/breaker/reset [post]
+func (h *ToolHandler) ResetCircuitBreaker(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("tool_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, shared_types.HttpErrorResponse{Msg: "Invalid tool ID"})
+		return
+	}
+
+	breaker, err := h.toolService.ResetCircuitBreaker(c.Request.Context(), id)
+	if err != nil {
+		if errors.Is(err, service.ErrToolNotFound) {
+			c.JSON(http.StatusNotFound, shared_types.HttpErrorResponse{Msg: err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, shared_types.HttpErrorResponse{Msg: err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, breaker)
+}
+
+// ListLambdaFunctions godoc
+// @Summary List the Lambda functions of the account
+// @Description Lists the Lambda functions of the configured account and region with their tags, and the tools already invoking each of them
+// @Tags tool
+// @Produce json
+// @Success 200 {array} dto.ReadLambdaFunctionDTO
+// @Failure 502 {object} shared_types.HttpErrorResponse
+// @Failure 500 {object} shared_types.HttpErrorResponse
+// @Router /v1/tools/lambda/functions [get]
+func (h *ToolHandler) ListLambdaFunctions(c *gin.Context) {
+	functions, err := h.toolService.ListLambdaFunctions(c.Request.Context())
+	if err != nil {
+		if errors.Is(err, service.ErrLambdaDiscovery) {
+			c.JSON(http.StatusBadGateway, shared_types.HttpErrorResponse{Msg: err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, shared_types.HttpErrorResponse{Msg: err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, functions)
+}
+
+// ImportLambdaTools godoc
+// @Summary Import tools from Lambda functions
+// @Description Creates a draft tool for each function, pre-filled from its configuration and its "router:*" tags
+// @Description (router:name, router:version, router:description, router:invoke-type, router:check-status-type,
+// @Description router:request-interface, router:response-interface, router:request-schema, router:response-schema).
+// @Description Functions already registered, missing or with invalid tags are skipped with the reason.
+// @Tags tool
+// @Accept json
+// @Produce json
+// @Param request body dto.ImportLambdaToolsDTO true "Functions to import"
+// @Success 200 {object} dto.ImportLambdaToolsResultDTO
+// @Failure 400 {object} shared_types.HttpErrorResponse
+// @Failure 500 {object} shared_types.HttpErrorResponse
+// @Router /v1/tools/lambda/import [post]
+func (h *ToolHandler) ImportLambdaTools(c *gin.Context) {
+	var request dto.ImportLambdaToolsDTO
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, shared_types.HttpErrorResponse{Msg: err.Error()})
+		return
+	}
+	if len(request.FunctionNames) == 0 {
+		c.JSON(http.StatusBadRequest, shared_types.HttpErrorResponse{Msg: "function_names is required"})
+		return
+	}
+
+	result, err := h.toolService.ImportLambdaTools(c.Request.Context(), &request)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, shared_types.HttpErrorResponse{Msg: err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// GetToolResultCache godoc
+// @Summary Get the cached results of a tool
+// @Description Retrieves the cached results of a cacheable tool, including expired entries not purged yet
+// @Tags tool
+// @Produce json
+// @Param id path int true "Tool ID"
+// @Success 200 {array} dto.ReadToolResultCacheDTO
+// @Failure 400 {object} shared_types.HttpErrorResponse
+// @Failure 500 {object} shared_types.HttpErrorResponse
+// @Router /v1/tools/{id}/cache [get]
+func (h *ToolHandler) GetToolResultCache(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, shared_types.HttpErrorResponse{Msg: "Invalid tool ID"})
+		return
+	}
+
+	caches, err := h.toolService.GetAllToolResultCacheByToolID(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, shared_types.HttpErrorResponse{Msg: err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, caches)
+}
+
+// InvalidateToolResultCache godoc
+// @Summary Invalidate the cached results of a tool
+// @Description Drops every cached result of a tool, the next requests invoke the tool again
+// @Tags tool
+// @Produce json
+// @Param id path int true "Tool ID"
+// @Success 200 {object} shared_types.HttpSuccessResponse
+// @Failure 400 {object} shared_types.HttpErrorResponse
+// @Failure 500 {object} shared_types.HttpErrorResponse
+// @Router /v1/tools/{id}/cache [delete]
+func (h *ToolHandler) InvalidateToolResultCache(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, shared_types.HttpErrorResponse{Msg: "Invalid tool ID"})
+		return
+	}
+
+	deleted, err := h.toolService.InvalidateToolResultCache(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, shared_types.HttpErrorResponse{Msg: err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, shared_types.HttpSuccessResponse{Msg: fmt.Sprintf("%d cached results invalidated", deleted)})
+}
+
+// DeleteToolResultCache godoc
+// @Summary Delete a cached result of a tool
+// @Description Drops a single cached result of a tool
+// @Tags tool
+// @Produce json
+// @Param id path int true "Tool ID"
+// @Param cache_id path int true "Cached result ID"
+// @Success 200 {object} shared_types.HttpSuccessResponse
+// @Failure 400 {object} shared_types.HttpErrorResponse
+// @Failure 404 {object} shared_types.HttpErrorResponse
+// @Failure 500 {object} shared_types.HttpErrorResponse
+// @Router /v1/tools/{id}/cache/{cache_id} [delete]
+func (h *ToolHandler) DeleteToolResultCache(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, shared_types.HttpErrorResponse{Msg: "Invalid tool ID"})
+		return
+	}
+	cacheID, err := strconv.Atoi(c.Param("cache_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, shared_types.HttpErrorResponse{Msg: "Invalid cached result ID"})
+		return
+	}
+
+	if err := h.toolService.DeleteToolResultCache(c.Request.Context(), id, cacheID); err != nil {
+		if errors.Is(err, service.ErrToolResultCacheNotFound) {
+			c.JSON(http.StatusNotFound, shared_types.HttpErrorResponse{Msg: err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, shared_types.HttpErrorResponse{Msg: err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, shared_types.HttpSuccessResponse{Msg: "Cached result deleted successfully"})
+}
+
 // GetAllToolClientPermissionsByToolID godoc
 // @Summary Get all tool client permissions by tool ID
 // @Description Retrieves all tool client permissions for a specific tool
@@ -476,7 +810,7 @@ func (h *ToolHandler) GetAllToolRequestsForClient(c *gin.Context) {
 
 // GetToolRequestByID godoc
 // @Summary Get a tool request by ID
-// @Description Retrieves a tool request by its ID
+// @Description Retrieves a tool request by its ID, with its queue position while it waits for the concurrency limits of its client and tool
 // @Tags tool-request
 // @Produce json
 // @Param id path int true "Request ID"
@@ -499,99 +833,600 @@ func (h *ToolHandler) GetToolRequestByID(c *gin.Context) {
 	c.JSON(http.StatusOK, request)
 }
 
-// CreateToolRequest godoc
-// @Summary Create a new tool request
-// @Description Creates a new tool request
+// DownloadToolRequestPayload godoc
+// @Summary Download a payload of a tool request
+// @Description Streams the full JSON payload of a tool request: "request" (input of the tool), "response" (output mapped to the
+// @Description response interface) or "raw_response" (response of the provider as received).
+// @Description Payloads exceeding the offload threshold are kept in the blob store and only referenced by the tool request
+// @Description (request_data.payload_ref, response_data.payload_ref, response_data.raw_payload_ref), list endpoints do not load them.
 // @Tags tool-request
-// @Accept json
 // @Produce json
-// @Param request body dto.CreateToolRequestDTO true "Request to create"
-// @Success 201 {object} dto.ReadToolRequestDTO
+// @Param id path int true "Request ID"
+// @Param part path string true "Payload part" Enums(request, response, raw_response)
+// @Success 200 {object} object
 // @Failure 400 {object} shared_types.HttpErrorResponse
+// @Failure 403 {object} shared_types.HttpErrorResponse
+// @Failure 404 {object} shared_types.HttpErrorResponse
 // @Failure 500 {object} shared_types.HttpErrorResponse
-// @Router /v1/tool-requests [post]
-func (h *ToolHandler) CreateToolRequest(c *gin.Context) {
-	var request dto.CreateToolRequestDTO
-	if err := c.ShouldBindJSON(&request); err != nil {
-		c.JSON(http.StatusBadRequest, shared_types.HttpErrorResponse{Msg: err.Error()})
+// @Router /v1/tool-requests/{id}/payload/{part} [get]
+func (h *ToolHandler) DownloadToolRequestPayload(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, shared_types.HttpErrorResponse{Msg: "Invalid request ID"})
 		return
 	}
+	part := c.Param("part")
 
-	createdRequest, err := h.toolService.CreateToolRequest(c.Request.Context(), &request)
+	payload, err := h.toolService.OpenToolRequestPayload(c.Request.Context(), c.GetInt("clientID"), c.GetBool("isAdmin"), id, part)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, shared_types.HttpErrorResponse{Msg: err.Error()})
+		switch {
+		case errors.Is(err, service.ErrInvalidPayloadPart):
+			c.JSON(http.StatusBadRequest, shared_types.HttpErrorResponse{Msg: err.Error()})
+		case errors.Is(err, service.ErrToolRequestNotFound), errors.Is(err, service.ErrPayloadNotFound):
+			c.JSON(http.StatusNotFound, shared_types.HttpErrorResponse{Msg: err.Error()})
+		case errors.Is(err, service.ErrToolRequestAccessDenied):
+			c.JSON(http.StatusForbidden, shared_types.HttpErrorResponse{Msg: err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, shared_types.HttpErrorResponse{Msg: err.Error()})
+		}
 		return
 	}
-	c.JSON(http.StatusCreated, createdRequest)
+	defer payload.Body.Close()
+
+	headers := map[string]string{
+		"Content-Disposition": fmt.Sprintf(`attachment; filename="tool-request-%d-%s.json"`, id, part),
+	}
+	if payload.SHA256 != "" {
+		headers["ETag"] = `"` + payload.SHA256 + `"`
+	}
+	c.DataFromReader(http.StatusOK, payload.Size, "application/json", payload.Body, headers)
 }
 
-// UpdateToolRequest godoc
-// @Summary Update a tool request
-// @Description Updates an existing tool request
+// GetToolRequestLogs godoc
+// @Summary Get the execution logs of a tool request
+// @Description Retrieves the attempts of a tool request with the diagnostics reported by the engine, including the log tail
+// @Description of aws-lambda tools with "log_type": "Tail" (decoded last 4 KB of the execution log) and the stderr of local-exec
+// @Description tools, which other endpoints leave out.
 // @Tags tool-request
-// @Accept json
 // @Produce json
 // @Param id path int true "Request ID"
-// @Param request body dto.UpdateToolRequestDTO true "Request to update"
-// @Success 200 {object} shared_types.HttpSuccessResponse
+// @Success 200 {object} dto.ReadToolRequestLogsDTO
 // @Failure 400 {object} shared_types.HttpErrorResponse
 // @Failure 404 {object} shared_types.HttpErrorResponse
 // @Failure 500 {object} shared_types.HttpErrorResponse
-// @Router /v1/tool-requests/{id} [put]
-func (h *ToolHandler) UpdateToolRequest(c *gin.Context) {
+// @Router /v1/tool-requests/{id}/logs [get]
+func (h *ToolHandler) GetToolRequestLogs(c *gin.Context) {
 	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
 		c.JSON(http.StatusBadRequest, shared_types.HttpErrorResponse{Msg: "Invalid request ID"})
 		return
 	}
 
-	var request dto.UpdateToolRequestDTO
-	if err := c.ShouldBindJSON(&request); err != nil {
-		c.JSON(http.StatusBadRequest, shared_types.HttpErrorResponse{Msg: err.Error()})
-		return
-	}
-
-	if err := h.toolService.UpdateToolRequest(c.Request.Context(), id, &request); err != nil {
+	logs, err := h.toolService.GetToolRequestLogs(c.Request.Context(), id)
+	if err != nil {
+		if errors.Is(err, service.ErrToolRequestNotFound) {
+			c.JSON(http.StatusNotFound, shared_types.HttpErrorResponse{Msg: err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, shared_types.HttpErrorResponse{Msg: err.Error()})
 		return
 	}
-	c.JSON(http.StatusOK, shared_types.HttpSuccessResponse{Msg: "Request updated successfully"})
+	c.JSON(http.StatusOK, logs)
 }
 
-// DeleteToolRequest godoc
-// @Summary Delete a tool request
-// @Description Deletes an existing tool request
+// StreamToolRequestEvents godoc
+// @Summary Stream events of a tool request
+// @Description Streams status transitions, progress and the final result of a tool request as server-sent events.
+// @Description The stream starts with a "snapshot" event and ends after the "result" event.
+// @Description Only the client that created the tool request (or an admin) can stream it.
 // @Tags tool-request
-// @Produce json
+// @Produce text/event-stream
 // @Param id path int true "Request ID"
-// @Success 200 {object} shared_types.HttpSuccessResponse
+// @Success 200 {object} dto.ToolRequestEventDTO
 // @Failure 400 {object} shared_types.HttpErrorResponse
 // @Failure 404 {object} shared_types.HttpErrorResponse
 // @Failure 500 {object} shared_types.HttpErrorResponse
-// @Router /v1/tool-requests/{id} [delete]
-func (h *ToolHandler) DeleteToolRequest(c *gin.Context) {
+// @Router /v1/tool-requests/{id}/events [get]
+func (h *ToolHandler) StreamToolRequestEvents(c *gin.Context) {
 	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
 		c.JSON(http.StatusBadRequest, shared_types.HttpErrorResponse{Msg: "Invalid request ID"})
 		return
 	}
 
-	if err := h.toolService.DeleteToolRequest(c.Request.Context(), id); err != nil {
+	send := sseSender(c)
+	err = h.toolService.StreamToolRequestEvents(c.Request.Context(), c.GetInt("clientID"), c.GetBool("isAdmin"), id, send)
+	// nothing was streamed yet when the tool request could not be read
+	if err != nil && !c.Writer.Written() {
+		if errors.Is(err, service.ErrToolRequestNotFound) {
+			c.JSON(http.StatusNotFound, shared_types.HttpErrorResponse{Msg: err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, shared_types.HttpErrorResponse{Msg: err.Error()})
-		return
 	}
-	c.JSON(http.StatusOK, shared_types.HttpSuccessResponse{Msg: "Request deleted successfully"})
 }
 
-// SelectTool godoc
-// @Summary Select a tool
-// @Description Selects a tool based on user prompt
+// StreamToolRequestEventsForClient godoc
+// @Summary Stream events of the client's tool requests
+// @Description Streams status transitions, progress and results of every tool request of the client as server-sent events
+// @Tags tool-request
+// @Produce text/event-stream
+// @Success 200 {object} dto.ToolRequestEventDTO
+// @Failure 500 {object} shared_types.HttpErrorResponse
+// @Router /v1/tool-requests/client/events [get]
+func (h *ToolHandler) StreamToolRequestEventsForClient(c *gin.Context) {
+	send := sseSender(c)
+	// establish the stream right away, events may not come for a while
+	if err := send(dto.ToolRequestEventDTO{Event: "ping"}); err != nil {
+		return
+	}
+
+	_ = h.toolService.StreamClientToolRequestEvents(c.Request.Context(), c.GetInt("clientID"), send)
+}
+
+// sseSender writes each event as a server-sent event named after its Event field.
+func sseSender(c *gin.Context) func(dto.ToolRequestEventDTO) error {
+	return func(event dto.ToolRequestEventDTO) error {
+		if !c.Writer.Written() {
+			c.Header("Cache-Control", "no-cache")
+			c.Header("Connection", "keep-alive")
+			c.Header("X-Accel-Buffering", "no")
+		}
+		c.SSEvent(event.Event, event)
+		c.Writer.Flush()
+		return c.Request.Context().Err()
+	}
+}
+
+// CreateToolRequest godoc
+// @Summary Create a new tool request
+// @Description Creates a new tool request
+// @Tags tool-request
+// @Accept json
+// @Produce json
+// @Param request body dto.CreateToolRequestDTO true "Request to create"
+// @Success 201 {object} dto.ReadToolRequestDTO
+// @Failure 400 {object} shared_types.HttpErrorResponse
+// @Failure 500 {object} shared_types.HttpErrorResponse
+// @Router /v1/tool-requests [post]
+func (h *ToolHandler) CreateToolRequest(c *gin.Context) {
+	var request dto.CreateToolRequestDTO
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, shared_types.HttpErrorResponse{Msg: err.Error()})
+		return
+	}
+
+	createdRequest, err := h.toolService.CreateToolRequest(c.Request.Context(), &request)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, shared_types.HttpErrorResponse{Msg: err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, createdRequest)
+}
+
+// UpdateToolRequest godoc
+// @Summary Update a tool request
+// @Description Updates an existing tool request
+// @Tags tool-request
+// @Accept json
+// @Produce json
+// @Param id path int true "Request ID"
+// @Param request body dto.UpdateToolRequestDTO true "Request to update"
+// @Success 200 {object} shared_types.HttpSuccessResponse
+// @Failure 400 {object} shared_types.HttpErrorResponse
+// @Failure 404 {object} shared_types.HttpErrorResponse
+// @Failure 500 {object} shared_types.HttpErrorResponse
+// @Router /v1/tool-requests/{id} [put]
+func (h *ToolHandler) UpdateToolRequest(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, shared_types.HttpErrorResponse{Msg: "Invalid request ID"})
+		return
+	}
+
+	var request dto.UpdateToolRequestDTO
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, shared_types.HttpErrorResponse{Msg: err.Error()})
+		return
+	}
+
+	if err := h.toolService.UpdateToolRequest(c.Request.Context(), id, &request); err != nil {
+		if errors.Is(err, service.ErrToolRequestNotFound) {
+			c.JSON(http.StatusNotFound, shared_types.HttpErrorResponse{Msg: err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, shared_types.HttpErrorResponse{Msg: err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, shared_types.HttpSuccessResponse{Msg: "Request updated successfully"})
+}
+
+// DeleteToolRequest godoc
+// @Summary Delete a tool request
+// @Description Deletes an existing tool request
+// @Tags tool-request
+// @Produce json
+// @Param id path int true "Request ID"
+// @Success 200 {object} shared_types.HttpSuccessResponse
+// @Failure 400 {object} shared_types.HttpErrorResponse
+// @Failure 404 {object} shared_types.HttpErrorResponse
+// @Failure 500 {object} shared_types.HttpErrorResponse
+// @Router /v1/tool-requests/{id} [delete]
+func (h *ToolHandler) DeleteToolRequest(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, shared_types.HttpErrorResponse{Msg: "Invalid request ID"})
+		return
+	}
+
+	if err := h.toolService.DeleteToolRequest(c.Request.Context(), id); err != nil {
+		c.JSON(http.StatusInternalServerError, shared_types.HttpErrorResponse{Msg: err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, shared_types.HttpSuccessResponse{Msg: "Request deleted successfully"})
+}
+
+// CancelToolRequest godoc
+// @Summary Cancel a tool request
+// @Description Cancels a pending or running tool request of the client and aborts its execution
+// @Tags tool-request
+// @Produce json
+// @Param id path int true "Request ID"
+// @Success 200 {object} dto.ReadToolRequestDTO
+// @Failure 400 {object} shared_types.HttpErrorResponse
+// @Failure 403 {object} shared_types.HttpErrorResponse
+// @Failure 404 {object} shared_types.HttpErrorResponse
+// @Failure 409 {object} shared_types.HttpErrorResponse
+// @Failure 500 {object} shared_types.HttpErrorResponse
+// @Router /v1/tool-requests/{id}/cancel [post]
+func (h *ToolHandler) CancelToolRequest(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, shared_types.HttpErrorResponse{Msg: "Invalid request ID"})
+		return
+	}
+
+	request, err := h.toolService.CancelToolRequest(c.Request.Context(), c.GetInt("clientID"), c.GetBool("isAdmin"), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrToolRequestNotFound):
+			c.JSON(http.StatusNotFound, shared_types.HttpErrorResponse{Msg: err.Error()})
+		case errors.Is(err, service.ErrToolRequestForbidden):
+			c.JSON(http.StatusForbidden, shared_types.HttpErrorResponse{Msg: err.Error()})
+		case errors.Is(err, service.ErrToolRequestNotCancellable):
+			c.JSON(http.StatusConflict, shared_types.HttpErrorResponse{Msg: err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, shared_types.HttpErrorResponse{Msg: err.Error()})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, request)
+}
+
+// GetAllToolSchedules godoc
+// @Summary Get all tool schedules
+// @Description Retrieves the schedules of every client
+// @Tags tool-schedule
+// @Produce json
+// @Success 200 {array} dto.ReadToolScheduleDTO
+// @Failure 500 {object} shared_types.HttpErrorResponse
+// @Router /v1/tool-schedules [get]
+func (h *ToolHandler) GetAllToolSchedules(c *gin.Context) {
+	schedules, err := h.toolService.GetAllToolSchedules(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, shared_types.HttpErrorResponse{Msg: err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, schedules)
+}
+
+// GetAllToolSchedulesByToolID godoc
+// @Summary Get all tool schedules by tool ID
+// @Description Retrieves the schedules of a specific tool
+// @Tags tool-schedule
+// @Produce json
+// @Param tool_id path int true "Tool ID"
+// @Success 200 {array} dto.ReadToolScheduleDTO
+// @Failure 400 {object} shared_types.HttpErrorResponse
+// @Failure 500 {object} shared_types.HttpErrorResponse
+// @Router /v1/tool-schedules/tool/{tool_id} [get]
+func (h *ToolHandler) GetAllToolSchedulesByToolID(c *gin.Context) {
+	toolID, err := strconv.Atoi(c.Param("tool_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, shared_types.HttpErrorResponse{Msg: "Invalid tool ID"})
+		return
+	}
+
+	schedules, err := h.toolService.GetAllToolSchedulesByToolID(c.Request.Context(), toolID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, shared_types.HttpErrorResponse{Msg: err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, schedules)
+}
+
+// GetAllToolSchedulesForClient godoc
+// @Summary Get all tool schedules for the current client
+// @Description Retrieves the schedules of the authenticated client
+// @Tags tool-schedule
+// @Produce json
+// @Success 200 {array} dto.ReadToolScheduleDTO
+// @Failure 500 {object} shared_types.HttpErrorResponse
+// @Router /v1/tool-schedules/client [get]
+func (h *ToolHandler) GetAllToolSchedulesForClient(c *gin.Context) {
+	schedules, err := h.toolService.GetAllToolSchedulesByClientID(c.Request.Context(), c.GetInt("clientID"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, shared_types.HttpErrorResponse{Msg: err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, schedules)
+}
+
+// GetToolScheduleByID godoc
+// @Summary Get a tool schedule by ID
+// @Description Retrieves a schedule of the client (any schedule for an admin)
+// @Tags tool-schedule
+// @Produce json
+// @Param id path int true "Schedule ID"
+// @Success 200 {object} dto.ReadToolScheduleDTO
+// @Failure 400 {object} shared_types.HttpErrorResponse
+// @Failure 403 {object} shared_types.HttpErrorResponse
+// @Failure 404 {object} shared_types.HttpErrorResponse
+// @Failure 500 {object} shared_types.HttpErrorResponse
+// @Router /v1/tool-schedules/{id} [get]
+func (h *ToolHandler) GetToolScheduleByID(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, shared_types.HttpErrorResponse{Msg: "Invalid schedule ID"})
+		return
+	}
+
+	schedule, err := h.toolService.GetToolScheduleByID(c.Request.Context(), c.GetInt("clientID"), c.GetBool("isAdmin"), id)
+	if err != nil {
+		respondToolScheduleError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, schedule)
+}
+
+// CreateToolSchedule godoc
+// @Summary Create a tool schedule
+// @Description Schedules executions of a tool with a payload on a cron expression.
+// @Description The client needs the permissions required by ExecuteTool, checked again on every run.
+// @Tags tool-schedule
+// @Accept json
+// @Produce json
+// @Param schedule body dto.CreateToolScheduleDTO true "Tool schedule"
+// @Success 201 {object} dto.ReadToolScheduleDTO
+// @Failure 400 {object} shared_types.HttpErrorResponse
+// @Failure 403 {object} shared_types.HttpErrorResponse
+// @Failure 422 {object} dto.PayloadValidationErrorDTO
+// @Failure 500 {object} shared_types.HttpErrorResponse
+// @Router /v1/tool-schedules [post]
+func (h *ToolHandler) CreateToolSchedule(c *gin.Context) {
+	var schedule dto.CreateToolScheduleDTO
+	if err := c.ShouldBindJSON(&schedule); err != nil {
+		c.JSON(http.StatusBadRequest, shared_types.HttpErrorResponse{Msg: err.Error()})
+		return
+	}
+
+	createdSchedule, err := h.toolService.CreateToolSchedule(c.Request.Context(), c.GetInt("clientID"), &schedule)
+	if err != nil {
+		respondToolScheduleError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, createdSchedule)
+}
+
+// UpdateToolSchedule godoc
+// @Summary Update a tool schedule
+// @Description Replaces the definition of a schedule, an active schedule moves to the next run of the new definition
+// @Tags tool-schedule
+// @Accept json
+// @Produce json
+// @Param id path int true "Schedule ID"
+// @Param schedule body dto.UpdateToolScheduleDTO true "Tool schedule"
+// @Success 200 {object} dto.ReadToolScheduleDTO
+// @Failure 400 {object} shared_types.HttpErrorResponse
+// @Failure 403 {object} shared_types.HttpErrorResponse
+// @Failure 404 {object} shared_types.HttpErrorResponse
+// @Failure 422 {object} dto.PayloadValidationErrorDTO
+// @Failure 500 {object} shared_types.HttpErrorResponse
+// @Router /v1/tool-schedules/{id} [put]
+func (h *ToolHandler) UpdateToolSchedule(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, shared_types.HttpErrorResponse{Msg: "Invalid schedule ID"})
+		return
+	}
+
+	var schedule dto.UpdateToolScheduleDTO
+	if err := c.ShouldBindJSON(&schedule); err != nil {
+		c.JSON(http.StatusBadRequest, shared_types.HttpErrorResponse{Msg: err.Error()})
+		return
+	}
+
+	updatedSchedule, err := h.toolService.UpdateToolSchedule(
+		c.Request.Context(), c.GetInt("clientID"), c.GetBool("isAdmin"), id, &schedule,
+	)
+	if err != nil {
+		respondToolScheduleError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, updatedSchedule)
+}
+
+// DeleteToolSchedule godoc
+// @Summary Delete a tool schedule
+// @Description Deletes a schedule, the tool requests it created are kept
+// @Tags tool-schedule
+// @Produce json
+// @Param id path int true "Schedule ID"
+// @Success 200 {object} shared_types.HttpSuccessResponse
+// @Failure 400 {object} shared_types.HttpErrorResponse
+// @Failure 403 {object} shared_types.HttpErrorResponse
+// @Failure 404 {object} shared_types.HttpErrorResponse
+// @Failure 500 {object} shared_types.HttpErrorResponse
+// @Router /v1/tool-schedules/{id} [delete]
+func (h *ToolHandler) DeleteToolSchedule(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, shared_types.HttpErrorResponse{Msg: "Invalid schedule ID"})
+		return
+	}
+
+	if err := h.toolService.DeleteToolSchedule(c.Request.Context(), c.GetInt("clientID"), c.GetBool("isAdmin"), id); err != nil {
+		respondToolScheduleError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, shared_types.HttpSuccessResponse{Msg: "Schedule deleted successfully"})
+}
+
+// PauseToolSchedule godoc
+// @Summary Pause a tool schedule
+// @Description Stops the runs of a schedule until it is resumed
+// @Tags tool-schedule
+// @Produce json
+// @Param id path int true "Schedule ID"
+// @Success 200 {object} dto.ReadToolScheduleDTO
+// @Failure 400 {object} shared_types.HttpErrorResponse
+// @Failure 403 {object} shared_types.HttpErrorResponse
+// @Failure 404 {object} shared_types.HttpErrorResponse
+// @Failure 500 {object} shared_types.HttpErrorResponse
+// @Router /v1/tool-schedules/{id}/pause [post]
+func (h *ToolHandler) PauseToolSchedule(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, shared_types.HttpErrorResponse{Msg: "Invalid schedule ID"})
+		return
+	}
+
+	schedule, err := h.toolService.PauseToolSchedule(c.Request.Context(), c.GetInt("clientID"), c.GetBool("isAdmin"), id)
+	if err != nil {
+		respondToolScheduleError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, schedule)
+}
+
+// ResumeToolSchedule godoc
+// @Summary Resume a tool schedule
+// @Description Restarts a paused schedule from its next run, the runs of the pause are not fired
+// @Tags tool-schedule
+// @Produce json
+// @Param id path int true "Schedule ID"
+// @Success 200 {object} dto.ReadToolScheduleDTO
+// @Failure 400 {object} shared_types.HttpErrorResponse
+// @Failure 403 {object} shared_types.HttpErrorResponse
+// @Failure 404 {object} shared_types.HttpErrorResponse
+// @Failure 500 {object} shared_types.HttpErrorResponse
+// @Router /v1/tool-schedules/{id}/resume [post]
+func (h *ToolHandler) ResumeToolSchedule(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, shared_types.HttpErrorResponse{Msg: "Invalid schedule ID"})
+		return
+	}
+
+	schedule, err := h.toolService.ResumeToolSchedule(c.Request.Context(), c.GetInt("clientID"), c.GetBool("isAdmin"), id)
+	if err != nil {
+		respondToolScheduleError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, schedule)
+}
+
+// GetToolScheduleNextRuns godoc
+// @Summary Preview the next runs of a tool schedule
+// @Description Lists the next runs of a schedule in its timezone (as if it was active)
+// @Tags tool-schedule
+// @Produce json
+// @Param id path int true "Schedule ID"
+// @Param count query int false "Number of runs (default 5, max 50)"
+// @Success 200 {object} dto.ToolScheduleRunsDTO
+// @Failure 400 {object} shared_types.HttpErrorResponse
+// @Failure 403 {object} shared_types.HttpErrorResponse
+// @Failure 404 {object} shared_types.HttpErrorResponse
+// @Failure 500 {object} shared_types.HttpErrorResponse
+// @Router /v1/tool-schedules/{id}/next-runs [get]
+func (h *ToolHandler) GetToolScheduleNextRuns(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, shared_types.HttpErrorResponse{Msg: "Invalid schedule ID"})
+		return
+	}
+	count := 0
+	if value := c.Query("count"); value != "" {
+		if count, err = strconv.Atoi(value); err != nil {
+			c.JSON(http.StatusBadRequest, shared_types.HttpErrorResponse{Msg: "Invalid count"})
+			return
+		}
+	}
+
+	runs, err := h.toolService.GetToolScheduleNextRuns(
+		c.Request.Context(), c.GetInt("clientID"), c.GetBool("isAdmin"), id, count,
+	)
+	if err != nil {
+		respondToolScheduleError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, runs)
+}
+
+// PreviewToolSchedule godoc
+// @Summary Preview the next runs of a cron expression
+// @Description Lists the next runs of a cron expression in a timezone, to check it before creating a schedule
+// @Tags tool-schedule
+// @Accept json
+// @Produce json
+// @Param preview body dto.PreviewToolScheduleDTO true "Cron expression and timezone"
+// @Success 200 {object} dto.ToolScheduleRunsDTO
+// @Failure 400 {object} shared_types.HttpErrorResponse
+// @Failure 500 {object} shared_types.HttpErrorResponse
+// @Router /v1/tool-schedules/preview [post]
+func (h *ToolHandler) PreviewToolSchedule(c *gin.Context) {
+	var preview dto.PreviewToolScheduleDTO
+	if err := c.ShouldBindJSON(&preview); err != nil {
+		c.JSON(http.StatusBadRequest, shared_types.HttpErrorResponse{Msg: err.Error()})
+		return
+	}
+
+	runs, err := h.toolService.PreviewToolSchedule(c.Request.Context(), &preview)
+	if err != nil {
+		respondToolScheduleError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, runs)
+}
+
+func respondToolScheduleError(c *gin.Context, err error) {
+	var validationErr *service.PayloadValidationError
+	switch {
+	case errors.As(err, &validationErr):
+		c.JSON(http.StatusUnprocessableEntity, validationErr.ToDTO())
+	case errors.Is(err, service.ErrToolScheduleNotFound):
+		c.JSON(http.StatusNotFound, shared_types.HttpErrorResponse{Msg: err.Error()})
+	case errors.Is(err, service.ErrToolScheduleForbidden), errors.Is(err, service.ErrToolExecutionRefused):
+		c.JSON(http.StatusForbidden, shared_types.HttpErrorResponse{Msg: err.Error()})
+	case errors.Is(err, service.ErrInvalidToolSchedule):
+		c.JSON(http.StatusBadRequest, shared_types.HttpErrorResponse{Msg: err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, shared_types.HttpErrorResponse{Msg: err.Error()})
+	}
+}
+
+// SelectTool godoc
+// @Summary Select a tool
+// @Description Selects a tool based on user prompt
 // @Tags tool
 // @Accept json
 // @Produce json
 // @Param prompt body dto.SelectToolRequestDTO true "User prompt"
 // @Success 200 {object} dto.SelectToolResponseDTO
 // @Failure 400 {object} shared_types.HttpErrorResponse
+// @Failure 404 {object} shared_types.HttpErrorResponse
+// @Failure 429 {object} shared_types.HttpErrorResponse
+// @Failure 503 {object} shared_types.HttpErrorResponse
 // @Failure 500 {object} shared_types.HttpErrorResponse
 // @Router /v1/tools/select [post]
 func (h *ToolHandler) SelectTool(c *gin.Context) {
@@ -603,6 +1438,14 @@ func (h *ToolHandler) SelectTool(c *gin.Context) {
 
 	response, err := h.toolService.SelectTool(c.Request.Context(), c.GetInt("clientID"), request.UserPrompt)
 	if err != nil {
+		if errors.Is(err, service.ErrNoHealthyTool) {
+			c.JSON(http.StatusServiceUnavailable, shared_types.HttpErrorResponse{Msg: err.Error()})
+			return
+		}
+		if errors.Is(err, service.ErrToolNotFound) {
+			c.JSON(http.StatusNotFound, shared_types.HttpErrorResponse{Msg: err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, shared_types.HttpErrorResponse{Msg: err.Error()})
 		return
 	}
@@ -611,14 +1454,23 @@ func (h *ToolHandler) SelectTool(c *gin.Context) {
 
 // ExecuteTool godoc
 // @Summary Execute a tool
-// @Description Executes a tool based on user prompt
+// @Description Executes a tool based on user prompt.
+// @Description The payload is validated against the RequestInterface of the tool first, rejected with 422 listing every error.
+// @Description With webhook_url, the URL receives a signed POST when the tool request finishes.
+// @Description With wait, blocks until the tool request finishes or the wait elapses and returns the tool request.
+// @Description While the circuit breaker of the tool is open, the execution is refused with the circuit_open status, retry_at and a Retry-After header.
+// @Description Executions are rate limited per client (X-RateLimit-* headers), 429 with Retry-After when exceeded.
+// @Description Executions (and the runs of tool schedules) count against the daily and monthly quotas of the client, refused with 429, the quota_exceeded status, retry_at and a Retry-After header when reached.
 // @Tags tool
 // @Accept json
 // @Produce json
 // @Param tool_id path int true "Tool ID"
+// @Param wait query string false "Maximum wait for the result (e.g. 30s, or seconds), up to 2m"
 // @Param request body dto.ToolExecutionRequestDTO true "Request to execute"
 // @Success 200 {object} dto.ToolExecutionResponseDTO
 // @Failure 400 {object} shared_types.HttpErrorResponse
+// @Failure 429 {object} shared_types.HttpErrorResponse
+// @Failure 422 {object} dto.PayloadValidationErrorDTO
 // @Failure 500 {object} shared_types.HttpErrorResponse
 // @Router /v1/tools/{tool_id}/execute [post]
 func (h *ToolHandler) ExecuteTool(c *gin.Context) {
@@ -633,11 +1485,171 @@ func (h *ToolHandler) ExecuteTool(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, shared_types.HttpErrorResponse{Msg: err.Error()})
 		return
 	}
+	if request.WebhookURL != "" {
+		if err := utils.ValidateHTTPURL(request.WebhookURL); err != nil {
+			c.JSON(http.StatusBadRequest, shared_types.HttpErrorResponse{Msg: "Invalid webhook_url: " + err.Error()})
+			return
+		}
+	}
+
+	wait, err := parseWait(c.Query("wait"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, shared_types.HttpErrorResponse{Msg: err.Error()})
+		return
+	}
+
+	response, err := h.toolService.ExecuteTool(c.Request.Context(), c.GetInt("clientID"), c.GetBool("isAdmin"), toolID, request)
+	h.respondToolExecution(c, wait, response, err)
+}
+
+// ExecuteToolWithFiles godoc
+// @Summary Execute a tool with file inputs
+// @Description Multipart variant of ExecuteTool for tools with file inputs (valueType "file", or the "file" format in a request schema).
+// @Description The payload field holds the JSON object of the other inputs, and each file part is named after the key of its file input.
+// @Description Files are kept in the blob store and the tool receives their path or a presigned URL in place of the content.
+// @Description The tool request lists the uploaded files in request_data.artifacts with their size and SHA-256 checksum.
+// @Tags tool
+// @Accept mpfd
+// @Produce json
+// @Param tool_id path int true "Tool ID"
+// @Param wait query string false "Maximum wait for the result (e.g. 30s, or seconds), up to 2m"
+// @Param payload formData string false "JSON object of the inputs which are not files"
+// @Param webhook_url formData string false "URL receiving a signed POST when the tool request finishes"
+// @Param file formData file false "A file input: the part is named after the key of the input (one part per input)"
+// @Success 200 {object} dto.ToolExecutionResponseDTO
+// @Failure 400 {object} shared_types.HttpErrorResponse
+// @Failure 429 {object} shared_types.HttpErrorResponse
+// @Failure 413 {object} shared_types.HttpErrorResponse
+// @Failure 422 {object} dto.PayloadValidationErrorDTO
+// @Failure 500 {object} shared_types.HttpErrorResponse
+// @Failure 503 {object} shared_types.HttpErrorResponse
+// @Router /v1/tools/{tool_id}/execute/multipart [post]
+func (h *ToolHandler) ExecuteToolWithFiles(c *gin.Context) {
+	toolID, err := strconv.Atoi(c.Param("tool_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, shared_types.HttpErrorResponse{Msg: "Invalid tool ID"})
+		return
+	}
 
-	response, err := h.toolService.ExecuteTool(c.Request.Context(), c.GetInt("clientID"), toolID, request)
+	wait, err := parseWait(c.Query("wait"))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, shared_types.HttpErrorResponse{Msg: err.Error()})
+		c.JSON(http.StatusBadRequest, shared_types.HttpErrorResponse{Msg: err.Error()})
+		return
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, h.maxUploadBytes)
+	if err := c.Request.ParseMultipartForm(multipartMemory); err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			c.JSON(http.StatusRequestEntityTooLarge, shared_types.HttpErrorResponse{
+				Msg: fmt.Sprintf("Upload exceeds %d bytes", h.maxUploadBytes),
+			})
+			return
+		}
+		c.JSON(http.StatusBadRequest, shared_types.HttpErrorResponse{Msg: "Invalid multipart form: " + err.Error()})
+		return
+	}
+	form := c.Request.MultipartForm
+	defer form.RemoveAll()
+
+	var request dto.ToolExecutionRequestDTO
+	if values := form.Value["payload"]; len(values) > 0 && strings.TrimSpace(values[0]) != "" {
+		if err := json.Unmarshal([]byte(values[0]), &request.Payload); err != nil {
+			c.JSON(http.StatusBadRequest, shared_types.HttpErrorResponse{Msg: "Invalid payload: must be a JSON object"})
+			return
+		}
+	}
+	if values := form.Value["webhook_url"]; len(values) > 0 {
+		request.WebhookURL = values[0]
+	}
+	if request.WebhookURL != "" {
+		if err := utils.ValidateHTTPURL(request.WebhookURL); err != nil {
+			c.JSON(http.StatusBadRequest, shared_types.HttpErrorResponse{Msg: "Invalid webhook_url: " + err.Error()})
+			return
+		}
+	}
+
+	files := make([]service.ToolExecutionFile, 0, len(form.File))
+	for field, headers := range form.File {
+		if len(headers) != 1 {
+			c.JSON(http.StatusBadRequest, shared_types.HttpErrorResponse{
+				Msg: fmt.Sprintf("Expected a single file for %s, got %d", field, len(headers)),
+			})
+			return
+		}
+		header := headers[0]
+		files = append(files, service.ToolExecutionFile{
+			Field:       field,
+			FileName:    header.Filename,
+			ContentType: header.Header.Get("Content-Type"),
+			Open: func() (io.ReadSeekCloser, error) {
+				return header.Open()
+			},
+		})
+	}
+	slices.SortFunc(files, func(a, b service.ToolExecutionFile) int {
+		return strings.Compare(a.Field, b.Field)
+	})
+
+	response, err := h.toolService.ExecuteToolWithFiles(
+		c.Request.Context(), c.GetInt("clientID"), c.GetBool("isAdmin"), toolID, request, files,
+	)
+	h.respondToolExecution(c, wait, response, err)
+}
+
+// respondToolExecution writes the response of ExecuteTool, after waiting for the tool request in wait mode.
+func (h *ToolHandler) respondToolExecution(
+	c *gin.Context, wait time.Duration, response *dto.ToolExecutionResponseDTO, err error,
+) {
+	if err != nil {
+		var validationErr *service.PayloadValidationError
+		switch {
+		case errors.As(err, &validationErr):
+			c.JSON(http.StatusUnprocessableEntity, validationErr.ToDTO())
+		case errors.Is(err, service.ErrBlobStoreUnavailable):
+			c.JSON(http.StatusServiceUnavailable, shared_types.HttpErrorResponse{Msg: err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, shared_types.HttpErrorResponse{Msg: err.Error()})
+		}
+		return
+	}
+
+	if wait > 0 && response.Status == valueobject.ToolExecutionStatusSuccess {
+		toolRequest, err := h.toolService.WaitToolRequest(c.Request.Context(), response.ToolRequestID, wait)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, shared_types.HttpErrorResponse{Msg: err.Error()})
+			return
+		}
+		response.ToolRequest = toolRequest
+	}
+	if response.RetryAt != nil {
+		retryAfter := max(int(math.Ceil(time.Until(*response.RetryAt).Seconds())), 1)
+		c.Header("Retry-After", strconv.Itoa(retryAfter))
+	}
+	if response.Status == valueobject.ToolExecutionStatusQuotaExceeded {
+		c.JSON(http.StatusTooManyRequests, response)
 		return
 	}
 	c.JSON(http.StatusOK, response)
 }
+
+// parseWait reads the wait query parameter as a duration ("30s", "1m") or a number of seconds ("30").
+func parseWait(value string) (time.Duration, error) {
+	if value == "" {
+		return 0, nil
+	}
+
+	wait, err := time.ParseDuration(value)
+	if err != nil {
+		seconds, convErr := strconv.ParseFloat(value, 64)
+		if convErr != nil {
+			return 0, fmt.Errorf("invalid wait %q", value)
+		}
+		wait = time.Duration(seconds * float64(time.Second))
+	}
+	if wait < 0 {
+		return 0, fmt.Errorf("invalid wait %q", value)
+	}
+
+	return wait, nil
+}
@@ -9,17 +9,19 @@ import (
 func SetupToolRoutes(
 	router *gin.Engine,
 	db *pgxpool.Pool,
+	rateLimits *authd.RateLimits,
 	toolHandler *ToolHandler,
 ) {
 	toolRoutes := router.Group("/v1/tools")
 	{
-		toolDefaultRoutes := toolRoutes.Group("", authd.DefaultAuthMiddleWare(db))
+		toolDefaultRoutes := toolRoutes.Group("", authd.DefaultAuthMiddleWare(db), rateLimits.Read())
 		{
 			toolDefaultRoutes.GET("/:id", toolHandler.GetToolByID)
 			toolDefaultRoutes.GET("/uuid/:uuid", toolHandler.GetToolByUUID)
 			toolDefaultRoutes.GET("/client", toolHandler.GetAllToolsForClient)
-			toolDefaultRoutes.POST("/select", toolHandler.SelectTool)
-			toolDefaultRoutes.POST("/:tool_id/execute", toolHandler.ExecuteTool)
+			toolDefaultRoutes.POST("/select", rateLimits.Select(), toolHandler.SelectTool)
+			toolDefaultRoutes.POST("/:tool_id/execute", rateLimits.Execute(), toolHandler.ExecuteTool)
+			toolDefaultRoutes.POST("/:tool_id/execute/multipart", rateLimits.Execute(), toolHandler.ExecuteToolWithFiles)
 		}
 
 		toolAdminRoutes := toolRoutes.Group("", authd.AdminAuthMiddleWare(db))
@@ -29,13 +31,24 @@ func SetupToolRoutes(
 			toolAdminRoutes.POST("", toolHandler.CreateTool)
 			toolAdminRoutes.PUT("/:id", toolHandler.UpdateTool)
 			toolAdminRoutes.DELETE("/:id", toolHandler.DeleteTool)
+			toolAdminRoutes.POST("/:tool_id/publish", toolHandler.PublishTool)
+			toolAdminRoutes.GET("/:id/health", toolHandler.GetToolHealth)
+			toolAdminRoutes.POST("/:tool_id/health/check", toolHandler.CheckToolHealth)
+			toolAdminRoutes.GET("/breakers", toolHandler.GetAllCircuitBreakers)
+			toolAdminRoutes.GET("/:id/breaker", toolHandler.GetCircuitBreaker)
+			toolAdminRoutes.POST("/:tool_id/breaker/reset", toolHandler.ResetCircuitBreaker)
+			toolAdminRoutes.GET("/lambda/functions", toolHandler.ListLambdaFunctions)
+			toolAdminRoutes.POST("/lambda/import", toolHandler.ImportLambdaTools)
+			toolAdminRoutes.GET("/:id/cache", toolHandler.GetToolResultCache)
+			toolAdminRoutes.DELETE("/:id/cache", toolHandler.InvalidateToolResultCache)
+			toolAdminRoutes.DELETE("/:id/cache/:cache_id", toolHandler.DeleteToolResultCache)
 		}
 	}
 
 	// Tool Client Permission routes
 	toolPermissionRoutes := router.Group("/v1/tool-permissions")
 	{
-		toolPermissionDefaultRoutes := toolPermissionRoutes.Group("", authd.DefaultAuthMiddleWare(db))
+		toolPermissionDefaultRoutes := toolPermissionRoutes.Group("", authd.DefaultAuthMiddleWare(db), rateLimits.Read())
 		{
 			toolPermissionDefaultRoutes.GET("/client", toolHandler.GetAllToolClientPermissionsForClient)
 		}
@@ -53,19 +66,47 @@ func SetupToolRoutes(
 	// Tool Request routes
 	toolRequestRoutes := router.Group("/v1/tool-requests")
 	{
-		toolRequestDefaultRoutes := toolRequestRoutes.Group("", authd.DefaultAuthMiddleWare(db))
+		toolRequestDefaultRoutes := toolRequestRoutes.Group("", authd.DefaultAuthMiddleWare(db), rateLimits.Read())
 		{
 			toolRequestDefaultRoutes.GET("/client", toolHandler.GetAllToolRequestsForClient)
+			toolRequestDefaultRoutes.GET("/client/events", toolHandler.StreamToolRequestEventsForClient)
 			toolRequestDefaultRoutes.GET("/:id", toolHandler.GetToolRequestByID)
+			toolRequestDefaultRoutes.GET("/:id/events", toolHandler.StreamToolRequestEvents)
+			toolRequestDefaultRoutes.GET("/:id/payload/:part", toolHandler.DownloadToolRequestPayload)
+			toolRequestDefaultRoutes.POST("/:id/cancel", toolHandler.CancelToolRequest)
 		}
 
 		toolRequestAdminRoutes := toolRequestRoutes.Group("", authd.AdminAuthMiddleWare(db))
 		{
 			toolRequestAdminRoutes.GET("/tool/:tool_id", toolHandler.GetAllToolRequestsByToolID)
 			toolRequestAdminRoutes.GET("/client/:client_id", toolHandler.GetAllToolRequestsByClientID)
+			toolRequestAdminRoutes.GET("/:id/logs", toolHandler.GetToolRequestLogs)
 			toolRequestAdminRoutes.POST("", toolHandler.CreateToolRequest)
 			toolRequestAdminRoutes.PUT("/:id", toolHandler.UpdateToolRequest)
 			toolRequestAdminRoutes.DELETE("/:id", toolHandler.DeleteToolRequest)
 		}
 	}
+
+	// Tool Schedule routes
+	toolScheduleRoutes := router.Group("/v1/tool-schedules")
+	{
+		toolScheduleDefaultRoutes := toolScheduleRoutes.Group("", authd.DefaultAuthMiddleWare(db), rateLimits.Read())
+		{
+			toolScheduleDefaultRoutes.GET("/client", toolHandler.GetAllToolSchedulesForClient)
+			toolScheduleDefaultRoutes.GET("/:id", toolHandler.GetToolScheduleByID)
+			toolScheduleDefaultRoutes.GET("/:id/next-runs", toolHandler.GetToolScheduleNextRuns)
+			toolScheduleDefaultRoutes.POST("", toolHandler.CreateToolSchedule)
+			toolScheduleDefaultRoutes.POST("/preview", toolHandler.PreviewToolSchedule)
+			toolScheduleDefaultRoutes.PUT("/:id", toolHandler.UpdateToolSchedule)
+			toolScheduleDefaultRoutes.DELETE("/:id", toolHandler.DeleteToolSchedule)
+			toolScheduleDefaultRoutes.POST("/:id/pause", toolHandler.PauseToolSchedule)
+			toolScheduleDefaultRoutes.POST("/:id/resume", toolHandler.ResumeToolSchedule)
+		}
+
+		toolScheduleAdminRoutes := toolScheduleRoutes.Group("", authd.AdminAuthMiddleWare(db))
+		{
+			toolScheduleAdminRoutes.GET("", toolHandler.GetAllToolSchedules)
+			toolScheduleAdminRoutes.GET("/tool/:tool_id", toolHandler.GetAllToolSchedulesByToolID)
+		}
+	}
 }
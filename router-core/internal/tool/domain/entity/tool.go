@@ -6,6 +6,7 @@ import (
 
 	"aigendrug.com/router-core/internal/tool/application/dto"
 	"aigendrug.com/router-core/internal/tool/domain/shared_type"
+	"aigendrug.com/router-core/internal/tool/domain/valueobject"
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5/pgtype"
 )
@@ -18,6 +19,8 @@ type Tool struct {
 	Description       string                        `json:"description" db:"description"`
 	EngineInterface   shared_type.EngineInterface   `json:"engine_interface" db:"engine_interface"`
 	ProviderInterface shared_type.ProviderInterface `json:"provider_interface" db:"provider_interface"`
+	Draft             bool                          `json:"draft" db:"draft"`
+	Health            shared_type.ToolHealth        `json:"health" db:"-"`
 	CreatedAt         time.Time                     `json:"created_at" db:"created_at"`
 	UpdatedAt         time.Time                     `json:"updated_at" db:"updated_at"`
 }
@@ -30,6 +33,12 @@ type ToolRow struct {
 	Description       string             `json:"description" db:"description"`
 	EngineInterface   string             `json:"engine_interface" db:"engine_interface"`
 	ProviderInterface string             `json:"provider_interface" db:"provider_interface"`
+	Draft             bool               `json:"draft" db:"draft"`
+	HealthStatus      string             `json:"health_status" db:"health_status"`
+	HealthMessage     string             `json:"health_message" db:"health_message"`
+	HealthFailures    int                `json:"health_failures" db:"health_failures"`
+	HealthCheckedAt   pgtype.Timestamptz `json:"health_checked_at" db:"health_checked_at"`
+	HealthChangedAt   pgtype.Timestamptz `json:"health_changed_at" db:"health_changed_at"`
 	CreatedAt         pgtype.Timestamptz `json:"created_at" db:"created_at"`
 	UpdatedAt         pgtype.Timestamptz `json:"updated_at" db:"updated_at"`
 }
@@ -60,6 +69,7 @@ func (t *Tool) ToRow() *ToolRow {
 		Description:       t.Description,
 		EngineInterface:   string(engineInterface),
 		ProviderInterface: string(providerInterface),
+		Draft:             t.Draft,
 		CreatedAt:         pgtype.Timestamptz{Time: t.CreatedAt},
 		UpdatedAt:         pgtype.Timestamptz{Time: t.UpdatedAt},
 	}
@@ -80,6 +90,21 @@ func (tr *ToolRow) ToEntity() *Tool {
 		return nil
 	}
 
+	health := shared_type.ToolHealth{
+		Status:              valueobject.ToolHealthStatus(tr.HealthStatus),
+		Message:             tr.HealthMessage,
+		ConsecutiveFailures: tr.HealthFailures,
+	}
+	if health.Status == "" {
+		health.Status = valueobject.ToolHealthStatusUnknown
+	}
+	if tr.HealthCheckedAt.Valid {
+		health.CheckedAt = &tr.HealthCheckedAt.Time
+	}
+	if tr.HealthChangedAt.Valid {
+		health.ChangedAt = &tr.HealthChangedAt.Time
+	}
+
 	return &Tool{
 		ID:                tr.ID,
 		UUID:              uuid,
@@ -88,6 +113,8 @@ func (tr *ToolRow) ToEntity() *Tool {
 		Description:       tr.Description,
 		EngineInterface:   engineInterface,
 		ProviderInterface: providerInterface,
+		Draft:             tr.Draft,
+		Health:            health,
 		CreatedAt:         tr.CreatedAt.Time,
 		UpdatedAt:         tr.UpdatedAt.Time,
 	}
@@ -102,5 +129,9 @@ func (t *Tool) ToDTO() *dto.ReadToolDTO {
 		Description:       t.Description,
 		EngineInterface:   t.EngineInterface,
 		ProviderInterface: t.ProviderInterface,
+		Draft:             t.Draft,
+		Health:            t.Health,
+		RequestSchema:     t.ProviderInterface.ResolvedRequestSchema(),
+		ResponseSchema:    t.ProviderInterface.ResolvedResponseSchema(),
 	}
 }
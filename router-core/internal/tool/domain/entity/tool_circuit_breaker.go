@@ -0,0 +1,46 @@
+package entity
+
+import (
+	"time"
+
+	"aigendrug.com/router-core/internal/tool/domain/valueobject"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// ToolCircuitBreaker is the circuit breaker of a tool shared by the replicas: its last trip, by any replica, until
+// it is closed by a successful trial or reset. Trips counts the trips of every replica since the last reset.
+// The invocations counted toward a trip are kept by each replica.
+type ToolCircuitBreaker struct {
+	ToolID    int                             `json:"tool_id" db:"tool_id"`
+	State     valueobject.CircuitBreakerState `json:"state" db:"state"`
+	OpenedAt  time.Time                       `json:"opened_at" db:"opened_at"`
+	OpenUntil time.Time                       `json:"open_until" db:"open_until"`
+	Trips     int                             `json:"trips" db:"trips"`
+	LastError string                          `json:"last_error" db:"last_error"`
+	ResetAt   time.Time                       `json:"reset_at" db:"reset_at"`
+	UpdatedAt time.Time                       `json:"updated_at" db:"updated_at"`
+}
+
+type ToolCircuitBreakerRow struct {
+	ToolID    int                `json:"tool_id" db:"tool_id"`
+	State     string             `json:"state" db:"state"`
+	OpenedAt  pgtype.Timestamptz `json:"opened_at" db:"opened_at"`
+	OpenUntil pgtype.Timestamptz `json:"open_until" db:"open_until"`
+	Trips     int                `json:"trips" db:"trips"`
+	LastError string             `json:"last_error" db:"last_error"`
+	ResetAt   pgtype.Timestamptz `json:"reset_at" db:"reset_at"`
+	UpdatedAt pgtype.Timestamptz `json:"updated_at" db:"updated_at"`
+}
+
+func (t *ToolCircuitBreakerRow) ToEntity() *ToolCircuitBreaker {
+	return &ToolCircuitBreaker{
+		ToolID:    t.ToolID,
+		State:     valueobject.CircuitBreakerState(t.State),
+		OpenedAt:  t.OpenedAt.Time,
+		OpenUntil: t.OpenUntil.Time,
+		Trips:     t.Trips,
+		LastError: t.LastError,
+		ResetAt:   t.ResetAt.Time,
+		UpdatedAt: t.UpdatedAt.Time,
+	}
+}
@@ -0,0 +1,66 @@
+package entity
+
+import (
+	"time"
+
+	"aigendrug.com/router-core/internal/tool/application/dto"
+	"aigendrug.com/router-core/internal/tool/domain/valueobject"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// ToolHealthCheck is a health check of a tool. Healthy is its result, Status the health of the tool after it.
+type ToolHealthCheck struct {
+	ID        int                          `json:"id" db:"id"`
+	ToolID    int                          `json:"tool_id" db:"tool_id"`
+	Healthy   bool                         `json:"healthy" db:"healthy"`
+	Status    valueobject.ToolHealthStatus `json:"status" db:"status"`
+	Message   string                       `json:"message" db:"message"`
+	LatencyMs int64                        `json:"latency_ms" db:"latency_ms"`
+	CheckedAt time.Time                    `json:"checked_at" db:"checked_at"`
+}
+
+type ToolHealthCheckRow struct {
+	ID        int                `json:"id" db:"id"`
+	ToolID    int                `json:"tool_id" db:"tool_id"`
+	Healthy   bool               `json:"healthy" db:"healthy"`
+	Status    string             `json:"status" db:"status"`
+	Message   string             `json:"message" db:"message"`
+	LatencyMs int64              `json:"latency_ms" db:"latency_ms"`
+	CheckedAt pgtype.Timestamptz `json:"checked_at" db:"checked_at"`
+}
+
+func (t *ToolHealthCheck) ToRow() *ToolHealthCheckRow {
+	return &ToolHealthCheckRow{
+		ID:        t.ID,
+		ToolID:    t.ToolID,
+		Healthy:   t.Healthy,
+		Status:    string(t.Status),
+		Message:   t.Message,
+		LatencyMs: t.LatencyMs,
+		CheckedAt: pgtype.Timestamptz{Time: t.CheckedAt, Valid: true},
+	}
+}
+
+func (t *ToolHealthCheckRow) ToEntity() *ToolHealthCheck {
+	return &ToolHealthCheck{
+		ID:        t.ID,
+		ToolID:    t.ToolID,
+		Healthy:   t.Healthy,
+		Status:    valueobject.ToolHealthStatus(t.Status),
+		Message:   t.Message,
+		LatencyMs: t.LatencyMs,
+		CheckedAt: t.CheckedAt.Time,
+	}
+}
+
+func (t *ToolHealthCheck) ToDTO() *dto.ReadToolHealthCheckDTO {
+	return &dto.ReadToolHealthCheckDTO{
+		ID:        t.ID,
+		ToolID:    t.ToolID,
+		Healthy:   t.Healthy,
+		Status:    t.Status,
+		Message:   t.Message,
+		LatencyMs: t.LatencyMs,
+		CheckedAt: t.CheckedAt,
+	}
+}
@@ -18,6 +18,9 @@ type ToolRequest struct {
 	RequestData  shared_type.ToolRequestData         `json:"request_data" db:"request_data"`
 	ResponseData shared_type.ToolRequestResponseData `json:"response_data" db:"response_data"`
 	Status       valueobject.ToolRequestStatus       `json:"status" db:"status"`
+	Attempts     []shared_type.ToolRequestAttempt    `json:"attempts" db:"attempts"`
+	ClaimCount   int                                 `json:"claim_count" db:"claim_count"`
+	LockedBy     string                              `json:"locked_by" db:"locked_by"`
 	CreatedAt    time.Time                           `json:"created_at" db:"created_at"`
 	UpdatedAt    time.Time                           `json:"updated_at" db:"updated_at"`
 }
@@ -30,6 +33,9 @@ type ToolRequestRow struct {
 	RequestData  string             `json:"request_data" db:"request_data"`
 	ResponseData string             `json:"response_data" db:"response_data"`
 	Status       string             `json:"status" db:"status"`
+	Attempts     pgtype.Text        `json:"attempts" db:"attempts"`
+	ClaimCount   int                `json:"claim_count" db:"claim_count"`
+	LockedBy     pgtype.Text        `json:"locked_by" db:"locked_by"`
 	CreatedAt    pgtype.Timestamptz `json:"created_at" db:"created_at"`
 	UpdatedAt    pgtype.Timestamptz `json:"updated_at" db:"updated_at"`
 }
@@ -43,6 +49,14 @@ func (t *ToolRequest) ToRow() *ToolRequestRow {
 	if err != nil {
 		return nil
 	}
+	attempts := pgtype.Text{}
+	if len(t.Attempts) > 0 {
+		attemptsRaw, err := json.Marshal(t.Attempts)
+		if err != nil {
+			return nil
+		}
+		attempts = pgtype.Text{String: string(attemptsRaw), Valid: true}
+	}
 
 	return &ToolRequestRow{
 		ID:           t.ID,
@@ -52,6 +66,9 @@ func (t *ToolRequest) ToRow() *ToolRequestRow {
 		RequestData:  string(requestData),
 		ResponseData: string(responseData),
 		Status:       t.Status.String(),
+		Attempts:     attempts,
+		ClaimCount:   t.ClaimCount,
+		LockedBy:     pgtype.Text{String: t.LockedBy, Valid: t.LockedBy != ""},
 		CreatedAt:    pgtype.Timestamptz{Time: t.CreatedAt},
 		UpdatedAt:    pgtype.Timestamptz{Time: t.UpdatedAt},
 	}
@@ -66,6 +83,12 @@ func (t *ToolRequestRow) ToEntity() *ToolRequest {
 	if err := json.Unmarshal([]byte(t.ResponseData), &responseData); err != nil {
 		return nil
 	}
+	attempts := []shared_type.ToolRequestAttempt{}
+	if t.Attempts.Valid && t.Attempts.String != "" {
+		if err := json.Unmarshal([]byte(t.Attempts.String), &attempts); err != nil {
+			return nil
+		}
+	}
 
 	return &ToolRequest{
 		ID:           t.ID,
@@ -75,6 +98,9 @@ func (t *ToolRequestRow) ToEntity() *ToolRequest {
 		RequestData:  requestData,
 		ResponseData: responseData,
 		Status:       valueobject.ToolRequestStatus(t.Status),
+		Attempts:     attempts,
+		ClaimCount:   t.ClaimCount,
+		LockedBy:     t.LockedBy.String,
 		CreatedAt:    t.CreatedAt.Time,
 		UpdatedAt:    t.UpdatedAt.Time,
 	}
@@ -89,7 +115,37 @@ func (t *ToolRequest) ToDTO() *dto.ReadToolRequestDTO {
 		RequestData:  t.RequestData,
 		ResponseData: t.ResponseData,
 		Status:       t.Status,
+		Attempts:     withoutToolLogs(t.Attempts),
+		AttemptCount: len(t.Attempts),
+		CacheHit:     t.ResponseData.CacheHit != nil,
 		CreatedAt:    t.CreatedAt,
 		UpdatedAt:    t.UpdatedAt,
 	}
 }
+
+// ToLogsDTO returns the attempts of the tool request with their execution logs, for admins.
+func (t *ToolRequest) ToLogsDTO() *dto.ReadToolRequestLogsDTO {
+	return &dto.ReadToolRequestLogsDTO{
+		ToolRequestID: t.ID,
+		ToolID:        t.ToolID,
+		ToolName:      t.ToolName,
+		Status:        t.Status,
+		Attempts:      t.Attempts,
+	}
+}
+
+// withoutToolLogs copies the attempts without the log tails and the stderr of their diagnostics,
+// which may hold anything the tool logged.
+func withoutToolLogs(attempts []shared_type.ToolRequestAttempt) []shared_type.ToolRequestAttempt {
+	redacted := make([]shared_type.ToolRequestAttempt, len(attempts))
+	for i, attempt := range attempts {
+		if attempt.Diagnostics != nil && (attempt.Diagnostics.LogTail != "" || attempt.Diagnostics.Stderr != "") {
+			diagnostics := *attempt.Diagnostics
+			diagnostics.LogTail = ""
+			diagnostics.Stderr, diagnostics.StderrTruncated = "", false
+			attempt.Diagnostics = &diagnostics
+		}
+		redacted[i] = attempt
+	}
+	return redacted
+}
@@ -0,0 +1,37 @@
+package entity
+
+import (
+	"testing"
+
+	"aigendrug.com/router-core/internal/tool/domain/shared_type"
+)
+
+func TestToolRequestDTOsRedactToolLogs(t *testing.T) {
+	exitCode := 1
+	toolRequest := &ToolRequest{
+		ID: 1,
+		Attempts: []shared_type.ToolRequestAttempt{
+			{Attempt: 1, Diagnostics: &shared_type.InvocationDiagnostics{
+				ExitCode: &exitCode, Stderr: "secret=hunter2", StderrTruncated: true,
+			}},
+			{Attempt: 2, Diagnostics: &shared_type.InvocationDiagnostics{
+				AWSRequestID: "req-1", LogTail: "START RequestId: req-1",
+			}},
+		},
+	}
+
+	for i, attempt := range toolRequest.ToDTO().Attempts {
+		diagnostics := attempt.Diagnostics
+		if diagnostics.Stderr != "" || diagnostics.StderrTruncated || diagnostics.LogTail != "" {
+			t.Fatalf("ToDTO() attempt %d diagnostics = %+v, want the tool logs left out", i+1, diagnostics)
+		}
+	}
+	if diagnostics := toolRequest.ToDTO().Attempts[0].Diagnostics; diagnostics.ExitCode == nil || *diagnostics.ExitCode != 1 {
+		t.Fatalf("ToDTO() dropped the exit code: %+v", diagnostics)
+	}
+
+	logs := toolRequest.ToLogsDTO().Attempts
+	if logs[0].Diagnostics.Stderr != "secret=hunter2" || logs[1].Diagnostics.LogTail == "" {
+		t.Fatalf("ToLogsDTO() attempts = %+v, want the tool logs", logs)
+	}
+}
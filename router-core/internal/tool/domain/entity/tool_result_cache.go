@@ -0,0 +1,87 @@
+package entity
+
+import (
+	"time"
+
+	"aigendrug.com/router-core/internal/tool/application/dto"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// ToolResultCache is a cached result of a cacheable tool.
+// The result is the response of the tool request ToolRequestID, reused for payloads hashing to CacheKey until ExpiresAt.
+type ToolResultCache struct {
+	ID            int        `json:"id" db:"id"`
+	ToolID        int        `json:"tool_id" db:"tool_id"`
+	ToolVersion   string     `json:"tool_version" db:"tool_version"`
+	CacheKey      string     `json:"cache_key" db:"cache_key"`
+	ToolRequestID int        `json:"tool_request_id" db:"tool_request_id"`
+	HitCount      int        `json:"hit_count" db:"hit_count"`
+	LastHitAt     *time.Time `json:"last_hit_at" db:"last_hit_at"`
+	ExpiresAt     time.Time  `json:"expires_at" db:"expires_at"`
+	CreatedAt     time.Time  `json:"created_at" db:"created_at"`
+}
+
+type ToolResultCacheRow struct {
+	ID            int                `json:"id" db:"id"`
+	ToolID        int                `json:"tool_id" db:"tool_id"`
+	ToolVersion   string             `json:"tool_version" db:"tool_version"`
+	CacheKey      string             `json:"cache_key" db:"cache_key"`
+	ToolRequestID int                `json:"tool_request_id" db:"tool_request_id"`
+	HitCount      int                `json:"hit_count" db:"hit_count"`
+	LastHitAt     pgtype.Timestamptz `json:"last_hit_at" db:"last_hit_at"`
+	ExpiresAt     pgtype.Timestamptz `json:"expires_at" db:"expires_at"`
+	CreatedAt     pgtype.Timestamptz `json:"created_at" db:"created_at"`
+}
+
+func (t *ToolResultCache) ToRow() *ToolResultCacheRow {
+	lastHitAt := pgtype.Timestamptz{}
+	if t.LastHitAt != nil {
+		lastHitAt = pgtype.Timestamptz{Time: *t.LastHitAt, Valid: true}
+	}
+
+	return &ToolResultCacheRow{
+		ID:            t.ID,
+		ToolID:        t.ToolID,
+		ToolVersion:   t.ToolVersion,
+		CacheKey:      t.CacheKey,
+		ToolRequestID: t.ToolRequestID,
+		HitCount:      t.HitCount,
+		LastHitAt:     lastHitAt,
+		ExpiresAt:     pgtype.Timestamptz{Time: t.ExpiresAt, Valid: true},
+		CreatedAt:     pgtype.Timestamptz{Time: t.CreatedAt},
+	}
+}
+
+func (t *ToolResultCacheRow) ToEntity() *ToolResultCache {
+	var lastHitAt *time.Time
+	if t.LastHitAt.Valid {
+		lastHitAt = &t.LastHitAt.Time
+	}
+
+	return &ToolResultCache{
+		ID:            t.ID,
+		ToolID:        t.ToolID,
+		ToolVersion:   t.ToolVersion,
+		CacheKey:      t.CacheKey,
+		ToolRequestID: t.ToolRequestID,
+		HitCount:      t.HitCount,
+		LastHitAt:     lastHitAt,
+		ExpiresAt:     t.ExpiresAt.Time,
+		CreatedAt:     t.CreatedAt.Time,
+	}
+}
+
+func (t *ToolResultCache) ToDTO() *dto.ReadToolResultCacheDTO {
+	return &dto.ReadToolResultCacheDTO{
+		ID:            t.ID,
+		ToolID:        t.ToolID,
+		ToolVersion:   t.ToolVersion,
+		CacheKey:      t.CacheKey,
+		ToolRequestID: t.ToolRequestID,
+		HitCount:      t.HitCount,
+		LastHitAt:     t.LastHitAt,
+		ExpiresAt:     t.ExpiresAt,
+		Expired:       !t.ExpiresAt.After(time.Now()),
+		CreatedAt:     t.CreatedAt,
+	}
+}
@@ -0,0 +1,153 @@
+package entity
+
+import (
+	"encoding/json"
+	"time"
+
+	"aigendrug.com/router-core/internal/tool/application/dto"
+	"aigendrug.com/router-core/internal/tool/domain/valueobject"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// ToolSchedule creates a tool request for the client with Payload on every activation of CronExpression,
+// evaluated in Timezone. NextRunAt is nil while the schedule is paused (or when it has no activation left).
+type ToolSchedule struct {
+	ID                int                                     `json:"id" db:"id"`
+	ToolID            int                                     `json:"tool_id" db:"tool_id"`
+	ToolName          string                                  `json:"tool_name" db:"tool_name"`
+	ClientID          int                                     `json:"client_id" db:"client_id"`
+	Name              string                                  `json:"name" db:"name"`
+	CronExpression    string                                  `json:"cron_expression" db:"cron_expression"`
+	Timezone          string                                  `json:"timezone" db:"timezone"`
+	Payload           map[string]any                          `json:"payload" db:"payload"`
+	WebhookURL        string                                  `json:"webhook_url" db:"webhook_url"`
+	MissedRunPolicy   valueobject.ToolScheduleMissedRunPolicy `json:"missed_run_policy" db:"missed_run_policy"`
+	Status            valueobject.ToolScheduleStatus          `json:"status" db:"status"`
+	NextRunAt         *time.Time                              `json:"next_run_at" db:"next_run_at"`
+	LastRunAt         *time.Time                              `json:"last_run_at" db:"last_run_at"`
+	LastToolRequestID *int                                    `json:"last_tool_request_id" db:"last_tool_request_id"`
+	LastError         string                                  `json:"last_error" db:"last_error"`
+	CreatedAt         time.Time                               `json:"created_at" db:"created_at"`
+	UpdatedAt         time.Time                               `json:"updated_at" db:"updated_at"`
+}
+
+type ToolScheduleRow struct {
+	ID                int                `json:"id" db:"id"`
+	ToolID            int                `json:"tool_id" db:"tool_id"`
+	ToolName          string             `json:"tool_name" db:"tool_name"`
+	ClientID          int                `json:"client_id" db:"client_id"`
+	Name              string             `json:"name" db:"name"`
+	CronExpression    string             `json:"cron_expression" db:"cron_expression"`
+	Timezone          string             `json:"timezone" db:"timezone"`
+	Payload           string             `json:"payload" db:"payload"`
+	WebhookURL        pgtype.Text        `json:"webhook_url" db:"webhook_url"`
+	MissedRunPolicy   string             `json:"missed_run_policy" db:"missed_run_policy"`
+	Status            string             `json:"status" db:"status"`
+	NextRunAt         pgtype.Timestamptz `json:"next_run_at" db:"next_run_at"`
+	LastRunAt         pgtype.Timestamptz `json:"last_run_at" db:"last_run_at"`
+	LastToolRequestID pgtype.Int4        `json:"last_tool_request_id" db:"last_tool_request_id"`
+	LastError         pgtype.Text        `json:"last_error" db:"last_error"`
+	CreatedAt         pgtype.Timestamptz `json:"created_at" db:"created_at"`
+	UpdatedAt         pgtype.Timestamptz `json:"updated_at" db:"updated_at"`
+}
+
+func (t *ToolSchedule) ToRow() *ToolScheduleRow {
+	payload, err := json.Marshal(t.Payload)
+	if err != nil {
+		return nil
+	}
+
+	lastToolRequestID := pgtype.Int4{}
+	if t.LastToolRequestID != nil {
+		lastToolRequestID = pgtype.Int4{Int32: int32(*t.LastToolRequestID), Valid: true}
+	}
+
+	return &ToolScheduleRow{
+		ID:                t.ID,
+		ToolID:            t.ToolID,
+		ToolName:          t.ToolName,
+		ClientID:          t.ClientID,
+		Name:              t.Name,
+		CronExpression:    t.CronExpression,
+		Timezone:          t.Timezone,
+		Payload:           string(payload),
+		WebhookURL:        pgtype.Text{String: t.WebhookURL, Valid: t.WebhookURL != ""},
+		MissedRunPolicy:   t.MissedRunPolicy.String(),
+		Status:            t.Status.String(),
+		NextRunAt:         timestamptz(t.NextRunAt),
+		LastRunAt:         timestamptz(t.LastRunAt),
+		LastToolRequestID: lastToolRequestID,
+		LastError:         pgtype.Text{String: t.LastError, Valid: t.LastError != ""},
+		CreatedAt:         pgtype.Timestamptz{Time: t.CreatedAt},
+		UpdatedAt:         pgtype.Timestamptz{Time: t.UpdatedAt},
+	}
+}
+
+func (t *ToolScheduleRow) ToEntity() *ToolSchedule {
+	payload := map[string]any{}
+	if err := json.Unmarshal([]byte(t.Payload), &payload); err != nil {
+		return nil
+	}
+
+	var lastToolRequestID *int
+	if t.LastToolRequestID.Valid {
+		id := int(t.LastToolRequestID.Int32)
+		lastToolRequestID = &id
+	}
+
+	return &ToolSchedule{
+		ID:                t.ID,
+		ToolID:            t.ToolID,
+		ToolName:          t.ToolName,
+		ClientID:          t.ClientID,
+		Name:              t.Name,
+		CronExpression:    t.CronExpression,
+		Timezone:          t.Timezone,
+		Payload:           payload,
+		WebhookURL:        t.WebhookURL.String,
+		MissedRunPolicy:   valueobject.ToolScheduleMissedRunPolicy(t.MissedRunPolicy),
+		Status:            valueobject.ToolScheduleStatus(t.Status),
+		NextRunAt:         timePtr(t.NextRunAt),
+		LastRunAt:         timePtr(t.LastRunAt),
+		LastToolRequestID: lastToolRequestID,
+		LastError:         t.LastError.String,
+		CreatedAt:         t.CreatedAt.Time,
+		UpdatedAt:         t.UpdatedAt.Time,
+	}
+}
+
+func (t *ToolSchedule) ToDTO() *dto.ReadToolScheduleDTO {
+	return &dto.ReadToolScheduleDTO{
+		ID:                t.ID,
+		ToolID:            t.ToolID,
+		ToolName:          t.ToolName,
+		ClientID:          t.ClientID,
+		Name:              t.Name,
+		CronExpression:    t.CronExpression,
+		Timezone:          t.Timezone,
+		Payload:           t.Payload,
+		WebhookURL:        t.WebhookURL,
+		MissedRunPolicy:   t.MissedRunPolicy,
+		Status:            t.Status,
+		NextRunAt:         t.NextRunAt,
+		LastRunAt:         t.LastRunAt,
+		LastToolRequestID: t.LastToolRequestID,
+		LastError:         t.LastError,
+		CreatedAt:         t.CreatedAt,
+		UpdatedAt:         t.UpdatedAt,
+	}
+}
+
+func timestamptz(t *time.Time) pgtype.Timestamptz {
+	if t == nil {
+		return pgtype.Timestamptz{}
+	}
+	return pgtype.Timestamptz{Time: *t, Valid: true}
+}
+
+func timePtr(t pgtype.Timestamptz) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	return &t.Time
+}
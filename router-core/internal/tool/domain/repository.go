@@ -2,8 +2,10 @@ package domain
 
 import (
 	"context"
+	"time"
 
 	"aigendrug.com/router-core/internal/tool/domain/entity"
+	"aigendrug.com/router-core/internal/tool/domain/shared_type"
 	"aigendrug.com/router-core/internal/tool/domain/valueobject"
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
@@ -19,8 +21,28 @@ type ToolRepository interface {
 	FindAllToolsByClientID(ctx context.Context, clientID int, permissionLevel valueobject.ToolClientPermissionLevel) ([]*entity.Tool, error)
 	CreateTool(ctx context.Context, tool *entity.Tool) (*entity.Tool, error)
 	UpdateTool(ctx context.Context, tool *entity.Tool) error
+	// UpdateToolDraft reports false when the tool does not exist.
+	UpdateToolDraft(ctx context.Context, id int, draft bool) (bool, error)
 	DeleteTool(ctx context.Context, id int) error
 
+	// ToolHealth
+	// ClaimDueToolHealthCheck returns nil when no tool is due; the claimed tool is not due again for lease.
+	ClaimDueToolHealthCheck(ctx context.Context, lease time.Duration) (*entity.Tool, error)
+	UpdateToolHealth(ctx context.Context, toolID int, health shared_type.ToolHealth, nextCheckAt time.Time) error
+	// CreateToolHealthCheck records a check and keeps the historyLimit most recent checks of the tool.
+	CreateToolHealthCheck(ctx context.Context, check *entity.ToolHealthCheck, historyLimit int) error
+	FindAllToolHealthChecksByToolID(ctx context.Context, toolID int, limit int) ([]*entity.ToolHealthCheck, error)
+	FindAllToolIDsByHealthStatus(ctx context.Context, status valueobject.ToolHealthStatus) ([]int, error)
+
+	// ToolCircuitBreaker
+	FindAllToolCircuitBreakers(ctx context.Context) ([]*entity.ToolCircuitBreaker, error)
+	// TripToolCircuitBreaker opens the circuit breaker of the tool from openedAt until openUntil and counts the trip.
+	TripToolCircuitBreaker(ctx context.Context, toolID int, openedAt time.Time, openUntil time.Time, lastError string) error
+	// CloseToolCircuitBreaker closes the circuit breaker of the tool, unless it was opened again after openedAt.
+	CloseToolCircuitBreaker(ctx context.Context, toolID int, openedAt time.Time) error
+	// ResetToolCircuitBreaker closes the circuit breaker of the tool and clears its trips.
+	ResetToolCircuitBreaker(ctx context.Context, toolID int, resetAt time.Time) error
+
 	// ToolClientPermission
 	FindAllToolClientPermissionsByToolID(ctx context.Context, toolID int) ([]*entity.ToolClientPermission, error)
 	FindAllToolClientPermissionsByClientID(ctx context.Context, clientID int) ([]*entity.ToolClientPermission, error)
@@ -36,4 +58,55 @@ type ToolRepository interface {
 	CreateToolRequest(ctx context.Context, toolRequest *entity.ToolRequest) (*entity.ToolRequest, error)
 	UpdateToolRequest(ctx context.Context, toolRequest *entity.ToolRequest) error
 	DeleteToolRequest(ctx context.Context, id int) error
+
+	// ToolRequest queue
+	// ClaimToolRequest claims the oldest pending request within the concurrency limits.
+	// It returns nil when no pending request is available. Claims are serialized by LockToolRequestClaims.
+	ClaimToolRequest(
+		ctx context.Context, workerID string, lease time.Duration, limits shared_type.ConcurrencyLimits,
+	) (*entity.ToolRequest, error)
+	// ClaimToolRequestStatusCheck claims the running request whose next status check is the most overdue
+	// (see WaitToolRequestStatusCheck), nil when no status check is due. The claim count is left unchanged.
+	ClaimToolRequestStatusCheck(ctx context.Context, workerID string, lease time.Duration) (*entity.ToolRequest, error)
+	// WaitToolRequestStatusCheck saves the claimed running request and releases it until its next status check
+	// at nextPollAt. It reports false when the request is no longer claimed by its worker.
+	WaitToolRequestStatusCheck(ctx context.Context, toolRequest *entity.ToolRequest, nextPollAt time.Time) (bool, error)
+	// LockToolRequestClaims holds the claims of every replica until the transaction ends,
+	// so that the running requests counted against the limits do not change meanwhile.
+	LockToolRequestClaims(ctx context.Context) error
+	// FindToolRequestQueuePosition returns the position (from 1) of a pending request among the pending requests
+	// competing for the same concurrency limits as ClaimToolRequest, 0 when the request is not pending.
+	FindToolRequestQueuePosition(ctx context.Context, id int, limits shared_type.ConcurrencyLimits) (int, error)
+	RenewToolRequestLease(ctx context.Context, id int, workerID string, lease time.Duration) (bool, error)
+	UpdateClaimedToolRequest(ctx context.Context, toolRequest *entity.ToolRequest) (bool, error)
+	CompleteToolRequest(ctx context.Context, toolRequest *entity.ToolRequest) (bool, error)
+	// CancelToolRequest reports false when the request already finished.
+	CancelToolRequest(ctx context.Context, toolRequest *entity.ToolRequest) (bool, error)
+	// ReleaseExpiredToolRequests returns the number of requeued requests and the IDs of the failed ones.
+	ReleaseExpiredToolRequests(ctx context.Context, maxClaims int) (requeued int64, failed []int, err error)
+
+	// ToolResultCache
+	// FindToolResultCache returns nil when the tool has no unexpired result for the key.
+	FindToolResultCache(ctx context.Context, toolID int, cacheKey string) (*entity.ToolResultCache, error)
+	FindAllToolResultCacheByToolID(ctx context.Context, toolID int) ([]*entity.ToolResultCache, error)
+	// UpsertToolResultCache replaces the result cached for the key, and drops the expired results of the tool.
+	UpsertToolResultCache(ctx context.Context, cache *entity.ToolResultCache) error
+	RecordToolResultCacheHit(ctx context.Context, id int) error
+	DeleteToolResultCache(ctx context.Context, toolID int, id int) (bool, error)
+	DeleteAllToolResultCacheByToolID(ctx context.Context, toolID int) (int64, error)
+
+	// ToolSchedule
+	FindAllToolSchedules(ctx context.Context) ([]*entity.ToolSchedule, error)
+	FindAllToolSchedulesByToolID(ctx context.Context, toolID int) ([]*entity.ToolSchedule, error)
+	FindAllToolSchedulesByClientID(ctx context.Context, clientID int) ([]*entity.ToolSchedule, error)
+	FindToolScheduleByID(ctx context.Context, id int) (*entity.ToolSchedule, error)
+	CreateToolSchedule(ctx context.Context, schedule *entity.ToolSchedule) (*entity.ToolSchedule, error)
+	// UpdateToolSchedule saves the definition, status and next run of the schedule.
+	UpdateToolSchedule(ctx context.Context, schedule *entity.ToolSchedule) error
+	DeleteToolSchedule(ctx context.Context, id int) error
+	// ClaimDueToolSchedule locks an active schedule whose next run is due, skipping schedules locked by other replicas.
+	// It returns nil when no schedule is due. Must run in a transaction, the lock is held until it ends.
+	ClaimDueToolSchedule(ctx context.Context) (*entity.ToolSchedule, error)
+	// RecordToolScheduleRun saves the next run and the outcome of the last run of the schedule.
+	RecordToolScheduleRun(ctx context.Context, schedule *entity.ToolSchedule) error
 }
@@ -1,24 +1,113 @@
 package shared_type
 
-import "aigendrug.com/router-core/internal/tool/domain/valueobject"
+import (
+	"fmt"
+
+	"aigendrug.com/router-core/internal/tool/domain/valueobject"
+)
 
 type EngineInterface struct {
 	EngineInterfaceType            valueobject.EngineInterfaceType            `json:"engine_interface_type" validate:"required"`
 	EngineInterfaceInvokeType      valueobject.EngineInterfaceInvokeType      `json:"engine_interface_invoke_type" validate:"required"`
 	EngineInterfaceCheckStatusType valueobject.EngineInterfaceCheckStatusType `json:"engine_interface_check_status_type" validate:"required"`
 	EngineImpl                     map[string]any                             `json:"engine_impl" validate:"required"`
+	RetryPolicy                    *RetryPolicy                               `json:"retry_policy,omitempty"`
+	CachePolicy                    *CachePolicy                               `json:"cache_policy,omitempty"`
+	ConcurrencyPolicy              *ConcurrencyPolicy                         `json:"concurrency_policy,omitempty"`
+}
+
+// RetryPolicy
+//
+// RetryPolicy controls how many times a failed invocation is attempted again. A tool without a policy is invoked once.
+// - MaxAttempts: Total number of attempts including the first one.
+// - BaseDelaySeconds / MaxDelaySeconds: Delay before the n-th retry is BaseDelaySeconds * 2^(n-1), capped at MaxDelaySeconds.
+// - Jitter: Ratio (0 ~ 1) of the delay randomly subtracted, to spread retries of concurrent requests.
+// - RetryableErrors: Error classes which are retried. Defaults to throttled, timeout, network and server_error.
+// - RetryableStatusCodes: Status codes of http-server tools which are retried. Defaults to 408, 429, 500, 502, 503 and 504.
+// Failures carrying a status code are decided by RetryableStatusCodes, other failures by RetryableErrors.
+type RetryPolicy struct {
+	MaxAttempts          int                               `json:"max_attempts" example:"3"`
+	BaseDelaySeconds     float64                           `json:"base_delay_seconds" example:"1"`
+	MaxDelaySeconds      float64                           `json:"max_delay_seconds" example:"30"`
+	Jitter               float64                           `json:"jitter" example:"0.2"`
+	RetryableErrors      []valueobject.ExecutionErrorClass `json:"retryable_errors,omitempty"`
+	RetryableStatusCodes []int                             `json:"retryable_status_codes,omitempty"`
+}
+
+// CachePolicy
+//
+// CachePolicy marks a tool as a pure function of its input. A successful result is reused for the same payload
+// (and the same tool id and version) until TTLSeconds elapse, the tool is not invoked again meanwhile.
+// - TTLSeconds: Lifetime of a cached result. The tool is not cached when it is not positive.
+type CachePolicy struct {
+	TTLSeconds float64 `json:"ttl_seconds" example:"86400"`
+}
+
+// ConcurrencyPolicy
+//
+// ConcurrencyPolicy overrides the concurrency limits of the router (SCHEDULER_MAX_PER_TOOL / _PER_CLIENT_TOOL) for a tool,
+// e.g. to stay under the reserved concurrency of its Lambda function. Requests beyond the limits stay pending, in FIFO order.
+// - MaxInFlight: Maximum requests of the tool running at once, all clients together.
+// - MaxInFlightPerClient: Maximum requests of the tool running at once for a single client.
+// A limit of 0 keeps the router limit, negative limits are rejected.
+type ConcurrencyPolicy struct {
+	MaxInFlight          int `json:"max_in_flight,omitempty" example:"10"`
+	MaxInFlightPerClient int `json:"max_in_flight_per_client,omitempty" example:"2"`
+}
+
+// Check reports a limit which is negative. A nil policy is valid.
+func (p *ConcurrencyPolicy) Check() error {
+	if p == nil {
+		return nil
+	}
+	if p.MaxInFlight < 0 {
+		return fmt.Errorf("concurrency_policy.max_in_flight must not be negative, got %d", p.MaxInFlight)
+	}
+	if p.MaxInFlightPerClient < 0 {
+		return fmt.Errorf("concurrency_policy.max_in_flight_per_client must not be negative, got %d", p.MaxInFlightPerClient)
+	}
+	return nil
+}
+
+// ConcurrencyLimits are the limits of running tool requests applied when a request is claimed, 0 when unlimited.
+// PerTool and PerClientTool are overridden by the ConcurrencyPolicy of the tool.
+type ConcurrencyLimits struct {
+	PerClient     int
+	PerTool       int
+	PerClientTool int
 }
 
 // EngineImpl
 //
 // EngineImple stores engine-specific implementation as dynamic fields.
 // Example fields:
-// - "function_name": Name of the function to invoke. Provided when EngineInterfaceType is aws-lambda.
+// - "function_name": Name, ARN or partial ARN of the function to invoke. Provided when EngineInterfaceType is aws-lambda.
+// - "qualifier", "region", "role_arn", "external_id": Alias or version invoked, region of the function and role
+//   assumed to reach it (e.g. in another account). Optional when EngineInterfaceType is aws-lambda.
 // - "url": URL of the HTTP server. Provided when EngineInterfaceType is http-server.
+// - "command", "args", "input_mode", "env_allowlist", "env", "timeout_seconds", "cpu_seconds", "max_output_bytes",
+//   "max_stderr_bytes": Command run on the router host and its limits. Provided when EngineInterfaceType is local-exec
+//   (see InvokeLocalExec).
+// - "address", "service", "method", "descriptor_set", "metadata", "tls", "tls_*": Server, unary method and
+//   connection of the tool. Provided when EngineInterfaceType is grpc (see InvokeGRPC).
 // - "aws_lambda_function_name": AWS Lambda function name. Provided when EngineInterfaceType is aws-lambda.
+// - "log_type": None (default) or Tail to capture the execution log of each attempt. Optional when EngineInterfaceType is aws-lambda.
 // - "aws_lambda_invoke_type": AWS Lambda invoke type. Provided when EngineInterfaceType is aws-lambda.
 // - "aws_lambda_check_status_type": AWS Lambda check status type. Provided when EngineInterfaceType is aws-lambda.
 // - "delay_seconds": Delay seconds. Provided when EngineInterfaceCheckStatusType is delayed.
 // - "status_url": Status URL. Provided when EngineInterfaceCheckStatusType is poll-http.
+//   May contain "{request_id}", "{tool_request_id}", "{aws_request_id}" or any scalar field of the invocation response (e.g. "{job_id}").
+// - "status_headers": Headers sent with each status request. Optional when EngineInterfaceCheckStatusType is poll-http.
+// - "poll_interval_seconds", "poll_max_interval_seconds", "poll_backoff_multiplier", "poll_timeout_seconds":
+//   Polling policy overrides. Optional when EngineInterfaceInvokeType is async-event.
 // - "aws_s3_bucket": AWS S3 bucket name. Provided when EngineInterfaceCheckStatusType is aws-s3-trigger.
 // - "aws_s3_key": AWS S3 key. Provided when EngineInterfaceCheckStatusType is aws-s3-trigger.
+//   Bucket and key accept the same placeholders as "status_url".
+// - "aws_s3_error_key": AWS S3 key written by the tool on failure. Optional when EngineInterfaceCheckStatusType is aws-s3-trigger.
+// - "cancel_url": Endpoint called when the tool request is cancelled. Optional when EngineInterfaceInvokeType is async-event.
+//   Accepts the same placeholders as "status_url".
+// - "cancel_method", "cancel_headers": Method (default POST) and headers of the cancel call.
+// - "health_url", "health_headers": Endpoint checked (GET, 2xx or 3xx) by the health prober. Optional when
+//   EngineInterfaceType is http-server, the health of the tool stays unknown without it.
+// - "health_payload": Payload of a test invocation made by the health prober instead of the engine check (see Probe).
+// - "health_interval_seconds": Interval between two health checks of the tool (default HEALTH_INTERVAL_SECONDS).
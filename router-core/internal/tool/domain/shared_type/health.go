@@ -0,0 +1,22 @@
+package shared_type
+
+import (
+	"time"
+
+	"aigendrug.com/router-core/internal/tool/domain/valueobject"
+)
+
+// ToolHealth
+//
+// ToolHealth is the current health of a tool, maintained by the health prober.
+// - Status: unknown until the first check, then healthy, degraded or unhealthy (see ToolHealthStatus).
+// - Message: Error of the last failed check, or the reason the tool is not probed.
+// - ConsecutiveFailures: Failed checks since the last successful one.
+// - CheckedAt / ChangedAt: Time of the last check and of the last status change.
+type ToolHealth struct {
+	Status              valueobject.ToolHealthStatus `json:"status" example:"healthy"`
+	Message             string                       `json:"message,omitempty" example:"function state is Failed"`
+	ConsecutiveFailures int                          `json:"consecutive_failures" example:"0"`
+	CheckedAt           *time.Time                   `json:"checked_at,omitempty" example:"2021-01-01T00:00:00Z"`
+	ChangedAt           *time.Time                   `json:"changed_at,omitempty" example:"2021-01-01T00:00:00Z"`
+}
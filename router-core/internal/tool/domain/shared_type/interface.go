@@ -1,8 +1,70 @@
 package shared_type
 
+import (
+	"time"
+
+	"aigendrug.com/router-core/internal/tool/domain/valueobject"
+)
+
+// ArtifactReferencePrefix starts the payload value of a file input, followed by the key of its artifact.
+// It is replaced by the path or presigned URL of the file when the tool is invoked.
+const ArtifactReferencePrefix = "artifact://"
+
+// ToolRequestData
+//
+// WebhookURL: URL notified when the request finishes, given with the ExecuteTool call.
+//
+// Artifacts: Files uploaded with the request, referenced from the payload by their Reference.
+//
+// PayloadRef: Set instead of Payload when the payload exceeded the offload threshold and was moved to the blob store.
+//
+// Schedule: Set when the request was created by a tool schedule.
 type ToolRequestData struct {
-	RequestIdentifier string         `json:"request_identifier"`
-	Payload           map[string]any `json:"payload"`
+	RequestIdentifier string                `json:"request_identifier"`
+	Payload           map[string]any        `json:"payload"`
+	PayloadRef        *BlobReference        `json:"payload_ref,omitempty"`
+	WebhookURL        string                `json:"webhook_url,omitempty"`
+	Artifacts         []ToolRequestArtifact `json:"artifacts,omitempty"`
+	Schedule          *ToolRequestSchedule  `json:"schedule,omitempty"`
+}
+
+// ToolRequestSchedule identifies the run of a tool schedule which created a tool request.
+// ScheduledFor is the activation time of the run, earlier than the creation of the request when the run was missed.
+type ToolRequestSchedule struct {
+	ScheduleID   int       `json:"schedule_id"`
+	ScheduledFor time.Time `json:"scheduled_for"`
+}
+
+// BlobReference points to a JSON payload moved to the blob store,
+// downloaded with GET /v1/tool-requests/{id}/payload/{part}.
+type BlobReference struct {
+	Backend string `json:"backend"`
+	Key     string `json:"key"`
+	Size    int64  `json:"size"`
+	SHA256  string `json:"sha256"`
+}
+
+// ToolRequestArtifact is a file input of a tool request kept in the blob store.
+//
+// Field: Key of the payload holding the file.
+//
+// Backend / Key: Blob store backend (local or s3) and key of the stored file.
+//
+// SHA256: Hex encoded checksum of the content.
+type ToolRequestArtifact struct {
+	Field       string    `json:"field"`
+	FileName    string    `json:"file_name"`
+	ContentType string    `json:"content_type,omitempty"`
+	Size        int64     `json:"size"`
+	SHA256      string    `json:"sha256"`
+	Backend     string    `json:"backend"`
+	Key         string    `json:"key"`
+	UploadedAt  time.Time `json:"uploaded_at"`
+}
+
+// Reference is the payload value standing for the artifact until the tool is invoked.
+func (a ToolRequestArtifact) Reference() string {
+	return ArtifactReferencePrefix + a.Key
 }
 
 // ResponseData
@@ -13,12 +75,79 @@ type ToolRequestData struct {
 // - Use "type" field to determine the implementation. (same with EngineInterfaceCheckStatusType of correspoding tool)
 // - If EngineInterfaceCheckStatusType is none, this field is not required.
 // - If EngineInterfaceCheckStatusType is delayed, use "delay_seconds" field to determine the delay time.
-// - If EngineInterfaceCheckStatusType is poll-http, retrieve response payload from "url" field using GET method.
-// - If EngineInterfaceCheckStatusType is aws-s3-trigger, retrieve response payload from "aws-s3-bucket" and "aws-s3-key" fields.
+// - If EngineInterfaceCheckStatusType is poll-http, retrieve response payload from "url" field using GET method (with optional "headers").
+// - If EngineInterfaceCheckStatusType is aws-s3-trigger, retrieve response payload from "aws_s3_bucket" and "aws_s3_key" fields.
+// - "invoked_at" is the time of the invocation (RFC 3339), the poll timeout runs from it.
+// - "status_checks", "status_check_errors" and "last_progress" track the status checks made so far.
+//
+// Payload: Output of the tool, holding the keys declared by its ResponseInterface coerced to their value types.
+//
+// RawPayload: Response of the provider as received, kept for debugging.
 //
-// Payload: Payload of the response.
+// PayloadRef / RawPayloadRef: Set instead of Payload / RawPayload when they exceeded the offload threshold.
+//
+// CacheHit: Set when the response was served from the result cache of the tool instead of invoking it.
+//
+// Error: Reason of the failure when the request failed.
 type ToolRequestResponseData struct {
-	ResponseIdentifier string         `json:"response_identifier"`
-	CheckStatusImpl    map[string]any `json:"check_status_impl"`
-	Payload            map[string]any `json:"payload"`
+	ResponseIdentifier string               `json:"response_identifier"`
+	CheckStatusImpl    map[string]any       `json:"check_status_impl"`
+	Payload            map[string]any       `json:"payload"`
+	PayloadRef         *BlobReference       `json:"payload_ref,omitempty"`
+	RawPayload         map[string]any       `json:"raw_payload,omitempty"`
+	RawPayloadRef      *BlobReference       `json:"raw_payload_ref,omitempty"`
+	CacheHit           *ToolRequestCacheHit `json:"cache_hit,omitempty"`
+	Error              string               `json:"error,omitempty"`
+}
+
+// ToolRequestCacheHit describes the cached result a response was served from.
+// SourceToolRequestID is the tool request which produced the result.
+type ToolRequestCacheHit struct {
+	CacheKey            string    `json:"cache_key"`
+	SourceToolRequestID int       `json:"source_tool_request_id"`
+	CachedAt            time.Time `json:"cached_at"`
+	ExpiresAt           time.Time `json:"expires_at"`
+}
+
+// ToolRequestAttempt records a single invocation attempt of a tool request.
+//
+// ErrorClass and StatusCode are set when the attempt failed,
+// Retryable tells whether the retry policy allowed another attempt after it.
+// Diagnostics holds what the engine reported about the invocation, successful or not.
+type ToolRequestAttempt struct {
+	Attempt    int                             `json:"attempt"`
+	StartedAt  time.Time                       `json:"started_at"`
+	FinishedAt time.Time                       `json:"finished_at"`
+	Error      string                          `json:"error,omitempty"`
+	ErrorClass valueobject.ExecutionErrorClass `json:"error_class,omitempty"`
+	StatusCode int                             `json:"status_code,omitempty"`
+	Retryable  bool                            `json:"retryable,omitempty"`
+
+	Diagnostics *InvocationDiagnostics `json:"diagnostics,omitempty"`
+}
+
+// InvocationDiagnostics are the details of an invocation reported by its engine.
+//
+// local-exec tools report the exit code, or the signal which killed the command, and the captured stderr
+// (its last bytes when it exceeded the stderr cap). The stderr is only returned to admins.
+// aws-lambda tools report the request id, the executed version and the function error with the error type
+// of its payload. Tools opting in to the log tail ("log_type": "Tail") also report the last 4 KB of the
+// execution log and the metrics of its REPORT line. The log tail is only returned to admins.
+type InvocationDiagnostics struct {
+	ExitCode        *int   `json:"exit_code,omitempty"`
+	Signal          string `json:"signal,omitempty"`
+	Stderr          string `json:"stderr,omitempty"`
+	StderrTruncated bool   `json:"stderr_truncated,omitempty"`
+	DurationMs      int64  `json:"duration_ms,omitempty"`
+	CPUTimeMs       int64  `json:"cpu_time_ms,omitempty"`
+
+	AWSRequestID     string `json:"aws_request_id,omitempty"`
+	ExecutedVersion  string `json:"executed_version,omitempty"`
+	FunctionError    string `json:"function_error,omitempty"`
+	ErrorType        string `json:"error_type,omitempty"`
+	LogTail          string `json:"log_tail,omitempty"`
+	BilledDurationMs int64  `json:"billed_duration_ms,omitempty"`
+	InitDurationMs   int64  `json:"init_duration_ms,omitempty"`
+	MemorySizeMB     int    `json:"memory_size_mb,omitempty"`
+	MaxMemoryUsedMB  int    `json:"max_memory_used_mb,omitempty"`
 }
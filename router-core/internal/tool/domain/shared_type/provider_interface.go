@@ -1,13 +1,31 @@
 package shared_type
 
+import (
+	"fmt"
+
+	"aigendrug.com/router-core/internal/shared/jsonschema"
+)
+
+// ProviderInterface
+//
+// RequestInterface / ResponseInterface: Flat list of the scalar input / output keys of the tool.
+//
+// RequestSchema / ResponseSchema: JSON Schema of the input / output document, for nested objects, arrays,
+// enums, ranges, patterns, etc. The "x-location" of a top-level property (body, query or header) tells
+// where it is sent or read. A schema takes precedence over the element list, which stays supported as a shorthand.
+//
+// File inputs (valueType "file", or a top-level string property with the "file" format) are uploaded with the
+// multipart execution and given to the tool as a reference to the stored file (path or presigned URL).
 type ProviderInterface struct {
 	URL                 string             `json:"url" valdate:"required,url"`
 	AuthStrategy        string             `json:"authStrategy" validate:"required"`
 	RequestMethod       string             `json:"requestMethod" validate:"required,oneof=GET POST PUT DELETE"`
 	RequestContentType  string             `json:"requestContentType" validate:"required"`
 	ResponseContentType string             `json:"responseContentType" validate:"required"`
-	RequestInterface    []InterfaceElement `json:"requestInterface" validate:"required,min=1,dive"`
-	ResponseInterface   []InterfaceElement `json:"responseInterface" validate:"required,min=1,dive"`
+	RequestInterface    []InterfaceElement `json:"requestInterface" validate:"required_without=RequestSchema,dive"`
+	ResponseInterface   []InterfaceElement `json:"responseInterface" validate:"required_without=ResponseSchema,dive"`
+	RequestSchema       *jsonschema.Schema `json:"requestSchema,omitempty"`
+	ResponseSchema      *jsonschema.Schema `json:"responseSchema,omitempty"`
 }
 
 type InterfaceElement struct {
@@ -15,12 +33,55 @@ type InterfaceElement struct {
 	Type              string            `json:"type" validate:"required,oneof=body query header"`
 	Required          bool              `json:"required"`
 	Key               string            `json:"key" validate:"required"`
-	ValueType         string            `json:"valueType" validate:"required,oneof=string number boolean"`
+	ValueType         string            `json:"valueType" validate:"required,oneof=string number boolean file"`
 	BindedElementType BindedElementType `json:"bindedElementType" validate:"required"`
 }
 
 type BindedElementType struct {
 	Label           string `json:"label" validate:"required"`
 	HTMLElementType string `json:"htmlElementType" validate:"required"`
-	ValueType       string `json:"valueType" validate:"required,oneof=string number boolean"`
+	ValueType       string `json:"valueType" validate:"required,oneof=string number boolean file"`
+}
+
+// ResolvedRequestSchema returns the schema of the tool input: RequestSchema, or the schema
+// equivalent to RequestInterface. It is nil when the tool declares neither (any input is accepted).
+func (p ProviderInterface) ResolvedRequestSchema() *jsonschema.Schema {
+	return resolveSchema(p.RequestSchema, p.RequestInterface)
+}
+
+// ResolvedResponseSchema is ResolvedRequestSchema for the tool output.
+func (p ProviderInterface) ResolvedResponseSchema() *jsonschema.Schema {
+	return resolveSchema(p.ResponseSchema, p.ResponseInterface)
+}
+
+func resolveSchema(schema *jsonschema.Schema, elements []InterfaceElement) *jsonschema.Schema {
+	if schema != nil {
+		return schema
+	}
+	if len(elements) == 0 {
+		return nil
+	}
+
+	converted := make([]jsonschema.Element, len(elements))
+	for i, element := range elements {
+		converted[i] = jsonschema.Element{
+			Key:       element.Key,
+			Location:  element.Type,
+			ValueType: element.ValueType,
+			Required:  element.Required,
+			Title:     element.BindedElementType.Label,
+		}
+	}
+	return jsonschema.FromElements(converted)
+}
+
+// Check reports invalid request / response schemas.
+func (p ProviderInterface) Check() error {
+	if err := p.ResolvedRequestSchema().Check(); err != nil {
+		return fmt.Errorf("request schema: %w", err)
+	}
+	if err := p.ResolvedResponseSchema().Check(); err != nil {
+		return fmt.Errorf("response schema: %w", err)
+	}
+	return nil
 }
@@ -0,0 +1,18 @@
+package valueobject
+
+type CircuitBreakerState string
+
+const (
+	// invocations go through, their failures are counted
+	CircuitBreakerStateClosed CircuitBreakerState = "closed"
+
+	// the failure rate tripped the breaker, invocations are rejected until the open duration elapses
+	CircuitBreakerStateOpen CircuitBreakerState = "open"
+
+	// trial invocations decide whether the breaker closes or opens again
+	CircuitBreakerStateHalfOpen CircuitBreakerState = "half_open"
+)
+
+func (s CircuitBreakerState) String() string {
+	return string(s)
+}
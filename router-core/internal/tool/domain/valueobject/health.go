@@ -0,0 +1,21 @@
+package valueobject
+
+type ToolHealthStatus string
+
+const (
+	// never probed, or the tool declares no health check
+	ToolHealthStatusUnknown ToolHealthStatus = "unknown"
+
+	// the last health check succeeded
+	ToolHealthStatusHealthy ToolHealthStatus = "healthy"
+
+	// the last health checks failed, fewer times in a row than the unhealthy threshold
+	ToolHealthStatusDegraded ToolHealthStatus = "degraded"
+
+	// the health checks failed at least the unhealthy threshold in a row, the tool is left out of selection
+	ToolHealthStatusUnhealthy ToolHealthStatus = "unhealthy"
+)
+
+func (t ToolHealthStatus) String() string {
+	return string(t)
+}
@@ -3,6 +3,7 @@ package valueobject
 type EngineInterfaceType string
 type EngineInterfaceInvokeType string
 type EngineInterfaceCheckStatusType string
+type ExecutionErrorClass string
 
 // EngineInterfaceType defines source of each tool
 const (
@@ -11,6 +12,12 @@ const (
 
 	// provided by http server endpoint
 	EngineInterfaceHTTPServer EngineInterfaceType = "http-server"
+
+	// command run on the router host
+	EngineInterfaceLocalExec EngineInterfaceType = "local-exec"
+
+	// unary method of a gRPC server
+	EngineInterfaceGRPC EngineInterfaceType = "grpc"
 )
 
 // EngineInterfaceInvokeType defines how to invoke the tool
@@ -36,3 +43,39 @@ const (
 	// (async-event) poll the status of the tool using AWS S3 trigger
 	EngineInterfaceCheckStatusTypeAWSS3Trigger EngineInterfaceCheckStatusType = "aws-s3-trigger"
 )
+
+// ExecutionErrorClass classifies a failed invocation, used to decide whether it is retried
+const (
+	// rate limited by the provider (e.g. Lambda TooManyRequestsException, HTTP 429)
+	ExecutionErrorClassThrottled ExecutionErrorClass = "throttled"
+
+	// the invocation did not complete within its timeout
+	ExecutionErrorClassTimeout ExecutionErrorClass = "timeout"
+
+	// the provider could not be reached (connection refused, DNS, reset, etc.)
+	ExecutionErrorClassNetwork ExecutionErrorClass = "network"
+
+	// the provider failed to handle the request (HTTP 5xx, Lambda service exceptions)
+	ExecutionErrorClassServerError ExecutionErrorClass = "server_error"
+
+	// the provider rejected the request (HTTP 4xx, invalid Lambda request)
+	ExecutionErrorClassClientError ExecutionErrorClass = "client_error"
+
+	// the tool itself reported an error (e.g. unhandled Lambda function error)
+	ExecutionErrorClassFunctionError ExecutionErrorClass = "function_error"
+
+	// the response could not be decoded
+	ExecutionErrorClassInvalidResponse ExecutionErrorClass = "invalid_response"
+
+	// the tool is not configured properly (e.g. missing engine impl fields)
+	ExecutionErrorClassConfiguration ExecutionErrorClass = "configuration"
+
+	// the execution was aborted (e.g. the tool request was cancelled)
+	ExecutionErrorClassCancelled ExecutionErrorClass = "cancelled"
+
+	// the circuit breaker of the tool is open, the tool was not invoked
+	ExecutionErrorClassCircuitOpen ExecutionErrorClass = "circuit_open"
+
+	// any other error
+	ExecutionErrorClassUnknown ExecutionErrorClass = "unknown"
+)
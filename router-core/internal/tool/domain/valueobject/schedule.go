@@ -0,0 +1,39 @@
+package valueobject
+
+type ToolScheduleStatus string
+type ToolScheduleMissedRunPolicy string
+
+const (
+	ToolScheduleStatusActive ToolScheduleStatus = "active"
+	ToolScheduleStatusPaused ToolScheduleStatus = "paused"
+)
+
+// ToolScheduleMissedRunPolicy defines what happens to the runs of a schedule missed while no replica was running
+const (
+	// drop the missed runs, the schedule resumes with its next run
+	ToolScheduleMissedRunPolicySkip ToolScheduleMissedRunPolicy = "skip"
+
+	// fire a single run in place of the missed runs (default)
+	ToolScheduleMissedRunPolicyRunOnce ToolScheduleMissedRunPolicy = "run_once"
+
+	// fire every missed run, up to a bound
+	ToolScheduleMissedRunPolicyRunAll ToolScheduleMissedRunPolicy = "run_all"
+)
+
+func (t ToolScheduleStatus) String() string {
+	return string(t)
+}
+
+func (t ToolScheduleMissedRunPolicy) String() string {
+	return string(t)
+}
+
+// IsValid reports whether the policy is one of the known policies.
+func (t ToolScheduleMissedRunPolicy) IsValid() bool {
+	switch t {
+	case ToolScheduleMissedRunPolicySkip, ToolScheduleMissedRunPolicyRunOnce, ToolScheduleMissedRunPolicyRunAll:
+		return true
+	default:
+		return false
+	}
+}
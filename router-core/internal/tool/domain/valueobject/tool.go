@@ -4,17 +4,33 @@ type ToolRequestStatus string
 type ToolExecutionStatus string
 
 const (
-	ToolRequestStatusPending ToolRequestStatus = "pending"
-	ToolRequestStatusSuccess ToolRequestStatus = "success"
-	ToolRequestStatusFailed  ToolRequestStatus = "failed"
+	ToolRequestStatusPending   ToolRequestStatus = "pending"
+	ToolRequestStatusRunning   ToolRequestStatus = "running"
+	ToolRequestStatusSuccess   ToolRequestStatus = "success"
+	ToolRequestStatusFailed    ToolRequestStatus = "failed"
+	ToolRequestStatusCancelled ToolRequestStatus = "cancelled"
 )
 
 const (
 	ToolExecutionStatusSuccess      ToolExecutionStatus = "success"
 	ToolExecutionStatusUnauthorized ToolExecutionStatus = "unauthorized"
 	ToolExecutionStatusFailed       ToolExecutionStatus = "failed"
+	// the circuit breaker of the tool is open, retry after the retry_at of the response
+	ToolExecutionStatusCircuitOpen ToolExecutionStatus = "circuit_open"
+	// the daily or monthly execution quota of the client is reached, retry after the retry_at of the response
+	ToolExecutionStatusQuotaExceeded ToolExecutionStatus = "quota_exceeded"
 )
 
 func (t ToolRequestStatus) String() string {
 	return string(t)
 }
+
+// IsTerminal reports whether the tool request reached its final status.
+func (t ToolRequestStatus) IsTerminal() bool {
+	switch t {
+	case ToolRequestStatusSuccess, ToolRequestStatusFailed, ToolRequestStatusCancelled:
+		return true
+	default:
+		return false
+	}
+}
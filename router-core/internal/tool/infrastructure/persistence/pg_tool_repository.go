@@ -2,10 +2,13 @@ package persistence
 
 import (
 	"context"
+	"errors"
+	"time"
 
 	"aigendrug.com/router-core/internal/shared/database/postgres"
 	"aigendrug.com/router-core/internal/tool/domain"
 	"aigendrug.com/router-core/internal/tool/domain/entity"
+	"aigendrug.com/router-core/internal/tool/domain/shared_type"
 	"aigendrug.com/router-core/internal/tool/domain/valueobject"
 	"github.com/georgysavva/scany/v2/pgxscan"
 	"github.com/google/uuid"
@@ -30,7 +33,9 @@ func (r *pgToolRepository) FindAllTools(ctx context.Context) ([]*entity.Tool, er
 		SELECT 
 			id, uuid, name, 
 			version, description, engine_interface, 
-			provider_interface, created_at, updated_at
+			provider_interface, draft, health_status, health_message,
+			health_failures, health_checked_at, health_changed_at,
+			created_at, updated_at
 		FROM tools
 	`
 
@@ -56,7 +61,9 @@ func (r *pgToolRepository) FindToolByID(ctx context.Context, id int) (*entity.To
 		SELECT 
 			id, uuid, name,
 			version, description, engine_interface,
-			provider_interface, created_at, updated_at
+			provider_interface, draft, health_status, health_message,
+			health_failures, health_checked_at, health_changed_at,
+			created_at, updated_at
 		FROM tools
 		WHERE id = $1
 	`
@@ -74,7 +81,9 @@ func (r *pgToolRepository) FindToolByUUID(ctx context.Context, uuid uuid.UUID) (
 		SELECT 
 			id, uuid, name,
 			version, description, engine_interface,
-			provider_interface, created_at, updated_at
+			provider_interface, draft, health_status, health_message,
+			health_failures, health_checked_at, health_changed_at,
+			created_at, updated_at
 		FROM tools
 		WHERE uuid = $1
 	`
@@ -94,10 +103,12 @@ func (r *pgToolRepository) FindAllToolsByClientID(
 		SELECT
 			t.id, t.uuid, t.name,
 			t.version, t.description, t.engine_interface,
-			t.provider_interface, t.created_at, t.updated_at
+			t.provider_interface, t.draft, t.health_status, t.health_message,
+			t.health_failures, t.health_checked_at, t.health_changed_at,
+			t.created_at, t.updated_at
 		FROM tools t
 		JOIN tool_client_permissions tcp ON t.id = tcp.tool_id
-		WHERE tcp.client_id = $1 AND tcp.permission_level = $2
+		WHERE tcp.client_id = $1 AND tcp.permission_level = $2 AND NOT t.draft
 	`
 
 	var tools []*entity.ToolRow
@@ -119,12 +130,14 @@ func (r *pgToolRepository) FindAllToolsByClientID(
 
 func (r *pgToolRepository) CreateTool(ctx context.Context, tool *entity.Tool) (*entity.Tool, error) {
 	query := `
-		INSERT INTO tools (uuid, name, version, description, engine_interface, provider_interface)
-		VALUES ($1, $2, $3, $4, $5, $6)
+		INSERT INTO tools (uuid, name, version, description, engine_interface, provider_interface, draft)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
 		RETURNING 
 			id, uuid, name, 
 			version, description, engine_interface, 
-			provider_interface, created_at, updated_at
+			provider_interface, draft, health_status, health_message,
+			health_failures, health_checked_at, health_changed_at,
+			created_at, updated_at
 	`
 
 	toolRaw := tool.ToRow()
@@ -132,7 +145,7 @@ func (r *pgToolRepository) CreateTool(ctx context.Context, tool *entity.Tool) (*
 	createdTool := &entity.ToolRow{}
 	if err := r.db.QueryRow(ctx, query,
 		toolRaw.UUID, toolRaw.Name, toolRaw.Version,
-		toolRaw.Description, toolRaw.EngineInterface, toolRaw.ProviderInterface,
+		toolRaw.Description, toolRaw.EngineInterface, toolRaw.ProviderInterface, toolRaw.Draft,
 	).Scan(
 		&createdTool.ID,
 		&createdTool.UUID,
@@ -141,6 +154,12 @@ func (r *pgToolRepository) CreateTool(ctx context.Context, tool *entity.Tool) (*
 		&createdTool.Description,
 		&createdTool.EngineInterface,
 		&createdTool.ProviderInterface,
+		&createdTool.Draft,
+		&createdTool.HealthStatus,
+		&createdTool.HealthMessage,
+		&createdTool.HealthFailures,
+		&createdTool.HealthCheckedAt,
+		&createdTool.HealthChangedAt,
 		&createdTool.CreatedAt,
 		&createdTool.UpdatedAt,
 	); err != nil {
@@ -153,10 +172,10 @@ func (r *pgToolRepository) CreateTool(ctx context.Context, tool *entity.Tool) (*
 func (r *pgToolRepository) UpdateTool(ctx context.Context, tool *entity.Tool) error {
 	query := `
 		UPDATE tools
-		SET 
+		SET
 			name = $1, version = $2, description = $3, 
 			engine_interface = $4, provider_interface = $5, 
-			updated_at = CURRENT_TIMESTAMP
+			updated_at = CURRENT_TIMESTAMP, health_probe_at = NULL
 		WHERE id = $6
 	`
 
@@ -170,6 +189,192 @@ func (r *pgToolRepository) UpdateTool(ctx context.Context, tool *entity.Tool) er
 	return err
 }
 
+func (r *pgToolRepository) UpdateToolDraft(ctx context.Context, id int, draft bool) (bool, error) {
+	query := `
+		UPDATE tools
+		SET draft = $1, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $2
+	`
+
+	tag, err := r.db.Exec(ctx, query, draft, id)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+func (r *pgToolRepository) ClaimDueToolHealthCheck(ctx context.Context, lease time.Duration) (*entity.Tool, error) {
+	query := `
+		UPDATE tools
+		SET health_probe_at = CURRENT_TIMESTAMP + $1 * INTERVAL '1 millisecond'
+		WHERE id = (
+			SELECT id FROM tools
+			WHERE health_probe_at IS NULL OR health_probe_at <= CURRENT_TIMESTAMP
+			ORDER BY health_probe_at NULLS FIRST, id
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING
+			id, uuid, name,
+			version, description, engine_interface,
+			provider_interface, draft, health_status, health_message,
+			health_failures, health_checked_at, health_changed_at,
+			created_at, updated_at
+	`
+
+	var tool entity.ToolRow
+	if err := pgxscan.Get(ctx, r.db, &tool, query, lease.Milliseconds()); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return tool.ToEntity(), nil
+}
+
+func (r *pgToolRepository) UpdateToolHealth(
+	ctx context.Context, toolID int, health shared_type.ToolHealth, nextCheckAt time.Time,
+) error {
+	query := `
+		UPDATE tools
+		SET
+			health_status = $1, health_message = $2, health_failures = $3,
+			health_checked_at = $4, health_changed_at = $5, health_probe_at = $6
+		WHERE id = $7
+	`
+
+	_, err := r.db.Exec(ctx, query,
+		string(health.Status), health.Message, health.ConsecutiveFailures,
+		health.CheckedAt, health.ChangedAt, nextCheckAt, toolID,
+	)
+	return err
+}
+
+func (r *pgToolRepository) CreateToolHealthCheck(
+	ctx context.Context, check *entity.ToolHealthCheck, historyLimit int,
+) error {
+	query := `
+		WITH pruned AS (
+			DELETE FROM tool_health_checks
+			WHERE id IN (
+				SELECT id FROM tool_health_checks
+				WHERE tool_id = $1
+				ORDER BY id DESC
+				OFFSET GREATEST($7::int - 1, 0)
+			)
+		)
+		INSERT INTO tool_health_checks (tool_id, healthy, status, message, latency_ms, checked_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`
+
+	checkRaw := check.ToRow()
+
+	_, err := r.db.Exec(ctx, query,
+		checkRaw.ToolID, checkRaw.Healthy, checkRaw.Status, checkRaw.Message, checkRaw.LatencyMs, checkRaw.CheckedAt,
+		historyLimit,
+	)
+	return err
+}
+
+func (r *pgToolRepository) FindAllToolHealthChecksByToolID(
+	ctx context.Context, toolID int, limit int,
+) ([]*entity.ToolHealthCheck, error) {
+	query := `
+		SELECT id, tool_id, healthy, status, message, latency_ms, checked_at
+		FROM tool_health_checks
+		WHERE tool_id = $1
+		ORDER BY id DESC
+		LIMIT $2
+	`
+
+	var checks []*entity.ToolHealthCheckRow
+	if err := pgxscan.Select(ctx, r.db, &checks, query, toolID, limit); err != nil {
+		return nil, err
+	}
+
+	checksEntity := make([]*entity.ToolHealthCheck, len(checks))
+	for i, check := range checks {
+		checksEntity[i] = check.ToEntity()
+	}
+
+	return checksEntity, nil
+}
+
+func (r *pgToolRepository) FindAllToolCircuitBreakers(ctx context.Context) ([]*entity.ToolCircuitBreaker, error) {
+	query := `
+		SELECT tool_id, state, opened_at, open_until, trips, last_error, reset_at, updated_at
+		FROM tool_circuit_breakers
+		ORDER BY tool_id
+	`
+
+	var breakers []*entity.ToolCircuitBreakerRow
+	if err := pgxscan.Select(ctx, r.db, &breakers, query); err != nil {
+		return nil, err
+	}
+
+	breakersEntity := make([]*entity.ToolCircuitBreaker, len(breakers))
+	for i, breaker := range breakers {
+		breakersEntity[i] = breaker.ToEntity()
+	}
+
+	return breakersEntity, nil
+}
+
+func (r *pgToolRepository) TripToolCircuitBreaker(
+	ctx context.Context, toolID int, openedAt time.Time, openUntil time.Time, lastError string,
+) error {
+	query := `
+		INSERT INTO tool_circuit_breakers (tool_id, state, opened_at, open_until, trips, last_error)
+		VALUES ($1, 'open', $2, $3, 1, $4)
+		ON CONFLICT (tool_id) DO UPDATE
+		SET
+			state = 'open', opened_at = EXCLUDED.opened_at, open_until = EXCLUDED.open_until,
+			trips = tool_circuit_breakers.trips + 1, last_error = EXCLUDED.last_error, updated_at = CURRENT_TIMESTAMP
+	`
+
+	_, err := r.db.Exec(ctx, query, toolID, openedAt, openUntil, lastError)
+	return err
+}
+
+func (r *pgToolRepository) CloseToolCircuitBreaker(ctx context.Context, toolID int, openedAt time.Time) error {
+	query := `
+		UPDATE tool_circuit_breakers
+		SET state = 'closed', updated_at = CURRENT_TIMESTAMP
+		WHERE tool_id = $1 AND state = 'open' AND opened_at <= $2
+	`
+
+	_, err := r.db.Exec(ctx, query, toolID, openedAt)
+	return err
+}
+
+func (r *pgToolRepository) ResetToolCircuitBreaker(ctx context.Context, toolID int, resetAt time.Time) error {
+	query := `
+		INSERT INTO tool_circuit_breakers (tool_id, state, reset_at)
+		VALUES ($1, 'closed', $2)
+		ON CONFLICT (tool_id) DO UPDATE
+		SET
+			state = 'closed', opened_at = NULL, open_until = NULL, trips = 0, last_error = '',
+			reset_at = EXCLUDED.reset_at, updated_at = CURRENT_TIMESTAMP
+	`
+
+	_, err := r.db.Exec(ctx, query, toolID, resetAt)
+	return err
+}
+
+func (r *pgToolRepository) FindAllToolIDsByHealthStatus(
+	ctx context.Context, status valueobject.ToolHealthStatus,
+) ([]int, error) {
+	query := `SELECT id FROM tools WHERE health_status = $1 ORDER BY id`
+
+	var ids []int
+	if err := pgxscan.Select(ctx, r.db, &ids, query, status); err != nil {
+		return nil, err
+	}
+
+	return ids, nil
+}
+
 func (r *pgToolRepository) DeleteTool(ctx context.Context, id int) error {
 	query := `
 		DELETE FROM tools
@@ -310,6 +515,9 @@ func (r *pgToolRepository) FindToolRequestByID(
 			tr.request_data, 
 			tr.response_data, 
 			tr.status, 
+			tr.attempts,
+			tr.claim_count,
+			tr.locked_by,
 			tr.created_at, 
 			tr.updated_at
 		FROM tool_requests tr
@@ -337,6 +545,9 @@ func (r *pgToolRepository) FindAllToolRequestsByToolID(
 			tr.request_data, 
 			tr.response_data, 
 			tr.status, 
+			tr.attempts,
+			tr.claim_count,
+			tr.locked_by,
 			tr.created_at, 
 			tr.updated_at
 		FROM tool_requests tr
@@ -373,6 +584,9 @@ func (r *pgToolRepository) FindAllToolRequestsByClientID(
 			tr.request_data, 
 			tr.response_data, 
 			tr.status, 
+			tr.attempts,
+			tr.claim_count,
+			tr.locked_by,
 			tr.created_at, 
 			tr.updated_at
 		FROM tool_requests tr
@@ -457,3 +671,615 @@ func (r *pgToolRepository) DeleteToolRequest(ctx context.Context, id int) error
 	_, err := r.db.Exec(ctx, query, id)
 	return err
 }
+
+const toolRequestColumns = `
+	tr.id,
+	tr.tool_id,
+	t.name as tool_name,
+	tr.client_id,
+	tr.request_data,
+	tr.response_data,
+	tr.status,
+	tr.attempts,
+	tr.claim_count,
+	tr.locked_by,
+	tr.created_at,
+	tr.updated_at
+`
+
+// toolRequestClaimLock is the advisory lock serializing the claims of tool requests (init.sql holds 7242001).
+const toolRequestClaimLock = 7242002
+
+// toolConcurrencyLimits selects per_tool and per_client_tool, the limits of the tool t: those of its concurrency
+// policy, or the router limits perTool and perClientTool (query placeholders). A limit which is not a small
+// non-negative integer (stored before it was validated) keeps the router limit.
+func toolConcurrencyLimits(perTool, perClientTool string) string {
+	return `
+		SELECT
+			COALESCE(NULLIF(p.max_in_flight, 0), ` + perTool + `) AS per_tool,
+			COALESCE(NULLIF(p.max_in_flight_per_client, 0), ` + perClientTool + `) AS per_client_tool
+		FROM (
+			SELECT
+				CASE WHEN (t.engine_interface::jsonb #>> '{concurrency_policy,max_in_flight}') ~ '^\d{1,9}$'
+					THEN (t.engine_interface::jsonb #>> '{concurrency_policy,max_in_flight}')::int END AS max_in_flight,
+				CASE WHEN (t.engine_interface::jsonb #>> '{concurrency_policy,max_in_flight_per_client}') ~ '^\d{1,9}$'
+					THEN (t.engine_interface::jsonb #>> '{concurrency_policy,max_in_flight_per_client}')::int END AS max_in_flight_per_client
+		) p
+	`
+}
+
+func (r *pgToolRepository) LockToolRequestClaims(ctx context.Context) error {
+	_, err := r.db.Exec(ctx, `SELECT pg_advisory_xact_lock($1)`, toolRequestClaimLock)
+	return err
+}
+
+func (r *pgToolRepository) ClaimToolRequest(
+	ctx context.Context, workerID string, lease time.Duration, limits shared_type.ConcurrencyLimits,
+) (*entity.ToolRequest, error) {
+	// a request is skipped while its client, its tool or the pair is at its limit, the next eligible one is claimed:
+	// requests wait in FIFO order within each limit, without blocking the requests of other clients and tools
+	query := `
+		WITH running AS (
+			SELECT client_id, tool_id, COUNT(*) AS in_flight
+			FROM tool_requests
+			WHERE status = 'running'
+			GROUP BY client_id, tool_id
+		),
+		candidate AS (
+			SELECT tr.id
+			FROM tool_requests tr
+			LEFT JOIN tools t ON tr.tool_id = t.id
+			CROSS JOIN LATERAL (` + toolConcurrencyLimits("$4", "$5") + `) l
+			WHERE tr.status = 'pending'
+				AND ($3 <= 0 OR (
+					SELECT COALESCE(SUM(in_flight), 0) FROM running WHERE running.client_id = tr.client_id
+				) < $3)
+				AND (l.per_tool <= 0 OR (
+					SELECT COALESCE(SUM(in_flight), 0) FROM running WHERE running.tool_id = tr.tool_id
+				) < l.per_tool)
+				AND (l.per_client_tool <= 0 OR (
+					SELECT COALESCE(SUM(in_flight), 0) FROM running
+					WHERE running.client_id = tr.client_id AND running.tool_id = tr.tool_id
+				) < l.per_client_tool)
+			ORDER BY tr.created_at, tr.id
+			FOR UPDATE OF tr SKIP LOCKED
+			LIMIT 1
+		),
+		claimed AS (
+			UPDATE tool_requests
+			SET 
+				status = 'running', locked_by = $1,
+				lease_expires_at = CURRENT_TIMESTAMP + make_interval(secs => $2),
+				heartbeat_at = CURRENT_TIMESTAMP,
+				claim_count = claim_count + 1, updated_at = CURRENT_TIMESTAMP
+			WHERE id = (SELECT id FROM candidate)
+			RETURNING *
+		)
+		SELECT ` + toolRequestColumns + `
+		FROM claimed tr
+		JOIN tools t ON tr.tool_id = t.id
+	`
+
+	var request entity.ToolRequestRow
+	if err := pgxscan.Get(ctx, r.db, &request, query,
+		workerID, lease.Seconds(), limits.PerClient, limits.PerTool, limits.PerClientTool,
+	); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return request.ToEntity(), nil
+}
+
+func (r *pgToolRepository) ClaimToolRequestStatusCheck(
+	ctx context.Context, workerID string, lease time.Duration,
+) (*entity.ToolRequest, error) {
+	query := `
+		WITH candidate AS (
+			SELECT id
+			FROM tool_requests
+			WHERE status = 'running' AND locked_by IS NULL AND next_poll_at <= CURRENT_TIMESTAMP
+			ORDER BY next_poll_at, id
+			FOR UPDATE SKIP LOCKED
+			LIMIT 1
+		),
+		claimed AS (
+			UPDATE tool_requests
+			SET 
+				locked_by = $1,
+				lease_expires_at = CURRENT_TIMESTAMP + make_interval(secs => $2),
+				heartbeat_at = CURRENT_TIMESTAMP,
+				next_poll_at = NULL, updated_at = CURRENT_TIMESTAMP
+			WHERE id = (SELECT id FROM candidate)
+			RETURNING *
+		)
+		SELECT ` + toolRequestColumns + `
+		FROM claimed tr
+		JOIN tools t ON tr.tool_id = t.id
+	`
+
+	var request entity.ToolRequestRow
+	if err := pgxscan.Get(ctx, r.db, &request, query, workerID, lease.Seconds()); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return request.ToEntity(), nil
+}
+
+func (r *pgToolRepository) WaitToolRequestStatusCheck(
+	ctx context.Context, request *entity.ToolRequest, nextPollAt time.Time,
+) (bool, error) {
+	query := `
+		UPDATE tool_requests
+		SET
+			request_data = $1, response_data = $2, attempts = $3, next_poll_at = $4,
+			locked_by = NULL, lease_expires_at = NULL, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $5 AND status = 'running' AND locked_by = $6
+	`
+
+	requestRaw := request.ToRow()
+
+	tag, err := r.db.Exec(ctx, query,
+		requestRaw.RequestData, requestRaw.ResponseData, requestRaw.Attempts, nextPollAt,
+		requestRaw.ID, requestRaw.LockedBy,
+	)
+	if err != nil {
+		return false, err
+	}
+
+	return tag.RowsAffected() == 1, nil
+}
+
+func (r *pgToolRepository) FindToolRequestQueuePosition(
+	ctx context.Context, id int, limits shared_type.ConcurrencyLimits,
+) (int, error) {
+	// the older pending requests sharing a limit of the request are claimed first (see ClaimToolRequest),
+	// without any limit it waits for every older pending request
+	query := `
+		SELECT COUNT(ahead.id) + 1
+		FROM tool_requests tr
+		LEFT JOIN tools t ON tr.tool_id = t.id
+		CROSS JOIN LATERAL (` + toolConcurrencyLimits("$3", "$4") + `) l
+		LEFT JOIN tool_requests ahead ON ahead.status = 'pending'
+			AND (ahead.created_at, ahead.id) < (tr.created_at, tr.id)
+			AND (
+				($2 > 0 AND ahead.client_id = tr.client_id)
+				OR (l.per_tool > 0 AND ahead.tool_id = tr.tool_id)
+				OR (l.per_client_tool > 0 AND ahead.client_id = tr.client_id AND ahead.tool_id = tr.tool_id)
+				OR ($2 <= 0 AND l.per_tool <= 0 AND l.per_client_tool <= 0)
+			)
+		WHERE tr.id = $1 AND tr.status = 'pending'
+		GROUP BY tr.id
+	`
+
+	var position int
+	if err := r.db.QueryRow(ctx, query,
+		id, limits.PerClient, limits.PerTool, limits.PerClientTool,
+	).Scan(&position); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return 0, nil
+		}
+		return 0, err
+	}
+
+	return position, nil
+}
+
+func (r *pgToolRepository) RenewToolRequestLease(
+	ctx context.Context, id int, workerID string, lease time.Duration,
+) (bool, error) {
+	query := `
+		UPDATE tool_requests
+		SET lease_expires_at = CURRENT_TIMESTAMP + make_interval(secs => $1), heartbeat_at = CURRENT_TIMESTAMP
+		WHERE id = $2 AND status = 'running' AND locked_by = $3
+	`
+
+	tag, err := r.db.Exec(ctx, query, lease.Seconds(), id, workerID)
+	if err != nil {
+		return false, err
+	}
+
+	return tag.RowsAffected() == 1, nil
+}
+
+func (r *pgToolRepository) UpdateClaimedToolRequest(
+	ctx context.Context, request *entity.ToolRequest,
+) (bool, error) {
+	query := `
+		UPDATE tool_requests
+		SET request_data = $1, response_data = $2, attempts = $3, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $4 AND status = 'running' AND locked_by = $5
+	`
+
+	requestRaw := request.ToRow()
+
+	tag, err := r.db.Exec(ctx, query,
+		requestRaw.RequestData, requestRaw.ResponseData, requestRaw.Attempts, requestRaw.ID, requestRaw.LockedBy,
+	)
+	if err != nil {
+		return false, err
+	}
+
+	return tag.RowsAffected() == 1, nil
+}
+
+func (r *pgToolRepository) CompleteToolRequest(
+	ctx context.Context, request *entity.ToolRequest,
+) (bool, error) {
+	query := `
+		UPDATE tool_requests
+		SET
+			request_data = $1, response_data = $2, status = $3, attempts = $4,
+			locked_by = NULL, lease_expires_at = NULL, next_poll_at = NULL, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $5 AND status = 'running' AND locked_by = $6
+	`
+
+	requestRaw := request.ToRow()
+
+	tag, err := r.db.Exec(ctx, query,
+		requestRaw.RequestData, requestRaw.ResponseData, requestRaw.Status, requestRaw.Attempts,
+		requestRaw.ID, requestRaw.LockedBy,
+	)
+	if err != nil {
+		return false, err
+	}
+
+	return tag.RowsAffected() == 1, nil
+}
+
+func (r *pgToolRepository) CancelToolRequest(
+	ctx context.Context, request *entity.ToolRequest,
+) (bool, error) {
+	query := `
+		UPDATE tool_requests
+		SET
+			response_data = $1, status = $2,
+			locked_by = NULL, lease_expires_at = NULL, next_poll_at = NULL, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $3 AND status IN ('pending', 'running')
+	`
+
+	requestRaw := request.ToRow()
+
+	tag, err := r.db.Exec(ctx, query, requestRaw.ResponseData, requestRaw.Status, requestRaw.ID)
+	if err != nil {
+		return false, err
+	}
+
+	return tag.RowsAffected() == 1, nil
+}
+
+func (r *pgToolRepository) ReleaseExpiredToolRequests(
+	ctx context.Context, maxClaims int,
+) (int64, []int, error) {
+	query := `
+		WITH released AS (
+			UPDATE tool_requests
+			SET 
+				status = CASE WHEN claim_count >= $1 THEN 'failed' ELSE 'pending' END,
+				response_data = CASE
+					WHEN claim_count >= $1 THEN jsonb_set(
+						COALESCE(NULLIF(response_data, '')::jsonb, '{}'::jsonb),
+						'{error}',
+						to_jsonb('lease expired after ' || claim_count || ' claims (worker ' || COALESCE(locked_by, '') || ')')
+					)::text
+					ELSE response_data
+				END,
+				locked_by = NULL, lease_expires_at = NULL, updated_at = CURRENT_TIMESTAMP
+			WHERE status = 'running' AND lease_expires_at < CURRENT_TIMESTAMP
+			RETURNING id, status
+		)
+		SELECT
+			COUNT(*) FILTER (WHERE status = 'pending'),
+			COALESCE(array_agg(id) FILTER (WHERE status = 'failed'), '{}')
+		FROM released
+	`
+
+	var requeued int64
+	var failed []int
+	if err := r.db.QueryRow(ctx, query, maxClaims).Scan(&requeued, &failed); err != nil {
+		return 0, nil, err
+	}
+
+	return requeued, failed, nil
+}
+
+func (r *pgToolRepository) FindToolResultCache(
+	ctx context.Context, toolID int, cacheKey string,
+) (*entity.ToolResultCache, error) {
+	query := `
+		SELECT 
+			id, tool_id, tool_version, cache_key, tool_request_id,
+			hit_count, last_hit_at, expires_at, created_at
+		FROM tool_result_cache
+		WHERE tool_id = $1 AND cache_key = $2 AND expires_at > CURRENT_TIMESTAMP
+	`
+
+	var cache entity.ToolResultCacheRow
+	if err := pgxscan.Get(ctx, r.db, &cache, query, toolID, cacheKey); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return cache.ToEntity(), nil
+}
+
+func (r *pgToolRepository) FindAllToolResultCacheByToolID(
+	ctx context.Context, toolID int,
+) ([]*entity.ToolResultCache, error) {
+	query := `
+		SELECT 
+			id, tool_id, tool_version, cache_key, tool_request_id,
+			hit_count, last_hit_at, expires_at, created_at
+		FROM tool_result_cache
+		WHERE tool_id = $1
+		ORDER BY created_at DESC
+	`
+
+	var caches []*entity.ToolResultCacheRow
+	if err := pgxscan.Select(ctx, r.db, &caches, query, toolID); err != nil {
+		return nil, err
+	}
+
+	cachesEntity := make([]*entity.ToolResultCache, len(caches))
+	for i, cache := range caches {
+		cachesEntity[i] = cache.ToEntity()
+	}
+
+	return cachesEntity, nil
+}
+
+func (r *pgToolRepository) UpsertToolResultCache(
+	ctx context.Context, cache *entity.ToolResultCache,
+) error {
+	query := `
+		WITH purged AS (
+			DELETE FROM tool_result_cache
+			WHERE tool_id = $1 AND cache_key <> $3 AND expires_at <= CURRENT_TIMESTAMP
+		)
+		INSERT INTO tool_result_cache (tool_id, tool_version, cache_key, tool_request_id, expires_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (tool_id, cache_key) DO UPDATE
+		SET
+			tool_version = EXCLUDED.tool_version,
+			tool_request_id = EXCLUDED.tool_request_id,
+			hit_count = 0, last_hit_at = NULL,
+			expires_at = EXCLUDED.expires_at, created_at = CURRENT_TIMESTAMP
+	`
+
+	cacheRaw := cache.ToRow()
+
+	_, err := r.db.Exec(ctx, query,
+		cacheRaw.ToolID, cacheRaw.ToolVersion, cacheRaw.CacheKey, cacheRaw.ToolRequestID, cacheRaw.ExpiresAt)
+	return err
+}
+
+func (r *pgToolRepository) RecordToolResultCacheHit(ctx context.Context, id int) error {
+	query := `
+		UPDATE tool_result_cache
+		SET hit_count = hit_count + 1, last_hit_at = CURRENT_TIMESTAMP
+		WHERE id = $1
+	`
+
+	_, err := r.db.Exec(ctx, query, id)
+	return err
+}
+
+func (r *pgToolRepository) DeleteToolResultCache(ctx context.Context, toolID int, id int) (bool, error) {
+	query := `
+		DELETE FROM tool_result_cache
+		WHERE tool_id = $1 AND id = $2
+	`
+
+	tag, err := r.db.Exec(ctx, query, toolID, id)
+	if err != nil {
+		return false, err
+	}
+
+	return tag.RowsAffected() == 1, nil
+}
+
+func (r *pgToolRepository) DeleteAllToolResultCacheByToolID(ctx context.Context, toolID int) (int64, error) {
+	query := `
+		DELETE FROM tool_result_cache
+		WHERE tool_id = $1
+	`
+
+	tag, err := r.db.Exec(ctx, query, toolID)
+	if err != nil {
+		return 0, err
+	}
+
+	return tag.RowsAffected(), nil
+}
+
+const toolScheduleColumns = `
+	ts.id,
+	ts.tool_id,
+	t.name as tool_name,
+	ts.client_id,
+	ts.name,
+	ts.cron_expression,
+	ts.timezone,
+	ts.payload,
+	ts.webhook_url,
+	ts.missed_run_policy,
+	ts.status,
+	ts.next_run_at,
+	ts.last_run_at,
+	ts.last_tool_request_id,
+	ts.last_error,
+	ts.created_at,
+	ts.updated_at
+`
+
+func (r *pgToolRepository) selectToolSchedules(
+	ctx context.Context, query string, args ...any,
+) ([]*entity.ToolSchedule, error) {
+	var schedules []*entity.ToolScheduleRow
+	if err := pgxscan.Select(ctx, r.db, &schedules, query, args...); err != nil {
+		return nil, err
+	}
+
+	schedulesEntity := make([]*entity.ToolSchedule, len(schedules))
+	for i, schedule := range schedules {
+		schedulesEntity[i] = schedule.ToEntity()
+	}
+
+	return schedulesEntity, nil
+}
+
+func (r *pgToolRepository) FindAllToolSchedules(ctx context.Context) ([]*entity.ToolSchedule, error) {
+	query := `
+		SELECT ` + toolScheduleColumns + `
+		FROM tool_schedules ts
+		JOIN tools t ON ts.tool_id = t.id
+		ORDER BY ts.id
+	`
+
+	return r.selectToolSchedules(ctx, query)
+}
+
+func (r *pgToolRepository) FindAllToolSchedulesByToolID(
+	ctx context.Context, toolID int,
+) ([]*entity.ToolSchedule, error) {
+	query := `
+		SELECT ` + toolScheduleColumns + `
+		FROM tool_schedules ts
+		JOIN tools t ON ts.tool_id = t.id
+		WHERE ts.tool_id = $1
+		ORDER BY ts.id
+	`
+
+	return r.selectToolSchedules(ctx, query, toolID)
+}
+
+func (r *pgToolRepository) FindAllToolSchedulesByClientID(
+	ctx context.Context, clientID int,
+) ([]*entity.ToolSchedule, error) {
+	query := `
+		SELECT ` + toolScheduleColumns + `
+		FROM tool_schedules ts
+		JOIN tools t ON ts.tool_id = t.id
+		WHERE ts.client_id = $1
+		ORDER BY ts.id
+	`
+
+	return r.selectToolSchedules(ctx, query, clientID)
+}
+
+func (r *pgToolRepository) FindToolScheduleByID(ctx context.Context, id int) (*entity.ToolSchedule, error) {
+	query := `
+		SELECT ` + toolScheduleColumns + `
+		FROM tool_schedules ts
+		JOIN tools t ON ts.tool_id = t.id
+		WHERE ts.id = $1
+	`
+
+	var schedule entity.ToolScheduleRow
+	if err := pgxscan.Get(ctx, r.db, &schedule, query, id); err != nil {
+		return nil, err
+	}
+
+	return schedule.ToEntity(), nil
+}
+
+func (r *pgToolRepository) CreateToolSchedule(
+	ctx context.Context, schedule *entity.ToolSchedule,
+) (*entity.ToolSchedule, error) {
+	query := `
+		INSERT INTO tool_schedules (
+			tool_id, client_id, name, cron_expression, timezone,
+			payload, webhook_url, missed_run_policy, status, next_run_at
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		RETURNING id
+	`
+
+	scheduleRaw := schedule.ToRow()
+
+	var id int
+	if err := r.db.QueryRow(ctx, query,
+		scheduleRaw.ToolID, scheduleRaw.ClientID, scheduleRaw.Name, scheduleRaw.CronExpression, scheduleRaw.Timezone,
+		scheduleRaw.Payload, scheduleRaw.WebhookURL, scheduleRaw.MissedRunPolicy, scheduleRaw.Status, scheduleRaw.NextRunAt,
+	).Scan(&id); err != nil {
+		return nil, err
+	}
+
+	return r.FindToolScheduleByID(ctx, id)
+}
+
+func (r *pgToolRepository) UpdateToolSchedule(ctx context.Context, schedule *entity.ToolSchedule) error {
+	query := `
+		UPDATE tool_schedules
+		SET
+			name = $1, cron_expression = $2, timezone = $3,
+			payload = $4, webhook_url = $5, missed_run_policy = $6,
+			status = $7, next_run_at = $8, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $9
+	`
+
+	scheduleRaw := schedule.ToRow()
+
+	_, err := r.db.Exec(ctx, query,
+		scheduleRaw.Name, scheduleRaw.CronExpression, scheduleRaw.Timezone,
+		scheduleRaw.Payload, scheduleRaw.WebhookURL, scheduleRaw.MissedRunPolicy,
+		scheduleRaw.Status, scheduleRaw.NextRunAt, scheduleRaw.ID,
+	)
+	return err
+}
+
+func (r *pgToolRepository) DeleteToolSchedule(ctx context.Context, id int) error {
+	query := `
+		DELETE FROM tool_schedules
+		WHERE id = $1
+	`
+
+	_, err := r.db.Exec(ctx, query, id)
+	return err
+}
+
+func (r *pgToolRepository) ClaimDueToolSchedule(ctx context.Context) (*entity.ToolSchedule, error) {
+	query := `
+		SELECT ` + toolScheduleColumns + `
+		FROM tool_schedules ts
+		JOIN tools t ON ts.tool_id = t.id
+		WHERE ts.status = 'active' AND ts.next_run_at <= CURRENT_TIMESTAMP
+		ORDER BY ts.next_run_at
+		LIMIT 1
+		FOR UPDATE OF ts SKIP LOCKED
+	`
+
+	var schedule entity.ToolScheduleRow
+	if err := pgxscan.Get(ctx, r.db, &schedule, query); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return schedule.ToEntity(), nil
+}
+
+func (r *pgToolRepository) RecordToolScheduleRun(ctx context.Context, schedule *entity.ToolSchedule) error {
+	query := `
+		UPDATE tool_schedules
+		SET
+			next_run_at = $1, last_run_at = $2, last_tool_request_id = $3, last_error = $4,
+			updated_at = CURRENT_TIMESTAMP
+		WHERE id = $5
+	`
+
+	scheduleRaw := schedule.ToRow()
+
+	_, err := r.db.Exec(ctx, query,
+		scheduleRaw.NextRunAt, scheduleRaw.LastRunAt, scheduleRaw.LastToolRequestID, scheduleRaw.LastError, scheduleRaw.ID,
+	)
+	return err
+}
@@ -0,0 +1,388 @@
+package persistence
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"aigendrug.com/router-core/internal/shared/database/postgres"
+	"aigendrug.com/router-core/internal/tool/domain"
+	"aigendrug.com/router-core/internal/tool/domain/entity"
+	"aigendrug.com/router-core/internal/tool/domain/shared_type"
+	"aigendrug.com/router-core/internal/tool/domain/valueobject"
+	"github.com/jackc/pgx/v5"
+)
+
+// The queries are run against the database of TEST_DATABASE_URL, which is migrated first.
+// Each test runs in a transaction rolled back at its end, the tests are skipped without a database.
+func testToolRepository(t *testing.T) (context.Context, pgx.Tx, domain.ToolRepository) {
+	t.Helper()
+
+	connectionString := os.Getenv("TEST_DATABASE_URL")
+	if connectionString == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+
+	ctx := context.Background()
+	if _, err := postgres.AutoMigrateFromConnectionString(ctx, connectionString); err != nil {
+		t.Fatalf("failed to migrate the test database: %v", err)
+	}
+	pool, err := postgres.NewPostgresPoolFromConnectionString(ctx, connectionString)
+	if err != nil {
+		t.Fatalf("failed to connect to the test database: %v", err)
+	}
+	t.Cleanup(pool.Close)
+
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = tx.Rollback(context.Background()) })
+
+	// the queue is global, requests left by other runs would be counted
+	if _, err := tx.Exec(ctx, `DELETE FROM tool_requests`); err != nil {
+		t.Fatal(err)
+	}
+
+	return ctx, tx, NewPgToolRepository(nil).WithTx(ctx, tx)
+}
+
+// queueFixture holds two clients and four tools: t1 without concurrency policy,
+// t2 with max_in_flight 1, t3 with max_in_flight_per_client 1 and t4 with a malformed policy.
+type queueFixture struct {
+	clients map[string]int
+	tools   map[string]int
+}
+
+func newQueueFixture(t *testing.T, ctx context.Context, tx pgx.Tx) queueFixture {
+	t.Helper()
+
+	f := queueFixture{clients: map[string]int{}, tools: map[string]int{}}
+	for _, name := range []string{"a", "b"} {
+		var id int
+		if err := tx.QueryRow(ctx, `
+			INSERT INTO clients (name, client_identifier) VALUES ($1, gen_random_uuid()::text) RETURNING id
+		`, name).Scan(&id); err != nil {
+			t.Fatal(err)
+		}
+		f.clients[name] = id
+	}
+
+	policies := map[string]string{
+		"t1": `{}`,
+		"t2": `{"concurrency_policy": {"max_in_flight": 1}}`,
+		"t3": `{"concurrency_policy": {"max_in_flight_per_client": 1}}`,
+		"t4": `{"concurrency_policy": {"max_in_flight": "many", "max_in_flight_per_client": -1}}`,
+	}
+	for name, engineInterface := range policies {
+		var id int
+		if err := tx.QueryRow(ctx, `
+			INSERT INTO tools (uuid, name, version, engine_interface, provider_interface)
+			VALUES (gen_random_uuid(), $1, '1.0', $2, '{}') RETURNING id
+		`, name, engineInterface).Scan(&id); err != nil {
+			t.Fatal(err)
+		}
+		f.tools[name] = id
+	}
+
+	return f
+}
+
+type queuedRequest struct {
+	client string
+	tool   string
+	status valueobject.ToolRequestStatus
+}
+
+// enqueue inserts the requests in order, one second apart, and returns their ids.
+func (f queueFixture) enqueue(t *testing.T, ctx context.Context, tx pgx.Tx, requests []queuedRequest) []int {
+	t.Helper()
+
+	createdAt := time.Now().Add(-time.Hour)
+	ids := make([]int, len(requests))
+	for i, request := range requests {
+		if err := tx.QueryRow(ctx, `
+			INSERT INTO tool_requests (tool_id, client_id, request_data, status, created_at)
+			VALUES ($1, $2, '{}', $3, $4) RETURNING id
+		`, f.tools[request.tool], f.clients[request.client], request.status, createdAt.Add(time.Duration(i)*time.Second),
+		).Scan(&ids[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return ids
+}
+
+func pending(client string, tool string) queuedRequest {
+	return queuedRequest{client: client, tool: tool, status: valueobject.ToolRequestStatusPending}
+}
+
+func running(client string, tool string) queuedRequest {
+	return queuedRequest{client: client, tool: tool, status: valueobject.ToolRequestStatusRunning}
+}
+
+func TestClaimToolRequest(t *testing.T) {
+	tests := []struct {
+		name   string
+		limits shared_type.ConcurrencyLimits
+		queue  []queuedRequest
+		// index of the claimed request in the queue, -1 when none is eligible
+		want int
+	}{
+		{
+			name:  "oldest pending request first",
+			queue: []queuedRequest{pending("b", "t1"), pending("a", "t1")},
+			want:  0,
+		},
+		{
+			name:  "running requests are not claimed",
+			queue: []queuedRequest{running("a", "t1"), pending("a", "t1")},
+			want:  1,
+		},
+		{
+			name:  "unlimited",
+			queue: []queuedRequest{running("a", "t1"), running("a", "t1"), pending("a", "t1")},
+			want:  2,
+		},
+		{
+			name:   "client at its limit waits without blocking others",
+			limits: shared_type.ConcurrencyLimits{PerClient: 1},
+			queue:  []queuedRequest{running("a", "t1"), pending("a", "t1"), pending("b", "t1")},
+			want:   2,
+		},
+		{
+			name:   "client limit across tools",
+			limits: shared_type.ConcurrencyLimits{PerClient: 1},
+			queue:  []queuedRequest{running("a", "t1"), pending("a", "t3")},
+			want:   -1,
+		},
+		{
+			name:   "router tool limit",
+			limits: shared_type.ConcurrencyLimits{PerTool: 1},
+			queue:  []queuedRequest{running("a", "t1"), pending("b", "t1"), pending("b", "t3")},
+			want:   2,
+		},
+		{
+			name:  "tool limit of the policy",
+			queue: []queuedRequest{running("a", "t2"), pending("b", "t2"), pending("b", "t1")},
+			want:  2,
+		},
+		{
+			name:   "policy overrides the router tool limit",
+			limits: shared_type.ConcurrencyLimits{PerTool: 5},
+			queue:  []queuedRequest{running("a", "t2"), pending("b", "t2")},
+			want:   -1,
+		},
+		{
+			name:  "pair limit of the policy",
+			queue: []queuedRequest{running("a", "t3"), pending("a", "t3"), pending("b", "t3")},
+			want:  2,
+		},
+		{
+			name:   "router pair limit",
+			limits: shared_type.ConcurrencyLimits{PerClientTool: 1},
+			queue:  []queuedRequest{running("a", "t1"), pending("a", "t1"), pending("a", "t3")},
+			want:   2,
+		},
+		{
+			name:   "malformed policy keeps the router limits",
+			limits: shared_type.ConcurrencyLimits{PerTool: 1},
+			queue:  []queuedRequest{running("a", "t4"), pending("b", "t4"), pending("b", "t1")},
+			want:   2,
+		},
+		{
+			name:  "nothing pending",
+			queue: []queuedRequest{running("a", "t1")},
+			want:  -1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, tx, repo := testToolRepository(t)
+			fixture := newQueueFixture(t, ctx, tx)
+			ids := fixture.enqueue(t, ctx, tx, tt.queue)
+
+			claimed, err := repo.ClaimToolRequest(ctx, "worker-1", time.Minute, tt.limits)
+			if err != nil {
+				t.Fatalf("ClaimToolRequest() error = %v", err)
+			}
+			if tt.want < 0 {
+				if claimed != nil {
+					t.Fatalf("ClaimToolRequest() claimed request %d, want none", claimed.ID)
+				}
+				return
+			}
+
+			if claimed == nil || claimed.ID != ids[tt.want] {
+				t.Fatalf("ClaimToolRequest() = %+v, want request %d", claimed, ids[tt.want])
+			}
+			if claimed.Status != valueobject.ToolRequestStatusRunning || claimed.LockedBy != "worker-1" || claimed.ClaimCount != 1 {
+				t.Fatalf("claimed request is %s by %q after %d claims, want running by worker-1 after 1",
+					claimed.Status, claimed.LockedBy, claimed.ClaimCount)
+			}
+		})
+	}
+}
+
+func TestFindToolRequestQueuePosition(t *testing.T) {
+	ctx, tx, repo := testToolRepository(t)
+	fixture := newQueueFixture(t, ctx, tx)
+	ids := fixture.enqueue(t, ctx, tx, []queuedRequest{
+		running("a", "t1"),
+		pending("a", "t1"),
+		pending("b", "t2"),
+		pending("a", "t2"),
+		pending("b", "t1"),
+		{client: "b", tool: "t3", status: valueobject.ToolRequestStatusSuccess},
+		pending("a", "t3"),
+	})
+
+	tests := []struct {
+		name   string
+		index  int
+		limits shared_type.ConcurrencyLimits
+		want   int
+	}{
+		{name: "running request", index: 0, want: 0},
+		{name: "finished request", index: 5, want: 0},
+		{name: "first pending request", index: 1, want: 1},
+		{name: "without limits behind every older request", index: 4, want: 4},
+		{name: "first request of a limited tool", index: 2, want: 1},
+		{name: "behind the requests of a limited tool", index: 3, want: 2},
+		{name: "behind the requests of the client", index: 4, limits: shared_type.ConcurrencyLimits{PerClient: 1}, want: 2},
+		{name: "behind the requests of the client and the tool", index: 3, limits: shared_type.ConcurrencyLimits{PerClient: 1}, want: 3},
+		{name: "behind the requests of the tool", index: 4, limits: shared_type.ConcurrencyLimits{PerTool: 1}, want: 2},
+		{name: "first request of a limited pair", index: 6, want: 1},
+		{name: "behind the requests of the client for a limited pair", index: 6, limits: shared_type.ConcurrencyLimits{PerClient: 1}, want: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			position, err := repo.FindToolRequestQueuePosition(ctx, ids[tt.index], tt.limits)
+			if err != nil {
+				t.Fatalf("FindToolRequestQueuePosition() error = %v", err)
+			}
+			if position != tt.want {
+				t.Fatalf("FindToolRequestQueuePosition() = %d, want %d", position, tt.want)
+			}
+		})
+	}
+}
+
+func TestClaimToolRequestStatusCheck(t *testing.T) {
+	ctx, tx, repo := testToolRepository(t)
+	fixture := newQueueFixture(t, ctx, tx)
+
+	insert := func(status valueobject.ToolRequestStatus, lockedBy any, nextPollAt time.Time) int {
+		t.Helper()
+		var id int
+		if err := tx.QueryRow(ctx, `
+			INSERT INTO tool_requests (tool_id, client_id, request_data, status, locked_by, next_poll_at)
+			VALUES ($1, $2, '{}', $3, $4, $5) RETURNING id
+		`, fixture.tools["t1"], fixture.clients["a"], status, lockedBy, nextPollAt).Scan(&id); err != nil {
+			t.Fatal(err)
+		}
+		return id
+	}
+
+	now := time.Now()
+	due := insert(valueobject.ToolRequestStatusRunning, nil, now.Add(-time.Minute))
+	mostOverdue := insert(valueobject.ToolRequestStatusRunning, nil, now.Add(-2*time.Minute))
+	insert(valueobject.ToolRequestStatusRunning, nil, now.Add(time.Minute))
+	insert(valueobject.ToolRequestStatusRunning, "worker-2", now.Add(-time.Minute))
+	insert(valueobject.ToolRequestStatusCancelled, nil, now.Add(-time.Minute))
+
+	for _, want := range []int{mostOverdue, due} {
+		claimed, err := repo.ClaimToolRequestStatusCheck(ctx, "worker-1", time.Minute)
+		if err != nil {
+			t.Fatalf("ClaimToolRequestStatusCheck() error = %v", err)
+		}
+		if claimed == nil || claimed.ID != want {
+			t.Fatalf("ClaimToolRequestStatusCheck() = %+v, want request %d", claimed, want)
+		}
+		if claimed.LockedBy != "worker-1" || claimed.ClaimCount != 0 {
+			t.Fatalf("claimed request locked by %q after %d claims, want worker-1 after 0", claimed.LockedBy, claimed.ClaimCount)
+		}
+	}
+	if claimed, err := repo.ClaimToolRequestStatusCheck(ctx, "worker-1", time.Minute); err != nil || claimed != nil {
+		t.Fatalf("ClaimToolRequestStatusCheck() = %+v, %v, want no due status check", claimed, err)
+	}
+
+	// released until its next check, the request is claimed again once it is due
+	claimed, err := repo.FindToolRequestByID(ctx, due)
+	if err != nil {
+		t.Fatal(err)
+	}
+	waiting, err := repo.WaitToolRequestStatusCheck(ctx, claimed, now.Add(-time.Second))
+	if err != nil || !waiting {
+		t.Fatalf("WaitToolRequestStatusCheck() = %v, %v, want true", waiting, err)
+	}
+	if waiting, err := repo.WaitToolRequestStatusCheck(ctx, claimed, now); err != nil || waiting {
+		t.Fatalf("WaitToolRequestStatusCheck() of a released request = %v, %v, want false", waiting, err)
+	}
+	if again, err := repo.ClaimToolRequestStatusCheck(ctx, "worker-3", time.Minute); err != nil || again == nil || again.ID != due {
+		t.Fatalf("ClaimToolRequestStatusCheck() = %+v, %v, want request %d", again, err, due)
+	}
+
+	// the running requests waiting for their status check are not reaped
+	requeued, failed, err := repo.ReleaseExpiredToolRequests(ctx, 3)
+	if err != nil || requeued != 0 || len(failed) != 0 {
+		t.Fatalf("ReleaseExpiredToolRequests() = %d, %v, %v, want nothing released", requeued, failed, err)
+	}
+}
+
+func TestToolCircuitBreaker(t *testing.T) {
+	ctx, tx, repo := testToolRepository(t)
+	toolID := newQueueFixture(t, ctx, tx).tools["t1"]
+
+	find := func() *entity.ToolCircuitBreaker {
+		t.Helper()
+		breakers, err := repo.FindAllToolCircuitBreakers(ctx)
+		if err != nil {
+			t.Fatalf("FindAllToolCircuitBreakers() error = %v", err)
+		}
+		for _, breaker := range breakers {
+			if breaker.ToolID == toolID {
+				return breaker
+			}
+		}
+		t.Fatalf("FindAllToolCircuitBreakers() = %+v, want the breaker of tool %d", breakers, toolID)
+		return nil
+	}
+
+	openedAt := time.Now().Truncate(time.Microsecond)
+	for i := range 2 {
+		if err := repo.TripToolCircuitBreaker(ctx, toolID, openedAt.Add(time.Duration(i)*time.Second),
+			openedAt.Add(time.Minute), "503 service unavailable"); err != nil {
+			t.Fatalf("TripToolCircuitBreaker() error = %v", err)
+		}
+	}
+	if breaker := find(); breaker.State != valueobject.CircuitBreakerStateOpen || breaker.Trips != 2 ||
+		!breaker.OpenedAt.Equal(openedAt.Add(time.Second)) || breaker.LastError != "503 service unavailable" {
+		t.Fatalf("breaker after two trips = %+v", breaker)
+	}
+
+	// the trial of the first trip does not close the second one
+	if err := repo.CloseToolCircuitBreaker(ctx, toolID, openedAt); err != nil {
+		t.Fatal(err)
+	}
+	if breaker := find(); breaker.State != valueobject.CircuitBreakerStateOpen {
+		t.Fatalf("breaker closed by the trial of an earlier trip: %+v", breaker)
+	}
+	if err := repo.CloseToolCircuitBreaker(ctx, toolID, openedAt.Add(time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	if breaker := find(); breaker.State != valueobject.CircuitBreakerStateClosed || breaker.Trips != 2 {
+		t.Fatalf("breaker after its trial succeeded = %+v, want closed after 2 trips", breaker)
+	}
+
+	resetAt := openedAt.Add(2 * time.Second)
+	if err := repo.ResetToolCircuitBreaker(ctx, toolID, resetAt); err != nil {
+		t.Fatalf("ResetToolCircuitBreaker() error = %v", err)
+	}
+	if breaker := find(); breaker.State != valueobject.CircuitBreakerStateClosed || breaker.Trips != 0 ||
+		!breaker.OpenedAt.IsZero() || breaker.LastError != "" || !breaker.ResetAt.Equal(resetAt) {
+		t.Fatalf("breaker after a reset = %+v", breaker)
+	}
+}
@@ -0,0 +1,72 @@
+package dto
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type ReadWebhookEndpointDTO struct {
+	ID          int       `json:"id"`
+	ClientID    int       `json:"client_id"`
+	URL         string    `json:"url"`
+	Description string    `json:"description"`
+	IsActive    bool      `json:"is_active"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+type CreateWebhookEndpointDTO struct {
+	URL         string `json:"url" binding:"required" example:"https://example.com/hooks/tool-requests"`
+	Description string `json:"description" example:"Tool request notifications"`
+	IsActive    bool   `json:"is_active" example:"true"`
+}
+
+type UpdateWebhookEndpointDTO struct {
+	URL         string `json:"url" binding:"required"`
+	Description string `json:"description"`
+	IsActive    bool   `json:"is_active"`
+}
+
+// ReadWebhookSecretDTO holds the secret signing the webhooks of a client.
+type ReadWebhookSecretDTO struct {
+	ClientID  int       `json:"client_id"`
+	Secret    string    `json:"secret"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type ReadWebhookDeliveryAttemptDTO struct {
+	Attempt    int       `json:"attempt"`
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+	StatusCode int       `json:"status_code,omitempty"`
+	Error      string    `json:"error,omitempty"`
+}
+
+type ReadWebhookDeliveryDTO struct {
+	ID                int                              `json:"id"`
+	ClientID          int                              `json:"client_id"`
+	ToolRequestID     int                              `json:"tool_request_id"`
+	WebhookEndpointID *int                             `json:"webhook_endpoint_id,omitempty"`
+	URL               string                           `json:"url"`
+	Event             string                           `json:"event"`
+	Status            string                           `json:"status"`
+	AttemptCount      int                              `json:"attempt_count"`
+	Attempts          []*ReadWebhookDeliveryAttemptDTO `json:"attempts"`
+	NextAttemptAt     *time.Time                       `json:"next_attempt_at,omitempty"`
+	CreatedAt         time.Time                        `json:"created_at"`
+	UpdatedAt         time.Time                        `json:"updated_at"`
+}
+
+// WebhookPayloadDTO is the body POSTed to webhook endpoints.
+//
+// The request carries the headers
+// X-Webhook-ID (delivery id), X-Webhook-Event, X-Webhook-Timestamp (unix seconds) and
+// X-Webhook-Signature: "sha256=" + hex(HMAC-SHA256(secret, timestamp + "." + body)).
+//
+// ToolRequest is the tool request (as returned by GET /v1/tool-requests/{id}) when it finished.
+type WebhookPayloadDTO struct {
+	Event       string          `json:"event"`
+	DeliveryID  int             `json:"delivery_id"`
+	CreatedAt   time.Time       `json:"created_at"`
+	ToolRequest json.RawMessage `json:"tool_request" swaggertype:"object"`
+}
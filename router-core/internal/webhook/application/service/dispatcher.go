@@ -0,0 +1,320 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"aigendrug.com/router-core/internal/config"
+	"aigendrug.com/router-core/internal/shared/utils"
+	"aigendrug.com/router-core/internal/webhook/application/dto"
+	"aigendrug.com/router-core/internal/webhook/domain"
+	"aigendrug.com/router-core/internal/webhook/domain/entity"
+	"aigendrug.com/router-core/internal/webhook/domain/valueobject"
+	"github.com/google/uuid"
+)
+
+// Default delivery settings.
+// Overridden by config (WEBHOOK_*).
+const (
+	DefaultWebhookWorkers      = 4
+	DefaultWebhookMaxAttempts  = 8
+	DefaultWebhookTimeout      = 10 * time.Second
+	DefaultWebhookBaseDelay    = 10 * time.Second
+	DefaultWebhookMaxDelay     = 1 * time.Hour
+	DefaultWebhookPollInterval = 2 * time.Second
+
+	// the body of the endpoint's response read before the connection is released, it is not kept
+	webhookResponseDrainLimit = 512
+)
+
+// Headers of a webhook request.
+const (
+	WebhookHeaderID        = "X-Webhook-ID"
+	WebhookHeaderEvent     = "X-Webhook-Event"
+	WebhookHeaderTimestamp = "X-Webhook-Timestamp"
+	WebhookHeaderSignature = "X-Webhook-Signature"
+)
+
+// WebhookDispatcher POSTs the pending webhook deliveries stored in the database.
+//
+// Deliveries are claimed with SELECT ... FOR UPDATE SKIP LOCKED, so every replica can run dispatchers.
+// A failed delivery is retried with exponential backoff until its attempts are used up.
+// A delivery whose dispatcher died is claimed again once its lease expired.
+type WebhookDispatcher interface {
+	// Start launches the workers. They stop when ctx is done.
+	Start(ctx context.Context)
+	// Notify wakes an idle worker of this replica up, e.g. right after a delivery is enqueued.
+	Notify()
+}
+
+type webhookDispatcher struct {
+	webhookRepo domain.WebhookRepository
+	httpClient  *http.Client
+
+	workerID     string
+	workers      int
+	maxAttempts  int
+	timeout      time.Duration
+	baseDelay    time.Duration
+	maxDelay     time.Duration
+	pollInterval time.Duration
+
+	wakeup chan struct{}
+}
+
+func NewWebhookDispatcher(
+	config *config.Config,
+	webhookRepo domain.WebhookRepository,
+) WebhookDispatcher {
+	d := &webhookDispatcher{
+		webhookRepo:  webhookRepo,
+		httpClient:   newWebhookHTTPClient(config != nil && config.Webhook.AllowPrivate),
+		workerID:     newWorkerID(),
+		workers:      DefaultWebhookWorkers,
+		maxAttempts:  DefaultWebhookMaxAttempts,
+		timeout:      DefaultWebhookTimeout,
+		baseDelay:    DefaultWebhookBaseDelay,
+		maxDelay:     DefaultWebhookMaxDelay,
+		pollInterval: DefaultWebhookPollInterval,
+	}
+
+	if config != nil {
+		if v := config.Webhook.Workers; v > 0 {
+			d.workers = v
+		}
+		if v := config.Webhook.MaxAttempts; v > 0 {
+			d.maxAttempts = v
+		}
+		if v := config.Webhook.TimeoutSeconds; v > 0 {
+			d.timeout = secondsToDuration(v)
+		}
+		if v := config.Webhook.BaseDelaySeconds; v > 0 {
+			d.baseDelay = secondsToDuration(v)
+		}
+		if v := config.Webhook.MaxDelaySeconds; v > 0 {
+			d.maxDelay = secondsToDuration(v)
+		}
+		if v := config.Webhook.PollIntervalSeconds; v > 0 {
+			d.pollInterval = secondsToDuration(v)
+		}
+	}
+
+	if d.maxDelay < d.baseDelay {
+		d.maxDelay = d.baseDelay
+	}
+
+	d.wakeup = make(chan struct{}, d.workers)
+
+	return d
+}
+
+func secondsToDuration(seconds float64) time.Duration {
+	return time.Duration(seconds * float64(time.Second))
+}
+
+func newWorkerID() string {
+	hostname, err := os.Hostname()
+	if err != nil || hostname == "" {
+		hostname = "router-core"
+	}
+	return fmt.Sprintf("%s-%s", hostname, uuid.NewString())
+}
+
+func (d *webhookDispatcher) Start(ctx context.Context) {
+	fmt.Printf("starting webhook dispatcher %s with %d workers\n", d.workerID, d.workers)
+
+	for i := 0; i < d.workers; i++ {
+		go d.runWorker(ctx)
+	}
+}
+
+func (d *webhookDispatcher) Notify() {
+	select {
+	case d.wakeup <- struct{}{}:
+	default:
+	}
+}
+
+func (d *webhookDispatcher) runWorker(ctx context.Context) {
+	ticker := time.NewTicker(d.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		for d.claimAndDeliver(ctx) {
+			if ctx.Err() != nil {
+				return
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-d.wakeup:
+		case <-ticker.C:
+		}
+	}
+}
+
+// claimAndDeliver makes a single attempt of a due delivery and reports whether one was claimed.
+func (d *webhookDispatcher) claimAndDeliver(ctx context.Context) bool {
+	// the lease only has to outlive a single attempt
+	lease := 2*d.timeout + 30*time.Second
+
+	claimCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	webhookDelivery, err := d.webhookRepo.ClaimWebhookDelivery(claimCtx, d.workerID, lease)
+	cancel()
+	if err != nil {
+		fmt.Printf("failed to claim webhook delivery: %v\n", err)
+		return false
+	}
+	if webhookDelivery == nil {
+		return false
+	}
+
+	d.deliver(ctx, webhookDelivery)
+
+	return true
+}
+
+func (d *webhookDispatcher) deliver(ctx context.Context, webhookDelivery *entity.WebhookDelivery) {
+	attempt := entity.WebhookDeliveryAttempt{
+		Attempt:   webhookDelivery.AttemptCount + 1,
+		StartedAt: time.Now(),
+	}
+
+	statusCode, err := d.post(ctx, webhookDelivery)
+	attempt.FinishedAt = time.Now()
+	attempt.StatusCode = statusCode
+	if err != nil {
+		attempt.Error = err.Error()
+	}
+
+	webhookDelivery.AttemptCount = attempt.Attempt
+	webhookDelivery.Attempts = append(webhookDelivery.Attempts, attempt)
+	webhookDelivery.NextAttemptAt = attempt.FinishedAt
+
+	switch {
+	case err == nil:
+		webhookDelivery.Status = valueobject.WebhookDeliveryStatusSuccess
+	case attempt.Attempt >= d.maxAttempts:
+		webhookDelivery.Status = valueobject.WebhookDeliveryStatusFailed
+		fmt.Printf("webhook delivery %d to %s failed after %d attempts: %v\n",
+			webhookDelivery.ID, webhookDelivery.URL, attempt.Attempt, err)
+	default:
+		webhookDelivery.Status = valueobject.WebhookDeliveryStatusPending
+		webhookDelivery.NextAttemptAt = attempt.FinishedAt.Add(d.delay(attempt.Attempt))
+	}
+
+	saveCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 30*time.Second)
+	defer cancel()
+
+	saved, saveErr := d.webhookRepo.UpdateClaimedWebhookDelivery(saveCtx, webhookDelivery)
+	if saveErr != nil {
+		fmt.Printf("failed to update webhook delivery %d: %v\n", webhookDelivery.ID, saveErr)
+		return
+	}
+	if !saved {
+		fmt.Printf("lease of webhook delivery %d was lost\n", webhookDelivery.ID)
+	}
+}
+
+// delay returns the backoff after the given attempt, with up to 20% jitter.
+func (d *webhookDispatcher) delay(attempt int) time.Duration {
+	delay := float64(d.baseDelay) * math.Pow(2, float64(attempt-1))
+	if delay > float64(d.maxDelay) {
+		delay = float64(d.maxDelay)
+	}
+	delay -= delay * 0.2 * rand.Float64()
+	return time.Duration(delay)
+}
+
+// post sends the signed delivery and returns the status code of the response (zero when there was none).
+func (d *webhookDispatcher) post(ctx context.Context, webhookDelivery *entity.WebhookDelivery) (int, error) {
+	secret, err := d.webhookRepo.GetOrCreateWebhookSecret(ctx, webhookDelivery.ClientID, newWebhookSecret())
+	if err != nil {
+		return 0, fmt.Errorf("failed to get webhook secret: %w", err)
+	}
+
+	body, err := json.Marshal(dto.WebhookPayloadDTO{
+		Event:       webhookDelivery.Event.String(),
+		DeliveryID:  webhookDelivery.ID,
+		CreatedAt:   webhookDelivery.CreatedAt,
+		ToolRequest: json.RawMessage(webhookDelivery.Payload),
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to marshal webhook payload: %w", err)
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+
+	postCtx, cancel := context.WithTimeout(ctx, d.timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(postCtx, http.MethodPost, webhookDelivery.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(WebhookHeaderID, strconv.Itoa(webhookDelivery.ID))
+	req.Header.Set(WebhookHeaderEvent, webhookDelivery.Event.String())
+	req.Header.Set(WebhookHeaderTimestamp, timestamp)
+	req.Header.Set(WebhookHeaderSignature, SignWebhookPayload(secret.Secret, timestamp, body))
+
+	resp, err := d.httpClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	io.Copy(io.Discard, io.LimitReader(resp.Body, webhookResponseDrainLimit))
+
+	// the body of the endpoint is not kept: the attempts are readable by the client which set the URL
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("endpoint responded %d", resp.StatusCode)
+	}
+
+	return resp.StatusCode, nil
+}
+
+// newWebhookHTTPClient returns the client posting the deliveries. Webhook URLs are set by clients, so unless
+// allowPrivate (WEBHOOK_ALLOW_PRIVATE) is set, connections to loopback, private and link-local addresses
+// (e.g. other services of the router network, the cloud metadata endpoint) are refused when dialing.
+func newWebhookHTTPClient(allowPrivate bool) *http.Client {
+	if allowPrivate {
+		return &http.Client{}
+	}
+
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+		Control:   utils.PublicDialControl,
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	// a proxy would be dialed instead of the endpoint, defeating the check
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &http.Client{Transport: transport}
+}
+
+// SignWebhookPayload returns the X-Webhook-Signature of body sent at timestamp.
+// Receivers recompute it with their secret and compare it in constant time.
+func SignWebhookPayload(secret string, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,106 @@
+package service
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"aigendrug.com/router-core/internal/shared/utils"
+)
+
+func TestSignWebhookPayload(t *testing.T) {
+	tests := []struct {
+		name      string
+		secret    string
+		timestamp string
+		body      string
+		want      string
+	}{
+		{
+			name:      "delivery",
+			secret:    "whsec_test",
+			timestamp: "1700000000",
+			body:      `{"event":"tool_request.finished","tool_request_id":42}`,
+			want:      "sha256=e307a5c880515c22fee258ef7f244c58b49f19b831ec2fbb3acbf385bd6884a9",
+		},
+		{
+			name:      "empty secret and body",
+			secret:    "",
+			timestamp: "0",
+			body:      "",
+			want:      "sha256=b849d5a581847b281957065739df36df2463d1977ea8d6e1e4e6cf33fadc68c3",
+		},
+		{
+			name:      "another secret and timestamp",
+			secret:    "s3cr3t",
+			timestamp: "1700000001",
+			body:      `{"a":1}`,
+			want:      "sha256=6b1ba38b696390c3f71d30bf27a0409a8c7a619c2a003287fe6cf0cf94a9aadf",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SignWebhookPayload(tt.secret, tt.timestamp, []byte(tt.body)); got != tt.want {
+				t.Fatalf("SignWebhookPayload() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSignWebhookPayloadCoversEveryInput(t *testing.T) {
+	base := SignWebhookPayload("secret", "1700000000", []byte(`{"a":1}`))
+
+	tests := []struct {
+		name      string
+		secret    string
+		timestamp string
+		body      string
+	}{
+		{name: "secret", secret: "secret2", timestamp: "1700000000", body: `{"a":1}`},
+		{name: "timestamp", secret: "secret", timestamp: "1700000001", body: `{"a":1}`},
+		{name: "body", secret: "secret", timestamp: "1700000000", body: `{"a":2}`},
+		// the separator keeps the timestamp and the body apart
+		{name: "timestamp moved into the body", secret: "secret", timestamp: "170000000", body: `0{"a":1}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if SignWebhookPayload(tt.secret, tt.timestamp, []byte(tt.body)) == base {
+				t.Fatalf("changing the %s kept the signature", tt.name)
+			}
+		})
+	}
+}
+
+func TestWebhookHTTPClientRefusesPrivateAddresses(t *testing.T) {
+	endpoint := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer endpoint.Close()
+
+	tests := []struct {
+		name         string
+		allowPrivate bool
+		wantRefused  bool
+	}{
+		{name: "private addresses refused", wantRefused: true},
+		{name: "private addresses allowed", allowPrivate: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := newWebhookHTTPClient(tt.allowPrivate).Post(endpoint.URL, "application/json", nil)
+			if resp != nil {
+				resp.Body.Close()
+			}
+			if refused := errors.Is(err, utils.ErrPrivateAddress); refused != tt.wantRefused {
+				t.Fatalf("POST to %s error = %v, want refused %v", endpoint.URL, err, tt.wantRefused)
+			}
+			if !tt.wantRefused && err != nil {
+				t.Fatalf("POST to %s error = %v", endpoint.URL, err)
+			}
+		})
+	}
+}
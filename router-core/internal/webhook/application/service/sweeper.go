@@ -0,0 +1,92 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"aigendrug.com/router-core/internal/webhook/domain"
+)
+
+// Delivery sweeper settings.
+const (
+	DefaultWebhookSweepInterval = 1 * time.Minute
+
+	// a tool request finished more recently may still be enqueued by the notifier hook of its replica
+	webhookSweepGrace = 1 * time.Minute
+	// tool requests finished earlier are not swept
+	webhookSweepLookback = 24 * time.Hour
+	// tool requests enqueued per sweep
+	webhookSweepBatchSize = 500
+)
+
+// WebhookDeliverySweeper enqueues the deliveries of the finished tool requests which have none.
+//
+// Deliveries are enqueued by the notifier hook of the replica finishing the tool request. They are lost when the
+// replica stops (or the database is unreachable) in between, the sweeper finds these tool requests and enqueues
+// them again. EnqueueToolRequestDeliveries is idempotent, so every replica can run a sweeper.
+type WebhookDeliverySweeper interface {
+	// Start launches the sweeper. It stops when ctx is done.
+	Start(ctx context.Context)
+}
+
+type webhookDeliverySweeper struct {
+	webhookRepo    domain.WebhookRepository
+	webhookService WebhookService
+
+	interval time.Duration
+}
+
+func NewWebhookDeliverySweeper(
+	webhookRepo domain.WebhookRepository,
+	webhookService WebhookService,
+) WebhookDeliverySweeper {
+	return &webhookDeliverySweeper{
+		webhookRepo:    webhookRepo,
+		webhookService: webhookService,
+		interval:       DefaultWebhookSweepInterval,
+	}
+}
+
+func (s *webhookDeliverySweeper) Start(ctx context.Context) {
+	fmt.Printf("starting webhook delivery sweeper (interval %v)\n", s.interval)
+
+	go s.run(ctx)
+}
+
+func (s *webhookDeliverySweeper) run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if err := s.sweep(ctx, time.Now()); err != nil {
+			fmt.Printf("failed to sweep webhook deliveries: %v\n", err)
+		}
+	}
+}
+
+// sweep enqueues the deliveries of a batch of the tool requests finished within the lookback, up to the grace
+// before now, which have none. The rest is enqueued by the next sweeps.
+func (s *webhookDeliverySweeper) sweep(ctx context.Context, now time.Time) error {
+	toolRequestIDs, err := s.webhookRepo.FindUndeliveredToolRequestIDs(
+		ctx, now.Add(-webhookSweepLookback), now.Add(-webhookSweepGrace), webhookSweepBatchSize,
+	)
+	if err != nil {
+		return err
+	}
+
+	for _, toolRequestID := range toolRequestIDs {
+		if ctx.Err() != nil {
+			return nil
+		}
+		fmt.Printf("enqueuing the missing webhook deliveries of tool request %d\n", toolRequestID)
+		s.webhookService.EnqueueToolRequestDeliveries(ctx, toolRequestID)
+	}
+	return nil
+}
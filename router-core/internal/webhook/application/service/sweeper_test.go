@@ -0,0 +1,76 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"aigendrug.com/router-core/internal/webhook/domain"
+)
+
+type sweepWebhookRepository struct {
+	domain.WebhookRepository
+
+	toolRequestIDs []int
+	err            error
+
+	finishedAfter  time.Time
+	finishedBefore time.Time
+	limit          int
+}
+
+func (r *sweepWebhookRepository) FindUndeliveredToolRequestIDs(
+	ctx context.Context, finishedAfter, finishedBefore time.Time, limit int,
+) ([]int, error) {
+	r.finishedAfter, r.finishedBefore, r.limit = finishedAfter, finishedBefore, limit
+	return r.toolRequestIDs, r.err
+}
+
+type enqueueWebhookService struct {
+	WebhookService
+
+	enqueued []int
+}
+
+func (s *enqueueWebhookService) EnqueueToolRequestDeliveries(ctx context.Context, toolRequestID int) {
+	s.enqueued = append(s.enqueued, toolRequestID)
+}
+
+func TestWebhookDeliverySweeperSweep(t *testing.T) {
+	now := time.Date(2026, 1, 2, 12, 0, 0, 0, time.UTC)
+
+	t.Run("enqueues the undelivered tool requests", func(t *testing.T) {
+		repo := &sweepWebhookRepository{toolRequestIDs: []int{3, 7}}
+		service := &enqueueWebhookService{}
+		sweeper := NewWebhookDeliverySweeper(repo, service).(*webhookDeliverySweeper)
+
+		if err := sweeper.sweep(context.Background(), now); err != nil {
+			t.Fatalf("sweep() = %v", err)
+		}
+		if !slices.Equal(service.enqueued, []int{3, 7}) {
+			t.Fatalf("enqueued tool requests %v, want [3 7]", service.enqueued)
+		}
+		// tool requests just finished are left to the notifier hook
+		if !repo.finishedBefore.Equal(now.Add(-webhookSweepGrace)) || !repo.finishedAfter.Equal(now.Add(-webhookSweepLookback)) {
+			t.Fatalf("swept tool requests finished from %v to %v", repo.finishedAfter, repo.finishedBefore)
+		}
+		if repo.limit != webhookSweepBatchSize {
+			t.Fatalf("swept %d tool requests, want %d", repo.limit, webhookSweepBatchSize)
+		}
+	})
+
+	t.Run("reports a failed query", func(t *testing.T) {
+		repo := &sweepWebhookRepository{err: errors.New("connection refused")}
+		service := &enqueueWebhookService{}
+		sweeper := NewWebhookDeliverySweeper(repo, service).(*webhookDeliverySweeper)
+
+		if err := sweeper.sweep(context.Background(), now); err == nil {
+			t.Fatal("sweep() succeeded with the query failing")
+		}
+		if len(service.enqueued) != 0 {
+			t.Fatalf("enqueued tool requests %v, want none", service.enqueued)
+		}
+	})
+}
@@ -0,0 +1,294 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"aigendrug.com/router-core/internal/shared/database/postgres"
+	"aigendrug.com/router-core/internal/shared/utils"
+	tooldomain "aigendrug.com/router-core/internal/tool/domain"
+	"aigendrug.com/router-core/internal/webhook/application/dto"
+	"aigendrug.com/router-core/internal/webhook/domain"
+	"aigendrug.com/router-core/internal/webhook/domain/entity"
+	"aigendrug.com/router-core/internal/webhook/domain/valueobject"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// DefaultWebhookDeliveryListLimit bounds the deliveries listed for a client.
+const DefaultWebhookDeliveryListLimit = 100
+
+var (
+	ErrWebhookEndpointNotFound  = errors.New("webhook endpoint not found")
+	ErrWebhookEndpointForbidden = errors.New("you don't have permission to manage this webhook endpoint")
+	ErrInvalidWebhookURL        = errors.New("invalid webhook url")
+)
+
+type WebhookService interface {
+	GetWebhookEndpointsByClientID(ctx context.Context, clientID int) ([]*dto.ReadWebhookEndpointDTO, error)
+	CreateWebhookEndpoint(ctx context.Context, clientID int, webhookEndpoint *dto.CreateWebhookEndpointDTO) (*dto.ReadWebhookEndpointDTO, error)
+	UpdateWebhookEndpoint(ctx context.Context, clientID int, isAdmin bool, id int, webhookEndpoint *dto.UpdateWebhookEndpointDTO) (*dto.ReadWebhookEndpointDTO, error)
+	DeleteWebhookEndpoint(ctx context.Context, clientID int, isAdmin bool, id int) error
+
+	// GetWebhookSecret returns the secret signing the webhooks of the client, creating it on first use.
+	GetWebhookSecret(ctx context.Context, clientID int) (*dto.ReadWebhookSecretDTO, error)
+	RotateWebhookSecret(ctx context.Context, clientID int) (*dto.ReadWebhookSecretDTO, error)
+
+	GetWebhookDeliveriesByClientID(ctx context.Context, clientID int) ([]*dto.ReadWebhookDeliveryDTO, error)
+	GetWebhookDeliveriesByToolRequestID(ctx context.Context, clientID int, isAdmin bool, toolRequestID int) ([]*dto.ReadWebhookDeliveryDTO, error)
+
+	// EnqueueToolRequestDeliveries creates a delivery of the finished tool request for every active
+	// endpoint of its client and for the webhook URL given with the ExecuteTool call.
+	// A tool request is delivered at most once per URL, so calling it again is harmless.
+	// Its deliveries are created after the tool request finished: the ones lost meanwhile (e.g. the replica
+	// stopped) are created by the WebhookDeliverySweeper.
+	EnqueueToolRequestDeliveries(ctx context.Context, toolRequestID int)
+}
+
+type webhookService struct {
+	db          *pgxpool.Pool
+	webhookRepo domain.WebhookRepository
+	toolRepo    tooldomain.ToolRepository
+	dispatcher  WebhookDispatcher
+}
+
+func NewWebhookService(
+	dbPool *pgxpool.Pool,
+	webhookRepo domain.WebhookRepository,
+	toolRepo tooldomain.ToolRepository,
+	dispatcher WebhookDispatcher,
+) WebhookService {
+	return &webhookService{
+		db:          dbPool,
+		webhookRepo: webhookRepo,
+		toolRepo:    toolRepo,
+		dispatcher:  dispatcher,
+	}
+}
+
+func newWebhookSecret() string {
+	return "whsec-" + utils.GenerateRandomString(40)
+}
+
+func (s *webhookService) GetWebhookEndpointsByClientID(
+	ctx context.Context, clientID int,
+) ([]*dto.ReadWebhookEndpointDTO, error) {
+	webhookEndpoints, err := s.webhookRepo.FindAllWebhookEndpointsByClientID(ctx, clientID)
+	if err != nil {
+		return nil, err
+	}
+
+	webhookEndpointsDTO := make([]*dto.ReadWebhookEndpointDTO, len(webhookEndpoints))
+	for i, webhookEndpoint := range webhookEndpoints {
+		webhookEndpointsDTO[i] = webhookEndpoint.ToDTO()
+	}
+	return webhookEndpointsDTO, nil
+}
+
+func (s *webhookService) CreateWebhookEndpoint(
+	ctx context.Context, clientID int, webhookEndpoint *dto.CreateWebhookEndpointDTO,
+) (*dto.ReadWebhookEndpointDTO, error) {
+	if err := utils.ValidateHTTPURL(webhookEndpoint.URL); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidWebhookURL, err)
+	}
+
+	createdWebhookEndpoint, err := s.webhookRepo.CreateWebhookEndpoint(ctx, &entity.WebhookEndpoint{
+		ClientID:    clientID,
+		URL:         webhookEndpoint.URL,
+		Description: webhookEndpoint.Description,
+		IsActive:    webhookEndpoint.IsActive,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// make sure the client can verify the first delivery
+	if _, err := s.webhookRepo.GetOrCreateWebhookSecret(ctx, clientID, newWebhookSecret()); err != nil {
+		return nil, err
+	}
+
+	return createdWebhookEndpoint.ToDTO(), nil
+}
+
+func (s *webhookService) findOwnedWebhookEndpoint(
+	ctx context.Context, clientID int, isAdmin bool, id int,
+) (*entity.WebhookEndpoint, error) {
+	webhookEndpoint, err := s.webhookRepo.FindWebhookEndpointByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrWebhookEndpointNotFound
+		}
+		return nil, err
+	}
+
+	if !isAdmin && webhookEndpoint.ClientID != clientID {
+		return nil, ErrWebhookEndpointForbidden
+	}
+
+	return webhookEndpoint, nil
+}
+
+func (s *webhookService) UpdateWebhookEndpoint(
+	ctx context.Context, clientID int, isAdmin bool, id int, webhookEndpoint *dto.UpdateWebhookEndpointDTO,
+) (*dto.ReadWebhookEndpointDTO, error) {
+	if err := utils.ValidateHTTPURL(webhookEndpoint.URL); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidWebhookURL, err)
+	}
+
+	existing, err := s.findOwnedWebhookEndpoint(ctx, clientID, isAdmin, id)
+	if err != nil {
+		return nil, err
+	}
+
+	existing.URL = webhookEndpoint.URL
+	existing.Description = webhookEndpoint.Description
+	existing.IsActive = webhookEndpoint.IsActive
+
+	if err := s.webhookRepo.UpdateWebhookEndpoint(ctx, existing); err != nil {
+		return nil, err
+	}
+
+	updated, err := s.webhookRepo.FindWebhookEndpointByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return updated.ToDTO(), nil
+}
+
+func (s *webhookService) DeleteWebhookEndpoint(ctx context.Context, clientID int, isAdmin bool, id int) error {
+	if _, err := s.findOwnedWebhookEndpoint(ctx, clientID, isAdmin, id); err != nil {
+		return err
+	}
+	return s.webhookRepo.DeleteWebhookEndpoint(ctx, id)
+}
+
+func (s *webhookService) GetWebhookSecret(ctx context.Context, clientID int) (*dto.ReadWebhookSecretDTO, error) {
+	webhookSecret, err := s.webhookRepo.GetOrCreateWebhookSecret(ctx, clientID, newWebhookSecret())
+	if err != nil {
+		return nil, err
+	}
+	return webhookSecret.ToDTO(), nil
+}
+
+func (s *webhookService) RotateWebhookSecret(ctx context.Context, clientID int) (*dto.ReadWebhookSecretDTO, error) {
+	webhookSecret, err := s.webhookRepo.UpsertWebhookSecret(ctx, clientID, newWebhookSecret())
+	if err != nil {
+		return nil, err
+	}
+	return webhookSecret.ToDTO(), nil
+}
+
+func (s *webhookService) GetWebhookDeliveriesByClientID(
+	ctx context.Context, clientID int,
+) ([]*dto.ReadWebhookDeliveryDTO, error) {
+	webhookDeliveries, err := s.webhookRepo.FindAllWebhookDeliveriesByClientID(
+		ctx, clientID, DefaultWebhookDeliveryListLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	webhookDeliveriesDTO := make([]*dto.ReadWebhookDeliveryDTO, len(webhookDeliveries))
+	for i, webhookDelivery := range webhookDeliveries {
+		webhookDeliveriesDTO[i] = webhookDelivery.ToDTO()
+	}
+	return webhookDeliveriesDTO, nil
+}
+
+func (s *webhookService) GetWebhookDeliveriesByToolRequestID(
+	ctx context.Context, clientID int, isAdmin bool, toolRequestID int,
+) ([]*dto.ReadWebhookDeliveryDTO, error) {
+	webhookDeliveries, err := s.webhookRepo.FindAllWebhookDeliveriesByToolRequestID(ctx, toolRequestID)
+	if err != nil {
+		return nil, err
+	}
+
+	webhookDeliveriesDTO := make([]*dto.ReadWebhookDeliveryDTO, 0, len(webhookDeliveries))
+	for _, webhookDelivery := range webhookDeliveries {
+		if !isAdmin && webhookDelivery.ClientID != clientID {
+			continue
+		}
+		webhookDeliveriesDTO = append(webhookDeliveriesDTO, webhookDelivery.ToDTO())
+	}
+	return webhookDeliveriesDTO, nil
+}
+
+func (s *webhookService) EnqueueToolRequestDeliveries(ctx context.Context, toolRequestID int) {
+	if err := s.enqueueToolRequestDeliveries(ctx, toolRequestID); err != nil {
+		fmt.Printf("failed to enqueue webhook deliveries of tool request %d: %v\n", toolRequestID, err)
+	}
+}
+
+func (s *webhookService) enqueueToolRequestDeliveries(ctx context.Context, toolRequestID int) error {
+	toolRequest, err := s.toolRepo.FindToolRequestByID(ctx, toolRequestID)
+	if err != nil {
+		return err
+	}
+	if !toolRequest.Status.IsTerminal() {
+		return nil
+	}
+
+	type target struct {
+		url               string
+		webhookEndpointID *int
+	}
+	var targets []target
+
+	if url := toolRequest.RequestData.WebhookURL; url != "" {
+		targets = append(targets, target{url: url})
+	}
+
+	webhookEndpoints, err := s.webhookRepo.FindAllWebhookEndpointsByClientID(ctx, toolRequest.ClientID)
+	if err != nil {
+		return err
+	}
+	for _, webhookEndpoint := range webhookEndpoints {
+		if webhookEndpoint.IsActive {
+			targets = append(targets, target{url: webhookEndpoint.URL, webhookEndpointID: &webhookEndpoint.ID})
+		}
+	}
+
+	if len(targets) == 0 {
+		return nil
+	}
+
+	payload, err := json.Marshal(toolRequest.ToDTO())
+	if err != nil {
+		return err
+	}
+
+	// all or none of the deliveries are created, the sweeper enqueues the tool request again when none was
+	enqueued, err := postgres.WithTxResult(ctx, s.db, func(tx pgx.Tx) (bool, error) {
+		txRepo := s.webhookRepo.WithTx(ctx, tx)
+
+		enqueued := false
+		for _, t := range targets {
+			created, err := txRepo.CreateWebhookDelivery(ctx, &entity.WebhookDelivery{
+				ClientID:          toolRequest.ClientID,
+				ToolRequestID:     toolRequest.ID,
+				WebhookEndpointID: t.webhookEndpointID,
+				URL:               t.url,
+				Event:             valueobject.WebhookEventToolRequestFinished,
+				Payload:           string(payload),
+				Status:            valueobject.WebhookDeliveryStatusPending,
+			})
+			if err != nil {
+				return false, err
+			}
+			if created != nil {
+				enqueued = true
+			}
+		}
+		return enqueued, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if enqueued {
+		s.dispatcher.Notify()
+	}
+	return nil
+}
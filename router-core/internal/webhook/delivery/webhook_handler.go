@@ -0,0 +1,273 @@
+package delivery
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	shared_types "aigendrug.com/router-core/internal/shared/types"
+	"aigendrug.com/router-core/internal/webhook/application/dto"
+	"aigendrug.com/router-core/internal/webhook/application/service"
+	"github.com/gin-gonic/gin"
+)
+
+type WebhookHandler struct {
+	webhookService service.WebhookService
+}
+
+func NewWebhookHandler(webhookService service.WebhookService) *WebhookHandler {
+	return &WebhookHandler{webhookService: webhookService}
+}
+
+var _ dto.WebhookPayloadDTO // for swagger
+
+func webhookErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, service.ErrWebhookEndpointNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, service.ErrWebhookEndpointForbidden):
+		return http.StatusForbidden
+	case errors.Is(err, service.ErrInvalidWebhookURL):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// GetWebhookEndpointsForClient godoc
+// @Summary Get webhook endpoints of the current client
+// @Description Retrieves the webhook endpoints notified when a tool request of the client finishes
+// @Tags webhook
+// @Produce json
+// @Success 200 {array} dto.ReadWebhookEndpointDTO
+// @Failure 500 {object} shared_types.HttpErrorResponse
+// @Router /v1/webhooks [get]
+func (h *WebhookHandler) GetWebhookEndpointsForClient(c *gin.Context) {
+	webhookEndpoints, err := h.webhookService.GetWebhookEndpointsByClientID(c.Request.Context(), c.GetInt("clientID"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, shared_types.HttpErrorResponse{Msg: err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, webhookEndpoints)
+}
+
+// GetWebhookEndpointsByClientID godoc
+// @Summary Get webhook endpoints by client ID
+// @Description Retrieves the webhook endpoints of a client
+// @Tags webhook
+// @Produce json
+// @Param client_id path int true "Client ID"
+// @Success 200 {array} dto.ReadWebhookEndpointDTO
+// @Failure 400 {object} shared_types.HttpErrorResponse
+// @Failure 500 {object} shared_types.HttpErrorResponse
+// @Router /v1/webhooks/client/{client_id} [get]
+func (h *WebhookHandler) GetWebhookEndpointsByClientID(c *gin.Context) {
+	clientID, err := strconv.Atoi(c.Param("client_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, shared_types.HttpErrorResponse{Msg: "Invalid client ID"})
+		return
+	}
+
+	webhookEndpoints, err := h.webhookService.GetWebhookEndpointsByClientID(c.Request.Context(), clientID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, shared_types.HttpErrorResponse{Msg: err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, webhookEndpoints)
+}
+
+// CreateWebhookEndpoint godoc
+// @Summary Register a webhook endpoint
+// @Description Registers a URL receiving a signed POST whenever a tool request of the client reaches success, failed or cancelled
+// @Tags webhook
+// @Accept json
+// @Produce json
+// @Param webhook body dto.CreateWebhookEndpointDTO true "Webhook endpoint"
+// @Success 201 {object} dto.ReadWebhookEndpointDTO
+// @Failure 400 {object} shared_types.HttpErrorResponse
+// @Failure 500 {object} shared_types.HttpErrorResponse
+// @Router /v1/webhooks [post]
+func (h *WebhookHandler) CreateWebhookEndpoint(c *gin.Context) {
+	var webhookEndpoint dto.CreateWebhookEndpointDTO
+	if err := c.ShouldBindJSON(&webhookEndpoint); err != nil {
+		c.JSON(http.StatusBadRequest, shared_types.HttpErrorResponse{Msg: err.Error()})
+		return
+	}
+
+	createdWebhookEndpoint, err := h.webhookService.CreateWebhookEndpoint(
+		c.Request.Context(), c.GetInt("clientID"), &webhookEndpoint,
+	)
+	if err != nil {
+		c.JSON(webhookErrorStatus(err), shared_types.HttpErrorResponse{Msg: err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, createdWebhookEndpoint)
+}
+
+// UpdateWebhookEndpoint godoc
+// @Summary Update a webhook endpoint
+// @Description Updates a webhook endpoint of the client
+// @Tags webhook
+// @Accept json
+// @Produce json
+// @Param id path int true "Webhook endpoint ID"
+// @Param webhook body dto.UpdateWebhookEndpointDTO true "Webhook endpoint"
+// @Success 200 {object} dto.ReadWebhookEndpointDTO
+// @Failure 400 {object} shared_types.HttpErrorResponse
+// @Failure 403 {object} shared_types.HttpErrorResponse
+// @Failure 404 {object} shared_types.HttpErrorResponse
+// @Failure 500 {object} shared_types.HttpErrorResponse
+// @Router /v1/webhooks/{id} [put]
+func (h *WebhookHandler) UpdateWebhookEndpoint(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, shared_types.HttpErrorResponse{Msg: "Invalid webhook endpoint ID"})
+		return
+	}
+
+	var webhookEndpoint dto.UpdateWebhookEndpointDTO
+	if err := c.ShouldBindJSON(&webhookEndpoint); err != nil {
+		c.JSON(http.StatusBadRequest, shared_types.HttpErrorResponse{Msg: err.Error()})
+		return
+	}
+
+	updatedWebhookEndpoint, err := h.webhookService.UpdateWebhookEndpoint(
+		c.Request.Context(), c.GetInt("clientID"), c.GetBool("isAdmin"), id, &webhookEndpoint,
+	)
+	if err != nil {
+		c.JSON(webhookErrorStatus(err), shared_types.HttpErrorResponse{Msg: err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, updatedWebhookEndpoint)
+}
+
+// DeleteWebhookEndpoint godoc
+// @Summary Delete a webhook endpoint
+// @Description Deletes a webhook endpoint of the client. Its past deliveries are kept.
+// @Tags webhook
+// @Produce json
+// @Param id path int true "Webhook endpoint ID"
+// @Success 204 "No Content"
+// @Failure 400 {object} shared_types.HttpErrorResponse
+// @Failure 403 {object} shared_types.HttpErrorResponse
+// @Failure 404 {object} shared_types.HttpErrorResponse
+// @Failure 500 {object} shared_types.HttpErrorResponse
+// @Router /v1/webhooks/{id} [delete]
+func (h *WebhookHandler) DeleteWebhookEndpoint(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, shared_types.HttpErrorResponse{Msg: "Invalid webhook endpoint ID"})
+		return
+	}
+
+	if err := h.webhookService.DeleteWebhookEndpoint(
+		c.Request.Context(), c.GetInt("clientID"), c.GetBool("isAdmin"), id,
+	); err != nil {
+		c.JSON(webhookErrorStatus(err), shared_types.HttpErrorResponse{Msg: err.Error()})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// GetWebhookSecret godoc
+// @Summary Get the webhook secret
+// @Description Retrieves the secret signing the webhooks of the client (X-Webhook-Signature), creating it on first use
+// @Tags webhook
+// @Produce json
+// @Success 200 {object} dto.ReadWebhookSecretDTO
+// @Failure 500 {object} shared_types.HttpErrorResponse
+// @Router /v1/webhooks/secret [get]
+func (h *WebhookHandler) GetWebhookSecret(c *gin.Context) {
+	webhookSecret, err := h.webhookService.GetWebhookSecret(c.Request.Context(), c.GetInt("clientID"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, shared_types.HttpErrorResponse{Msg: err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, webhookSecret)
+}
+
+// RotateWebhookSecret godoc
+// @Summary Rotate the webhook secret
+// @Description Replaces the secret signing the webhooks of the client. Deliveries sent afterwards are signed with the new secret.
+// @Tags webhook
+// @Produce json
+// @Success 200 {object} dto.ReadWebhookSecretDTO
+// @Failure 500 {object} shared_types.HttpErrorResponse
+// @Router /v1/webhooks/secret/rotate [post]
+func (h *WebhookHandler) RotateWebhookSecret(c *gin.Context) {
+	webhookSecret, err := h.webhookService.RotateWebhookSecret(c.Request.Context(), c.GetInt("clientID"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, shared_types.HttpErrorResponse{Msg: err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, webhookSecret)
+}
+
+// GetWebhookDeliveriesForClient godoc
+// @Summary Get webhook deliveries of the current client
+// @Description Retrieves the latest webhook deliveries of the client with their attempts
+// @Tags webhook
+// @Produce json
+// @Success 200 {array} dto.ReadWebhookDeliveryDTO
+// @Failure 500 {object} shared_types.HttpErrorResponse
+// @Router /v1/webhooks/deliveries [get]
+func (h *WebhookHandler) GetWebhookDeliveriesForClient(c *gin.Context) {
+	webhookDeliveries, err := h.webhookService.GetWebhookDeliveriesByClientID(c.Request.Context(), c.GetInt("clientID"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, shared_types.HttpErrorResponse{Msg: err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, webhookDeliveries)
+}
+
+// GetWebhookDeliveriesByClientID godoc
+// @Summary Get webhook deliveries by client ID
+// @Description Retrieves the latest webhook deliveries of a client with their attempts
+// @Tags webhook
+// @Produce json
+// @Param client_id path int true "Client ID"
+// @Success 200 {array} dto.ReadWebhookDeliveryDTO
+// @Failure 400 {object} shared_types.HttpErrorResponse
+// @Failure 500 {object} shared_types.HttpErrorResponse
+// @Router /v1/webhooks/deliveries/client/{client_id} [get]
+func (h *WebhookHandler) GetWebhookDeliveriesByClientID(c *gin.Context) {
+	clientID, err := strconv.Atoi(c.Param("client_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, shared_types.HttpErrorResponse{Msg: "Invalid client ID"})
+		return
+	}
+
+	webhookDeliveries, err := h.webhookService.GetWebhookDeliveriesByClientID(c.Request.Context(), clientID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, shared_types.HttpErrorResponse{Msg: err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, webhookDeliveries)
+}
+
+// GetWebhookDeliveriesByToolRequestID godoc
+// @Summary Get webhook deliveries of a tool request
+// @Description Retrieves the webhook deliveries of a tool request of the client with their attempts
+// @Tags webhook
+// @Produce json
+// @Param tool_request_id path int true "Tool request ID"
+// @Success 200 {array} dto.ReadWebhookDeliveryDTO
+// @Failure 400 {object} shared_types.HttpErrorResponse
+// @Failure 500 {object} shared_types.HttpErrorResponse
+// @Router /v1/webhooks/deliveries/tool-request/{tool_request_id} [get]
+func (h *WebhookHandler) GetWebhookDeliveriesByToolRequestID(c *gin.Context) {
+	toolRequestID, err := strconv.Atoi(c.Param("tool_request_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, shared_types.HttpErrorResponse{Msg: "Invalid tool request ID"})
+		return
+	}
+
+	webhookDeliveries, err := h.webhookService.GetWebhookDeliveriesByToolRequestID(
+		c.Request.Context(), c.GetInt("clientID"), c.GetBool("isAdmin"), toolRequestID,
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, shared_types.HttpErrorResponse{Msg: err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, webhookDeliveries)
+}
@@ -0,0 +1,37 @@
+package delivery
+
+import (
+	authd "aigendrug.com/router-core/internal/auth/delivery"
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+func SetupWebhookRoutes(
+	router *gin.Engine,
+	db *pgxpool.Pool,
+	rateLimits *authd.RateLimits,
+	webhookHandler *WebhookHandler,
+) {
+	webhookRoutes := router.Group("/v1/webhooks")
+	{
+		webhookDefaultRoutes := webhookRoutes.Group("", authd.DefaultAuthMiddleWare(db), rateLimits.Read())
+		{
+			webhookDefaultRoutes.GET("", webhookHandler.GetWebhookEndpointsForClient)
+			webhookDefaultRoutes.POST("", webhookHandler.CreateWebhookEndpoint)
+			webhookDefaultRoutes.PUT("/:id", webhookHandler.UpdateWebhookEndpoint)
+			webhookDefaultRoutes.DELETE("/:id", webhookHandler.DeleteWebhookEndpoint)
+
+			webhookDefaultRoutes.GET("/secret", webhookHandler.GetWebhookSecret)
+			webhookDefaultRoutes.POST("/secret/rotate", webhookHandler.RotateWebhookSecret)
+
+			webhookDefaultRoutes.GET("/deliveries", webhookHandler.GetWebhookDeliveriesForClient)
+			webhookDefaultRoutes.GET("/deliveries/tool-request/:tool_request_id", webhookHandler.GetWebhookDeliveriesByToolRequestID)
+		}
+
+		webhookAdminRoutes := webhookRoutes.Group("", authd.AdminAuthMiddleWare(db))
+		{
+			webhookAdminRoutes.GET("/client/:client_id", webhookHandler.GetWebhookEndpointsByClientID)
+			webhookAdminRoutes.GET("/deliveries/client/:client_id", webhookHandler.GetWebhookDeliveriesByClientID)
+		}
+	}
+}
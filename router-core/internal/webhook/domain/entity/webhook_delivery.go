@@ -0,0 +1,155 @@
+package entity
+
+import (
+	"encoding/json"
+	"time"
+
+	"aigendrug.com/router-core/internal/webhook/application/dto"
+	"aigendrug.com/router-core/internal/webhook/domain/valueobject"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// WebhookDeliveryAttempt records a single POST of a webhook delivery.
+type WebhookDeliveryAttempt struct {
+	Attempt    int       `json:"attempt"`
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+	StatusCode int       `json:"status_code,omitempty"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// WebhookDelivery is the notification of a finished tool request to a single URL.
+//
+// WebhookEndpointID is nil for the URL given with the ExecuteTool call.
+// Payload is the JSON of the tool request at the time it finished.
+type WebhookDelivery struct {
+	ID                int                               `json:"id" db:"id"`
+	ClientID          int                               `json:"client_id" db:"client_id"`
+	ToolRequestID     int                               `json:"tool_request_id" db:"tool_request_id"`
+	WebhookEndpointID *int                              `json:"webhook_endpoint_id" db:"webhook_endpoint_id"`
+	URL               string                            `json:"url" db:"url"`
+	Event             valueobject.WebhookEvent          `json:"event" db:"event"`
+	Payload           string                            `json:"payload" db:"payload"`
+	Status            valueobject.WebhookDeliveryStatus `json:"status" db:"status"`
+	AttemptCount      int                               `json:"attempt_count" db:"attempt_count"`
+	Attempts          []WebhookDeliveryAttempt          `json:"attempts" db:"attempts"`
+	NextAttemptAt     time.Time                         `json:"next_attempt_at" db:"next_attempt_at"`
+	LockedBy          string                            `json:"locked_by" db:"locked_by"`
+	CreatedAt         time.Time                         `json:"created_at" db:"created_at"`
+	UpdatedAt         time.Time                         `json:"updated_at" db:"updated_at"`
+}
+
+type WebhookDeliveryRow struct {
+	ID                int                `json:"id" db:"id"`
+	ClientID          int                `json:"client_id" db:"client_id"`
+	ToolRequestID     int                `json:"tool_request_id" db:"tool_request_id"`
+	WebhookEndpointID pgtype.Int4        `json:"webhook_endpoint_id" db:"webhook_endpoint_id"`
+	URL               string             `json:"url" db:"url"`
+	Event             string             `json:"event" db:"event"`
+	Payload           string             `json:"payload" db:"payload"`
+	Status            string             `json:"status" db:"status"`
+	AttemptCount      int                `json:"attempt_count" db:"attempt_count"`
+	Attempts          pgtype.Text        `json:"attempts" db:"attempts"`
+	NextAttemptAt     pgtype.Timestamptz `json:"next_attempt_at" db:"next_attempt_at"`
+	LockedBy          pgtype.Text        `json:"locked_by" db:"locked_by"`
+	CreatedAt         pgtype.Timestamptz `json:"created_at" db:"created_at"`
+	UpdatedAt         pgtype.Timestamptz `json:"updated_at" db:"updated_at"`
+}
+
+func (w *WebhookDelivery) ToDTO() *dto.ReadWebhookDeliveryDTO {
+	attempts := make([]*dto.ReadWebhookDeliveryAttemptDTO, len(w.Attempts))
+	for i, attempt := range w.Attempts {
+		attempts[i] = &dto.ReadWebhookDeliveryAttemptDTO{
+			Attempt:    attempt.Attempt,
+			StartedAt:  attempt.StartedAt,
+			FinishedAt: attempt.FinishedAt,
+			StatusCode: attempt.StatusCode,
+			Error:      attempt.Error,
+		}
+	}
+
+	var nextAttemptAt *time.Time
+	if w.Status == valueobject.WebhookDeliveryStatusPending {
+		nextAttemptAt = &w.NextAttemptAt
+	}
+
+	return &dto.ReadWebhookDeliveryDTO{
+		ID:                w.ID,
+		ClientID:          w.ClientID,
+		ToolRequestID:     w.ToolRequestID,
+		WebhookEndpointID: w.WebhookEndpointID,
+		URL:               w.URL,
+		Event:             string(w.Event),
+		Status:            string(w.Status),
+		AttemptCount:      w.AttemptCount,
+		Attempts:          attempts,
+		NextAttemptAt:     nextAttemptAt,
+		CreatedAt:         w.CreatedAt,
+		UpdatedAt:         w.UpdatedAt,
+	}
+}
+
+func (w *WebhookDeliveryRow) ToEntity() *WebhookDelivery {
+	var webhookEndpointID *int
+	if w.WebhookEndpointID.Valid {
+		id := int(w.WebhookEndpointID.Int32)
+		webhookEndpointID = &id
+	}
+
+	var attempts []WebhookDeliveryAttempt
+	if w.Attempts.Valid && w.Attempts.String != "" {
+		if err := json.Unmarshal([]byte(w.Attempts.String), &attempts); err != nil {
+			return nil
+		}
+	}
+
+	return &WebhookDelivery{
+		ID:                w.ID,
+		ClientID:          w.ClientID,
+		ToolRequestID:     w.ToolRequestID,
+		WebhookEndpointID: webhookEndpointID,
+		URL:               w.URL,
+		Event:             valueobject.WebhookEvent(w.Event),
+		Payload:           w.Payload,
+		Status:            valueobject.WebhookDeliveryStatus(w.Status),
+		AttemptCount:      w.AttemptCount,
+		Attempts:          attempts,
+		NextAttemptAt:     w.NextAttemptAt.Time,
+		LockedBy:          w.LockedBy.String,
+		CreatedAt:         w.CreatedAt.Time,
+		UpdatedAt:         w.UpdatedAt.Time,
+	}
+}
+
+func (w *WebhookDelivery) ToRow() *WebhookDeliveryRow {
+	var webhookEndpointID pgtype.Int4
+	if w.WebhookEndpointID != nil {
+		webhookEndpointID = pgtype.Int4{Int32: int32(*w.WebhookEndpointID), Valid: true}
+	}
+
+	var attempts pgtype.Text
+	if len(w.Attempts) > 0 {
+		attemptsJSON, err := json.Marshal(w.Attempts)
+		if err != nil {
+			return nil
+		}
+		attempts = pgtype.Text{String: string(attemptsJSON), Valid: true}
+	}
+
+	return &WebhookDeliveryRow{
+		ID:                w.ID,
+		ClientID:          w.ClientID,
+		ToolRequestID:     w.ToolRequestID,
+		WebhookEndpointID: webhookEndpointID,
+		URL:               w.URL,
+		Event:             w.Event.String(),
+		Payload:           w.Payload,
+		Status:            w.Status.String(),
+		AttemptCount:      w.AttemptCount,
+		Attempts:          attempts,
+		NextAttemptAt:     pgtype.Timestamptz{Time: w.NextAttemptAt, Valid: !w.NextAttemptAt.IsZero()},
+		LockedBy:          pgtype.Text{String: w.LockedBy, Valid: w.LockedBy != ""},
+		CreatedAt:         pgtype.Timestamptz{Time: w.CreatedAt},
+		UpdatedAt:         pgtype.Timestamptz{Time: w.UpdatedAt},
+	}
+}
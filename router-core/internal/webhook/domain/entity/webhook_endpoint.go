@@ -0,0 +1,92 @@
+package entity
+
+import (
+	"time"
+
+	"aigendrug.com/router-core/internal/webhook/application/dto"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+type WebhookEndpoint struct {
+	ID          int       `json:"id" db:"id"`
+	ClientID    int       `json:"client_id" db:"client_id"`
+	URL         string    `json:"url" db:"url"`
+	Description string    `json:"description" db:"description"`
+	IsActive    bool      `json:"is_active" db:"is_active"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`
+}
+
+type WebhookEndpointRow struct {
+	ID          int                `json:"id" db:"id"`
+	ClientID    int                `json:"client_id" db:"client_id"`
+	URL         string             `json:"url" db:"url"`
+	Description pgtype.Text        `json:"description" db:"description"`
+	IsActive    bool               `json:"is_active" db:"is_active"`
+	CreatedAt   pgtype.Timestamptz `json:"created_at" db:"created_at"`
+	UpdatedAt   pgtype.Timestamptz `json:"updated_at" db:"updated_at"`
+}
+
+func (w *WebhookEndpoint) ToDTO() *dto.ReadWebhookEndpointDTO {
+	return &dto.ReadWebhookEndpointDTO{
+		ID:          w.ID,
+		ClientID:    w.ClientID,
+		URL:         w.URL,
+		Description: w.Description,
+		IsActive:    w.IsActive,
+		CreatedAt:   w.CreatedAt,
+		UpdatedAt:   w.UpdatedAt,
+	}
+}
+
+func (w *WebhookEndpointRow) ToEntity() *WebhookEndpoint {
+	return &WebhookEndpoint{
+		ID:          w.ID,
+		ClientID:    w.ClientID,
+		URL:         w.URL,
+		Description: w.Description.String,
+		IsActive:    w.IsActive,
+		CreatedAt:   w.CreatedAt.Time,
+		UpdatedAt:   w.UpdatedAt.Time,
+	}
+}
+
+func (w *WebhookEndpoint) ToRow() *WebhookEndpointRow {
+	return &WebhookEndpointRow{
+		ID:          w.ID,
+		ClientID:    w.ClientID,
+		URL:         w.URL,
+		Description: pgtype.Text{String: w.Description, Valid: w.Description != ""},
+		IsActive:    w.IsActive,
+		CreatedAt:   pgtype.Timestamptz{Time: w.CreatedAt},
+		UpdatedAt:   pgtype.Timestamptz{Time: w.UpdatedAt},
+	}
+}
+
+type WebhookSecret struct {
+	ClientID  int       `json:"client_id" db:"client_id"`
+	Secret    string    `json:"secret" db:"secret"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+type WebhookSecretRow struct {
+	ClientID  int                `json:"client_id" db:"client_id"`
+	Secret    string             `json:"secret" db:"secret"`
+	CreatedAt pgtype.Timestamptz `json:"created_at" db:"created_at"`
+}
+
+func (w *WebhookSecret) ToDTO() *dto.ReadWebhookSecretDTO {
+	return &dto.ReadWebhookSecretDTO{
+		ClientID:  w.ClientID,
+		Secret:    w.Secret,
+		CreatedAt: w.CreatedAt,
+	}
+}
+
+func (w *WebhookSecretRow) ToEntity() *WebhookSecret {
+	return &WebhookSecret{
+		ClientID:  w.ClientID,
+		Secret:    w.Secret,
+		CreatedAt: w.CreatedAt.Time,
+	}
+}
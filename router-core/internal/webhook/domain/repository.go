@@ -0,0 +1,40 @@
+package domain
+
+import (
+	"context"
+	"time"
+
+	"aigendrug.com/router-core/internal/webhook/domain/entity"
+	"github.com/jackc/pgx/v5"
+)
+
+type WebhookRepository interface {
+	WithTx(ctx context.Context, tx pgx.Tx) WebhookRepository
+
+	// WebhookEndpoint
+	FindWebhookEndpointByID(ctx context.Context, id int) (*entity.WebhookEndpoint, error)
+	FindAllWebhookEndpointsByClientID(ctx context.Context, clientID int) ([]*entity.WebhookEndpoint, error)
+	CreateWebhookEndpoint(ctx context.Context, webhookEndpoint *entity.WebhookEndpoint) (*entity.WebhookEndpoint, error)
+	UpdateWebhookEndpoint(ctx context.Context, webhookEndpoint *entity.WebhookEndpoint) error
+	DeleteWebhookEndpoint(ctx context.Context, id int) error
+
+	// WebhookSecret
+	// GetOrCreateWebhookSecret stores secret as the secret of the client unless it already has one.
+	GetOrCreateWebhookSecret(ctx context.Context, clientID int, secret string) (*entity.WebhookSecret, error)
+	UpsertWebhookSecret(ctx context.Context, clientID int, secret string) (*entity.WebhookSecret, error)
+
+	// WebhookDelivery
+	FindAllWebhookDeliveriesByClientID(ctx context.Context, clientID int, limit int) ([]*entity.WebhookDelivery, error)
+	FindAllWebhookDeliveriesByToolRequestID(ctx context.Context, toolRequestID int) ([]*entity.WebhookDelivery, error)
+	// CreateWebhookDelivery returns nil when the tool request was already delivered to the URL.
+	CreateWebhookDelivery(ctx context.Context, webhookDelivery *entity.WebhookDelivery) (*entity.WebhookDelivery, error)
+	// FindUndeliveredToolRequestIDs returns the tool requests finished between finishedAfter and finishedBefore
+	// which have a webhook URL or an active endpoint of their client but no delivery, oldest first.
+	FindUndeliveredToolRequestIDs(ctx context.Context, finishedAfter, finishedBefore time.Time, limit int) ([]int, error)
+
+	// WebhookDelivery queue
+	// ClaimWebhookDelivery returns nil when no delivery is due.
+	ClaimWebhookDelivery(ctx context.Context, workerID string, lease time.Duration) (*entity.WebhookDelivery, error)
+	// UpdateClaimedWebhookDelivery reports false when the lease of the delivery was lost.
+	UpdateClaimedWebhookDelivery(ctx context.Context, webhookDelivery *entity.WebhookDelivery) (bool, error)
+}
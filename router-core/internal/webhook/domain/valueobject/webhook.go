@@ -0,0 +1,32 @@
+package valueobject
+
+type WebhookDeliveryStatus string
+
+const (
+	// waiting for its next attempt
+	WebhookDeliveryStatusPending WebhookDeliveryStatus = "pending"
+
+	// claimed by a dispatcher which is sending it
+	WebhookDeliveryStatusDelivering WebhookDeliveryStatus = "delivering"
+
+	// the endpoint answered with a 2xx status code
+	WebhookDeliveryStatusSuccess WebhookDeliveryStatus = "success"
+
+	// every attempt failed
+	WebhookDeliveryStatusFailed WebhookDeliveryStatus = "failed"
+)
+
+func (s WebhookDeliveryStatus) String() string {
+	return string(s)
+}
+
+type WebhookEvent string
+
+const (
+	// the tool request reached a terminal status (success, failed, cancelled)
+	WebhookEventToolRequestFinished WebhookEvent = "tool_request.finished"
+)
+
+func (e WebhookEvent) String() string {
+	return string(e)
+}
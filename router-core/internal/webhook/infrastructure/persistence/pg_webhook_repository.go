@@ -0,0 +1,309 @@
+package persistence
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"aigendrug.com/router-core/internal/shared/database/postgres"
+	"aigendrug.com/router-core/internal/webhook/domain"
+	"aigendrug.com/router-core/internal/webhook/domain/entity"
+	"github.com/georgysavva/scany/v2/pgxscan"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const webhookDeliveryColumns = `
+	id, client_id, tool_request_id, webhook_endpoint_id, url, event, payload, status,
+	attempt_count, attempts, next_attempt_at, locked_by, created_at, updated_at
+`
+
+type pgWebhookRepository struct {
+	db postgres.DbExecutor
+}
+
+func NewPgWebhookRepository(dbPool *pgxpool.Pool) domain.WebhookRepository {
+	return &pgWebhookRepository{db: dbPool}
+}
+
+func (r *pgWebhookRepository) WithTx(ctx context.Context, tx pgx.Tx) domain.WebhookRepository {
+	return &pgWebhookRepository{db: tx}
+}
+
+func (r *pgWebhookRepository) FindWebhookEndpointByID(
+	ctx context.Context, id int,
+) (*entity.WebhookEndpoint, error) {
+	query := `
+		SELECT id, client_id, url, description, is_active, created_at, updated_at
+		FROM webhook_endpoints
+		WHERE id = $1
+	`
+
+	var webhookEndpoint entity.WebhookEndpointRow
+	if err := pgxscan.Get(ctx, r.db, &webhookEndpoint, query, id); err != nil {
+		return nil, err
+	}
+
+	return webhookEndpoint.ToEntity(), nil
+}
+
+func (r *pgWebhookRepository) FindAllWebhookEndpointsByClientID(
+	ctx context.Context, clientID int,
+) ([]*entity.WebhookEndpoint, error) {
+	query := `
+		SELECT id, client_id, url, description, is_active, created_at, updated_at
+		FROM webhook_endpoints
+		WHERE client_id = $1
+		ORDER BY id
+	`
+
+	var webhookEndpoints []*entity.WebhookEndpointRow
+	if err := pgxscan.Select(ctx, r.db, &webhookEndpoints, query, clientID); err != nil {
+		return nil, err
+	}
+
+	webhookEndpointsEntity := make([]*entity.WebhookEndpoint, len(webhookEndpoints))
+	for i, webhookEndpoint := range webhookEndpoints {
+		webhookEndpointsEntity[i] = webhookEndpoint.ToEntity()
+	}
+
+	return webhookEndpointsEntity, nil
+}
+
+func (r *pgWebhookRepository) CreateWebhookEndpoint(
+	ctx context.Context, webhookEndpoint *entity.WebhookEndpoint,
+) (*entity.WebhookEndpoint, error) {
+	query := `
+		INSERT INTO webhook_endpoints (client_id, url, description, is_active)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, client_id, url, description, is_active, created_at, updated_at
+	`
+
+	webhookEndpointRaw := webhookEndpoint.ToRow()
+
+	var createdWebhookEndpoint entity.WebhookEndpointRow
+	if err := pgxscan.Get(ctx, r.db, &createdWebhookEndpoint, query,
+		webhookEndpointRaw.ClientID, webhookEndpointRaw.URL, webhookEndpointRaw.Description, webhookEndpointRaw.IsActive,
+	); err != nil {
+		return nil, err
+	}
+
+	return createdWebhookEndpoint.ToEntity(), nil
+}
+
+func (r *pgWebhookRepository) UpdateWebhookEndpoint(
+	ctx context.Context, webhookEndpoint *entity.WebhookEndpoint,
+) error {
+	query := `
+		UPDATE webhook_endpoints
+		SET url = $1, description = $2, is_active = $3, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $4
+	`
+
+	webhookEndpointRaw := webhookEndpoint.ToRow()
+
+	_, err := r.db.Exec(ctx, query,
+		webhookEndpointRaw.URL, webhookEndpointRaw.Description, webhookEndpointRaw.IsActive, webhookEndpointRaw.ID,
+	)
+
+	return err
+}
+
+func (r *pgWebhookRepository) DeleteWebhookEndpoint(ctx context.Context, id int) error {
+	query := `
+		DELETE FROM webhook_endpoints
+		WHERE id = $1
+	`
+
+	_, err := r.db.Exec(ctx, query, id)
+	return err
+}
+
+func (r *pgWebhookRepository) GetOrCreateWebhookSecret(
+	ctx context.Context, clientID int, secret string,
+) (*entity.WebhookSecret, error) {
+	// the no-op update makes RETURNING yield the existing row on conflict
+	query := `
+		INSERT INTO webhook_secrets (client_id, secret)
+		VALUES ($1, $2)
+		ON CONFLICT (client_id) DO UPDATE SET client_id = EXCLUDED.client_id
+		RETURNING client_id, secret, created_at
+	`
+
+	var webhookSecret entity.WebhookSecretRow
+	if err := pgxscan.Get(ctx, r.db, &webhookSecret, query, clientID, secret); err != nil {
+		return nil, err
+	}
+
+	return webhookSecret.ToEntity(), nil
+}
+
+func (r *pgWebhookRepository) UpsertWebhookSecret(
+	ctx context.Context, clientID int, secret string,
+) (*entity.WebhookSecret, error) {
+	query := `
+		INSERT INTO webhook_secrets (client_id, secret)
+		VALUES ($1, $2)
+		ON CONFLICT (client_id) DO UPDATE SET secret = EXCLUDED.secret, created_at = CURRENT_TIMESTAMP
+		RETURNING client_id, secret, created_at
+	`
+
+	var webhookSecret entity.WebhookSecretRow
+	if err := pgxscan.Get(ctx, r.db, &webhookSecret, query, clientID, secret); err != nil {
+		return nil, err
+	}
+
+	return webhookSecret.ToEntity(), nil
+}
+
+func (r *pgWebhookRepository) FindAllWebhookDeliveriesByClientID(
+	ctx context.Context, clientID int, limit int,
+) ([]*entity.WebhookDelivery, error) {
+	query := `
+		SELECT ` + webhookDeliveryColumns + `
+		FROM webhook_deliveries
+		WHERE client_id = $1
+		ORDER BY id DESC
+		LIMIT $2
+	`
+
+	return r.selectWebhookDeliveries(ctx, query, clientID, limit)
+}
+
+func (r *pgWebhookRepository) FindAllWebhookDeliveriesByToolRequestID(
+	ctx context.Context, toolRequestID int,
+) ([]*entity.WebhookDelivery, error) {
+	query := `
+		SELECT ` + webhookDeliveryColumns + `
+		FROM webhook_deliveries
+		WHERE tool_request_id = $1
+		ORDER BY id
+	`
+
+	return r.selectWebhookDeliveries(ctx, query, toolRequestID)
+}
+
+func (r *pgWebhookRepository) selectWebhookDeliveries(
+	ctx context.Context, query string, args ...any,
+) ([]*entity.WebhookDelivery, error) {
+	var webhookDeliveries []*entity.WebhookDeliveryRow
+	if err := pgxscan.Select(ctx, r.db, &webhookDeliveries, query, args...); err != nil {
+		return nil, err
+	}
+
+	webhookDeliveriesEntity := make([]*entity.WebhookDelivery, len(webhookDeliveries))
+	for i, webhookDelivery := range webhookDeliveries {
+		webhookDeliveriesEntity[i] = webhookDelivery.ToEntity()
+	}
+
+	return webhookDeliveriesEntity, nil
+}
+
+func (r *pgWebhookRepository) CreateWebhookDelivery(
+	ctx context.Context, webhookDelivery *entity.WebhookDelivery,
+) (*entity.WebhookDelivery, error) {
+	query := `
+		INSERT INTO webhook_deliveries (client_id, tool_request_id, webhook_endpoint_id, url, event, payload, status)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (tool_request_id, url) DO NOTHING
+		RETURNING ` + webhookDeliveryColumns
+
+	webhookDeliveryRaw := webhookDelivery.ToRow()
+
+	var createdWebhookDelivery entity.WebhookDeliveryRow
+	if err := pgxscan.Get(ctx, r.db, &createdWebhookDelivery, query,
+		webhookDeliveryRaw.ClientID, webhookDeliveryRaw.ToolRequestID, webhookDeliveryRaw.WebhookEndpointID,
+		webhookDeliveryRaw.URL, webhookDeliveryRaw.Event, webhookDeliveryRaw.Payload, webhookDeliveryRaw.Status,
+	); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return createdWebhookDelivery.ToEntity(), nil
+}
+
+func (r *pgWebhookRepository) FindUndeliveredToolRequestIDs(
+	ctx context.Context, finishedAfter, finishedBefore time.Time, limit int,
+) ([]int, error) {
+	query := `
+		SELECT tr.id
+		FROM tool_requests tr
+		WHERE 
+			tr.status IN ('success', 'failed', 'cancelled')
+			AND tr.updated_at >= $1 AND tr.updated_at < $2
+			AND NOT EXISTS (SELECT 1 FROM webhook_deliveries wd WHERE wd.tool_request_id = tr.id)
+			AND (
+				COALESCE(NULLIF(tr.request_data, '')::jsonb->>'webhook_url', '') <> ''
+				OR EXISTS (
+					SELECT 1
+					FROM webhook_endpoints we
+					WHERE we.client_id = tr.client_id AND we.is_active AND we.created_at <= tr.updated_at
+				)
+			)
+		ORDER BY tr.updated_at, tr.id
+		LIMIT $3`
+
+	var toolRequestIDs []int
+	if err := pgxscan.Select(ctx, r.db, &toolRequestIDs, query, finishedAfter, finishedBefore, limit); err != nil {
+		return nil, err
+	}
+
+	return toolRequestIDs, nil
+}
+
+func (r *pgWebhookRepository) ClaimWebhookDelivery(
+	ctx context.Context, workerID string, lease time.Duration,
+) (*entity.WebhookDelivery, error) {
+	query := `
+		UPDATE webhook_deliveries
+		SET 
+			status = 'delivering', locked_by = $1,
+			lease_expires_at = CURRENT_TIMESTAMP + make_interval(secs => $2), updated_at = CURRENT_TIMESTAMP
+		WHERE id = (
+			SELECT id
+			FROM webhook_deliveries
+			WHERE 
+				(status = 'pending' AND next_attempt_at <= CURRENT_TIMESTAMP)
+				OR (status = 'delivering' AND lease_expires_at < CURRENT_TIMESTAMP)
+			ORDER BY next_attempt_at, id
+			FOR UPDATE SKIP LOCKED
+			LIMIT 1
+		)
+		RETURNING ` + webhookDeliveryColumns
+
+	var webhookDelivery entity.WebhookDeliveryRow
+	if err := pgxscan.Get(ctx, r.db, &webhookDelivery, query, workerID, lease.Seconds()); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return webhookDelivery.ToEntity(), nil
+}
+
+func (r *pgWebhookRepository) UpdateClaimedWebhookDelivery(
+	ctx context.Context, webhookDelivery *entity.WebhookDelivery,
+) (bool, error) {
+	query := `
+		UPDATE webhook_deliveries
+		SET 
+			status = $1, attempt_count = $2, attempts = $3, next_attempt_at = $4,
+			locked_by = NULL, lease_expires_at = NULL, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $5 AND status = 'delivering' AND locked_by = $6
+	`
+
+	webhookDeliveryRaw := webhookDelivery.ToRow()
+
+	tag, err := r.db.Exec(ctx, query,
+		webhookDeliveryRaw.Status, webhookDeliveryRaw.AttemptCount, webhookDeliveryRaw.Attempts,
+		webhookDeliveryRaw.NextAttemptAt, webhookDeliveryRaw.ID, webhookDeliveryRaw.LockedBy,
+	)
+	if err != nil {
+		return false, err
+	}
+
+	return tag.RowsAffected() == 1, nil
+}
@@ -1,6 +1,6 @@
 from fastapi import APIRouter, HTTPException
 from models import SelectRequest, SelectResponse
-from services import tool_selector_service
+from services import tool_selector_service, NoCandidateToolError
 
 router = APIRouter()
 
@@ -10,6 +10,8 @@ async def select_tool(request: SelectRequest):
     try:
         response = await tool_selector_service.select_tool(request)
         return response
+    except NoCandidateToolError as e:
+        raise HTTPException(status_code=404, detail=str(e))
     except ValueError as e:
         raise HTTPException(status_code=400, detail=str(e))
     except Exception as e:
@@ -12,7 +12,7 @@ class ToolRepository:
         return psycopg2.connect(self.connection_string)
 
     async def get_all_tools(self) -> List[Tool]:
-        """Retrieve all tools from database"""
+        """Retrieve all published tools from database (drafts are not offered to clients)"""
         with self._get_connection() as conn:
             with conn.cursor(cursor_factory=RealDictCursor) as cursor:
                 cursor.execute("""
@@ -20,6 +20,7 @@ class ToolRepository:
                            engine_interface, provider_interface, 
                            created_at, updated_at
                     FROM tools 
+                    WHERE NOT draft
                     ORDER BY created_at DESC
                 """)
                 rows = cursor.fetchall()
@@ -48,7 +49,7 @@ class ToolRepository:
                            engine_interface, provider_interface, 
                            created_at, updated_at
                     FROM tools 
-                    WHERE name = %s
+                    WHERE name = %s AND NOT draft
                     LIMIT 1
                 """, (name,))
                 row = cursor.fetchone()
@@ -1,5 +1,5 @@
 from dataclasses import dataclass
-from typing import Optional
+from typing import List, Optional
 from datetime import datetime
 from pydantic import BaseModel
 
@@ -17,6 +17,10 @@ class Tool:
 
 class SelectRequest(BaseModel):
     user_prompt: str
+    # tools which must not be selected (e.g. unhealthy tools)
+    excluded_tool_ids: List[int] = []
+    # tools offered only when too few other tools match the prompt
+    demoted_tool_ids: List[int] = []
     
 class SelectResponse(BaseModel):
     tool_id: int
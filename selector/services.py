@@ -1,4 +1,4 @@
-from typing import List
+from typing import List, Set
 from sentence_transformers import SentenceTransformer
 from sklearn.metrics.pairwise import cosine_similarity
 import numpy as np
@@ -7,6 +7,10 @@ from models import Tool, SelectRequest, SelectResponse
 from database import tool_repository
 from openai_service import model_service
 
+class NoCandidateToolError(ValueError):
+    """No tool is left to select once the excluded tools are removed"""
+
+
 class ToolSelectorService:
     def __init__(self):
         self.tool_repository = tool_repository
@@ -15,18 +19,27 @@ class ToolSelectorService:
 
     async def select_tool(self, request: SelectRequest) -> SelectResponse:
         """Select the most appropriate tool for user prompt"""
-        all_tools = await self.tool_repository.get_all_tools()
+        excluded_tool_ids = set(request.excluded_tool_ids)
+        all_tools = [
+            tool for tool in await self.tool_repository.get_all_tools()
+            if tool.id not in excluded_tool_ids
+        ]
         
         if not all_tools:
-            raise ValueError("No tools available")
+            raise NoCandidateToolError("No tools available")
         
-        candidate_tools = self._select_candidate_tools(request.user_prompt, all_tools)
+        candidate_tools = self._select_candidate_tools(
+            request.user_prompt, all_tools, set(request.demoted_tool_ids)
+        )
         
         selected_tool_name = await self.model_service.select_best_tool(
             request.user_prompt, candidate_tools
         )
         
-        selected_tool = await self.tool_repository.get_tool_by_name(selected_tool_name)
+        # resolved among the candidates, an excluded tool of the same name must not be returned
+        selected_tool = next((tool for tool in candidate_tools if tool.name == selected_tool_name), None)
+        if selected_tool is None:
+            raise ValueError(f"Tool with name '{selected_tool_name}' is not a candidate")
         
         explanation_message = await self.model_service.generate_selection_message(
             request.user_prompt, selected_tool
@@ -34,18 +47,31 @@ class ToolSelectorService:
         
         return SelectResponse(tool_id=selected_tool.id, message=explanation_message)
 
-    def _select_candidate_tools(self, user_prompt: str, all_tools: List[Tool], top_k: int = 5) -> List[Tool]:
-        """Select top K similar tools using SentenceTransformer embedding similarity"""
-        if len(all_tools) <= top_k:
-            return all_tools
+    def _select_candidate_tools(
+        self, user_prompt: str, all_tools: List[Tool], demoted_tool_ids: Set[int], top_k: int = 5
+    ) -> List[Tool]:
+        """Select top K similar tools using SentenceTransformer embedding similarity.
+        Demoted tools rank after the other tools, they fill the candidates left."""
+        preferred_tools = [tool for tool in all_tools if tool.id not in demoted_tool_ids]
+        demoted_tools = [tool for tool in all_tools if tool.id in demoted_tool_ids]
+
+        candidate_tools = self._rank_tools(user_prompt, preferred_tools, top_k)
+        if len(candidate_tools) < top_k:
+            candidate_tools += self._rank_tools(user_prompt, demoted_tools, top_k - len(candidate_tools))
+        return candidate_tools
+
+    def _rank_tools(self, user_prompt: str, tools: List[Tool], top_k: int) -> List[Tool]:
+        """Top K tools by embedding similarity with the user prompt"""
+        if len(tools) <= top_k:
+            return tools
             
-        tool_descriptions = [tool.description or "" for tool in all_tools]
+        tool_descriptions = [tool.description or "" for tool in tools]
         tool_embeddings = self.embedding_model.encode(tool_descriptions)
         user_embedding = self.embedding_model.encode([user_prompt])[0]
         
         similarities = cosine_similarity([user_embedding], tool_embeddings)[0]
         
         top_indices = np.argsort(similarities)[-top_k:][::-1]
-        return [all_tools[i] for i in top_indices]
+        return [tools[i] for i in top_indices]
 
 tool_selector_service = ToolSelectorService() 
\ No newline at end of file